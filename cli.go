@@ -0,0 +1,1079 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/consumer"
+	"github.com/hayletdomybest/mongo-tools/copier"
+	"github.com/hayletdomybest/mongo-tools/exporter"
+	"github.com/hayletdomybest/mongo-tools/generate"
+	"github.com/hayletdomybest/mongo-tools/gridfs"
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"github.com/hayletdomybest/mongo-tools/internal/cron"
+	"github.com/hayletdomybest/mongo-tools/internal/manifest"
+	"github.com/hayletdomybest/mongo-tools/verify"
+)
+
+const usage = `Usage: mongo-tools <command> [flags]
+
+Commands:
+  import   Import Extended JSON, BSON dump, mongodump archive, CSV/TSV, YAML and SQL dump (INSERT statements) files into MongoDB (default)
+  export   Export every collection to <collection>.json plus a mongodump-compatible <collection>.metadata.json and a checksum manifest.json
+  drop     Drop the collection(s) given by --collection
+  diff     Compare a file (or --source-db) with the live collection; exits 2 when they differ
+  verify   Check that each file's document count (and --hash content) matches its collection; exits 2 on mismatch
+  copy     Copy collections from --source-uri / --source-db straight into --uri / --db
+  sync     Copy like copy, then keep applying the source's changes (change stream) until stopped
+  tail     Append the change stream of --collection as NDJSON to --out (a file or - for stdout) until stopped
+  generate Fill collections with fake data from a --schema file, or write it to --out as .json files
+  consume  Write the Extended JSON messages of a Kafka --topic into --collection until stopped
+  ping     Check connectivity, the authenticated user and write access to --db (inserts and deletes a test document)
+  stats    Print document counts, average document size, storage and index sizes of the collections in --db
+  snapshot Save every collection of --db with its indexes, options and views to a timestamped directory under --dir
+  restore  Bring --db back to a snapshot under --dir (the latest unless --snapshot is given), replacing the collections it contains
+  serve    Run an HTTP API (POST /import, GET /status/<job>) that queues imports using the import flags as defaults
+
+Flags override the values from the environment / .env file. The .env is optional;
+--env-file <path> loads another file instead and --no-env skips it.
+--profile <name> applies a profile from mongo-tools.yaml (see mongo-tools.example.yaml)
+on top of the environment; flags still override it.
+import / export --gridfs <bucket> move binary files between --path and a GridFS bucket.
+import --plan <file> runs the steps of an import plan (see import-plan.example.yaml) instead of --path.
+import --schedule "0 3 * * *" stays running and repeats the import (or plan) on a cron schedule.
+stats --out before.json before an import and stats --compare before.json after it show what the load changed.
+export writes a manifest.json with the SHA-256 and document count of every file; import refuses a directory that does not match it unless --no-verify.
+export / snapshot --encryption-key (or --kms-data-key) write AES-GCM encrypted <file>.enc data files; import / restore decrypt them with the same key.
+export --resume continues an interrupted export after the last _id written instead of starting over.
+export --parallel 8 exports large collections as 8 _id ranges at once; --keep-shards keeps them as <collection>.shard-0001.json, ...
+snapshot --keep 7 --keep-days 30 deletes the older snapshots of --db after a successful one, for scheduled snapshot jobs.
+Run "mongo-tools <command> -h" for the flags of a command.
+`
+
+const extJSONModeUsage = `how .json files are parsed (env EXTJSON_MODE):
+relaxed    accept both canonical and relaxed Extended JSON, e.g. {"$date": "2024-01-02T03:04:05Z"}
+canonical  accept only canonical Extended JSON, e.g. {"$date": {"$numberLong": "1704164645000"}}; anything else is a parse error
+auto       try canonical first and fall back to relaxed`
+
+// earlyFlags 在解析旗標之前就要知道的旗標：其他旗標的預設值來自環境變數，.env 與 profile 必須先載入
+type earlyFlags struct {
+	Profile, ProfileFile string
+	EnvFile              string
+	EnvFileSet           bool // 有指定 --env-file（可以是空字串）
+	NoEnv                bool
+}
+
+// preParse 先找出 --profile、--profile-file、--env-file 與 --no-env，其餘旗標留給 parseArgs
+func preParse(args []string) earlyFlags {
+	var early earlyFlags
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		key, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch key {
+		case "no-env":
+			early.NoEnv = !hasValue || value == "true" || value == "1"
+			continue
+		case "profile", "profile-file", "env-file":
+		default:
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		switch key {
+		case "profile":
+			early.Profile = value
+		case "profile-file":
+			early.ProfileFile = value
+		case "env-file":
+			early.EnvFile, early.EnvFileSet = value, true
+		}
+	}
+	return early
+}
+
+// config 匯集 .env／環境變數與命令列旗標，旗標優先
+type config struct {
+	URI        string
+	DB         string
+	Path       string
+	Collection string
+
+	WriteConcern   string // w：數字、majority 或 tag
+	Journal        string // 空字串表示沿用 URI
+	WTimeout       time.Duration
+	ReadPreference string
+
+	MaxPoolSize            int           // 0 表示沿用 URI（或 driver 預設的 100）
+	SocketTimeout          time.Duration // 0 表示沿用 URI
+	ServerSelectionTimeout time.Duration // 0 表示沿用 URI（或 driver 預設的 30s）
+	Compressors            string        // 以逗號分隔的 zstd、zlib、snappy，依偏好排序
+
+	OpTimeout  time.Duration // 每個資料庫操作（連線、清空、每批寫入）的 timeout，0 表示不限制
+	RunTimeout time.Duration // 整次執行的 timeout，0 表示不限制
+
+	JobMode        bool          // --job：Kubernetes Job 用，不詢問、JSON log、等待 MongoDB、寫出 termination message
+	WaitForMongo   time.Duration // 初次連線失敗時重試多久，0 表示不重試
+	TerminationLog string
+
+	TLS         bool
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string // 空字串表示私鑰在 TLSCertFile 內
+	TLSInsecure bool
+	X509        bool
+
+	AuthMechanism  string
+	Username       string
+	Password       string
+	AuthSource     string
+	AWSRoleARN     string
+	AWSSessionName string
+
+	LogFormat       string
+	LogLevel        string
+	Verbose         int           // -v：1（debug log），-vv：2（再加上 driver 的指令、連線池與 server selection 記錄）
+	SlowCommand     time.Duration // 超過這個時間的 driver 指令記一筆警告，0 表示不記錄
+	MappingFile     string
+	Include         string
+	Exclude         string
+	DependsOn       string
+	Strategies      string
+	Mode            string // import：mongoimport 相容的 --mode，轉換成 Strategy
+	UpsertFields    string // import：mongoimport 相容的 --upsertFields，等同 --key
+	Stamp           bool
+	StampHashField  string
+	StampTimeField  string
+	Drift           bool // verify：檢查匯入後在資料庫內被修改過的文件
+	Scope           string
+	TenantValue     string
+	Delimiter       string
+	FieldHints      string
+	Filter          string
+	OnlyFields      string
+	DropFields      string
+	Coerce          string
+	CoerceIDFields  string
+	ShiftDates      string
+	ShiftDatesField string
+	Transform       string
+	MaskFile        string
+	HooksFile       string
+	PlanFile        string
+	ScheduleExpr    string         // --schedule 的 cron 表示式
+	Schedule        *cron.Schedule // 解析後的 --schedule，nil 表示只執行一次
+	ScheduleStatus  string
+	Plan            *importer.Plan // import：--plan 載入的步驟
+	Watch           bool
+	ReportFile      string
+	SummaryFormat   string // summaryTable、summaryJSON 或 summaryCSV
+	NotifyWebhook   string // 匯入結束後 POST 結果的 URL
+	NotifySlack     string // Slack incoming webhook URL
+	NotifyOn        string // notifyAlways 或 notifyFailure
+	Stdin           bool
+	Ordered         bool
+	Yes             bool // 略過破壞性操作的確認
+	AllowProd       bool // 允許對看起來是正式環境的 URI 執行破壞性操作
+
+	Query      string // export / copy：Extended JSON 查詢條件
+	Projection string
+	QueryFile  string
+	Rename     string // copy：<來源>:<目標>,...；import / verify：<來源>=<目標>,...
+	GridFS     string // import / export：改為在目錄與這個 GridFS bucket 之間搬移檔案
+
+	GenerateSchemaFile string
+	GenerateSchema     *generate.Schema
+	GenerateOut        string // generate：寫成檔案的目錄，不連線到 MongoDB
+
+	TailOut           string // tail：附加寫入的檔案，- 表示 stdout
+	MetricsAddr       string // watch、sync、tail、serve：/metrics 的監聽位址
+	TailFullDocuments bool
+
+	EncryptionKey     string // export / snapshot 加密、import / restore / verify / diff 解密的 base64 金鑰
+	EncryptionKeyFile string
+	KMSDataKey        string // KMS 加密過的 data key（base64），執行時以 KMS Decrypt 解開
+
+	SnapshotDir string // snapshot / restore：存放 snapshot 的目錄
+	Snapshot    string // restore：要還原的 snapshot，空字串表示最新的
+	DropExtra   bool   // restore：還原後刪除 snapshot 沒有的 collection 與 view
+
+	SnapshotKeep     int // snapshot：只保留最新的幾份，0 表示不限制
+	SnapshotKeepDays int // snapshot：保留幾天內的，0 表示不限制
+
+	StatsOut     string // stats：把結果存成 JSON
+	StatsCompare string // stats：與之前 --out 存的 JSON 比較
+
+	ServeAddr      string // serve：HTTP API 的監聽位址
+	ServeToken     string // serve：非空時每個 request 都要帶 Authorization: Bearer <token>；監聽 loopback 以外的位址時必須設定
+	ServeMaxUpload int64  // serve：POST /import 的 body 上限（位元組）
+
+	KafkaBrokers  string // consume：host:port,...
+	KafkaTLS      bool
+	KafkaSASL     string // plain、scram-sha-256 或 scram-sha-512
+	KafkaUsername string
+	KafkaPassword string
+
+	SourceURI string // diff：來源資料庫
+	SourceDB  string
+	Delta     string
+	Import    importer.Options
+	Export    exporter.Options
+	Copy      copier.Options
+	Verify    verify.Options
+	Generate  generate.Options
+	Consume   consumer.Options
+}
+
+// parseArgs 解析子命令與旗標；沒給子命令時沿用 MODE 環境變數（預設 import）
+func parseArgs(args []string) (string, config) {
+	cmd := os.Getenv("MODE")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	if cmd == "" {
+		cmd = "import"
+	}
+
+	switch cmd {
+	case "import", "export", "drop", "diff", "copy", "sync", "verify", "tail", "generate", "consume", "ping", "stats", "snapshot", "restore":
+	case "serve":
+	case "help":
+		fmt.Print(usage)
+		os.Exit(0)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		log.Fatalf("Unknown command: %s", cmd)
+	}
+
+	var cfg config
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	// serve 接受 import 的所有旗標，作為每個 job 的預設設定
+	serving := cmd == "serve"
+	if serving {
+		cmd = "import"
+		fs.StringVar(&cfg.ServeAddr, "listen", envOr("SERVE_ADDR", "127.0.0.1:8080"), "address the HTTP API listens on; anything but a loopback address requires --serve-token (env SERVE_ADDR)")
+		fs.StringVar(&cfg.ServeToken, "serve-token", os.Getenv("SERVE_TOKEN"), "require Authorization: Bearer <token> on every request; prefer the env var (env SERVE_TOKEN)")
+		fs.Int64Var(&cfg.ServeMaxUpload, "max-upload-mb", int64(envInt("SERVE_MAX_UPLOAD_MB", 1024)), "largest POST /import body, in MiB (env SERVE_MAX_UPLOAD_MB)")
+	}
+	fs.StringVar(&cfg.URI, "uri", os.Getenv("MONGO_URI"), "MongoDB connection URI (env MONGO_URI)")
+	fs.StringVar(&cfg.DB, "db", os.Getenv("MONGO_DB"), "target database (env MONGO_DB)")
+	fs.StringVar(&cfg.Path, "path", os.Getenv("JSON_PATH"), "file, directory, http(s):// URL or s3:// URL (prefix when ending in /) to import; output directory for export (env JSON_PATH)")
+	fs.String("env-file", envOr("ENV_FILE", ".env"), "file with environment variables to load before the flags; a missing default .env is ignored (env ENV_FILE)")
+	fs.Bool("no-env", false, "do not load any env file; use only the environment and flags")
+	fs.String("profile", os.Getenv("PROFILE"), "apply the named profile from --profile-file before the flags (env PROFILE)")
+	fs.String("profile-file", envOr("PROFILE_FILE", defaultProfileFile), "YAML file with profiles: { <name>: { uri, db, path, strategy, env } } (env PROFILE_FILE)")
+	fs.StringVar(&cfg.LogFormat, "log-format", envOr("LOG_FORMAT", "text"), "text or json (env LOG_FORMAT)")
+	fs.StringVar(&cfg.LogLevel, "log-level", envOr("LOG_LEVEL", "info"), "debug, info, warn or error (env LOG_LEVEL)")
+	verbose := fs.Bool("v", false, "verbose: debug logging, same as --log-level debug")
+	veryVerbose := fs.Bool("vv", false, "very verbose: -v plus every driver command, failed and retried commands, connection pool resets and server selection")
+	fs.DurationVar(&cfg.SlowCommand, "slow-command", envDuration("SLOW_COMMAND", 0), "log a warning for every MongoDB command that takes longer than this, e.g. 5s; 0 disables (env SLOW_COMMAND)")
+	fs.StringVar(&cfg.WriteConcern, "write-concern", os.Getenv("WRITE_CONCERN"), "write concern w: a number, majority or a tag set name (env WRITE_CONCERN)")
+	fs.StringVar(&cfg.Journal, "journal", os.Getenv("WRITE_JOURNAL"), "require journal acknowledgment, true or false; empty keeps the URI setting (env WRITE_JOURNAL)")
+	fs.DurationVar(&cfg.WTimeout, "wtimeout", envDuration("WRITE_TIMEOUT", 0), "write concern timeout, e.g. 5s (env WRITE_TIMEOUT)")
+	fs.StringVar(&cfg.ReadPreference, "read-preference", os.Getenv("READ_PREFERENCE"), "primary, primaryPreferred, secondary, secondaryPreferred or nearest (env READ_PREFERENCE)")
+	fs.IntVar(&cfg.MaxPoolSize, "max-pool-size", envInt("MAX_POOL_SIZE", 0), "maximum connections per server; 0 keeps the URI setting or the driver default of 100 (env MAX_POOL_SIZE)")
+	fs.DurationVar(&cfg.SocketTimeout, "socket-timeout", envDuration("SOCKET_TIMEOUT", 0), "how long a read or write on a connection may block, e.g. 2m for slow WAN links; 0 keeps the URI setting (env SOCKET_TIMEOUT)")
+	fs.DurationVar(&cfg.ServerSelectionTimeout, "server-selection-timeout", envDuration("SERVER_SELECTION_TIMEOUT", 0), "how long to wait for a suitable server before failing an operation; 0 keeps the URI setting or the driver default of 30s (env SERVER_SELECTION_TIMEOUT)")
+	fs.StringVar(&cfg.Compressors, "compressors", os.Getenv("COMPRESSORS"), "comma-separated wire compressors in order of preference: zstd, zlib, snappy; the server picks the first one it supports (env COMPRESSORS)")
+	fs.DurationVar(&cfg.OpTimeout, "op-timeout", envDuration("OP_TIMEOUT", 5*time.Minute), "timeout of each database operation: connecting, clearing a collection, writing a batch; 0 disables (env OP_TIMEOUT)")
+	fs.BoolVar(&cfg.JobMode, "job", envBool("JOB_MODE"), "run as a Kubernetes Job: JSON logs unless --log-format is given, never prompt, wait for MongoDB (default "+defaultJobWait.String()+") and write the outcome to --termination-log (env JOB_MODE)")
+	fs.DurationVar(&cfg.WaitForMongo, "wait-for-mongo", envDuration("WAIT_FOR_MONGO", 0), "keep retrying the initial connection for this long, e.g. 2m, instead of failing at once; exits with code 3 when it gives up (env WAIT_FOR_MONGO)")
+	fs.StringVar(&cfg.TerminationLog, "termination-log", envOr("TERMINATION_LOG", defaultTerminationLog), "with --job, write the exit code and the last error or message to this file if it exists (env TERMINATION_LOG)")
+	fs.DurationVar(&cfg.RunTimeout, "run-timeout", envDuration("RUN_TIMEOUT", 0), "timeout of the whole run, e.g. 2h; 0 disables (env RUN_TIMEOUT)")
+	fs.BoolVar(&cfg.TLS, "tls", envBool("TLS"), "connect with TLS; implied by the other --tls-* flags (env TLS)")
+	fs.StringVar(&cfg.TLSCAFile, "tls-ca-file", os.Getenv("TLS_CA_FILE"), "PEM file with the certificate authorities used to verify the server (env TLS_CA_FILE)")
+	fs.StringVar(&cfg.TLSCertFile, "tls-cert-file", os.Getenv("TLS_CERT_FILE"), "PEM client certificate; may also contain the private key (env TLS_CERT_FILE)")
+	fs.StringVar(&cfg.TLSKeyFile, "tls-key-file", os.Getenv("TLS_KEY_FILE"), "PEM private key of the client certificate when it is not in --tls-cert-file (env TLS_KEY_FILE)")
+	fs.BoolVar(&cfg.TLSInsecure, "tls-insecure", envBool("TLS_INSECURE"), "skip verification of the server certificate and host name; testing only (env TLS_INSECURE)")
+	fs.BoolVar(&cfg.X509, "x509", envBool("AUTH_X509"), "authenticate with the client certificate (MONGODB-X509) (env AUTH_X509)")
+	fs.StringVar(&cfg.AuthMechanism, "auth-mechanism", os.Getenv("AUTH_MECHANISM"), "SCRAM-SHA-1, SCRAM-SHA-256, MONGODB-X509, MONGODB-AWS, PLAIN (LDAP) or GSSAPI; empty keeps the URI setting (env AUTH_MECHANISM)")
+	fs.StringVar(&cfg.Username, "username", os.Getenv("MONGO_USERNAME"), "user name; overrides the one in the URI (env MONGO_USERNAME)")
+	fs.StringVar(&cfg.Password, "password", os.Getenv("MONGO_PASSWORD"), "password; prefer the MONGO_PASSWORD env var so it does not show up in the process list (env MONGO_PASSWORD)")
+	fs.StringVar(&cfg.AuthSource, "auth-source", os.Getenv("AUTH_SOURCE"), "database holding the user; defaults to $external for MONGODB-X509, MONGODB-AWS, PLAIN and GSSAPI (env AUTH_SOURCE)")
+	fs.StringVar(&cfg.AWSRoleARN, "aws-role-arn", os.Getenv("MONGO_AWS_ROLE_ARN"), "IAM role to assume through STS for MONGODB-AWS (env MONGO_AWS_ROLE_ARN)")
+	fs.StringVar(&cfg.AWSSessionName, "aws-session-name", envOr("MONGO_AWS_SESSION_NAME", "mongo-tools"), "role session name used with --aws-role-arn (env MONGO_AWS_SESSION_NAME)")
+	fs.StringVar(&cfg.Collection, "collection", "", "target collection for a single file, or only this collection for a directory / export; comma-separated for drop and copy; the test collection for ping (default "+defaultPingCollection+"); comma-separated for stats")
+
+	if cmd == "import" || cmd == "export" || cmd == "verify" || cmd == "diff" || cmd == "snapshot" || cmd == "restore" {
+		fs.StringVar(&cfg.EncryptionKey, "encryption-key", os.Getenv("ENCRYPTION_KEY"), "base64 AES-256 key (openssl rand -base64 32); export and snapshot encrypt their data files with AES-GCM as <file>.enc, import, restore, verify and diff decrypt .enc files; prefer the env var (env ENCRYPTION_KEY)")
+		fs.StringVar(&cfg.EncryptionKeyFile, "encryption-key-file", os.Getenv("ENCRYPTION_KEY_FILE"), "file holding the base64 key, e.g. a mounted secret (env ENCRYPTION_KEY_FILE)")
+		fs.StringVar(&cfg.KMSDataKey, "kms-data-key", os.Getenv("ENCRYPTION_KMS_DATA_KEY"), "base64 CiphertextBlob of an AWS KMS data key (aws kms generate-data-key --key-spec AES_256); decrypted with KMS at startup using the standard AWS_* credentials (env ENCRYPTION_KMS_DATA_KEY)")
+	}
+
+	if cmd == "import" || cmd == "export" {
+		fs.StringVar(&cfg.GridFS, "gridfs", os.Getenv("GRIDFS_BUCKET"), "move binary files between --path and this GridFS bucket instead of importing / exporting collections; attributes are kept in "+gridfs.ManifestFile+" (env GRIDFS_BUCKET)")
+	}
+
+	if cmd == "import" || cmd == "drop" || cmd == "copy" || cmd == "sync" || cmd == "generate" || cmd == "restore" {
+		fs.BoolVar(&cfg.Yes, "yes", envBool("ASSUME_YES"), "do not ask for confirmation before clearing or dropping collections (env ASSUME_YES)")
+		fs.BoolVar(&cfg.AllowProd, "allow-prod", envBool("ALLOW_PROD"), "allow clearing or dropping collections when the host or database looks like production (env ALLOW_PROD)")
+	}
+
+	if cmd == "import" {
+		fs.StringVar(&cfg.Import.Strategy, "strategy", envOr("IMPORT_STRATEGY", importer.StrategyTruncate), "truncate (not atomic without --atomic-swap or --transactional), upsert, merge, append (insert without clearing) or delete (delete the documents whose --key matches a document of the file) (env IMPORT_STRATEGY)")
+		fs.StringVar(&cfg.Mode, "mode", os.Getenv("IMPORT_MODE"), "mongoimport-compatible alternative to --strategy: insert (append, skipping duplicate keys), upsert, merge (upsert that $sets the file's fields) or delete (env IMPORT_MODE)")
+		fs.StringVar(&cfg.UpsertFields, "upsertFields", os.Getenv("UPSERT_FIELDS"), "mongoimport-compatible form of --key; without --mode it implies --mode upsert (env UPSERT_FIELDS)")
+		fs.StringVar(&cfg.Strategies, "strategies", os.Getenv("IMPORT_STRATEGIES"), "comma-separated <collection>:<strategy> overrides of --strategy; collection names may be globs and the first match wins, e.g. lookup_codes:truncate,users:merge,audit_*:append (env IMPORT_STRATEGIES)")
+		fs.StringVar(&cfg.Scope, "scope", os.Getenv("IMPORT_SCOPE"), `with the truncate strategy, delete only the documents matching this Extended JSON query before inserting instead of clearing the collection, e.g. '{"tenantId": "acme"}' (env IMPORT_SCOPE)`)
+		fs.StringVar(&cfg.Import.TenantField, "tenant-field", os.Getenv("TENANT_FIELD"), "set this field (a.b path) to --tenant-value on every document, so one fixture set can seed many tenants; without --scope the truncate strategy only deletes that tenant's documents. Keys such as _id must still be unique across tenants (env TENANT_FIELD)")
+		fs.StringVar(&cfg.TenantValue, "tenant-value", os.Getenv("TENANT_VALUE"), "tenant identifier written to --tenant-field (env TENANT_VALUE)")
+		fs.StringVar(&cfg.Import.KeyField, "key", envOr("IMPORT_KEY", "_id"), "key field used by the upsert, merge and delete strategies; comma-separated fields (a.b paths) match on all of them, e.g. tenantId,email (env IMPORT_KEY)")
+		fs.BoolVar(&cfg.Import.MergeUpdate, "merge-update", envBool("MERGE_UPDATE"), "with the merge strategy, $set the file's fields on existing documents instead of leaving them untouched (env MERGE_UPDATE)")
+		fs.IntVar(&cfg.Import.Concurrency, "concurrency", envInt("CONCURRENCY", 1), "number of files imported in parallel (env CONCURRENCY)")
+		fs.IntVar(&cfg.Import.InsertWorkers, "insert-workers", envInt("INSERT_WORKERS", 1), "batches of a single file inserted in parallel, unordered (env INSERT_WORKERS)")
+		fs.BoolVar(&cfg.Ordered, "ordered", !envBool("UNORDERED"), "stop each insert batch at the first error; --ordered=false keeps inserting the rest of the batch (env UNORDERED=true)")
+		fs.BoolVar(&cfg.Import.IgnoreDuplicates, "ignore-duplicates", envBool("IGNORE_DUPLICATES"), "skip documents that fail with a duplicate key error and report how many; implies --ordered=false (env IGNORE_DUPLICATES)")
+		fs.StringVar(&cfg.Import.DedupeBy, "dedupe-by", os.Getenv("DEDUPE_BY"), "drop documents of a file that repeat this key (a.b path), e.g. _id, and report how many were dropped (env DEDUPE_BY)")
+		fs.StringVar(&cfg.Import.DedupeKeep, "dedupe-keep", envOr("DEDUPE_KEEP", importer.DedupeLast), "which occurrence --dedupe-by keeps: last (reads the whole file into memory) or first (env DEDUPE_KEEP)")
+		fs.BoolVar(&cfg.Import.PreserveOrder, "preserve-order", envBool("PRESERVE_ORDER"), "keep the field order of JSON / BSON documents as in the file instead of Go map order (env PRESERVE_ORDER)")
+		fs.StringVar(&cfg.MappingFile, "mapping", os.Getenv("MAPPING_FILE"), "YAML/JSON file mapping file paths or globs to collections (env MAPPING_FILE)")
+		fs.BoolVar(&cfg.Import.DBFromFilename, "db-from-filename", envBool("DB_FROM_FILENAME"), "take the database from <db>.<collection>.json file names (env DB_FROM_FILENAME)")
+		fs.BoolVar(&cfg.Import.Recursive, "recursive", envBool("RECURSIVE"), "include subdirectories; files in <path>/<db>/ go to database <db>, as laid out by mongodump (env RECURSIVE)")
+		fs.StringVar(&cfg.Include, "include", os.Getenv("IMPORT_INCLUDE"), "comma-separated globs on collection names; only matching files of a directory are imported, e.g. users,orders_* (env IMPORT_INCLUDE)")
+		fs.StringVar(&cfg.Exclude, "exclude", os.Getenv("IMPORT_EXCLUDE"), "comma-separated globs on collection names to skip in a directory, e.g. logs,analytics; wins over --include (env IMPORT_EXCLUDE)")
+		fs.StringVar(&cfg.DependsOn, "depends-on", os.Getenv("IMPORT_DEPENDS_ON"), "comma-separated <collection>:<dependency> pairs; files of a directory are imported after the collections they depend on and skipped when those fail, e.g. users:tenants,orders:users (env IMPORT_DEPENDS_ON)")
+		fs.StringVar(&cfg.Import.CollectionPrefix, "collection-prefix", os.Getenv("IMPORT_COLLECTION_PREFIX"), "write every collection and view under this prefix, e.g. staging_ to load fixtures next to the real data; strategies, transforms, masks and collection filters still use the original names (env IMPORT_COLLECTION_PREFIX)")
+		fs.StringVar(&cfg.Rename, "rename", os.Getenv("IMPORT_RENAME"), "write collections under another name, e.g. users=users_b,orders=orders_b; applied before --collection-prefix (env IMPORT_RENAME)")
+		fs.StringVar(&cfg.Delimiter, "delimiter", os.Getenv("CSV_DELIMITER"), `CSV/TSV delimiter; a single character or "tab" (env CSV_DELIMITER)`)
+		fs.StringVar(&cfg.FieldHints, "fields", os.Getenv("CSV_FIELDS"), "CSV/TSV column types, e.g. name:string,age:int,created:date (env CSV_FIELDS)")
+		fs.StringVar(&cfg.Import.ExtJSONMode, "extjson-mode", envOr("EXTJSON_MODE", importer.ExtJSONRelaxed), extJSONModeUsage)
+		fs.BoolVar(&cfg.Import.RejectDuplicateFields, "reject-duplicate-fields", envBool("REJECT_DUPLICATE_FIELDS"), "treat documents with a repeated field (at any depth) and CSV headers with a repeated column as invalid instead of keeping the last value (env REJECT_DUPLICATE_FIELDS)")
+		fs.StringVar(&cfg.Import.NonFinite, "nan", envOr("NAN_HANDLING", importer.NonFiniteKeep), "what to do with NaN and Infinity values: keep, null (replace with null) or reject (treat the document as invalid) (env NAN_HANDLING)")
+		fs.IntVar(&cfg.Import.Retry.Attempts, "retry-attempts", envInt("RETRY_ATTEMPTS", 3), "attempts per write on transient errors; 1 disables retries (env RETRY_ATTEMPTS)")
+		fs.DurationVar(&cfg.Import.Retry.Backoff, "retry-backoff", envDuration("RETRY_BACKOFF", 500*time.Millisecond), "initial retry backoff, doubled on each attempt (env RETRY_BACKOFF)")
+		fs.Float64Var(&cfg.Import.Retry.Jitter, "retry-jitter", envFloat("RETRY_JITTER", 0.2), "random jitter applied to the backoff, 0-1 (env RETRY_JITTER)")
+		fs.BoolVar(&cfg.Import.Transactional, "transactional", envBool("TRANSACTIONAL"), "make each file's clear + insert atomic (transaction, or staging collection + rename) (env TRANSACTIONAL)")
+		fs.BoolVar(&cfg.Import.AtomicSwap, "atomic-swap", envBool("ATOMIC_SWAP"), "load into <collection>.__staging, build indexes, then rename over the target (env ATOMIC_SWAP)")
+		fs.BoolVar(&cfg.Stdin, "stdin", false, "read NDJSON / JSON array from standard input (same as --path -); requires --collection unless --archive")
+		fs.BoolVar(&cfg.Import.Archive, "archive", envBool("IMPORT_ARCHIVE"), "--path or --stdin is a mongodump --archive stream (gzip is detected); every collection is restored into the database it was dumped from, with its options and indexes. Files ending in .archive are recognized without it (env IMPORT_ARCHIVE)")
+		fs.BoolVar(&cfg.Watch, "watch", envBool("WATCH"), "after the initial import, re-import files in the directory whenever they change (env WATCH)")
+		fs.BoolVar(&cfg.Import.SkipUnchanged, "skip-unchanged", envBool("SKIP_UNCHANGED"), "skip files whose SHA-256 matches the last successful import recorded in _import_meta (env SKIP_UNCHANGED)")
+		fs.BoolVar(&cfg.Import.Force, "force", false, "with --skip-unchanged, import every file anyway and refresh the checksums")
+		fs.BoolVar(&cfg.Import.Resume, "resume", envBool("RESUME"), "record progress after every batch and continue an interrupted import from the last checkpoint (env RESUME)")
+		fs.BoolVar(&cfg.Import.FailFast, "fail-fast", envBool("FAIL_FAST"), "stop starting new files after the first failure (env FAIL_FAST)")
+		fs.StringVar(&cfg.NotifyWebhook, "notify-webhook", os.Getenv("NOTIFY_WEBHOOK"), "POST a JSON summary of the run (event, host and the --report contents) to this URL when it ends (env NOTIFY_WEBHOOK)")
+		fs.StringVar(&cfg.NotifySlack, "notify-slack", os.Getenv("SLACK_WEBHOOK_URL"), "post a summary of the run (files, docs, failures, duration) to this Slack incoming webhook URL (env SLACK_WEBHOOK_URL)")
+		fs.StringVar(&cfg.NotifyOn, "notify-on", envOr("NOTIFY_ON", notifyAlways), "always, or failure to only notify about failed and interrupted runs (env NOTIFY_ON)")
+		fs.StringVar(&cfg.ReportFile, "report", os.Getenv("REPORT_FILE"), "write a JSON report with per-file counts, durations, errors and warnings to this path (env REPORT_FILE)")
+		fs.StringVar(&cfg.SummaryFormat, "summary-format", envOr("SUMMARY_FORMAT", summaryTable), "per-file summary printed at the end: table, or json / csv on stdout for piping elsewhere, with the logs moved to stderr (env SUMMARY_FORMAT)")
+		fs.BoolVar(&cfg.Import.Quiet, "quiet", envBool("QUIET"), "disable per-batch progress output (env QUIET)")
+		fs.StringVar(&cfg.ShiftDates, "shift-dates", os.Getenv("SHIFT_DATES"), "shift every date so the newest one in the data becomes now, or the given RFC 3339 time / 2006-01-02, keeping the spacing (env SHIFT_DATES)")
+		fs.StringVar(&cfg.ShiftDatesField, "shift-dates-field", os.Getenv("SHIFT_DATES_FIELD"), "take the newest date only from this field (a.b path), e.g. createdAt; all dates are still shifted (env SHIFT_DATES_FIELD)")
+		fs.BoolVar(&cfg.Import.Templates, "templates", envBool("TEMPLATES"), "expand {{NOW}}, {{UUID}} and {{ENV:NAME}} in string values; a value that is exactly {{NOW}} becomes a date (env TEMPLATES)")
+		fs.StringVar(&cfg.Transform, "transform", os.Getenv("TRANSFORM_FILE"), "YAML/JSON file with per-collection rename, drop, convert, derive and set rules (env TRANSFORM_FILE)")
+		fs.StringVar(&cfg.MaskFile, "mask", os.Getenv("MASK_FILE"), "YAML/JSON file listing per-collection fields to hash, redact, fake or format-preserve (env MASK_FILE)")
+		fs.StringVar(&cfg.HooksFile, "hooks", os.Getenv("HOOKS_FILE"), "YAML file with per-collection shell commands, server commands or aggregations to run before and after each import (env HOOKS_FILE)")
+		fs.StringVar(&cfg.ScheduleExpr, "schedule", os.Getenv("IMPORT_SCHEDULE"), "stay running and repeat the import (or --plan) on this cron schedule in local time, e.g. \"0 3 * * *\" or @hourly; runs never overlap (env IMPORT_SCHEDULE)")
+		fs.StringVar(&cfg.ScheduleStatus, "schedule-status", os.Getenv("SCHEDULE_STATUS"), "with --schedule, keep a JSON file with the next run time and the status and totals of the last run (env SCHEDULE_STATUS)")
+		fs.StringVar(&cfg.PlanFile, "plan", os.Getenv("IMPORT_PLAN"), "YAML import plan listing sources, targets, strategies, transforms and depends_on ordering (see import-plan.example.yaml); --path is ignored (env IMPORT_PLAN)")
+		fs.StringVar(&cfg.Filter, "filter", os.Getenv("IMPORT_FILTER"), `only import documents matching this Extended JSON query, e.g. '{"status": "active"}' (env IMPORT_FILTER)`)
+		fs.StringVar(&cfg.OnlyFields, "only-fields", os.Getenv("IMPORT_ONLY_FIELDS"), "keep only these comma-separated fields (a.b paths) and _id of every document as it is read; transforms, filters and keys see the slimmed document (env IMPORT_ONLY_FIELDS)")
+		fs.StringVar(&cfg.DropFields, "drop-fields", os.Getenv("IMPORT_DROP_FIELDS"), "remove these comma-separated fields (a.b paths) from every document as it is read, e.g. attachments,profile.avatar (env IMPORT_DROP_FIELDS)")
+		fs.StringVar(&cfg.Coerce, "coerce", os.Getenv("IMPORT_COERCE"), "comma-separated fixes for dumps whose values became strings: objectid (24 hex characters in --coerce-id-fields), date (ISO-8601 date-times) and number (numeric strings without leading zeros to int, long or double) (env IMPORT_COERCE)")
+		fs.StringVar(&cfg.CoerceIDFields, "coerce-id-fields", os.Getenv("IMPORT_COERCE_ID_FIELDS"), "comma-separated globs on field names the objectid coercion applies to (default "+strings.Join(importer.DefaultCoerceIDFields, ",")+") (env IMPORT_COERCE_ID_FIELDS)")
+		fs.BoolVar(&cfg.Import.ValidateSchema, "validate-schema", envBool("VALIDATE_SCHEMA"), "check every document against the collection's $jsonSchema validator before inserting (env VALIDATE_SCHEMA)")
+		fs.StringVar(&cfg.Import.ViewsFile, "views", os.Getenv("VIEWS_FILE"), `Extended JSON file of views to create after the import, e.g. [{"name": "active_users", "source": "users", "pipeline": [...]}] (env VIEWS_FILE)`)
+		fs.StringVar(&cfg.Import.SchemaFile, "schema-file", os.Getenv("SCHEMA_FILE"), "validate against this local JSON Schema file instead; implies --validate-schema (env SCHEMA_FILE)")
+		fs.IntVar(&cfg.Import.PreSplit, "presplit", envInt("PRESPLIT", 0), "when the target is an empty sharded collection, split it into this many chunks by the file's shard key values and spread them across the shards before inserting; documents missing the shard key are always rejected (env PRESPLIT)")
+		fs.BoolVar(&cfg.Import.SkipInvalid, "skip-invalid", envBool("SKIP_INVALID"), "skip documents that fail to parse instead of failing the file (env SKIP_INVALID)")
+		fs.BoolVar(&cfg.Import.SkipOversized, "skip-oversized", envBool("SKIP_OVERSIZED"), "skip documents over MongoDB's 16MB BSON limit, logging their file and line, instead of failing the file (env SKIP_OVERSIZED)")
+		fs.StringVar(&cfg.Import.ErrorsFile, "errors-file", envOr("ERRORS_FILE", "import-errors.log"), "where --skip-invalid and --skip-oversized record skipped documents (env ERRORS_FILE)")
+		fs.IntVar(&cfg.Import.BatchSize, "batch-size", envInt("BATCH_SIZE", importer.DefaultBatchSize), "documents per insert batch (env BATCH_SIZE)")
+		fs.Float64Var(&cfg.Import.RateLimit.DocsPerSec, "max-docs-per-sec", envFloat("MAX_DOCS_PER_SEC", 0), "throttle writes to this many documents per second across all files and workers; 0 disables (env MAX_DOCS_PER_SEC)")
+		fs.Float64Var(&cfg.Import.RateLimit.BatchesPerSec, "max-batches-per-sec", envFloat("MAX_BATCHES_PER_SEC", 0), "throttle to this many insert / bulk write batches per second; 0 disables (env MAX_BATCHES_PER_SEC)")
+	}
+
+	if cmd == "export" {
+		fs.StringVar(&cfg.Export.Format, "export-format", envOr("EXPORT_FORMAT", exporter.FormatArray), "array (one document per line), ndjson, pretty (indented array) or bson (mongodump's format, restorable with mongorestore), which can all be imported again, or parquet, avro (with a generated schema) or arrow (IPC stream) for data-lake, Kafka and Arrow tooling (env EXPORT_FORMAT)")
+		fs.StringVar(&cfg.Query, "query", os.Getenv("EXPORT_QUERY"), `only export documents matching this Extended JSON query, e.g. {"status": "active"} (env EXPORT_QUERY)`)
+		fs.StringVar(&cfg.Projection, "projection", os.Getenv("EXPORT_PROJECTION"), `Extended JSON projection, e.g. {"attachments": 0} (env EXPORT_PROJECTION)`)
+		fs.StringVar(&cfg.QueryFile, "query-file", os.Getenv("EXPORT_QUERY_FILE"), "YAML file with a query / projection per collection; overrides --query / --projection for matching collections (env EXPORT_QUERY_FILE)")
+		fs.IntVar(&cfg.Export.BatchSize, "batch-size", envInt("EXPORT_BATCH_SIZE", 0), "documents per cursor batch; 0 uses the server default (env EXPORT_BATCH_SIZE)")
+		fs.BoolVar(&cfg.Export.Resume, "resume", envBool("EXPORT_RESUME"), "export in _id order and record progress in <file>.checkpoint after every cursor batch; running the same command again continues after the last _id and skips finished collections. Not available for parquet, avro, arrow or encrypted exports (env EXPORT_RESUME)")
+		fs.IntVar(&cfg.Export.Parallel, "parallel", envInt("EXPORT_PARALLEL", 1), "split every collection's _id space into this many ranges sampled to hold about the same number of documents and export them at once; the pieces are merged into <collection>.json in _id order. Collections under 1000 documents per range are exported in one piece (env EXPORT_PARALLEL)")
+		fs.BoolVar(&cfg.Export.KeepShards, "keep-shards", envBool("EXPORT_KEEP_SHARDS"), "with --parallel, keep <collection>.shard-0001.json, <collection>.shard-0002.json, ... instead of merging them; import maps them to <collection> and, as for any files sharing a collection, refuses the truncate and upsert strategies, so load them with --strategy append (env EXPORT_KEEP_SHARDS)")
+	}
+
+	if cmd == "import" || cmd == "verify" || cmd == "diff" {
+		fs.BoolVar(&cfg.Stamp, "stamp", envBool("IMPORT_STAMP"), "stamp every imported document with a content hash and the import time; upsert / merge skip documents whose hash is unchanged, verify / diff ignore the fields (env IMPORT_STAMP)")
+		fs.StringVar(&cfg.StampHashField, "stamp-hash-field", envOr("STAMP_HASH_FIELD", importer.DefaultStampHashField), "field holding the content hash with --stamp (env STAMP_HASH_FIELD)")
+		fs.StringVar(&cfg.StampTimeField, "stamp-time-field", envOr("STAMP_TIME_FIELD", importer.DefaultStampTimeField), "field holding the import time with --stamp; empty leaves it out (env STAMP_TIME_FIELD)")
+	}
+
+	if cmd == "verify" {
+		fs.BoolVar(&cfg.Verify.Hash, "hash", envBool("VERIFY_HASH"), "also compare an order-independent hash of the documents; reads the whole collection (env VERIFY_HASH)")
+		fs.BoolVar(&cfg.Drift, "drift", envBool("VERIFY_DRIFT"), "with --stamp, also report documents changed in the database since they were imported (env VERIFY_DRIFT)")
+		fs.StringVar(&cfg.MappingFile, "mapping", os.Getenv("MAPPING_FILE"), "YAML/JSON file mapping file paths or globs to collections (env MAPPING_FILE)")
+		fs.BoolVar(&cfg.Import.DBFromFilename, "db-from-filename", envBool("DB_FROM_FILENAME"), "take the database from <db>.<collection>.json file names (env DB_FROM_FILENAME)")
+		fs.BoolVar(&cfg.Import.Recursive, "recursive", envBool("RECURSIVE"), "include subdirectories; files in <path>/<db>/ go to database <db>, as laid out by mongodump (env RECURSIVE)")
+		fs.StringVar(&cfg.Import.CollectionPrefix, "collection-prefix", os.Getenv("IMPORT_COLLECTION_PREFIX"), "check the collections written with the same --collection-prefix (env IMPORT_COLLECTION_PREFIX)")
+		fs.StringVar(&cfg.Rename, "rename", os.Getenv("IMPORT_RENAME"), "check the collections written with the same --rename (env IMPORT_RENAME)")
+		fs.StringVar(&cfg.Include, "include", os.Getenv("IMPORT_INCLUDE"), "comma-separated globs on collection names; only matching files of a directory are imported, e.g. users,orders_* (env IMPORT_INCLUDE)")
+		fs.StringVar(&cfg.Exclude, "exclude", os.Getenv("IMPORT_EXCLUDE"), "comma-separated globs on collection names to skip in a directory, e.g. logs,analytics; wins over --include (env IMPORT_EXCLUDE)")
+		fs.StringVar(&cfg.Delimiter, "delimiter", os.Getenv("CSV_DELIMITER"), `CSV/TSV delimiter; a single character or "tab" (env CSV_DELIMITER)`)
+		fs.StringVar(&cfg.FieldHints, "fields", os.Getenv("CSV_FIELDS"), "CSV/TSV column types, e.g. name:string,age:int,created:date (env CSV_FIELDS)")
+		fs.StringVar(&cfg.Import.ExtJSONMode, "extjson-mode", envOr("EXTJSON_MODE", importer.ExtJSONRelaxed), extJSONModeUsage)
+		fs.BoolVar(&cfg.Import.RejectDuplicateFields, "reject-duplicate-fields", envBool("REJECT_DUPLICATE_FIELDS"), "treat documents with a repeated field (at any depth) and CSV headers with a repeated column as invalid instead of keeping the last value (env REJECT_DUPLICATE_FIELDS)")
+		fs.StringVar(&cfg.Import.NonFinite, "nan", envOr("NAN_HANDLING", importer.NonFiniteKeep), "what to do with NaN and Infinity values: keep, null (replace with null) or reject (treat the document as invalid) (env NAN_HANDLING)")
+		fs.StringVar(&cfg.Transform, "transform", os.Getenv("TRANSFORM_FILE"), "apply the same transform file as the import (env TRANSFORM_FILE)")
+		fs.StringVar(&cfg.MaskFile, "mask", os.Getenv("MASK_FILE"), "apply the same mask file as the import; needs a fixed salt for --hash (env MASK_FILE)")
+		fs.StringVar(&cfg.Filter, "filter", os.Getenv("IMPORT_FILTER"), "apply the same filter as the import (env IMPORT_FILTER)")
+		fs.StringVar(&cfg.OnlyFields, "only-fields", os.Getenv("IMPORT_ONLY_FIELDS"), "apply the same --only-fields as the import (env IMPORT_ONLY_FIELDS)")
+		fs.StringVar(&cfg.DropFields, "drop-fields", os.Getenv("IMPORT_DROP_FIELDS"), "apply the same --drop-fields as the import (env IMPORT_DROP_FIELDS)")
+		fs.StringVar(&cfg.Coerce, "coerce", os.Getenv("IMPORT_COERCE"), "apply the same --coerce as the import (env IMPORT_COERCE)")
+		fs.StringVar(&cfg.CoerceIDFields, "coerce-id-fields", os.Getenv("IMPORT_COERCE_ID_FIELDS"), "apply the same --coerce-id-fields as the import (env IMPORT_COERCE_ID_FIELDS)")
+	}
+
+	if cmd == "copy" || cmd == "sync" {
+		fs.StringVar(&cfg.SourceURI, "source-uri", os.Getenv("SOURCE_URI"), "server to copy from (defaults to --uri) (env SOURCE_URI)")
+		fs.StringVar(&cfg.SourceDB, "source-db", os.Getenv("SOURCE_DB"), "database to copy from (defaults to --db) (env SOURCE_DB)")
+		fs.StringVar(&cfg.Query, "query", os.Getenv("COPY_QUERY"), "only copy documents matching this Extended JSON query (env COPY_QUERY)")
+		fs.StringVar(&cfg.Rename, "rename", os.Getenv("COPY_RENAME"), "write collections under another name, e.g. users:users_copy,orders:orders_2024 (env COPY_RENAME)")
+		fs.BoolVar(&cfg.Copy.Append, "append", envBool("COPY_APPEND"), "keep the documents already in the target collections instead of clearing them first (env COPY_APPEND)")
+		fs.IntVar(&cfg.Copy.BatchSize, "batch-size", envInt("BATCH_SIZE", copier.DefaultBatchSize), "documents per insert batch (env BATCH_SIZE)")
+	}
+
+	if cmd == "import" || cmd == "sync" || cmd == "tail" || cmd == "consume" {
+		fs.StringVar(&cfg.MetricsAddr, "metrics-addr", os.Getenv("METRICS_ADDR"), "serve Prometheus metrics (documents, errors, batch latency, lag) on this address at /metrics while watching, serving, syncing, tailing or consuming, e.g. :9090 (env METRICS_ADDR)")
+	}
+
+	if cmd == "consume" {
+		fs.StringVar(&cfg.KafkaBrokers, "brokers", os.Getenv("KAFKA_BROKERS"), "comma-separated Kafka brokers, e.g. kafka-1:9092,kafka-2:9092 (env KAFKA_BROKERS)")
+		fs.StringVar(&cfg.Consume.Topic, "topic", os.Getenv("KAFKA_TOPIC"), "topic with one Extended JSON document per message (env KAFKA_TOPIC)")
+		fs.StringVar(&cfg.Consume.Group, "group", envOr("KAFKA_GROUP", consumer.DefaultGroup), "consumer group the offsets are committed under (env KAFKA_GROUP)")
+		fs.StringVar(&cfg.Consume.StartOffset, "start-offset", envOr("KAFKA_START_OFFSET", consumer.StartFirst), "where a group without committed offsets starts: first or last (env KAFKA_START_OFFSET)")
+		fs.StringVar(&cfg.Consume.DLQTopic, "dlq-topic", os.Getenv("KAFKA_DLQ_TOPIC"), "send messages that cannot be parsed or written to this topic, with x-error and x-source-* headers; empty logs and skips them (env KAFKA_DLQ_TOPIC)")
+		fs.StringVar(&cfg.Consume.KeyField, "key", os.Getenv("KAFKA_KEY"), "upsert by this field (a.b path) so redelivered messages replace the same document; empty inserts (env KAFKA_KEY)")
+		fs.IntVar(&cfg.Consume.BatchSize, "batch-size", envInt("BATCH_SIZE", consumer.DefaultBatchSize), "messages per write; offsets are committed after each write (env BATCH_SIZE)")
+		fs.DurationVar(&cfg.Consume.BatchTimeout, "batch-timeout", envDuration("KAFKA_BATCH_TIMEOUT", consumer.DefaultBatchTimeout), "write a partial batch after waiting this long (env KAFKA_BATCH_TIMEOUT)")
+		fs.BoolVar(&cfg.KafkaTLS, "kafka-tls", envBool("KAFKA_TLS"), "connect to the brokers with TLS (env KAFKA_TLS)")
+		fs.StringVar(&cfg.KafkaSASL, "kafka-sasl", os.Getenv("KAFKA_SASL"), "SASL mechanism: plain, scram-sha-256 or scram-sha-512 (env KAFKA_SASL)")
+		fs.StringVar(&cfg.KafkaUsername, "kafka-username", os.Getenv("KAFKA_USERNAME"), "SASL user name (env KAFKA_USERNAME)")
+		fs.StringVar(&cfg.KafkaPassword, "kafka-password", os.Getenv("KAFKA_PASSWORD"), "SASL password; prefer the env var (env KAFKA_PASSWORD)")
+	}
+
+	if cmd == "tail" {
+		fs.StringVar(&cfg.TailOut, "out", envOr("TAIL_OUT", "-"), "file the events are appended to; - writes to stdout and moves the logs to stderr (env TAIL_OUT)")
+		fs.BoolVar(&cfg.TailFullDocuments, "full-documents", envBool("TAIL_FULL_DOCUMENTS"), "write the changed document after each insert / update / replace instead of the whole event; the output can be imported again (env TAIL_FULL_DOCUMENTS)")
+	}
+
+	if cmd == "snapshot" || cmd == "restore" {
+		fs.StringVar(&cfg.SnapshotDir, "dir", envOr("SNAPSHOT_DIR", "snapshots"), "directory holding the snapshots, one <db>-<UTC time> subdirectory each (env SNAPSHOT_DIR)")
+	}
+
+	if cmd == "snapshot" {
+		fs.IntVar(&cfg.SnapshotKeep, "keep", envInt("SNAPSHOT_KEEP", 0), "after a successful snapshot, delete the older snapshots of --db beyond the newest N; 0 keeps them all (env SNAPSHOT_KEEP)")
+		fs.IntVar(&cfg.SnapshotKeepDays, "keep-days", envInt("SNAPSHOT_KEEP_DAYS", 0), "after a successful snapshot, delete the snapshots of --db older than D days; with --keep, a snapshot is kept when either rule keeps it; 0 keeps them all (env SNAPSHOT_KEEP_DAYS)")
+	}
+
+	if cmd == "import" || cmd == "restore" {
+		fs.BoolVar(&cfg.Import.NoVerify, "no-verify", envBool("IMPORT_NO_VERIFY"), "import a directory even when its files do not match the "+manifest.File+" written by export (changed, missing or extra files, or an incomplete export) (env IMPORT_NO_VERIFY)")
+	}
+
+	if cmd == "restore" {
+		fs.StringVar(&cfg.Snapshot, "snapshot", os.Getenv("SNAPSHOT"), "snapshot to restore: a name under --dir, e.g. shop-20240102-030405, or the path of a snapshot directory; empty restores the latest snapshot of --db (env SNAPSHOT)")
+		fs.BoolVar(&cfg.DropExtra, "drop-extra", envBool("RESTORE_DROP_EXTRA"), "after a successful restore, also drop the collections and views of --db that are not in the snapshot (env RESTORE_DROP_EXTRA)")
+	}
+
+	if cmd == "stats" {
+		fs.StringVar(&cfg.StatsOut, "out", os.Getenv("STATS_OUT"), "also save the stats as JSON to this file, e.g. before an import (env STATS_OUT)")
+		fs.StringVar(&cfg.StatsCompare, "compare", os.Getenv("STATS_COMPARE"), "show the change of each value since the stats saved in this file with --out (env STATS_COMPARE)")
+	}
+
+	if cmd == "generate" {
+		fs.StringVar(&cfg.GenerateSchemaFile, "schema", os.Getenv("GENERATE_SCHEMA"), "YAML file with the collections, counts and field generators (see generate.example.yaml) (env GENERATE_SCHEMA)")
+		fs.StringVar(&cfg.GenerateOut, "out", os.Getenv("GENERATE_OUT"), "write <collection>.json files (one Extended JSON document per line) to this directory instead of inserting; no connection is made (env GENERATE_OUT)")
+		fs.IntVar(&cfg.Generate.Count, "count", envInt("GENERATE_COUNT", 0), "documents per collection, overriding the counts in the schema; 0 keeps them (env GENERATE_COUNT)")
+		fs.Int64Var(&cfg.Generate.Seed, "seed", int64(envInt("GENERATE_SEED", 0)), "random seed; the same seed and schema produce the same data; 0 picks one and logs it (env GENERATE_SEED)")
+		fs.BoolVar(&cfg.Generate.Append, "append", envBool("GENERATE_APPEND"), "keep the documents already in the target collections instead of clearing them first (env GENERATE_APPEND)")
+		fs.IntVar(&cfg.Generate.BatchSize, "batch-size", envInt("BATCH_SIZE", generate.DefaultBatchSize), "documents per insert batch (env BATCH_SIZE)")
+	}
+
+	if cmd == "diff" {
+		fs.StringVar(&cfg.SourceURI, "source-uri", "", "compare against a collection on this server instead of --path (defaults to --uri)")
+		fs.StringVar(&cfg.SourceDB, "source-db", "", "compare against the same collection in this database instead of --path")
+		fs.StringVar(&cfg.Delta, "delta", "", `write every difference as NDJSON to this file ("-" for stdout)`)
+		fs.StringVar(&cfg.Delimiter, "delimiter", os.Getenv("CSV_DELIMITER"), `CSV/TSV delimiter; a single character or "tab" (env CSV_DELIMITER)`)
+		fs.StringVar(&cfg.FieldHints, "fields", os.Getenv("CSV_FIELDS"), "CSV/TSV column types, e.g. name:string,age:int,created:date (env CSV_FIELDS)")
+		fs.StringVar(&cfg.Import.ExtJSONMode, "extjson-mode", envOr("EXTJSON_MODE", importer.ExtJSONRelaxed), extJSONModeUsage)
+		fs.BoolVar(&cfg.Import.RejectDuplicateFields, "reject-duplicate-fields", envBool("REJECT_DUPLICATE_FIELDS"), "treat documents with a repeated field (at any depth) and CSV headers with a repeated column as invalid instead of keeping the last value (env REJECT_DUPLICATE_FIELDS)")
+		fs.StringVar(&cfg.Import.NonFinite, "nan", envOr("NAN_HANDLING", importer.NonFiniteKeep), "what to do with NaN and Infinity values: keep, null (replace with null) or reject (treat the document as invalid) (env NAN_HANDLING)")
+	}
+
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		log.Fatalf("Unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg.Verbose = envInt("VERBOSE", 0)
+	switch {
+	case *veryVerbose:
+		cfg.Verbose = 2
+	case *verbose && cfg.Verbose < 1:
+		cfg.Verbose = 1
+	}
+	if cfg.Verbose > 0 {
+		cfg.LogLevel = "debug"
+	}
+
+	if cfg.Stdin {
+		cfg.Path = importer.Stdin
+	}
+	// generate --out 只寫檔案，不需要連線
+	offline := cmd == "generate" && cfg.GenerateOut != ""
+	if cfg.URI == "" && !offline {
+		log.Fatal("Missing MongoDB URI (--uri or MONGO_URI)")
+	}
+	if cfg.DB == "" && !offline {
+		log.Fatal("Missing database (--db or MONGO_DB)")
+	}
+	if cmd == "diff" && cfg.SourceURI != "" && cfg.SourceDB == "" {
+		log.Fatal("--source-uri requires --source-db")
+	}
+	if cmd == "diff" && cfg.SourceDB != "" {
+		if cfg.Collection == "" {
+			log.Fatal("diff with --source-db requires --collection")
+		}
+	} else if cmd != "drop" && cmd != "copy" && cmd != "sync" && cmd != "tail" && cmd != "generate" && cmd != "consume" && cmd != "ping" && cmd != "stats" && cmd != "snapshot" && cmd != "restore" && !serving && cfg.Path == "" && (cmd != "import" || cfg.PlanFile == "") {
+		log.Fatal("Missing path (--path or JSON_PATH)")
+	}
+	if cmd == "import" && cfg.PlanFile != "" {
+		if cfg.Stdin || cfg.Watch || cfg.GridFS != "" {
+			log.Fatal("--plan cannot be combined with --stdin, --watch or --gridfs")
+		}
+		plan, err := importer.LoadPlan(cfg.PlanFile)
+		if err != nil {
+			log.Fatalf("Invalid plan: %v", err)
+		}
+		cfg.Plan = plan
+	}
+	if cmd == "import" && cfg.GridFS != "" {
+		if fi, err := os.Stat(cfg.Path); err != nil || !fi.IsDir() {
+			log.Fatal("--gridfs requires --path to be a directory")
+		}
+	}
+	if cmd == "import" && cfg.Path == importer.Stdin && cfg.Collection == "" && !cfg.Import.Archive {
+		log.Fatal("Reading from stdin requires --collection (or --archive)")
+	}
+	if cmd == "import" && cfg.Import.Archive && cfg.Path != importer.Stdin && !serving {
+		if fi, err := os.Stat(cfg.Path); err == nil && fi.IsDir() {
+			log.Fatal("--archive requires --path to be an archive file or --stdin; .archive files in a directory are recognized without it")
+		}
+	}
+	if cmd == "import" && cfg.MetricsAddr != "" && !cfg.Watch && !serving {
+		log.Fatal("--metrics-addr requires --watch or serve")
+	}
+	if cmd == "import" && cfg.Watch {
+		if fi, err := os.Stat(cfg.Path); err != nil || !fi.IsDir() {
+			log.Fatal("--watch requires --path to be a directory")
+		}
+	}
+	if (cmd == "snapshot" || cmd == "restore") && cfg.SnapshotDir == "" {
+		log.Fatalf("%s requires --dir", cmd)
+	}
+	if cmd == "snapshot" && (cfg.SnapshotKeep < 0 || cfg.SnapshotKeepDays < 0) {
+		log.Fatalf("Invalid retention: --keep %d, --keep-days %d", cfg.SnapshotKeep, cfg.SnapshotKeepDays)
+	}
+	keys := 0
+	for _, k := range []string{cfg.EncryptionKey, cfg.EncryptionKeyFile, cfg.KMSDataKey} {
+		if k != "" {
+			keys++
+		}
+	}
+	if keys > 1 {
+		log.Fatal("--encryption-key, --encryption-key-file and --kms-data-key cannot be combined")
+	}
+	if keys > 0 && cfg.GridFS != "" {
+		log.Fatal("--gridfs cannot be combined with --encryption-key, --encryption-key-file or --kms-data-key")
+	}
+	if keys > 0 && cmd == "export" && cfg.Export.Resume {
+		log.Fatal("export --resume cannot be combined with --encryption-key, --encryption-key-file or --kms-data-key")
+	}
+	if keys > 0 && cmd == "export" && cfg.Export.Parallel > 1 && !cfg.Export.KeepShards {
+		log.Fatal("encrypted --parallel exports cannot be merged; add --keep-shards")
+	}
+	if cfg.TLSKeyFile != "" && cfg.TLSCertFile == "" {
+		log.Fatal("--tls-key-file requires --tls-cert-file")
+	}
+	if (cmd == "drop" || cmd == "tail" || cmd == "consume") && cfg.Collection == "" {
+		log.Fatalf("%s requires --collection", cmd)
+	}
+	if cmd == "generate" {
+		if cfg.GenerateSchemaFile == "" {
+			log.Fatal("generate requires --schema")
+		}
+		schema, err := generate.LoadSchema(cfg.GenerateSchemaFile)
+		if err != nil {
+			log.Fatalf("Invalid schema: %v", err)
+		}
+		if cfg.Generate.Count < 0 {
+			log.Fatalf("Invalid count: %d", cfg.Generate.Count)
+		}
+		if cfg.Generate.BatchSize <= 0 {
+			log.Fatalf("Invalid batch size: %d", cfg.Generate.BatchSize)
+		}
+		cfg.GenerateSchema = schema
+		cfg.Generate.DB, cfg.Generate.OpTimeout = cfg.DB, cfg.OpTimeout
+	}
+	if cmd == "consume" {
+		for _, b := range strings.Split(cfg.KafkaBrokers, ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				cfg.Consume.Brokers = append(cfg.Consume.Brokers, b)
+			}
+		}
+		if len(cfg.Consume.Brokers) == 0 || cfg.Consume.Topic == "" {
+			log.Fatal("consume requires --brokers and --topic")
+		}
+		if cfg.Consume.BatchSize <= 0 {
+			log.Fatalf("Invalid batch size: %d", cfg.Consume.BatchSize)
+		}
+		if cfg.Consume.BatchTimeout <= 0 {
+			log.Fatalf("Invalid batch timeout: %s", cfg.Consume.BatchTimeout)
+		}
+		if cfg.Consume.StartOffset != consumer.StartFirst && cfg.Consume.StartOffset != consumer.StartLast {
+			log.Fatalf("Invalid start offset: %s (expected first or last)", cfg.Consume.StartOffset)
+		}
+		if cfg.Consume.DLQTopic == cfg.Consume.Topic {
+			log.Fatal("--dlq-topic must differ from --topic")
+		}
+		mechanism, err := consumer.ParseSASL(cfg.KafkaSASL, cfg.KafkaUsername, cfg.KafkaPassword)
+		if err != nil {
+			log.Fatalf("Invalid SASL settings: %v", err)
+		}
+		if mechanism == nil && cfg.KafkaUsername != "" {
+			log.Fatal("--kafka-username requires --kafka-sasl")
+		}
+		cfg.Consume.SASL = mechanism
+		if cfg.KafkaTLS {
+			cfg.Consume.TLS = &tls.Config{}
+		}
+		cfg.Consume.DB, cfg.Consume.Collection, cfg.Consume.OpTimeout = cfg.DB, cfg.Collection, cfg.OpTimeout
+	}
+	if cmd == "copy" || cmd == "sync" {
+		if cfg.SourceDB == "" {
+			cfg.SourceDB = cfg.DB
+		}
+		if cfg.SourceURI == "" && cfg.SourceDB == cfg.DB {
+			log.Fatalf("%s requires --source-uri or a --source-db different from --db", cmd)
+		}
+		if cfg.Copy.BatchSize <= 0 {
+			log.Fatalf("Invalid batch size: %d", cfg.Copy.BatchSize)
+		}
+	}
+	if cmd == "import" {
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if cfg.UpsertFields != "" {
+			if set["key"] {
+				log.Fatal("--upsertFields and --key set the same thing; pass only one of them")
+			}
+			cfg.Import.KeyField = cfg.UpsertFields
+			// 跟 mongoimport 一樣，只給 --upsertFields 表示 upsert
+			if cfg.Mode == "" {
+				cfg.Mode = importer.StrategyUpsert
+			}
+		}
+		if cfg.Mode != "" {
+			if set["strategy"] {
+				log.Fatal("--mode and --strategy cannot be combined; --mode insert, upsert, merge and delete map to --strategy append (with --ignore-duplicates), upsert, merge (with --merge-update) and delete")
+			}
+			switch cfg.Mode {
+			case "insert":
+				cfg.Import.Strategy, cfg.Import.IgnoreDuplicates = importer.StrategyAppend, true
+			case "upsert":
+				cfg.Import.Strategy = importer.StrategyUpsert
+			case "merge":
+				cfg.Import.Strategy, cfg.Import.MergeUpdate = importer.StrategyMerge, true
+			case "delete":
+				cfg.Import.Strategy = importer.StrategyDelete
+			default:
+				log.Fatalf("Invalid mode: %s (expected insert, upsert, merge or delete)", cfg.Mode)
+			}
+		}
+		s := cfg.Import.Strategy
+		if s != importer.StrategyTruncate && s != importer.StrategyUpsert && s != importer.StrategyMerge && s != importer.StrategyAppend && s != importer.StrategyDelete {
+			log.Fatalf("Invalid strategy: %s (expected truncate, upsert, merge, append or delete)", s)
+		}
+		rules, err := importer.ParseStrategies(cfg.Strategies)
+		if err != nil {
+			log.Fatalf("Invalid strategies: %v", err)
+		}
+		cfg.Import.Strategies = rules
+	}
+	if cmd == "import" && cfg.Import.MergeUpdate && !cfg.Import.UsesStrategy(importer.StrategyMerge) {
+		log.Fatalf("--merge-update requires the merge strategy")
+	}
+	if cmd == "import" && cfg.Import.Archive && cfg.Path == importer.Stdin && cfg.Import.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete) && !cfg.Yes {
+		log.Fatal("an archive on stdin cannot be read twice to list the collections it deletes from; pass --yes, or use --strategy upsert, merge or append")
+	}
+	if cmd == "import" && cfg.Import.AtomicSwap && cfg.Import.UsesStrategy(importer.StrategyUpsert, importer.StrategyMerge, importer.StrategyAppend, importer.StrategyDelete) {
+		log.Fatalf("--atomic-swap replaces the whole collection and requires the truncate strategy")
+	}
+	if cfg.Scope != "" {
+		scope, err := importer.ParseFilter(cfg.Scope)
+		if err != nil {
+			log.Fatalf("Invalid scope: %v", err)
+		}
+		if !cfg.Import.UsesStrategy(importer.StrategyTruncate) {
+			log.Fatal("--scope only applies to the truncate strategy")
+		}
+		if cfg.Import.AtomicSwap {
+			log.Fatal("--atomic-swap replaces the whole collection and cannot be combined with --scope")
+		}
+		cfg.Import.Scope = scope
+	}
+	if (cfg.Import.TenantField == "") != (cfg.TenantValue == "") {
+		log.Fatal("--tenant-field and --tenant-value must be used together")
+	}
+	if cfg.Import.TenantField != "" {
+		if cfg.Import.AtomicSwap {
+			log.Fatal("--atomic-swap replaces the whole collection and cannot be combined with --tenant-field")
+		}
+		cfg.Import.TenantValue = cfg.TenantValue
+	}
+	if cfg.Stamp {
+		if cfg.StampHashField == "" {
+			log.Fatal("--stamp requires --stamp-hash-field")
+		}
+		cfg.Import.StampHashField, cfg.Import.StampTimeField = cfg.StampHashField, cfg.StampTimeField
+		cfg.Verify.Ignore = cfg.Import.StampFields()
+	} else if cfg.Drift {
+		log.Fatal("--drift requires --stamp")
+	}
+	if cmd == "import" && cfg.Import.DedupeKeep != importer.DedupeLast && cfg.Import.DedupeKeep != importer.DedupeFirst {
+		log.Fatalf("Invalid dedupe keep: %s (expected first or last)", cfg.Import.DedupeKeep)
+	}
+	if cmd == "import" && cfg.SummaryFormat != summaryTable && cfg.SummaryFormat != summaryJSON && cfg.SummaryFormat != summaryCSV {
+		log.Fatalf("Invalid summary format: %s (expected table, json or csv)", cfg.SummaryFormat)
+	}
+	if cmd == "import" && cfg.NotifyOn != notifyAlways && cfg.NotifyOn != notifyFailure {
+		log.Fatalf("Invalid notify-on: %s (expected always or failure)", cfg.NotifyOn)
+	}
+	for _, u := range []string{cfg.NotifyWebhook, cfg.NotifySlack} {
+		if u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+			log.Fatalf("Invalid notification URL: %s (expected http:// or https://)", u)
+		}
+	}
+	if cmd == "import" && cfg.Import.PreSplit < 0 {
+		log.Fatalf("Invalid presplit: %d", cfg.Import.PreSplit)
+	}
+	if cmd == "import" && cfg.Import.PreSplit > 1 && (cfg.Import.Transactional || cfg.Import.AtomicSwap) {
+		log.Fatal("--presplit cannot be combined with --transactional or --atomic-swap")
+	}
+	if cmd == "import" && cfg.Import.InsertWorkers <= 0 {
+		log.Fatalf("Invalid insert workers: %d", cfg.Import.InsertWorkers)
+	}
+	if (cmd == "import" || cmd == "diff" || cmd == "verify") && cfg.Import.ExtJSONMode != importer.ExtJSONRelaxed &&
+		cfg.Import.ExtJSONMode != importer.ExtJSONCanonical && cfg.Import.ExtJSONMode != importer.ExtJSONAuto {
+		log.Fatalf("Invalid Extended JSON mode: %s (expected canonical, relaxed or auto)", cfg.Import.ExtJSONMode)
+	}
+	if (cmd == "import" || cmd == "diff" || cmd == "verify") && cfg.Import.NonFinite != importer.NonFiniteKeep &&
+		cfg.Import.NonFinite != importer.NonFiniteNull && cfg.Import.NonFinite != importer.NonFiniteReject {
+		log.Fatalf("Invalid --nan: %s (expected keep, null or reject)", cfg.Import.NonFinite)
+	}
+	if cmd == "export" && cfg.Export.Format != exporter.FormatArray && cfg.Export.Format != exporter.FormatNDJSON &&
+		cfg.Export.Format != exporter.FormatPretty && cfg.Export.Format != exporter.FormatBSON && cfg.Export.Format != exporter.FormatParquet &&
+		cfg.Export.Format != exporter.FormatAvro && cfg.Export.Format != exporter.FormatArrow {
+		log.Fatalf("Invalid export format: %s (expected array, ndjson, pretty, bson, parquet, avro or arrow)", cfg.Export.Format)
+	}
+	if cmd == "export" && cfg.Export.BatchSize < 0 {
+		log.Fatalf("Invalid batch size: %d", cfg.Export.BatchSize)
+	}
+	if cmd == "export" && cfg.Export.Parallel < 1 {
+		log.Fatalf("Invalid --parallel: %d", cfg.Export.Parallel)
+	}
+	if cmd == "export" && cfg.Export.Parallel > 1 && cfg.Export.Resume {
+		log.Fatal("--parallel cannot be combined with --resume")
+	}
+	if cmd == "export" && cfg.Export.Resume && (cfg.Export.Format == exporter.FormatParquet || cfg.Export.Format == exporter.FormatAvro || cfg.Export.Format == exporter.FormatArrow) {
+		log.Fatalf("--resume is not supported with --export-format %s", cfg.Export.Format)
+	}
+	if cmd == "import" && cfg.Import.BatchSize <= 0 {
+		log.Fatalf("Invalid batch size: %d", cfg.Import.BatchSize)
+	}
+	if cmd == "import" && (cfg.Import.Retry.Attempts < 1 || cfg.Import.Retry.Jitter < 0 || cfg.Import.Retry.Jitter > 1) {
+		log.Fatalf("Invalid retry settings: attempts %d, jitter %v", cfg.Import.Retry.Attempts, cfg.Import.Retry.Jitter)
+	}
+	if cmd == "import" && (cfg.Import.RateLimit.DocsPerSec < 0 || cfg.Import.RateLimit.BatchesPerSec < 0) {
+		log.Fatalf("Invalid rate limit: %v docs/s, %v batches/s", cfg.Import.RateLimit.DocsPerSec, cfg.Import.RateLimit.BatchesPerSec)
+	}
+	if cmd == "import" && cfg.Import.Concurrency <= 0 {
+		log.Fatalf("Invalid concurrency: %d", cfg.Import.Concurrency)
+	}
+	if cfg.OpTimeout < 0 || cfg.RunTimeout < 0 {
+		log.Fatal("--op-timeout and --run-timeout must not be negative")
+	}
+	cfg.Import.DB = cfg.DB
+	cfg.Import.Unordered = !cfg.Ordered
+	cfg.Import.OpTimeout = cfg.OpTimeout
+	cfg.Import.Collection = cfg.Collection
+	cfg.Export.DB, cfg.Export.Collection, cfg.Export.OpTimeout = cfg.DB, cfg.Collection, cfg.OpTimeout
+	if cmd == "import" || cmd == "diff" || cmd == "verify" {
+		d, err := importer.ParseDelimiter(cfg.Delimiter)
+		if err != nil {
+			log.Fatalf("Invalid delimiter: %v", err)
+		}
+		hints, err := importer.ParseFieldHints(cfg.FieldHints)
+		if err != nil {
+			log.Fatalf("Invalid fields: %v", err)
+		}
+		cfg.Import.CSV = importer.CSVOptions{Delimiter: d, Fields: hints}
+	}
+	if cfg.ShiftDates != "" {
+		shift, err := importer.ParseDateShift(cfg.ShiftDates, cfg.ShiftDatesField)
+		if err != nil {
+			log.Fatalf("Invalid date shift: %v", err)
+		}
+		cfg.Import.DateShift = shift
+	} else if cfg.ShiftDatesField != "" {
+		log.Fatal("--shift-dates-field requires --shift-dates")
+	}
+	if cfg.Transform != "" {
+		transforms, err := importer.LoadTransforms(cfg.Transform)
+		if err != nil {
+			log.Fatalf("Invalid transform file: %v", err)
+		}
+		cfg.Import.Transforms = transforms
+	}
+	if cfg.MaskFile != "" {
+		mask, err := importer.LoadMaskConfig(cfg.MaskFile)
+		if err != nil {
+			log.Fatalf("Invalid mask file: %v", err)
+		}
+		cfg.Import.Mask = mask
+	}
+	if cfg.HooksFile != "" {
+		hooks, err := importer.LoadHooks(cfg.HooksFile)
+		if err != nil {
+			log.Fatalf("Invalid hooks file: %v", err)
+		}
+		cfg.Import.Hooks = hooks
+	}
+	if cfg.Filter != "" {
+		filter, err := importer.ParseFilter(cfg.Filter)
+		if err != nil {
+			log.Fatalf("Invalid filter: %v", err)
+		}
+		cfg.Import.Filter = filter
+	}
+	if cfg.OnlyFields != "" || cfg.DropFields != "" {
+		var err error
+		if cfg.Import.OnlyFields, err = importer.ParseFieldList(cfg.OnlyFields); err != nil {
+			log.Fatalf("Invalid only-fields: %v", err)
+		}
+		if cfg.Import.DropFields, err = importer.ParseFieldList(cfg.DropFields); err != nil {
+			log.Fatalf("Invalid drop-fields: %v", err)
+		}
+	}
+	if (cmd == "import" || cmd == "verify") && cfg.Rename != "" {
+		renames, err := importer.ParseRenames(cfg.Rename)
+		if err != nil {
+			log.Fatalf("Invalid rename: %v", err)
+		}
+		cfg.Import.Renames = renames
+	}
+	if cfg.Coerce != "" || cfg.CoerceIDFields != "" {
+		coercion, err := importer.ParseCoercion(cfg.Coerce, cfg.CoerceIDFields)
+		if err != nil {
+			log.Fatalf("Invalid coerce: %v", err)
+		}
+		cfg.Import.Coerce = coercion
+	}
+	if cmd == "export" {
+		var err error
+		if cfg.Export.Query.Filter, err = exporter.ParseDocument(cfg.Query); err != nil {
+			log.Fatalf("Invalid query: %v", err)
+		}
+		if cfg.Export.Query.Projection, err = exporter.ParseDocument(cfg.Projection); err != nil {
+			log.Fatalf("Invalid projection: %v", err)
+		}
+		if cfg.QueryFile != "" {
+			if cfg.Export.Queries, err = exporter.LoadQueries(cfg.QueryFile); err != nil {
+				log.Fatalf("Invalid query file: %v", err)
+			}
+		}
+	}
+	if cmd == "sync" && cfg.Query != "" {
+		log.Fatal("--query cannot be combined with sync: changes to documents outside the query would still be applied")
+	}
+	if cmd == "copy" || cmd == "sync" {
+		var err error
+		if cfg.Copy.Filter, err = exporter.ParseDocument(cfg.Query); err != nil {
+			log.Fatalf("Invalid query: %v", err)
+		}
+		if cfg.Copy.Renames, err = copier.ParseRenames(cfg.Rename); err != nil {
+			log.Fatalf("Invalid rename: %v", err)
+		}
+		for _, name := range strings.Split(cfg.Collection, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Copy.Collections = append(cfg.Copy.Collections, name)
+			}
+		}
+		cfg.Copy.SourceDB, cfg.Copy.DB, cfg.Copy.OpTimeout = cfg.SourceDB, cfg.DB, cfg.OpTimeout
+	}
+	if cmd == "import" || cmd == "verify" {
+		var err error
+		if cfg.Import.Include, err = importer.ParsePatterns(cfg.Include); err != nil {
+			log.Fatalf("Invalid include filter: %v", err)
+		}
+		if cfg.Import.Exclude, err = importer.ParsePatterns(cfg.Exclude); err != nil {
+			log.Fatalf("Invalid exclude filter: %v", err)
+		}
+	}
+	if cmd == "import" && cfg.DependsOn != "" {
+		deps, err := importer.ParseDependencies(cfg.DependsOn)
+		if err != nil {
+			log.Fatalf("Invalid depends-on: %v", err)
+		}
+		cfg.Import.DependsOn = deps
+	}
+	if cfg.MappingFile != "" {
+		mappings, err := importer.LoadMappings(cfg.MappingFile)
+		if err != nil {
+			log.Fatalf("Invalid mapping file: %v", err)
+		}
+		cfg.Import.Mappings = mappings
+	}
+
+	if cmd == "import" && cfg.ScheduleExpr != "" {
+		if serving || cfg.Stdin || cfg.Watch || cfg.GridFS != "" {
+			log.Fatal("--schedule cannot be combined with serve, --stdin, --watch or --gridfs")
+		}
+		schedule, err := cron.Parse(cfg.ScheduleExpr)
+		if err != nil {
+			log.Fatalf("Invalid schedule: %v", err)
+		}
+		if schedule.Next(time.Now()).IsZero() {
+			log.Fatalf("Invalid schedule: %s never runs", cfg.ScheduleExpr)
+		}
+		destructive := cfg.Import.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete)
+		if cfg.Plan != nil {
+			destructive = false
+			for _, step := range cfg.Plan.Steps {
+				destructive = destructive || step.Options(cfg.Import).UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete)
+			}
+		}
+		if destructive && !cfg.Yes {
+			log.Fatal("--schedule deletes documents without asking; pass --yes, or use --strategy upsert, merge or append")
+		}
+		cfg.Schedule = schedule
+	}
+	if cfg.ScheduleStatus != "" && cfg.Schedule == nil {
+		log.Fatal("--schedule-status requires --schedule")
+	}
+
+	if serving {
+		// --path 是 POST /import 的 path 所在的目錄；upload 不需要
+		if cfg.Stdin || cfg.Watch || cfg.PlanFile != "" || cfg.GridFS != "" {
+			log.Fatal("serve cannot be combined with --stdin, --watch, --plan or --gridfs")
+		}
+		if cfg.Path != "" {
+			if fi, err := os.Stat(cfg.Path); err != nil || !fi.IsDir() {
+				log.Fatal("serve requires --path to be a directory (the root of the paths given to POST /import)")
+			}
+		}
+		if !cfg.Yes && cfg.Import.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete) {
+			log.Fatal("serve deletes documents without asking; pass --yes, or use --strategy upsert, merge or append")
+		}
+		if cfg.ServeToken == "" && !isLoopback(cfg.ServeAddr) {
+			log.Fatalf("serve on %s accepts imports from the network; pass --serve-token, or listen on 127.0.0.1", cfg.ServeAddr)
+		}
+		if cfg.ServeMaxUpload <= 0 {
+			log.Fatalf("Invalid --max-upload-mb: %d", cfg.ServeMaxUpload)
+		}
+		cfg.ServeMaxUpload <<= 20
+		cmd = "serve"
+	}
+
+	if cfg.JobMode {
+		if cmd == "serve" || cmd == "sync" || cmd == "tail" || cmd == "consume" || cfg.Watch || cfg.Schedule != nil {
+			log.Fatal("--job is for runs that finish; it cannot be combined with serve, sync, tail, consume, --watch or --schedule")
+		}
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["log-format"] && os.Getenv("LOG_FORMAT") == "" {
+			cfg.LogFormat = "json"
+		}
+		if !set["wait-for-mongo"] && os.Getenv("WAIT_FOR_MONGO") == "" {
+			cfg.WaitForMongo = defaultJobWait
+		}
+	}
+	if cfg.WaitForMongo < 0 {
+		log.Fatalf("Invalid wait-for-mongo: %s", cfg.WaitForMongo)
+	}
+
+	return cmd, cfg
+}
+
+// envOr 讀取環境變數，空值時回傳預設值
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// envInt 讀取整數環境變數，空值時回傳預設值
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return n
+}
+
+// envBool 讀取布林環境變數，接受 strconv.ParseBool 的值（1/0、t/f、true/false）；yes/no 等其他值視為錯誤
+func envBool(key string) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return b
+}
+
+// envDuration 讀取時間長度環境變數（例如 500ms、30s）
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return d
+}
+
+// envFloat 讀取浮點數環境變數
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", key, err)
+	}
+	return f
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// clientOptions 以 URI 為基礎，再套用 --write-concern / --journal / --wtimeout / --read-preference、連線池與 timeout、TLS、認證旗標與 monitor；
+// 沒給的旗標沿用 URI 上的設定
+func clientOptions(cfg config) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(cfg.URI)
+	// ApplyURI 的解析錯誤會在 Validate 時回傳
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid MongoDB URI: %v", err)
+	}
+
+	if cfg.WriteConcern != "" || cfg.Journal != "" || cfg.WTimeout > 0 {
+		wc := &writeconcern.WriteConcern{}
+		if opts.WriteConcern != nil {
+			*wc = *opts.WriteConcern
+		}
+		if cfg.WriteConcern != "" {
+			wc.W = parseW(cfg.WriteConcern)
+		}
+		if cfg.Journal != "" {
+			j, err := strconv.ParseBool(cfg.Journal)
+			if err != nil {
+				return nil, fmt.Errorf("invalid journal: %v", err)
+			}
+			wc.Journal = &j
+		}
+		if cfg.WTimeout > 0 {
+			wc.WTimeout = cfg.WTimeout
+		}
+		if !wc.IsValid() {
+			return nil, fmt.Errorf("invalid write concern: %s", describeWriteConcern(wc))
+		}
+		opts.SetWriteConcern(wc)
+	}
+
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read preference: %v", err)
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read preference: %v", err)
+		}
+		opts.SetReadPreference(rp)
+	}
+
+	if err := applyPool(opts, cfg); err != nil {
+		return nil, err
+	}
+	if err := applyTLS(opts, cfg); err != nil {
+		return nil, err
+	}
+	if err := applyAuth(opts, cfg); err != nil {
+		return nil, err
+	}
+	applyMonitoring(opts, cfg)
+	return opts, nil
+}
+
+// applyPool 套用 --max-pool-size、--socket-timeout、--server-selection-timeout 與 --compressors，
+// 跨越 WAN 匯入時不必修改 URI 就能調整
+func applyPool(opts *options.ClientOptions, cfg config) error {
+	if cfg.MaxPoolSize < 0 {
+		return fmt.Errorf("invalid max pool size: %d", cfg.MaxPoolSize)
+	}
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(uint64(cfg.MaxPoolSize))
+	}
+	if cfg.SocketTimeout > 0 {
+		opts.SetSocketTimeout(cfg.SocketTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+	if cfg.Compressors == "" {
+		return nil
+	}
+	var compressors []string
+	for _, c := range strings.Split(cfg.Compressors, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		switch c {
+		case "":
+			continue
+		case "zstd", "zlib", "snappy":
+		default:
+			return fmt.Errorf("invalid compressor: %s (expected zstd, zlib or snappy)", c)
+		}
+		if !slices.Contains(compressors, c) {
+			compressors = append(compressors, c)
+		}
+	}
+	opts.SetCompressors(compressors)
+	return nil
+}
+
+// sourceConfig copy 與 diff 連到 --source-uri 時的設定：只沿用 read preference 與連線調整，
+// 寫入確認、TLS 與認證旗標只套用在目標
+func (c config) sourceConfig() config {
+	return config{
+		URI:                    c.SourceURI,
+		ReadPreference:         c.ReadPreference,
+		MaxPoolSize:            c.MaxPoolSize,
+		SocketTimeout:          c.SocketTimeout,
+		ServerSelectionTimeout: c.ServerSelectionTimeout,
+		Compressors:            c.Compressors,
+		Verbose:                c.Verbose,
+		SlowCommand:            c.SlowCommand,
+	}
+}
+
+// applyTLS 套用 --tls-*；URI 已經設定的 TLS 選項（tlsCAFile 等）保留，旗標只覆蓋有給的部分
+func applyTLS(opts *options.ClientOptions, cfg config) error {
+	if !cfg.TLS && cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && !cfg.TLSInsecure && !cfg.X509 {
+		return nil
+	}
+	tc := &tls.Config{}
+	if opts.TLSConfig != nil {
+		tc = opts.TLSConfig.Clone()
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return fmt.Errorf("invalid TLS CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("invalid TLS CA file: no PEM certificates in %s", cfg.TLSCAFile)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" {
+		// 沒給 --tls-key-file 時私鑰與憑證放在同一個 PEM 檔（跟 tlsCertificateKeyFile 一樣）
+		keyFile := cfg.TLSKeyFile
+		if keyFile == "" {
+			keyFile = cfg.TLSCertFile
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("invalid TLS client certificate: %v", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.TLSInsecure {
+		tc.InsecureSkipVerify = true
+	}
+	opts.SetTLSConfig(tc)
+
+	if cfg.X509 && len(tc.Certificates) == 0 {
+		return errors.New("X.509 authentication requires a client certificate (--tls-cert-file)")
+	}
+	return nil
+}
+
+// authMechanisms --auth-mechanism 可用的值；PLAIN 即 LDAP
+var authMechanisms = []string{"SCRAM-SHA-1", "SCRAM-SHA-256", "MONGODB-X509", "MONGODB-AWS", "PLAIN", "GSSAPI"}
+
+// applyAuth 套用 --auth-mechanism / --username / --password / --auth-source / --aws-role-arn；
+// URI 上的帳號密碼保留，旗標只覆蓋有給的部分
+func applyAuth(opts *options.ClientOptions, cfg config) error {
+	mechanism := strings.ToUpper(cfg.AuthMechanism)
+	if cfg.X509 {
+		if mechanism != "" && mechanism != "MONGODB-X509" {
+			return fmt.Errorf("--x509 conflicts with --auth-mechanism %s", cfg.AuthMechanism)
+		}
+		mechanism = "MONGODB-X509"
+	}
+	if mechanism == "" && cfg.Username == "" && cfg.Password == "" && cfg.AuthSource == "" && cfg.AWSRoleARN == "" {
+		return nil
+	}
+	if mechanism != "" && !slices.Contains(authMechanisms, mechanism) {
+		return fmt.Errorf("invalid auth mechanism: %s (expected %s)", cfg.AuthMechanism, strings.Join(authMechanisms, ", "))
+	}
+
+	cred := options.Credential{}
+	if opts.Auth != nil {
+		cred = *opts.Auth
+	}
+	if mechanism != "" {
+		cred.AuthMechanism = mechanism
+	}
+	if cfg.Username != "" {
+		cred.Username = cfg.Username
+	}
+	if cfg.Password != "" {
+		cred.Password = cfg.Password
+		cred.PasswordSet = true
+	}
+	if cfg.AuthSource != "" {
+		cred.AuthSource = cfg.AuthSource
+	}
+
+	switch cred.AuthMechanism {
+	case "MONGODB-X509", "MONGODB-AWS", "PLAIN", "GSSAPI":
+		// 這些機制的使用者定義在 $external
+		if cred.AuthSource == "" {
+			cred.AuthSource = "$external"
+		}
+	}
+	if cred.AuthMechanism == "MONGODB-X509" {
+		// 使用者名稱由 server 從憑證的 subject 取得
+		cred.Password, cred.PasswordSet = "", false
+	}
+
+	// MONGODB-AWS 沒有指定 role 時由 driver 依 AWS_ACCESS_KEY_ID / AWS_SESSION_TOKEN、ECS 或 EC2 metadata 取得憑證
+	if cfg.AWSRoleARN != "" {
+		if cred.AuthMechanism != "MONGODB-AWS" {
+			return errors.New("--aws-role-arn requires --auth-mechanism MONGODB-AWS")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		creds, err := remote.AssumeRole(ctx, cfg.AWSRoleARN, cfg.AWSSessionName)
+		if err != nil {
+			return fmt.Errorf("failed to assume role %s: %v", cfg.AWSRoleARN, err)
+		}
+		cred.Username, cred.Password, cred.PasswordSet = creds.AccessKeyID, creds.SecretAccessKey, true
+		cred.AuthMechanismProperties = map[string]string{"AWS_SESSION_TOKEN": creds.SessionToken}
+	}
+	if cred.AuthMechanism != "MONGODB-X509" && cred.AuthMechanism != "MONGODB-AWS" && cred.AuthMechanism != "GSSAPI" && cred.Username == "" {
+		return fmt.Errorf("%s authentication requires --username", mechanismName(cred.AuthMechanism))
+	}
+	opts.SetAuth(cred)
+	return nil
+}
+
+// mechanismName 用於錯誤訊息；空字串表示由 server 協商 SCRAM
+func mechanismName(m string) string {
+	if m == "" {
+		return "SCRAM"
+	}
+	return m
+}
+
+// parseW 數字視為節點數，其他（majority 或 tag 名稱）原樣傳給 server
+func parseW(s string) interface{} {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
+
+// describeWriteConcern 用於錯誤訊息與啟動時的記錄
+func describeWriteConcern(wc *writeconcern.WriteConcern) string {
+	if wc == nil {
+		return "server default"
+	}
+	s := fmt.Sprintf("w=%v", wc.W)
+	if wc.W == nil {
+		s = "w=default"
+	}
+	if wc.Journal != nil {
+		s += fmt.Sprintf(" j=%v", *wc.Journal)
+	}
+	if wc.WTimeout > 0 {
+		s += fmt.Sprintf(" wtimeout=%s", wc.WTimeout.Round(time.Millisecond))
+	}
+	return s
+}
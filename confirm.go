@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultProdPattern host 或 database 名稱符合時視為正式環境，可用 PROD_PATTERN 覆蓋
+const defaultProdPattern = `(?i)(^|[^a-z])prod(uction)?([^a-z]|$)`
+
+// namespace 會被清空或刪除的 collection
+type namespace struct {
+	DB, Collection string
+}
+
+// truncateAction 確認訊息中對 truncate 的描述；有 --scope 或 --tenant-field 時只刪除範圍內的文件
+func truncateAction(opts importer.Options) string {
+	if s := opts.TruncateScope(); len(s) > 0 {
+		scope, _ := bson.MarshalExtJSON(s, false, false)
+		return fmt.Sprintf("delete the documents matching %s in these collections and reload them", scope)
+	}
+	return "delete every document in these collections and reload them"
+}
+
+// deletes 會刪除文件的 strategy：truncate 清空後重新匯入，delete 刪除 key 與檔案內文件相同的文件
+func deletes(strategy string) bool {
+	return strategy == importer.StrategyTruncate || strategy == importer.StrategyDelete
+}
+
+// deleteAction 確認訊息中要做的事；依 opts 用到 truncate、delete 或兩者
+func deleteAction(opts importer.Options) string {
+	const byKey = "delete the documents whose --key matches a document of the file"
+	switch {
+	case !opts.UsesStrategy(importer.StrategyDelete):
+		return truncateAction(opts)
+	case !opts.UsesStrategy(importer.StrategyTruncate):
+		return byKey + " in these collections"
+	}
+	return truncateAction(opts) + " (for the delete strategy, " + byKey + ")"
+}
+
+// confirmDestructive 清空 / 刪除 collection 之前確認：URI 看起來是正式環境時沒有 --allow-prod 一律拒絕；
+// 否則列出 host、database、collection 與目前的文件數，等使用者輸入 yes（--yes 時略過）
+func confirmDestructive(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, action string, targets []namespace) {
+	if len(targets) == 0 {
+		return
+	}
+	hosts := strings.Join(clientOpts.Hosts, ",")
+
+	if !cfg.AllowProd {
+		matched, err := prodMatch(clientOpts, targets)
+		if err != nil {
+			log.Fatalf("Invalid PROD_PATTERN: %v", err)
+		}
+		if matched != "" {
+			fatal(fmt.Sprintf("❌ Refusing to %s on %s: it looks like production (matched %q); pass --allow-prod to proceed", action, hosts, matched),
+				"hosts", hosts, "matched", matched)
+		}
+	}
+	if cfg.Yes {
+		return
+	}
+	if cfg.JobMode {
+		fatal("❌ Cannot ask for confirmation in --job mode; pass --yes", "hosts", hosts)
+	}
+	if cfg.Path == importer.Stdin {
+		fatal("❌ Cannot ask for confirmation while reading data from stdin; pass --yes", "hosts", hosts)
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		fatal("❌ Not a terminal; pass --yes to confirm destructive operations", "hosts", hosts)
+	}
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(os.Stderr, "⚠️  This will %s on %s:\n", action, hosts)
+	seen := map[namespace]bool{}
+	for _, t := range targets {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		count := "?"
+		if n, err := client.Database(t.DB).Collection(t.Collection).EstimatedDocumentCount(ctx); err == nil {
+			count = fmt.Sprint(n)
+		}
+		fmt.Fprintf(w, "   %s.%s\t%s docs\n", t.DB, t.Collection, count)
+	}
+	w.Flush()
+	fmt.Fprint(os.Stderr, `Type "yes" to continue: `)
+
+	// 等待輸入時按 Ctrl+C 也要能離開
+	input := make(chan string, 1)
+	go func() {
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		input <- answer
+	}()
+	var answer string
+	select {
+	case <-ctx.Done():
+	case answer = <-input:
+	}
+	// 輸入與 Ctrl+C 同時發生時以 ctx 為準；Ctrl+C 與其他中斷一樣以 exitInterrupted 結束，--run-timeout 逾時則是一般的失敗
+	if err := ctx.Err(); err != nil {
+		fmt.Fprintln(os.Stderr)
+		if errors.Is(err, context.Canceled) {
+			fatalCode(exitInterrupted, "👋 Aborted.")
+		}
+		fatal("👋 Aborted.")
+	}
+	if strings.TrimSpace(strings.ToLower(answer)) != "yes" {
+		fatal("👋 Aborted.")
+	}
+}
+
+// prodMatch 回傳第一個符合 PROD_PATTERN 的 host 或 database 名稱，都不符合時回傳空字串
+func prodMatch(clientOpts *options.ClientOptions, targets []namespace) (string, error) {
+	pattern, err := regexp.Compile(envOr("PROD_PATTERN", defaultProdPattern))
+	if err != nil {
+		return "", err
+	}
+	candidates := append([]string{}, clientOpts.Hosts...)
+	for _, t := range targets {
+		candidates = append(candidates, t.DB)
+	}
+	for _, c := range candidates {
+		if pattern.MatchString(c) {
+			return c, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hayletdomybest/mongo-tools/consumer"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runConsume 把 --topic 的訊息寫入 --collection，直到 Ctrl+C / SIGTERM
+func runConsume(ctx context.Context, client *mongo.Client, cfg config) int {
+	cfg.Consume.Logger = logger
+	c, err := consumer.New(client, cfg.Consume)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid consume options: %v", err), errAttr(err))
+	}
+
+	stats, err := c.Run(ctx)
+	logger.Info(fmt.Sprintf("📊 %d inserted, %d upserted, %d duplicates, %d dead-lettered", stats.Inserted, stats.Upserted, stats.Duplicates, stats.DeadLettered),
+		"topic", cfg.Consume.Topic, "collection", cfg.Collection, "inserted", stats.Inserted, "upserted", stats.Upserted, "duplicates", stats.Duplicates, "dead_lettered", stats.DeadLettered)
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Consume failed: %v", err), "topic", cfg.Consume.Topic, errAttr(err))
+		return exitFailure
+	}
+	logger.Info("👋 Stopped consuming.")
+	return exitOK
+}
@@ -0,0 +1,368 @@
+// Package consumer 消費 Kafka topic 的 Extended JSON 訊息並分批寫入 collection：
+// 每批寫入成功後才 commit offset，中斷後重新啟動會從上次 commit 的位置接續（至少一次），
+// 無法解析或寫入的訊息轉送到 dead-letter topic。
+package consumer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/metrics"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 預設值
+const (
+	DefaultBatchSize    = 500
+	DefaultBatchTimeout = time.Second
+	DefaultGroup        = "mongo-tools"
+)
+
+// Options.StartOffset 的值
+const (
+	StartFirst = "first" // 新的 consumer group 從最早的訊息開始
+	StartLast  = "last"  // 新的 consumer group 只讀之後的訊息
+)
+
+// progressInterval 多久記錄一次累計的訊息數
+const progressInterval = 30 * time.Second
+
+// shutdownTimeout 停止時寫入並 commit 最後一批最多等多久
+const shutdownTimeout = 30 * time.Second
+
+// dead-letter 訊息附加的 header
+const (
+	HeaderError     = "x-error"
+	HeaderTopic     = "x-source-topic"
+	HeaderPartition = "x-source-partition"
+	HeaderOffset    = "x-source-offset"
+)
+
+// Options 消費設定
+type Options struct {
+	Brokers      []string
+	Topic        string
+	Group        string // consumer group，offset commit 在這個 group 底下
+	StartOffset  string // StartFirst（預設）或 StartLast，只影響還沒有 commit 過的 group
+	DLQTopic     string // 無效的訊息寫到這個 topic；空字串表示只記錄警告後略過
+	DB           string
+	Collection   string
+	KeyField     string        // 非空時依這個欄位（a.b 路徑）upsert，重送的訊息會覆蓋同一筆文件；否則 insert
+	BatchSize    int           // 每批寫入的訊息數
+	BatchTimeout time.Duration // 批次未滿時最多等多久就寫入
+	OpTimeout    time.Duration // 每批寫入與 commit 的 timeout；0 表示不限制
+	TLS          *tls.Config   // 非 nil 時以 TLS 連線到 broker
+	SASL         sasl.Mechanism
+	Metrics      *metrics.Metrics // --metrics-addr 的計數，nil 表示不記錄
+	Logger       *slog.Logger     // nil 時使用 slog.Default()
+}
+
+// Stats Run 處理的訊息數
+type Stats struct {
+	Inserted     int64
+	Upserted     int64 // KeyField 時新增或取代的文件
+	Duplicates   int64 // insert 時 _id 已存在的訊息，通常是中斷後重送的訊息
+	DeadLettered int64 // 無法解析或寫入、轉送到 DLQTopic（或略過）的訊息
+}
+
+// Consumer 把一個 topic 的訊息寫入一個 collection
+type Consumer struct {
+	client *mongo.Client
+	opts   Options
+	log    *slog.Logger
+	dlq    *kafka.Writer
+}
+
+// New 檢查 opts 並補齊預設值
+func New(client *mongo.Client, opts Options) (*Consumer, error) {
+	if len(opts.Brokers) == 0 || opts.Topic == "" {
+		return nil, errors.New("missing brokers or topic")
+	}
+	if opts.DB == "" || opts.Collection == "" {
+		return nil, errors.New("missing database or collection")
+	}
+	if opts.DLQTopic == opts.Topic {
+		return nil, errors.New("the dead-letter topic must differ from the consumed topic")
+	}
+	switch opts.StartOffset {
+	case "":
+		opts.StartOffset = StartFirst
+	case StartFirst, StartLast:
+	default:
+		return nil, fmt.Errorf("unknown start offset %q (expected first or last)", opts.StartOffset)
+	}
+	if opts.Group == "" {
+		opts.Group = DefaultGroup
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.BatchTimeout <= 0 {
+		opts.BatchTimeout = DefaultBatchTimeout
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	c := &Consumer{client: client, opts: opts, log: opts.Logger}
+	if opts.DLQTopic != "" {
+		c.dlq = &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Topic:        opts.DLQTopic,
+			RequiredAcks: kafka.RequireAll,
+			Transport:    &kafka.Transport{TLS: opts.TLS, SASL: opts.SASL},
+		}
+	}
+	return c, nil
+}
+
+// ParseSASL 依名稱（plain、scram-sha-256 或 scram-sha-512）建立 SASL 認證；name 為空字串時回傳 nil
+func ParseSASL(name, username, password string) (sasl.Mechanism, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("unknown SASL mechanism %q (expected plain, scram-sha-256 or scram-sha-512)", name)
+}
+
+// Run 持續消費直到 ctx 被取消；停止時先寫入並 commit 手上的批次。寫入 MongoDB、DLQ 或 commit 失敗時回傳錯誤，
+// 這一批沒有 commit，下次啟動時會重新讀到
+func (c *Consumer) Run(ctx context.Context) (Stats, error) {
+	var stats Stats
+	startOffset := kafka.FirstOffset
+	if c.opts.StartOffset == StartLast {
+		startOffset = kafka.LastOffset
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     c.opts.Brokers,
+		GroupID:     c.opts.Group,
+		Topic:       c.opts.Topic,
+		StartOffset: startOffset,
+		Dialer:      &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true, TLS: c.opts.TLS, SASLMechanism: c.opts.SASL},
+	})
+	defer reader.Close()
+	if c.dlq != nil {
+		defer c.dlq.Close()
+	}
+
+	ns := c.opts.DB + "." + c.opts.Collection
+	c.log.Info(fmt.Sprintf("📥 Consuming %s (group %s) into %s until stopped", c.opts.Topic, c.opts.Group, ns),
+		"topic", c.opts.Topic, "group", c.opts.Group, "target", ns)
+
+	batch := make([]kafka.Message, 0, c.opts.BatchSize)
+	deadline := time.Time{}
+	lastLog := time.Now()
+	for {
+		fetchCtx, cancel := ctx, context.CancelFunc(func() {})
+		if len(batch) > 0 {
+			fetchCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err == nil {
+			if len(batch) == 0 {
+				deadline = time.Now().Add(c.opts.BatchTimeout)
+			}
+			batch = append(batch, msg)
+			if len(batch) < c.opts.BatchSize {
+				continue
+			}
+		} else if ctx.Err() != nil {
+			// 停止前寫入手上的批次，ctx 已經取消，改用不會被取消的 context
+			if len(batch) == 0 {
+				return stats, nil
+			}
+			shutdown, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+			defer cancel()
+			return stats, c.flush(shutdown, reader, batch, &stats)
+		} else if !errors.Is(err, context.DeadlineExceeded) {
+			return stats, fmt.Errorf("failed to read from %s: %v", c.opts.Topic, err)
+		}
+
+		if err := c.flush(ctx, reader, batch, &stats); err != nil {
+			return stats, err
+		}
+		batch = batch[:0]
+		if time.Since(lastLog) >= progressInterval {
+			lastLog = time.Now()
+			c.log.Info(fmt.Sprintf("📊 %d inserted, %d upserted, %d duplicates, %d dead-lettered", stats.Inserted, stats.Upserted, stats.Duplicates, stats.DeadLettered),
+				"topic", c.opts.Topic, "inserted", stats.Inserted, "upserted", stats.Upserted, "duplicates", stats.Duplicates, "dead_lettered", stats.DeadLettered)
+		}
+	}
+}
+
+// flush 寫入一批訊息、把失敗的訊息送到 DLQ，全部成功後才 commit
+func (c *Consumer) flush(ctx context.Context, reader *kafka.Reader, batch []kafka.Message, stats *Stats) error {
+	started := time.Now()
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+
+	docs := make([]bson.D, 0, len(batch))
+	index := make([]int, 0, len(batch)) // docs 的第幾筆 → batch 的第幾筆
+	failed := map[int]error{}
+	for n, msg := range batch {
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(msg.Value, false, &doc); err != nil {
+			failed[n] = fmt.Errorf("invalid Extended JSON: %v", err)
+			continue
+		}
+		if c.opts.KeyField != "" {
+			if _, err := keyValue(doc, c.opts.KeyField); err != nil {
+				failed[n] = err
+				continue
+			}
+		}
+		docs = append(docs, doc)
+		index = append(index, n)
+	}
+
+	if len(docs) > 0 {
+		writeErrs, err := c.write(ctx, docs, stats)
+		if err != nil {
+			return fmt.Errorf("failed to write %d documents into %s.%s: %v", len(docs), c.opts.DB, c.opts.Collection, err)
+		}
+		for k, werr := range writeErrs {
+			failed[index[k]] = werr
+		}
+	}
+	c.opts.Metrics.Batch(time.Since(started))
+
+	for n := range batch {
+		if err, ok := failed[n]; ok {
+			if err := c.deadLetter(ctx, batch[n], err); err != nil {
+				return err
+			}
+			stats.DeadLettered++
+			c.opts.Metrics.Error(c.opts.Collection)
+		}
+	}
+	if err := reader.CommitMessages(ctx, batch...); err != nil {
+		return fmt.Errorf("failed to commit offsets: %v", err)
+	}
+	last := batch[len(batch)-1]
+	c.opts.Metrics.Lag(time.Since(last.Time))
+	c.log.Debug(fmt.Sprintf("✅ Wrote %d messages from %s up to partition %d offset %d", len(batch), c.opts.Topic, last.Partition, last.Offset),
+		"topic", c.opts.Topic, "count", len(batch), "failed", len(failed), "partition", last.Partition, "offset", last.Offset, "duration_ms", time.Since(started).Milliseconds())
+	return nil
+}
+
+// write 以 unordered 寫入 docs；回傳個別文件的寫入錯誤（依 docs 的位置），整批失敗時回傳 error。
+// insert 時 duplicate key 視為已經寫入過
+func (c *Consumer) write(ctx context.Context, docs []bson.D, stats *Stats) (map[int]error, error) {
+	coll := c.client.Database(c.opts.DB).Collection(c.opts.Collection)
+	var err error
+	operation := "insert"
+	if c.opts.KeyField == "" {
+		batch := make([]interface{}, len(docs))
+		for n, d := range docs {
+			batch[n] = d
+		}
+		_, err = coll.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+	} else {
+		operation = "upsert"
+		models := make([]mongo.WriteModel, len(docs))
+		for n, d := range docs {
+			key, _ := keyValue(d, c.opts.KeyField)
+			models[n] = mongo.NewReplaceOneModel().SetFilter(bson.D{{Key: c.opts.KeyField, Value: key}}).SetReplacement(d).SetUpsert(true)
+		}
+		_, err = coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	}
+
+	failed := map[int]error{}
+	duplicates := 0
+	if err != nil {
+		var bwe mongo.BulkWriteException
+		if !errors.As(err, &bwe) || bwe.WriteConcernError != nil {
+			return nil, err
+		}
+		for _, we := range bwe.WriteErrors {
+			if operation == "insert" && mongo.IsDuplicateKeyError(we) {
+				duplicates++
+				continue
+			}
+			failed[we.Index] = errors.New(we.Message)
+		}
+	}
+	written := len(docs) - len(failed) - duplicates
+	if operation == "insert" {
+		stats.Inserted += int64(written)
+	} else {
+		stats.Upserted += int64(written)
+	}
+	stats.Duplicates += int64(duplicates)
+	c.opts.Metrics.Docs(c.opts.Collection, operation, written)
+	return failed, nil
+}
+
+// deadLetter 把訊息原樣送到 DLQTopic，加上錯誤與原本位置的 header；沒有 DLQTopic 時只記錄警告
+func (c *Consumer) deadLetter(ctx context.Context, msg kafka.Message, cause error) error {
+	attrs := []any{"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, slog.String("error", cause.Error())}
+	if c.dlq == nil {
+		c.log.Warn(fmt.Sprintf("⚠️  Skipping message %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, cause), attrs...)
+		return nil
+	}
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+	if err := c.dlq.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}); err != nil {
+		return fmt.Errorf("failed to write message %s/%d@%d to %s: %v", msg.Topic, msg.Partition, msg.Offset, c.opts.DLQTopic, err)
+	}
+	c.log.Warn(fmt.Sprintf("⚠️  Sent message %s/%d@%d to %s: %v", msg.Topic, msg.Partition, msg.Offset, c.opts.DLQTopic, cause), attrs...)
+	return nil
+}
+
+// keyValue 取出 a.b 路徑的值；缺少時回傳錯誤
+func keyValue(doc bson.D, path string) (interface{}, error) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for n, part := range parts {
+		var next interface{}
+		found := false
+		for _, e := range cur {
+			if e.Key == part {
+				next, found = e.Value, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		if n == len(parts)-1 {
+			return next, nil
+		}
+		sub, ok := next.(bson.D)
+		if !ok {
+			break
+		}
+		cur = sub
+	}
+	return nil, fmt.Errorf("missing key field %s", path)
+}
+
+func (c *Consumer) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.OpTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.OpTimeout)
+}
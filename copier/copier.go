@@ -0,0 +1,231 @@
+// Package copier 把 collection 直接從一個 MongoDB 串流複製到另一個（或同一個 server 的另一個 database），
+// 不需要先匯出成檔案。
+package copier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultBatchSize 每次 InsertMany 的文件數
+const DefaultBatchSize = 1000
+
+// Options 複製設定
+type Options struct {
+	SourceDB    string            // 來源 database
+	DB          string            // 目標 database
+	Collections []string          // 只複製這些 collection，空的表示全部
+	Filter      bson.D            // 只複製符合這個查詢的文件（在來源 server 執行）
+	Renames     map[string]string // 來源 collection → 目標 collection，沒列出的沿用原名
+	Append      bool              // 不先清空目標 collection
+	BatchSize   int
+	OpTimeout   time.Duration    // 列出 collection、清空與每批寫入的 timeout；0 表示不限制
+	Metrics     *metrics.Metrics // sync 的 --metrics-addr 計數，nil 表示不記錄
+	Logger      *slog.Logger     // nil 時使用 slog.Default()
+}
+
+// Copier 在兩個 client 之間複製 collection；兩者可以是同一個 client
+type Copier struct {
+	src, dst *mongo.Client
+	opts     Options
+	log      *slog.Logger
+}
+
+// Result 單一 collection 的複製結果
+type Result struct {
+	Source   string
+	Target   string
+	Docs     int
+	Duration time.Duration
+	Err      error
+}
+
+// New 檢查 opts 並補齊預設值
+func New(src, dst *mongo.Client, opts Options) (*Copier, error) {
+	if opts.SourceDB == "" || opts.DB == "" {
+		return nil, errors.New("missing source or target database")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Copier{src: src, dst: dst, opts: opts, log: opts.Logger}, nil
+}
+
+// Target 來源 collection 對應的目標 collection
+func (c *Copier) Target(coll string) string {
+	if to, ok := c.opts.Renames[coll]; ok {
+		return to
+	}
+	return coll
+}
+
+// Collections 要複製的來源 collection：Options.Collections，或來源 database 的所有一般 collection（略過 view 與 system.*）
+func (c *Copier) Collections(ctx context.Context) ([]string, error) {
+	if len(c.opts.Collections) > 0 {
+		return c.opts.Collections, nil
+	}
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	names, err := c.src.Database(c.opts.SourceDB).ListCollectionNames(ctx, bson.M{"type": "collection"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %v", err)
+	}
+	var out []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, "system.") {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// CopyAll 依序複製 collections；個別 collection 的錯誤記錄在 Result.Err，ctx 被取消後不再開始新的 collection
+func (c *Copier) CopyAll(ctx context.Context, collections []string) []Result {
+	results := make([]Result, 0, len(collections))
+	for _, coll := range collections {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, c.CopyCollection(ctx, coll))
+	}
+	return results
+}
+
+// CopyCollection 以 cursor 讀取來源、分批寫入目標
+func (c *Copier) CopyCollection(ctx context.Context, coll string) (res Result) {
+	to := c.Target(coll)
+	res = Result{Source: c.opts.SourceDB + "." + coll, Target: c.opts.DB + "." + to}
+	started := time.Now()
+	defer func() {
+		res.Duration = time.Since(started)
+		if res.Err != nil && ctx.Err() == nil {
+			c.opts.Metrics.Error(res.Target)
+		}
+	}()
+
+	c.log.Info(fmt.Sprintf("🔀 Copying %s → %s", res.Source, res.Target), "source", res.Source, "target", res.Target)
+
+	target := c.dst.Database(c.opts.DB).Collection(to)
+	if !c.opts.Append {
+		err := c.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.DeleteMany(ctx, bson.M{})
+			return err
+		})
+		if err != nil {
+			c.log.Error(fmt.Sprintf("❌ Failed to clear collection %s: %v", res.Target, err), "target", res.Target, errAttr(err))
+			res.Err = err
+			return res
+		}
+	}
+
+	var filter interface{} = bson.D{}
+	if c.opts.Filter != nil {
+		filter = c.opts.Filter
+	}
+	findCtx, cancel := c.opContext(ctx)
+	defer cancel()
+	// cursor 之後以 ctx 讀取，大 collection 的複製時間不受 OpTimeout 限制
+	cursor, err := c.src.Database(c.opts.SourceDB).Collection(coll).Find(findCtx, filter, options.Find().SetBatchSize(int32(c.opts.BatchSize)))
+	if err != nil {
+		c.log.Error(fmt.Sprintf("❌ Failed to query %s: %v", res.Source, err), "source", res.Source, errAttr(err))
+		res.Err = err
+		return res
+	}
+	defer cursor.Close(ctx)
+
+	batch := make([]interface{}, 0, c.opts.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		written := time.Now()
+		err := c.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.InsertMany(ctx, batch)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		c.opts.Metrics.Batch(time.Since(written))
+		c.opts.Metrics.Docs(res.Target, "insert", len(batch))
+		res.Docs += len(batch)
+		batch = make([]interface{}, 0, c.opts.BatchSize)
+		return nil
+	}
+	for cursor.Next(ctx) {
+		// cursor.Current 在下一次 Next 後會被覆寫，必須複製一份
+		batch = append(batch, bson.Raw(append([]byte(nil), cursor.Current...)))
+		if len(batch) >= c.opts.BatchSize {
+			if err = flush(); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		c.log.Error(fmt.Sprintf("❌ Failed to copy %s → %s after %d docs: %v", res.Source, res.Target, res.Docs, err),
+			"source", res.Source, "target", res.Target, "count", res.Docs, errAttr(err))
+		res.Err = err
+		return res
+	}
+	c.log.Info(fmt.Sprintf("✅ Copied %d docs into %s", res.Docs, res.Target),
+		"source", res.Source, "target", res.Target, "count", res.Docs, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
+
+// ParseRenames 解析 --rename：以逗號分隔的 <來源>:<目標>，例如 users:users_copy,orders:orders_2024
+func ParseRenames(s string) (map[string]string, error) {
+	renames := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, ":")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename %q (expected <source>:<target>)", pair)
+		}
+		if _, dup := renames[from]; dup {
+			return nil, fmt.Errorf("%s is renamed more than once", from)
+		}
+		renames[from] = to
+	}
+	return renames, nil
+}
+
+// opContext OpTimeout 為 0 時不限制
+func (c *Copier) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.OpTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.OpTimeout)
+}
+
+func (c *Copier) withOpTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := c.opContext(ctx)
+	defer cancel()
+	return fn(ctx)
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
@@ -0,0 +1,151 @@
+package copier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// syncProgressInterval 持續同步時多久記錄一次累計的變更數
+const syncProgressInterval = 30 * time.Second
+
+// SyncStats Sync 套用到目標的變更數
+type SyncStats struct {
+	Inserted int64
+	Updated  int64 // update 與 replace
+	Deleted  int64
+	Skipped  int64 // drop、rename 等不會自動套用的事件，以及查不到最新內容的 update
+}
+
+// changeEvent change stream 事件中用到的欄位
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey  bson.Raw            `bson:"documentKey"`
+	FullDocument bson.Raw            `bson:"fullDocument"`
+	ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+}
+
+// Sync 先在來源 database 開啟 change stream，再以 CopyAll 做初次複製，之後持續把變更套用到目標，直到 ctx 被取消。
+// 複製期間發生的變更在複製完成後重播；insert / update / replace 一律以完整文件 upsert、delete 依 documentKey 刪除，
+// 重播已經複製過的變更不會重複寫入。沒有指定 Options.Collections 時，之後才建立的 collection 也會同步。
+// 來源必須是 replica set 或 sharded cluster；初次複製失敗時不進入持續同步
+func (c *Copier) Sync(ctx context.Context, collections []string) ([]Result, SyncStats, error) {
+	var stats SyncStats
+	match := bson.D{{Key: "ns.coll", Value: bson.D{{Key: "$not", Value: primitive.Regex{Pattern: `^system\.`}}}}}
+	if len(c.opts.Collections) > 0 {
+		match = bson.D{{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: collections}}}}
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	watchCtx, cancel := c.opContext(ctx)
+	stream, err := c.src.Database(c.opts.SourceDB).Watch(watchCtx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	cancel()
+	if err != nil {
+		return nil, stats, fmt.Errorf("failed to open a change stream on %s (requires a replica set or sharded cluster): %v", c.opts.SourceDB, err)
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	results := c.CopyAll(ctx, collections)
+	for _, r := range results {
+		if r.Err != nil {
+			return results, stats, fmt.Errorf("initial copy of %s failed: %v", r.Source, r.Err)
+		}
+	}
+	if ctx.Err() != nil {
+		return results, stats, nil
+	}
+
+	c.log.Info(fmt.Sprintf("🔄 Initial copy done; applying changes from %s to %s until stopped", c.opts.SourceDB, c.opts.DB),
+		"source_db", c.opts.SourceDB, "db", c.opts.DB)
+	lastLog := time.Now()
+	for stream.Next(ctx) {
+		var ev changeEvent
+		if err := stream.Decode(&ev); err != nil {
+			return results, stats, fmt.Errorf("failed to decode change event: %v", err)
+		}
+		if ev.OperationType == "invalidate" {
+			return results, stats, errors.New("the change stream was invalidated (the source database was dropped or renamed)")
+		}
+		applied := time.Now()
+		if err := c.apply(ctx, ev, &stats); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			c.opts.Metrics.Error(c.opts.DB + "." + c.Target(ev.NS.Coll))
+			c.log.Error(fmt.Sprintf("❌ Failed to apply %s on %s: %v", ev.OperationType, ev.NS.Coll, err),
+				"operation", ev.OperationType, "collection", ev.NS.Coll, errAttr(err))
+			return results, stats, err
+		}
+		c.opts.Metrics.Batch(time.Since(applied))
+		if ev.ClusterTime.T > 0 {
+			c.opts.Metrics.Lag(time.Since(time.Unix(int64(ev.ClusterTime.T), 0)))
+		}
+		if time.Since(lastLog) >= syncProgressInterval {
+			c.logSync(stats)
+			lastLog = time.Now()
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		return results, stats, fmt.Errorf("change stream failed: %v", err)
+	}
+	c.logSync(stats)
+	return results, stats, nil
+}
+
+// apply 把一個事件套用到對應的目標 collection
+func (c *Copier) apply(ctx context.Context, ev changeEvent, stats *SyncStats) error {
+	target := c.dst.Database(c.opts.DB).Collection(c.Target(ev.NS.Coll))
+	ns := c.opts.DB + "." + target.Name()
+	switch ev.OperationType {
+	case "insert", "update", "replace":
+		if ev.FullDocument == nil {
+			// update 之後文件已經被刪除，稍後的 delete 事件會處理
+			stats.Skipped++
+			return nil
+		}
+		err := c.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.ReplaceOne(ctx, ev.DocumentKey, ev.FullDocument, options.Replace().SetUpsert(true))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		c.opts.Metrics.Docs(ns, ev.OperationType, 1)
+		if ev.OperationType == "insert" {
+			stats.Inserted++
+		} else {
+			stats.Updated++
+		}
+	case "delete":
+		err := c.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.DeleteOne(ctx, ev.DocumentKey)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		c.opts.Metrics.Docs(ns, "delete", 1)
+		stats.Deleted++
+	default:
+		// drop、rename、dropDatabase 會影響整個 collection，不自動套用
+		stats.Skipped++
+		c.log.Warn(fmt.Sprintf("⚠️  Not applying %s on %s.%s to %s; apply it to the target yourself if needed", ev.OperationType, c.opts.SourceDB, ev.NS.Coll, ns),
+			"operation", ev.OperationType, "collection", ev.NS.Coll, "target", ns)
+		return nil
+	}
+	c.log.Debug(fmt.Sprintf("🔄 Applied %s to %s", ev.OperationType, ns), "operation", ev.OperationType, "target", ns)
+	return nil
+}
+
+func (c *Copier) logSync(s SyncStats) {
+	c.log.Info(fmt.Sprintf("🔄 Synced %d inserts, %d updates, %d deletes (%d skipped)", s.Inserted, s.Updated, s.Deleted, s.Skipped),
+		"inserted", s.Inserted, "updated", s.Updated, "deleted", s.Deleted, "skipped", s.Skipped)
+}
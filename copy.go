@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hayletdomybest/mongo-tools/copier"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runCopy 把 --source-uri / --source-db 的 collection 串流複製到 --uri / --db；
+// sync 在複製後持續套用來源的變更，直到 Ctrl+C / SIGTERM
+func runCopy(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, cmd string) int {
+	source := client
+	from := cfg.SourceDB
+	if cfg.SourceURI != "" {
+		opts, err := clientOptions(cfg.sourceConfig())
+		if err != nil {
+			fatal(fmt.Sprintf("Invalid source URI: %v", err), errAttr(err))
+		}
+		if source, err = connect(ctx, opts, cfg.OpTimeout); err != nil {
+			fatal(fmt.Sprintf("Mongo connect error: %v", err), errAttr(err))
+		}
+		defer source.Disconnect(context.TODO())
+	}
+
+	cfg.Copy.Logger = logger
+	cp, err := copier.New(source, client, cfg.Copy)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid copy options: %v", err), errAttr(err))
+	}
+	collections, err := cp.Collections(ctx)
+	if err != nil {
+		fatal(fmt.Sprintf("❌ Copy failed: %v", err), errAttr(err))
+	}
+	if !cfg.Copy.Append {
+		targets := make([]namespace, 0, len(collections))
+		for _, coll := range collections {
+			targets = append(targets, namespace{cfg.DB, cp.Target(coll)})
+		}
+		confirmDestructive(ctx, client, clientOpts, cfg, "delete every document in these collections and copy them from "+from, targets)
+	}
+
+	if cmd == "sync" {
+		return runSync(ctx, cp, collections)
+	}
+
+	results := cp.CopyAll(ctx, collections)
+	docs, failed := 0, 0
+	for _, r := range results {
+		docs += r.Docs
+		if r.Err != nil {
+			failed++
+		}
+	}
+	logger.Info(fmt.Sprintf("📊 %d collections, %d docs copied, %d failed, %d not started", len(results), docs, failed, len(collections)-len(results)),
+		"collections", len(results), "count", docs, "failed", failed, "not_run", len(collections)-len(results))
+	if failed > 0 || len(results) < len(collections) {
+		return exitFailure
+	}
+	logger.Info("✅ All copies completed.")
+	return exitOK
+}
+
+// runSync 初次複製後持續同步；以 Ctrl+C 正常結束
+func runSync(ctx context.Context, cp *copier.Copier, collections []string) int {
+	results, stats, err := cp.Sync(ctx, collections)
+	docs := 0
+	for _, r := range results {
+		docs += r.Docs
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Sync failed: %v", err), "count", docs, "inserted", stats.Inserted, "updated", stats.Updated, "deleted", stats.Deleted, errAttr(err))
+		return exitFailure
+	}
+	if len(results) < len(collections) {
+		logger.Error(fmt.Sprintf("❌ Interrupted during the initial copy (%d of %d collections)", len(results), len(collections)),
+			"collections", len(results), "not_run", len(collections)-len(results))
+		return exitInterrupted
+	}
+	logger.Info("👋 Stopped syncing.")
+	return exitOK
+}
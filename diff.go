@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hayletdomybest/mongo-tools/diff"
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// diffSamples 每種差異列出幾個 _id
+const diffSamples = 10
+
+// runDiff 比較 --path 的資料檔（或 --source-uri / --source-db 的 collection）與線上的 collection
+func runDiff(ctx context.Context, client *mongo.Client, cfg config) int {
+	coll := cfg.Collection
+	if coll == "" {
+		coll = importer.CollectionName(cfg.Path)
+	}
+	if coll == "" {
+		fatal(fmt.Sprintf("Cannot infer the collection from %s; use --collection", cfg.Path), "path", cfg.Path)
+	}
+	target := client.Database(cfg.DB).Collection(coll)
+
+	var src diff.Source
+	from := cfg.Path
+	if cfg.SourceDB != "" {
+		sourceClient := client
+		if cfg.SourceURI != "" {
+			opts, err := clientOptions(cfg.sourceConfig())
+			if err != nil {
+				fatal(fmt.Sprintf("Invalid source URI: %v", err), errAttr(err))
+			}
+			if sourceClient, err = connect(ctx, opts, cfg.OpTimeout); err != nil {
+				fatal(fmt.Sprintf("Mongo connect error: %v", err), errAttr(err))
+			}
+			defer sourceClient.Disconnect(context.TODO())
+		}
+		cs, err := diff.NewCursorSource(ctx, sourceClient.Database(cfg.SourceDB).Collection(coll))
+		if err != nil {
+			fatal(fmt.Sprintf("❌ Failed to query %s.%s: %v", cfg.SourceDB, coll, err), errAttr(err))
+		}
+		defer cs.Close()
+		src, from = cs, cfg.SourceDB+"."+coll
+	} else {
+		docs, err := importer.OpenDocuments(ctx, cfg.Path, cfg.Import)
+		if err != nil {
+			fatal(fmt.Sprintf("❌ Failed to read %s: %v", cfg.Path, err), "file", cfg.Path, errAttr(err))
+		}
+		defer docs.Close()
+		src = docs
+	}
+
+	opts := diff.Options{Samples: diffSamples, Ignore: cfg.Import.StampFields()}
+	if cfg.Delta != "" {
+		var w io.Writer = os.Stdout
+		if cfg.Delta != "-" {
+			f, err := os.Create(cfg.Delta)
+			if err != nil {
+				fatal(fmt.Sprintf("❌ Failed to create %s: %v", cfg.Delta, err), "file", cfg.Delta, errAttr(err))
+			}
+			defer f.Close()
+			w = f
+		}
+		opts.Delta = w
+	}
+
+	logger.Info(fmt.Sprintf("🔍 Comparing %s → %s.%s", from, cfg.DB, coll), "source", from, "collection", coll)
+	res, err := diff.Collection(ctx, src, target, opts)
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Diff failed: %v", err), "collection", coll, errAttr(err))
+		return exitFailure
+	}
+
+	for _, op := range []diff.Op{diff.OpAdd, diff.OpRemove, diff.OpChange} {
+		if ids := res.Samples[op]; len(ids) > 0 {
+			logger.Info(fmt.Sprintf("   %s: %s", op, strings.Join(ids, ", ")), "op", string(op), "ids", ids)
+		}
+	}
+	logger.Info(fmt.Sprintf("📊 %d added, %d removed, %d changed, %d unchanged", res.Added, res.Removed, res.Changed, res.Unchanged),
+		"collection", coll, "added", res.Added, "removed", res.Removed, "changed", res.Changed, "unchanged", res.Unchanged)
+	if !res.Identical() {
+		return exitDifferent
+	}
+	return exitOK
+}
@@ -0,0 +1,237 @@
+// Package diff 比較來源（資料檔或另一個 collection）與線上 collection 的差異，
+// 在決定是否要執行會清空資料的匯入之前先確認影響範圍。
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Source 逐筆提供來源文件，讀完回傳 io.EOF；*importer.Documents 即符合
+type Source interface {
+	Next() (bson.M, error)
+}
+
+// Op 差異的種類，以「匯入來源之後」的角度命名
+type Op string
+
+const (
+	OpAdd    Op = "add"    // 來源有、collection 沒有
+	OpRemove Op = "remove" // collection 有、來源沒有
+	OpChange Op = "change" // 兩邊的 _id 相同但內容不同
+)
+
+// Options 比較設定
+type Options struct {
+	Delta   io.Writer // 不為 nil 時把每筆差異以 NDJSON 寫出
+	Samples int       // 每種差異保留幾個 _id 供顯示
+	Ignore  []string  // 比較時忽略的最上層欄位，例如匯入時加上的中繼欄位
+}
+
+// Result 比較結果
+type Result struct {
+	Added, Removed, Changed, Unchanged int
+	Samples                            map[Op][]string // 每種差異前幾筆的 _id（Extended JSON）
+}
+
+// Identical 兩邊完全相同
+func (r Result) Identical() bool {
+	return r.Added == 0 && r.Removed == 0 && r.Changed == 0
+}
+
+// deltaRecord --delta 輸出的每一行
+type deltaRecord struct {
+	Op      Op              `json:"op"`
+	ID      json.RawMessage `json:"_id,omitempty"`
+	Fields  []string        `json:"fields,omitempty"` // change 時不同的最上層欄位
+	Doc     json.RawMessage `json:"doc,omitempty"`    // add / change 時來源的文件
+	Current json.RawMessage `json:"current,omitempty"`
+}
+
+// Collection 把來源整個讀進記憶體（以 _id 為索引），再掃過 target 一次比較
+func Collection(ctx context.Context, src Source, target *mongo.Collection, opts Options) (Result, error) {
+	res := Result{Samples: map[Op][]string{}}
+	d := &differ{opts: opts, res: &res}
+
+	source := map[string]bson.M{}
+	var order []string // 保持來源的順序，add 的輸出才穩定
+	var noID []bson.M
+	for {
+		doc, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		id, ok := doc["_id"]
+		if !ok {
+			// 沒有 _id 的文件無法比對，匯入時一定是新增
+			noID = append(noID, doc)
+			continue
+		}
+		key := idKey(id)
+		if _, dup := source[key]; !dup {
+			order = append(order, key)
+		}
+		source[key] = doc
+	}
+
+	cursor, err := target.Find(ctx, bson.M{})
+	if err != nil {
+		return res, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var current bson.M
+		if err := cursor.Decode(&current); err != nil {
+			return res, err
+		}
+		key := idKey(current["_id"])
+		want, ok := source[key]
+		if !ok {
+			if err := d.record(OpRemove, key, nil, current, nil); err != nil {
+				return res, err
+			}
+			continue
+		}
+		delete(source, key)
+		if fields := changedFields(want, current, opts.Ignore); len(fields) > 0 {
+			if err := d.record(OpChange, key, want, current, fields); err != nil {
+				return res, err
+			}
+			continue
+		}
+		res.Unchanged++
+	}
+	if err := cursor.Err(); err != nil {
+		return res, err
+	}
+
+	for _, key := range order {
+		if doc, ok := source[key]; ok {
+			if err := d.record(OpAdd, key, doc, nil, nil); err != nil {
+				return res, err
+			}
+		}
+	}
+	for _, doc := range noID {
+		if err := d.record(OpAdd, "", doc, nil, nil); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+type differ struct {
+	opts Options
+	res  *Result
+}
+
+func (d *differ) record(op Op, key string, doc, current bson.M, fields []string) error {
+	switch op {
+	case OpAdd:
+		d.res.Added++
+	case OpRemove:
+		d.res.Removed++
+	case OpChange:
+		d.res.Changed++
+	}
+	if key != "" && len(d.res.Samples[op]) < d.opts.Samples {
+		d.res.Samples[op] = append(d.res.Samples[op], key)
+	}
+	if d.opts.Delta == nil {
+		return nil
+	}
+
+	rec := deltaRecord{Op: op, Fields: fields}
+	if key != "" {
+		rec.ID = json.RawMessage(key)
+	}
+	var err error
+	if doc != nil {
+		if rec.Doc, err = bson.MarshalExtJSON(doc, true, false); err != nil {
+			return err
+		}
+	}
+	if current != nil && op != OpAdd {
+		if rec.Current, err = bson.MarshalExtJSON(current, true, false); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(d.opts.Delta, "%s\n", line)
+	return err
+}
+
+// idKey 以 canonical Extended JSON 表示 _id；int32(1) 與 int64(1) 會被當成不同的 _id（server 視為相同），
+// 同一份資料匯出再比較時型別一致，不影響結果
+func idKey(id interface{}) string {
+	b, err := bson.MarshalExtJSON(bson.M{"v": id}, true, false)
+	if err != nil {
+		return fmt.Sprint(id)
+	}
+	// {"v":...} → ...
+	return string(b[5 : len(b)-1])
+}
+
+// changedFields 回傳兩份文件中值不同（或只有一邊有）的最上層欄位，依名稱排序；ignore 的欄位不列入
+func changedFields(a, b bson.M, ignore []string) []string {
+	var fields []string
+	for k, v := range a {
+		if w, ok := b[k]; (!ok || !reflect.DeepEqual(v, w)) && !slices.Contains(ignore, k) {
+			fields = append(fields, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok && !slices.Contains(ignore, k) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// CursorSource 把另一個 collection 當成來源；呼叫端負責關閉 cursor
+type CursorSource struct {
+	ctx    context.Context
+	cursor *mongo.Cursor
+}
+
+// NewCursorSource 掃過整個 collection
+func NewCursorSource(ctx context.Context, coll *mongo.Collection) (*CursorSource, error) {
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	return &CursorSource{ctx: ctx, cursor: cursor}, nil
+}
+
+func (s *CursorSource) Next() (bson.M, error) {
+	if !s.cursor.Next(s.ctx) {
+		if err := s.cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	var doc bson.M
+	if err := s.cursor.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (s *CursorSource) Close() error {
+	return s.cursor.Close(s.ctx)
+}
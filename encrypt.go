@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hayletdomybest/mongo-tools/internal/crypt"
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+)
+
+// encryptionKey 依 --encryption-key、--encryption-key-file 或 --kms-data-key 取得 export 加密與 import 解密的 AES-256 金鑰；
+// 都沒有設定時回傳 nil。--kms-data-key 是 aws kms generate-data-key 的 CiphertextBlob，以 KMS Decrypt 解開
+func encryptionKey(ctx context.Context, cfg config) ([]byte, error) {
+	switch {
+	case cfg.EncryptionKey != "":
+		return crypt.ParseKey(cfg.EncryptionKey)
+	case cfg.EncryptionKeyFile != "":
+		data, err := os.ReadFile(cfg.EncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := crypt.ParseKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", cfg.EncryptionKeyFile, err)
+		}
+		return key, nil
+	case cfg.KMSDataKey != "":
+		blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(cfg.KMSDataKey))
+		if err != nil {
+			return nil, fmt.Errorf("KMS data key is not valid base64: %v", err)
+		}
+		key, err := remote.KMSDecrypt(ctx, blob)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != crypt.KeySize {
+			return nil, fmt.Errorf("KMS data key is %d bytes, expected %d (generate it with --key-spec AES_256)", len(key), crypt.KeySize)
+		}
+		logger.Debug("🔑 Decrypted the data key with KMS")
+		return key, nil
+	}
+	return nil, nil
+}
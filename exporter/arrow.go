@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// arrowWriter 以 Apache Arrow 的 IPC writer 寫出 streaming format：開頭一個 Schema message，
+// 每次 flush 一個 RecordBatch，最後是 end-of-stream 標記
+type arrowWriter struct {
+	buf *recordBuffer
+	w   *ipc.Writer
+}
+
+func newArrowWriter(out io.Writer) func(cols []*column) (columnarWriter, error) {
+	return func(cols []*column) (columnarWriter, error) {
+		schema := arrowSchema(cols)
+		return &arrowWriter{buf: newRecordBuffer(schema, cols), w: ipc.NewWriter(out, ipc.WithSchema(schema))}, nil
+	}
+}
+
+func (a *arrowWriter) add(doc bson.Raw) int {
+	return a.buf.add(doc)
+}
+
+func (a *arrowWriter) flush() error {
+	rec := a.buf.take()
+	defer rec.Release()
+	return a.w.Write(rec)
+}
+
+func (a *arrowWriter) close() error {
+	a.buf.release()
+	return a.w.Close()
+}
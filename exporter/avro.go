@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// avroWriter 以 goavro 寫出 Avro Object Container File：header 內含由欄位型別產生的 schema，
+// 每次 flush 一個 deflate 壓縮的 block；每個欄位都是 ["null", T] 的 union
+type avroWriter struct {
+	ocf   *goavro.OCFWriter
+	cols  []*column
+	names []string // 每個欄位在 schema 中的名稱
+	rows  []interface{}
+}
+
+// avroField schema 中的一個欄位
+type avroField struct {
+	Name    string        `json:"name"`
+	Doc     string        `json:"doc,omitempty"` // 名稱被改寫時記錄原本的欄位名稱
+	Type    []interface{} `json:"type"`
+	Default interface{}   `json:"default"`
+}
+
+// avroSchema 最上層的 record
+type avroSchema struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+// avroSchemaFor 欄位名稱改寫成 Avro 允許的 [A-Za-z_][A-Za-z0-9_]*，重複時加上序號
+func avroSchemaFor(db, coll string, cols []*column) avroSchema {
+	s := avroSchema{Type: "record", Name: avroName(coll)}
+	ns := strings.Split(db, ".")
+	for i := range ns {
+		ns[i] = avroName(ns[i])
+	}
+	s.Namespace = strings.Join(ns, ".")
+	used := map[string]bool{}
+	for _, c := range cols {
+		name := avroName(c.name)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%d", avroName(c.name), n)
+		}
+		used[name] = true
+		f := avroField{Name: name, Type: []interface{}{"null", avroType(c)}}
+		if name != c.name {
+			f.Doc = c.name
+		}
+		s.Fields = append(s.Fields, f)
+	}
+	return s
+}
+
+// avroName 把不允許的字元換成 _，數字開頭時前面補 _
+func avroName(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func avroType(c *column) interface{} {
+	switch c.kind {
+	case kindInt64:
+		return "long"
+	case kindDouble:
+		return "double"
+	case kindBool:
+		return "boolean"
+	case kindTimestamp:
+		return map[string]interface{}{"type": "long", "logicalType": "timestamp-millis"}
+	case kindDecimal:
+		return map[string]interface{}{"type": "bytes", "logicalType": "decimal", "precision": c.precision(), "scale": c.scale}
+	}
+	return "string"
+}
+
+// newAvroWriter record 名稱取自 collection，db 作為 namespace
+func newAvroWriter(out io.Writer, db, coll string) func(cols []*column) (columnarWriter, error) {
+	return func(cols []*column) (columnarWriter, error) {
+		s := avroSchemaFor(db, coll, cols)
+		schema, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{W: out, Schema: string(schema), CompressionName: goavro.CompressionDeflateLabel})
+		if err != nil {
+			return nil, err
+		}
+		a := &avroWriter{ocf: ocf, cols: cols}
+		for _, f := range s.Fields {
+			a.names = append(a.names, f.Name)
+		}
+		return a, nil
+	}
+}
+
+// avroBranch union 中值那一邊的名稱，goavro 以它選擇編碼方式
+func avroBranch(c *column) string {
+	switch c.kind {
+	case kindTimestamp:
+		return "long.timestamp-millis"
+	case kindDecimal:
+		return "bytes.decimal"
+	}
+	return avroType(c).(string)
+}
+
+func (a *avroWriter) add(doc bson.Raw) int {
+	nulled := 0
+	row := make(map[string]interface{}, len(a.cols))
+	for n, c := range a.cols {
+		v, ok := c.cell(doc)
+		if !ok {
+			nulled++
+		}
+		if v.null {
+			row[a.names[n]] = nil
+			continue
+		}
+		datum := v.v
+		switch x := v.v.(type) {
+		case int64:
+			if c.kind == kindTimestamp {
+				datum = time.UnixMilli(x).UTC()
+			}
+		case *big.Int:
+			datum = new(big.Rat).SetFrac(x, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.scale)), nil))
+		}
+		row[a.names[n]] = goavro.Union(avroBranch(c), datum)
+	}
+	a.rows = append(a.rows, row)
+	return nulled
+}
+
+func (a *avroWriter) flush() error {
+	if err := a.ocf.Append(a.rows); err != nil {
+		return fmt.Errorf("failed to encode avro block: %v", err)
+	}
+	a.rows = a.rows[:0]
+	return nil
+}
+
+func (a *avroWriter) close() error {
+	return nil
+}
@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+func TestAvroRoundTrip(t *testing.T) {
+	data := writeTestFile(t, func(out *bytes.Buffer) func(cols []*column) (columnarWriter, error) {
+		return newAvroWriter(out, "shop", "orders")
+	})
+
+	r, err := goavro.NewOCFReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Name, Namespace string
+		Fields          []struct {
+			Name string
+			Type []interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(r.Codec().Schema()), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Name != "orders" || schema.Namespace != "shop" {
+		t.Errorf("record is %s.%s, want shop.orders", schema.Namespace, schema.Name)
+	}
+	types := map[string]string{}
+	for _, f := range schema.Fields {
+		b, _ := json.Marshal(f.Type[1])
+		types[f.Name] = string(b)
+	}
+	for name, want := range map[string]string{
+		"_id":     `"string"`,
+		"count":   `"long"`,
+		"score":   `"double"`,
+		"active":  `"boolean"`,
+		"created": `{"logicalType":"timestamp-millis","type":"long"}`,
+		"price":   `{"logicalType":"decimal","precision":4,"scale":2,"type":"bytes"}`,
+		"tags":    `"string"`,
+	} {
+		if types[name] != want {
+			t.Errorf("%s is %s, want %s", name, types[name], want)
+		}
+	}
+
+	var rows []map[string]interface{}
+	for r.Scan() {
+		row, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row.(map[string]interface{}))
+	}
+	if len(rows) != 2 {
+		t.Fatalf("read %d rows, want 2", len(rows))
+	}
+	// 每個值都是 {"型別": 值} 的 union，null 為 nil
+	value := func(row int, field, branch string) interface{} {
+		t.Helper()
+		v := rows[row][field]
+		if v == nil {
+			return nil
+		}
+		return v.(map[string]interface{})[branch]
+	}
+	if got := value(0, "_id", "string"); got != testID.Hex() {
+		t.Errorf("_id = %v, want %s", got, testID.Hex())
+	}
+	if got := value(0, "name", "string"); got != "alice" || rows[1]["name"] != nil {
+		t.Errorf("name = %v, %v", got, rows[1]["name"])
+	}
+	if value(0, "count", "long") != int64(3) || value(1, "count", "long") != int64(1)<<40 {
+		t.Errorf("count = %v, %v", rows[0]["count"], rows[1]["count"])
+	}
+	if value(0, "score", "double") != 7.0 || value(1, "score", "double") != 2.5 {
+		t.Errorf("score = %v, %v", rows[0]["score"], rows[1]["score"])
+	}
+	for row, want := range []*big.Rat{big.NewRat(1250, 100), big.NewRat(-310, 100)} {
+		if got, ok := value(row, "price", "bytes.decimal").(*big.Rat); !ok || got.Cmp(want) != 0 {
+			t.Errorf("price[%d] = %v, want %s", row, rows[row]["price"], want.FloatString(2))
+		}
+	}
+	if got, ok := value(0, "created", "long.timestamp-millis").(time.Time); !ok || !got.Equal(testCreated) || rows[1]["created"] != nil {
+		t.Errorf("created = %v, %v, want %s and null", rows[0]["created"], rows[1]["created"], testCreated)
+	}
+	if value(0, "active", "boolean") != true || value(1, "active", "boolean") != false {
+		t.Errorf("active = %v, %v", rows[0]["active"], rows[1]["active"])
+	}
+	if got := value(0, "tags", "string"); got != `["a","b"]` || rows[1]["tags"] != nil {
+		t.Errorf("tags = %v, %v", got, rows[1]["tags"])
+	}
+}
+
+func TestAvroNames(t *testing.T) {
+	s := avroSchemaFor("my-db.v1", "2024 orders", []*column{{name: "_id"}, {name: "first-name"}, {name: "first_name"}, {name: "價格"}})
+	if s.Name != "_2024_orders" || s.Namespace != "my_db.v1" {
+		t.Errorf("record is %s.%s, want my_db.v1._2024_orders", s.Namespace, s.Name)
+	}
+	var names, docs []string
+	for _, f := range s.Fields {
+		names, docs = append(names, f.Name), append(docs, f.Doc)
+	}
+	want := []string{"_id", "first_name", "first_name_2", "__"}
+	for n := range want {
+		if names[n] != want[n] {
+			t.Fatalf("fields are %v, want %v", names, want)
+		}
+	}
+	if docs[1] != "first-name" || docs[0] != "" {
+		t.Errorf("docs are %q", docs)
+	}
+	schema, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goavro.NewCodec(string(schema)); err != nil {
+		t.Errorf("goavro rejected the schema: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/manifest"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// checkpointExt Resume 時放在資料檔旁的進度檔，例如 users.json.checkpoint；importer 不認得這個副檔名，不會當成資料匯入
+const checkpointExt = ".checkpoint"
+
+// checkpointState 進度檔的內容，每個 cursor batch 寫完後更新；整次匯出成功後刪除
+type checkpointState struct {
+	Settings  string          `json:"settings"`          // 格式、query 與 projection；不同時從頭匯出
+	LastID    json.RawMessage `json:"last_id,omitempty"` // 最後寫出的文件的 {"_id": ...}（canonical Extended JSON）
+	Docs      int             `json:"docs"`
+	Bytes     int64           `json:"bytes"`  // 資料檔寫到 LastID 為止的長度，接續前截斷到這裡
+	SHA256    string          `json:"sha256"` // 資料檔前 Bytes 個位元組的 SHA-256
+	Complete  bool            `json:"complete"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// checkpoint 單一 collection 匯出時的進度
+type checkpoint struct {
+	path    string
+	state   checkpointState
+	out     *outputFile   // 接續時已截斷到 state.Bytes 的資料檔；從頭匯出時在查詢後才建立
+	resumed int           // 這次從第幾筆文件接續，0 表示從頭開始
+	lastID  bson.RawValue // 接續時 min() 會再讀到上次最後一筆，略過它
+}
+
+// startCheckpoint 讀取上次中斷時的進度；設定不同、資料檔被改過或進度檔壞掉時警告並從頭匯出
+func (e *Exporter) startCheckpoint(coll, filePath string, q Query) *checkpoint {
+	cp := &checkpoint{
+		path:  filePath + checkpointExt,
+		state: checkpointState{Settings: fmt.Sprintf("%s %s %s", e.opts.Format, extJSON(q.Filter), extJSON(q.Projection))},
+	}
+	restart := func(reason string) *checkpoint {
+		e.log.Warn(fmt.Sprintf("⚠️  %s; exporting %s from the start", reason, coll), "collection", coll, "file", filePath)
+		return cp
+	}
+
+	data, err := os.ReadFile(cp.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp
+	}
+	var prev checkpointState
+	if err == nil {
+		err = json.Unmarshal(data, &prev)
+	}
+	switch {
+	case err != nil:
+		return restart(fmt.Sprintf("Cannot read %s (%v)", cp.path, err))
+	case prev.Settings != cp.state.Settings:
+		return restart(fmt.Sprintf("The format or query changed since %s was written", cp.path))
+	case prev.Complete:
+		if fi, err := os.Stat(filePath); err != nil || fi.Size() != prev.Bytes {
+			return restart(fmt.Sprintf("%s changed since it was exported", filePath))
+		}
+		cp.state = prev
+		return cp
+	case prev.Docs == 0:
+		return cp
+	}
+
+	var id bson.Raw
+	if err := bson.UnmarshalExtJSON(prev.LastID, true, &id); err != nil {
+		return restart(fmt.Sprintf("Invalid last _id in %s (%v)", cp.path, err))
+	}
+	out, err := resumeOutput(filePath, prev)
+	if err != nil {
+		return restart(fmt.Sprintf("Cannot continue %s (%v)", filePath, err))
+	}
+	cp.state, cp.out, cp.resumed, cp.lastID = prev, out, prev.Docs, id.Lookup("_id")
+	e.log.Info(fmt.Sprintf("⏯️  Resuming %s after %d docs (%d bytes)", coll, prev.Docs, prev.Bytes),
+		"collection", coll, "file", filePath, "count", prev.Docs, "bytes", prev.Bytes)
+	return cp
+}
+
+// resumeOutput 開啟中斷的資料檔，確認前 st.Bytes 個位元組與進度檔記錄的相同後截斷其餘的部分
+func resumeOutput(filePath string, st checkpointState) (*outputFile, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	h, err := manifest.ResumeHasher(file, io.LimitReader(file, st.Bytes))
+	if err == nil && (h.Bytes() != st.Bytes || h.Sum() != st.SHA256) {
+		err = errors.New("the file does not match the checkpoint")
+	}
+	if err == nil {
+		err = file.Truncate(st.Bytes)
+	}
+	if err == nil {
+		_, err = file.Seek(st.Bytes, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &outputFile{Writer: h, hash: h, file: file}, nil
+}
+
+// apply 依 _id 排序，接續時以 min() 從上次最後一筆開始；min() 照索引順序比較，_id 的型別不一致時也不會漏掉文件
+func (c *checkpoint) apply(opts *options.FindOptions) {
+	opts.SetSort(bson.D{{Key: "_id", Value: 1}})
+	if c.resumed > 0 {
+		opts.SetHint(bson.D{{Key: "_id", Value: 1}}).SetMin(bson.D{{Key: "_id", Value: c.lastID}})
+	}
+}
+
+// docs 已經寫出的文件數；nil 時為 0
+func (c *checkpoint) docs() int {
+	if c == nil {
+		return 0
+	}
+	return c.resumed
+}
+
+// skip 接續後的第一筆是上次最後寫出的文件（min() 包含下界）
+func (c *checkpoint) skip(doc bson.Raw) bool {
+	if c == nil || c.lastID.Type == 0 {
+		return false
+	}
+	last := c.lastID
+	c.lastID = bson.RawValue{}
+	return doc.Lookup("_id").Equal(last)
+}
+
+// batchDone 每個 cursor batch 寫完後把緩衝的內容落盤，再記下最後一筆的 _id 與資料檔長度；nil 時不做事
+func (c *checkpoint) batchDone(w *bufio.Writer, docs int, last bson.Raw) error {
+	if c == nil {
+		return nil
+	}
+	id, err := last.LookupErr("_id")
+	if err != nil {
+		return errors.New("--resume needs the _id of every document; do not exclude it with the projection")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := c.out.file.Sync(); err != nil {
+		return err
+	}
+	raw, err := bson.MarshalExtJSON(bson.D{{Key: "_id", Value: id}}, true, false)
+	if err != nil {
+		return err
+	}
+	c.state.LastID, c.state.Docs = raw, docs
+	c.state.Bytes, c.state.SHA256 = c.out.hash.Bytes(), c.out.hash.Sum()
+	return c.write()
+}
+
+// finish 記下匯出完成；整次匯出有 collection 失敗時，重新執行會略過已完成的 collection
+func (c *checkpoint) finish(res Result) error {
+	c.state.Complete, c.state.Docs, c.state.Bytes, c.state.SHA256 = true, res.Docs, res.Bytes, res.SHA256
+	return c.write()
+}
+
+// write 先寫暫存檔再 rename，中斷時不會留下寫一半的進度檔
+func (c *checkpoint) write() error {
+	c.state.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path+".tmp", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(c.path+".tmp", c.path)
+}
+
+// clearCheckpoints 所有 collection 都匯出成功後刪除進度檔，下次從頭匯出
+func (e *Exporter) clearCheckpoints(results []Result) {
+	for _, r := range results {
+		path := r.File + checkpointExt
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			e.log.Warn(fmt.Sprintf("⚠️  Failed to remove %s: %v", path, err), "file", path, errAttr(err))
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// columnarRowGroupSize 欄位式格式（parquet、avro、arrow）每個 row group / block / record batch 的筆數；
+// 寫出前整組都在記憶體中
+const columnarRowGroupSize = 50000
+
+// maxDecimalPrecision 128 bits 的 decimal 能放下的最大位數
+const maxDecimalPrecision = 38
+
+// columnKind 由整個 collection 的值推斷出的欄位型別
+type columnKind int
+
+const (
+	kindNull      columnKind = iota // 目前只看過 null
+	kindString                      // string、ObjectID（hex）與其他無法對應的型別（relaxed Extended JSON）
+	kindInt64                       // int32 / int64
+	kindDouble                      // double，或混合 int 與 double
+	kindBool                        // bool
+	kindTimestamp                   // date 與 timestamp，毫秒
+	kindDecimal                     // Decimal128，decimal(precision, scale)
+)
+
+// column 最上層的一個欄位；子文件與陣列寫成 Extended JSON 字串
+type column struct {
+	name      string
+	kind      columnKind
+	scale     int // kindDecimal：所有值中最多的小數位數
+	intDigits int // kindDecimal：所有值中最多的整數位數
+}
+
+// precision kindDecimal 的總位數
+func (c *column) precision() int {
+	return max(c.intDigits+c.scale, 1)
+}
+
+// columnarWriter 一種欄位式格式的輸出；欄位在建立時就已經決定
+type columnarWriter interface {
+	// add 加入一列；回傳因為型別不符而寫成 null 的值的數量
+	add(doc bson.Raw) int
+	// flush 把目前累積的列寫成一個 row group / block / record batch
+	flush() error
+	// close 寫出結尾（footer 等）
+	close() error
+}
+
+// writeColumnar 先讀過 first 推斷每個最上層欄位的型別，再以 reopen 重新查詢，依 newWriter 的格式寫出。
+// 型別對應：ObjectID → string、Decimal128 → decimal、date → timestamp(ms)、子文件與陣列 → Extended JSON 字串；
+// 同一個欄位出現多種型別時整欄改為字串。兩次查詢之間被改成不同型別的值寫成 null，回傳這類值的數量
+func writeColumnar(ctx context.Context, first *mongo.Cursor, reopen func() (*mongo.Cursor, error), newWriter func(cols []*column) (columnarWriter, error)) (int, int, error) {
+	cols, err := inferColumns(ctx, first)
+	if err != nil {
+		return 0, 0, err
+	}
+	w, err := newWriter(cols)
+	if err != nil {
+		return 0, 0, err
+	}
+	cursor, err := reopen()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	rows, nulled, inGroup := 0, 0, 0
+	for cursor.Next(ctx) {
+		nulled += w.add(cursor.Current)
+		rows++
+		if inGroup++; inGroup == columnarRowGroupSize {
+			if err := w.flush(); err != nil {
+				return rows, nulled, err
+			}
+			inGroup = 0
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return rows, nulled, err
+	}
+	if inGroup > 0 {
+		if err := w.flush(); err != nil {
+			return rows, nulled, err
+		}
+	}
+	return rows, nulled, w.close()
+}
+
+// inferColumns 依第一次出現的順序列出最上層欄位並決定型別；沒有任何文件時只有 _id
+func inferColumns(ctx context.Context, cursor *mongo.Cursor) ([]*column, error) {
+	var cols []*column
+	byName := map[string]*column{}
+	for cursor.Next(ctx) {
+		elems, err := cursor.Current.Elements()
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range elems {
+			c, ok := byName[e.Key()]
+			if !ok {
+				c = &column{name: e.Key()}
+				byName[e.Key()] = c
+				cols = append(cols, c)
+			}
+			c.observe(e.Value())
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		cols = append(cols, &column{name: "_id"})
+	}
+	for _, c := range cols {
+		if c.kind == kindDecimal && c.intDigits+c.scale > maxDecimalPrecision {
+			// 超過 38 位數的 decimal 放不進 128 bits
+			c.kind = kindString
+		}
+		if c.kind == kindNull {
+			c.kind = kindString
+		}
+	}
+	return cols, nil
+}
+
+// kindOf 單一值對應的欄位型別
+func kindOf(v bson.RawValue) columnKind {
+	switch v.Type {
+	case bsontype.Null, bsontype.Undefined:
+		return kindNull
+	case bsontype.Int32, bsontype.Int64:
+		return kindInt64
+	case bsontype.Double:
+		return kindDouble
+	case bsontype.Boolean:
+		return kindBool
+	case bsontype.DateTime, bsontype.Timestamp:
+		return kindTimestamp
+	case bsontype.Decimal128:
+		if _, _, err := v.Decimal128().BigInt(); err == nil {
+			return kindDecimal
+		}
+	}
+	// NaN / Infinity 的 Decimal128 也寫成字串
+	return kindString
+}
+
+func (c *column) observe(v bson.RawValue) {
+	k := kindOf(v)
+	switch {
+	case k == kindNull || k == c.kind:
+	case c.kind == kindNull:
+		c.kind = k
+	case (c.kind == kindInt64 && k == kindDouble) || (c.kind == kindDouble && k == kindInt64):
+		c.kind = kindDouble
+	default:
+		c.kind = kindString
+	}
+	if k == kindDecimal {
+		bi, exp, _ := v.Decimal128().BigInt()
+		digits := len(new(big.Int).Abs(bi).String())
+		c.scale = max(c.scale, -exp)
+		c.intDigits = max(c.intDigits, digits+exp)
+	}
+}
+
+// cellValue 一個值依欄位型別轉換後的內容：string、int64、float64、bool、int64（毫秒）或 *big.Int（乘上 10^scale）
+type cellValue struct {
+	v    interface{}
+	null bool // 沒有這個欄位、值是 null，或型別不符
+}
+
+// cell 把 doc 中這個欄位的值轉成 cellValue；型別不符時回傳 null 與 false
+func (c *column) cell(doc bson.Raw) (cellValue, bool) {
+	v := doc.Lookup(c.name)
+	if v.Type == 0 || kindOf(v) == kindNull {
+		return cellValue{null: true}, true
+	}
+	switch c.kind {
+	case kindString:
+		return cellValue{v: stringValue(v)}, true
+	case kindInt64:
+		if v.Type == bsontype.Int32 || v.Type == bsontype.Int64 {
+			n, _ := v.AsInt64OK()
+			return cellValue{v: n}, true
+		}
+	case kindDouble:
+		switch v.Type {
+		case bsontype.Double:
+			return cellValue{v: v.Double()}, true
+		case bsontype.Int32, bsontype.Int64:
+			n, _ := v.AsInt64OK()
+			return cellValue{v: float64(n)}, true
+		}
+	case kindBool:
+		if b, ok := v.BooleanOK(); ok {
+			return cellValue{v: b}, true
+		}
+	case kindTimestamp:
+		switch v.Type {
+		case bsontype.DateTime:
+			return cellValue{v: v.DateTime()}, true
+		case bsontype.Timestamp:
+			t, _ := v.Timestamp()
+			return cellValue{v: int64(t) * 1000}, true
+		}
+	case kindDecimal:
+		if d, ok := v.Decimal128OK(); ok {
+			bi, exp, err := d.BigInt()
+			if err == nil && -exp <= c.scale {
+				bi.Mul(bi, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.scale+exp)), nil))
+				if bi.BitLen() <= 127 {
+					return cellValue{v: bi}, true
+				}
+			}
+		}
+	}
+	return cellValue{null: true}, false
+}
+
+// stringValue 字串欄位的內容：string 與 symbol 原樣、ObjectID 為 hex，其他以 relaxed Extended JSON 表示
+func stringValue(v bson.RawValue) string {
+	switch v.Type {
+	case bsontype.String:
+		return v.StringValue()
+	case bsontype.Symbol:
+		s, _ := v.SymbolOK()
+		return s
+	case bsontype.ObjectID:
+		return v.ObjectID().Hex()
+	}
+	// 單一值不能直接轉成 Extended JSON，包在文件裡再取出
+	b, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, false, false)
+	if err != nil {
+		return v.String()
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(string(b), `{"v":`), "}")
+}
+
+// arrowSchema 欄位對應的 Arrow schema，所有欄位都可以是 null；parquet 與 arrow 都由它寫出
+func arrowSchema(cols []*column) *arrow.Schema {
+	fields := make([]arrow.Field, len(cols))
+	for n, c := range cols {
+		fields[n] = arrow.Field{Name: c.name, Type: arrowType(c), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func arrowType(c *column) arrow.DataType {
+	switch c.kind {
+	case kindInt64:
+		return arrow.PrimitiveTypes.Int64
+	case kindDouble:
+		return arrow.PrimitiveTypes.Float64
+	case kindBool:
+		return arrow.FixedWidthTypes.Boolean
+	case kindTimestamp:
+		return &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}
+	case kindDecimal:
+		return &arrow.Decimal128Type{Precision: int32(c.precision()), Scale: int32(c.scale)}
+	}
+	return arrow.BinaryTypes.String
+}
+
+// recordBuffer 把文件逐列累積成 Arrow record batch
+type recordBuffer struct {
+	cols []*column
+	b    *array.RecordBuilder
+}
+
+func newRecordBuffer(schema *arrow.Schema, cols []*column) *recordBuffer {
+	return &recordBuffer{cols: cols, b: array.NewRecordBuilder(memory.DefaultAllocator, schema)}
+}
+
+// add 加入一列；回傳因為型別不符而寫成 null 的值的數量
+func (r *recordBuffer) add(doc bson.Raw) int {
+	nulled := 0
+	for n, c := range r.cols {
+		v, ok := c.cell(doc)
+		if !ok {
+			nulled++
+		}
+		fb := r.b.Field(n)
+		if v.null {
+			fb.AppendNull()
+			continue
+		}
+		switch b := fb.(type) {
+		case *array.StringBuilder:
+			b.Append(v.v.(string))
+		case *array.Int64Builder:
+			b.Append(v.v.(int64))
+		case *array.Float64Builder:
+			b.Append(v.v.(float64))
+		case *array.BooleanBuilder:
+			b.Append(v.v.(bool))
+		case *array.TimestampBuilder:
+			b.Append(arrow.Timestamp(v.v.(int64)))
+		case *array.Decimal128Builder:
+			b.Append(decimal128.FromBigInt(v.v.(*big.Int)))
+		}
+	}
+	return nulled
+}
+
+// take 取出目前累積的列，呼叫端負責 Release
+func (r *recordBuffer) take() arrow.RecordBatch {
+	return r.b.NewRecordBatch()
+}
+
+func (r *recordBuffer) release() {
+	r.b.Release()
+}
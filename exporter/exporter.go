@@ -0,0 +1,479 @@
+// Package exporter 把 MongoDB 的 collection 匯出成 canonical Extended JSON，輸出可以直接被 importer 讀回。
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/crypt"
+	"github.com/hayletdomybest/mongo-tools/internal/manifest"
+	"github.com/hayletdomybest/mongo-tools/internal/metrics"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// 輸出格式；array、ndjson、pretty 與 bson 都可以直接被 importer 讀回
+const (
+	FormatArray   = "array"   // JSON Array，每筆一行（預設）
+	FormatNDJSON  = "ndjson"  // 每行一筆，方便 jq 等工具逐行處理
+	FormatPretty  = "pretty"  // 縮排的 JSON Array
+	FormatBSON    = "bson"    // 跟 mongodump 一樣的 <collection>.bson，搭配 metadata.json 可以用 mongorestore 還原
+	FormatParquet = "parquet" // 欄位式的 Parquet 檔（<collection>.parquet），給資料湖工具使用；import 無法讀回
+	FormatAvro    = "avro"    // Avro Object Container File（<collection>.avro），schema 由欄位型別產生；import 無法讀回
+	FormatArrow   = "arrow"   // Arrow IPC streaming format（<collection>.arrows）；import 無法讀回
+)
+
+// Options 匯出設定
+type Options struct {
+	DB         string        // 要匯出的 database
+	Collection string        // 只匯出這個 collection，空字串表示全部
+	Logger     *slog.Logger  // nil 時使用 slog.Default()
+	OpTimeout  time.Duration // 列出 collection 與每個查詢的 timeout，不含讀取 cursor 的時間；0 表示不限制
+	Query      Query         // 每個 collection 的查詢條件與 projection
+	Queries    []QueryRule   // 個別 collection 的查詢，見 LoadQueries；優先於 Query
+	Format     string        // array（預設）、ndjson、pretty、bson、parquet、avro 或 arrow
+	EncryptKey []byte        // 非 nil 時以 AES-256-GCM 加密資料檔，檔名加上 .enc；metadata 與 sidecar 不加密
+	Sidecars   bool          // 另外寫出 importer 讀取的 <collection>.indexes.json 與 <collection>.options.json（有選項時），snapshot 使用
+	BatchSize  int           // 每個 cursor batch 的文件數，0 表示使用 server 的預設值
+	Resume     bool          // 依 _id 排序匯出，每個 cursor batch 後記錄進度（<資料檔>.checkpoint），中斷後重新執行時從上次的 _id 接續
+	Parallel   int           // 大於 1 時把每個 collection 的 _id 空間切成這麼多段，同時匯出
+	KeepShards bool          // Parallel 時保留 <collection>.shard-0001.json 等分段檔，不合併成 <collection>.json
+
+	Metrics *metrics.Metrics // tail 的 --metrics-addr 計數，nil 表示不記錄
+}
+
+// Exporter 把 collection 匯出成檔案
+type Exporter struct {
+	client *mongo.Client
+	opts   Options
+	log    *slog.Logger
+}
+
+// Result 單一 collection 的匯出結果
+type Result struct {
+	Collection string
+	File       string
+	Docs       int
+	Bytes      int64    // 寫入的位元組數（加密後）
+	SHA256     string   // 資料檔的 SHA-256，記錄在 manifest.json
+	Shards     []Result // 平行匯出保留分段檔時每段的結果，此時 File 與 SHA256 沒有意義
+	Duration   time.Duration
+	Err        error
+}
+
+// New 檢查 opts 並補齊預設值
+func New(client *mongo.Client, opts Options) (*Exporter, error) {
+	if opts.DB == "" {
+		return nil, errors.New("missing database")
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = FormatArray
+	case FormatArray, FormatNDJSON, FormatPretty, FormatBSON, FormatParquet, FormatAvro, FormatArrow:
+	default:
+		return nil, fmt.Errorf("invalid format %q (expected array, ndjson, pretty, bson, parquet, avro or arrow)", opts.Format)
+	}
+	if opts.BatchSize < 0 {
+		return nil, fmt.Errorf("invalid batch size %d", opts.BatchSize)
+	}
+	if opts.Resume && isColumnar(opts.Format) {
+		return nil, errors.New("resume is not supported for parquet, avro and arrow, which are written in two passes")
+	}
+	if opts.Resume && opts.EncryptKey != nil {
+		return nil, errors.New("resume cannot be combined with encryption, which cannot continue a partly written file")
+	}
+	if opts.Parallel > 1 {
+		switch {
+		case isColumnar(opts.Format):
+			return nil, errors.New("parallel export is not supported for parquet, avro and arrow")
+		case opts.Resume:
+			return nil, errors.New("parallel export cannot be combined with resume")
+		case opts.EncryptKey != nil && !opts.KeepShards:
+			return nil, errors.New("encrypted shards cannot be merged; keep them split")
+		}
+	}
+	return &Exporter{client: client, opts: opts, log: opts.Logger}, nil
+}
+
+// isColumnar 先推斷欄位型別再寫出的格式
+func isColumnar(format string) bool {
+	return format == FormatParquet || format == FormatAvro || format == FormatArrow
+}
+
+// opContext OpTimeout 為 0 時不限制
+func (e *Exporter) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.opts.OpTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.opts.OpTimeout)
+}
+
+// ExportDatabase 把資料庫內每個 collection（或只有 Options.Collection）匯出成 <outDir>/<collection>.json（bson、parquet、avro、arrow 時為 .bson、.parquet、.avro、.arrows），
+// 並寫出 mongodump 格式的 <collection>.metadata.json，最後寫出記錄所有檔案 SHA-256 的 manifest.json；
+// 只有無法列出 collection、建立目錄或寫出 manifest 時才回傳 error，個別 collection 的錯誤記錄在 Result.Err
+func (e *Exporter) ExportDatabase(ctx context.Context, outDir string) ([]Result, error) {
+	db := e.client.Database(e.opts.DB)
+	m := manifest.Manifest{Version: manifest.Version, DB: e.opts.DB, StartedAt: time.Now().UTC(), Complete: true, Files: []manifest.Entry{}}
+
+	listCtx, cancel := e.opContext(ctx)
+	defer cancel()
+
+	// 只匯出一般與 time-series collection，略過 view 與 system.*
+	specs, err := db.ListCollectionSpecifications(listCtx, bson.M{"type": bson.M{"$in": bson.A{"collection", "timeseries"}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %v", err)
+	}
+
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory %s: %v", outDir, err)
+	}
+	// 先寫出 complete 為 false 的 manifest：匯出中途 crash 時，目錄裡不會留著上次完整匯出的 manifest
+	if err := e.writeManifest(outDir, manifest.Manifest{Version: m.Version, DB: m.DB, StartedAt: m.StartedAt, Files: []manifest.Entry{}}); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, spec := range specs {
+		name := spec.Name
+		if strings.HasPrefix(name, "system.") || (e.opts.Collection != "" && name != e.opts.Collection) {
+			continue
+		}
+		var res Result
+		if e.opts.Parallel > 1 && canSplit(spec) {
+			res = e.exportParallel(ctx, outDir, name, filepath.Join(outDir, name+e.dataExt()))
+		} else {
+			res = e.ExportCollection(ctx, name, filepath.Join(outDir, name+e.dataExt()))
+		}
+		var sidecars []string
+		if res.Err == nil && (spec.Type == "timeseries" || (e.opts.Sidecars && hasOptions(spec.Options))) {
+			sidecars = append(sidecars, filepath.Join(outDir, name+".options.json"))
+			res.Err = e.writeOptions(name, sidecars[len(sidecars)-1], spec.Options)
+		}
+		var indexes []bson.D
+		if res.Err == nil {
+			indexes, res.Err = e.listIndexes(ctx, name)
+		}
+		if res.Err == nil {
+			sidecars = append(sidecars, filepath.Join(outDir, name+".metadata.json"))
+			res.Err = e.writeMetadata(spec, indexes, sidecars[len(sidecars)-1])
+		}
+		if res.Err == nil && e.opts.Sidecars {
+			sidecars = append(sidecars, filepath.Join(outDir, name+".indexes.json"))
+			res.Err = e.writeIndexes(name, indexes, sidecars[len(sidecars)-1])
+		}
+		if res.Err == nil {
+			res.Err = addToManifest(&m, res, sidecars)
+		}
+		if res.Err != nil {
+			m.Complete = false
+			m.Failed = append(m.Failed, name)
+		}
+		results = append(results, res)
+	}
+	m.FinishedAt = time.Now().UTC()
+	if err := e.writeManifest(outDir, m); err != nil {
+		return results, err
+	}
+	if e.opts.Resume && m.Complete {
+		e.clearCheckpoints(results)
+	}
+	return results, nil
+}
+
+// dataExt 資料檔的副檔名，加密時加上 .enc，例如 users.json.enc
+func (e *Exporter) dataExt() string {
+	if e.opts.EncryptKey != nil {
+		return fileExt(e.opts.Format) + crypt.Ext
+	}
+	return fileExt(e.opts.Format)
+}
+
+// fileExt 各格式匯出檔的副檔名
+func fileExt(format string) string {
+	switch format {
+	case FormatBSON:
+		return ".bson"
+	case FormatParquet:
+		return ".parquet"
+	case FormatAvro:
+		return ".avro"
+	case FormatArrow:
+		return ".arrows"
+	}
+	return ".json"
+}
+
+// writeOptions 把 collection 的建立選項寫成 <collection>.options.json，匯入時 importer 才會以相同的設定
+// （timeseries、capped、validator、collation 等）建立 collection，而不是一般的 collection
+func (e *Exporter) writeOptions(coll, filePath string, opts bson.Raw) error {
+	data, err := bson.MarshalExtJSONIndent(opts, true, false, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filePath, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to write options of %s: %v", coll, err), "collection", coll, "file", filePath, errAttr(err))
+		return err
+	}
+	e.log.Info(fmt.Sprintf("🧱 Wrote options of %s → %s", coll, filePath), "collection", coll, "file", filePath)
+	return nil
+}
+
+// hasOptions 略過空的 {}
+func hasOptions(opts bson.Raw) bool {
+	elems, err := opts.Elements()
+	return err == nil && len(elems) > 0
+}
+
+// ExportCollection 把單一 collection 匯出到 filePath
+func (e *Exporter) ExportCollection(ctx context.Context, coll, filePath string) (res Result) {
+	res = Result{Collection: coll, File: filePath}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+
+	q := e.queryFor(coll)
+	attrs := []any{"collection", coll, "file", filePath}
+	if q.Filter != nil {
+		attrs = append(attrs, "query", extJSON(q.Filter))
+	}
+	if q.Projection != nil {
+		attrs = append(attrs, "projection", extJSON(q.Projection))
+	}
+	e.log.Info(fmt.Sprintf("📤 Exporting collection: %s → %s", coll, filePath), attrs...)
+
+	var cp *checkpoint
+	if e.opts.Resume {
+		cp = e.startCheckpoint(coll, filePath, q)
+		if cp.state.Complete {
+			res.Docs, res.Bytes, res.SHA256 = cp.state.Docs, cp.state.Bytes, cp.state.SHA256
+			e.log.Info(fmt.Sprintf("⏭️  %s was already exported (%d docs)", coll, res.Docs), "collection", coll, "file", filePath, "count", res.Docs)
+			return res
+		}
+	}
+
+	findCtx, cancel := e.opContext(ctx)
+	defer cancel()
+
+	// cursor 之後以 ctx 讀取，大 collection 的匯出時間不受 OpTimeout 限制
+	filter, findOpts := q.find()
+	if e.opts.BatchSize > 0 {
+		findOpts.SetBatchSize(int32(e.opts.BatchSize))
+	}
+	if cp != nil {
+		cp.apply(findOpts)
+	}
+	cursor, err := e.client.Database(e.opts.DB).Collection(coll).Find(findCtx, filter, findOpts)
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to query %s: %v", coll, err), "collection", coll, errAttr(err))
+		if cp != nil && cp.out != nil {
+			cp.out.Close()
+		}
+		res.Err = err
+		return res
+	}
+	defer cursor.Close(ctx)
+
+	var f *outputFile
+	if cp != nil && cp.out != nil {
+		f = cp.out
+	} else {
+		f, err = createOutput(filePath, e.opts.EncryptKey)
+		if err == nil && cp != nil {
+			cp.out = f
+			err = cp.write()
+		}
+	}
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to create file: %s (%v)", filePath, err), "file", filePath, errAttr(err))
+		if f != nil {
+			f.Close()
+		}
+		res.Err = err
+		return res
+	}
+
+	var newWriter func(cols []*column) (columnarWriter, error)
+	switch e.opts.Format {
+	case FormatParquet:
+		newWriter = newParquetWriter(f)
+	case FormatAvro:
+		newWriter = newAvroWriter(f, e.opts.DB, coll)
+	case FormatArrow:
+		newWriter = newArrowWriter(f)
+	}
+	if newWriter != nil {
+		// 第一次查詢推斷欄位型別，第二次寫出資料
+		nulled := 0
+		res.Docs, nulled, err = writeColumnar(ctx, cursor, func() (*mongo.Cursor, error) {
+			findCtx, cancel := e.opContext(ctx)
+			defer cancel()
+			return e.client.Database(e.opts.DB).Collection(coll).Find(findCtx, filter, findOpts)
+		}, newWriter)
+		if nulled > 0 {
+			e.log.Warn(fmt.Sprintf("⚠️  %d values of %s changed type during the export and were written as null", nulled, coll),
+				"collection", coll, "count", nulled)
+		}
+	} else if e.opts.Format == FormatBSON {
+		res.Docs, err = writeBSON(ctx, cursor, f, cp)
+	} else {
+		res.Docs, err = writeExtendedJSON(ctx, cursor, f, e.opts.Format, cp)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	res.SHA256, res.Bytes = f.hash.Sum(), f.hash.Bytes()
+	if err == nil && cp != nil {
+		if err = cp.finish(res); err != nil {
+			err = fmt.Errorf("failed to write %s: %v", cp.path, err)
+		}
+	}
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to export %s: %v", coll, err), "collection", coll, "file", filePath, errAttr(err))
+		res.Err = err
+		return res
+	}
+	e.log.Info(fmt.Sprintf("✅ Exported %d docs from %s", res.Docs, coll),
+		"collection", coll, "file", filePath, "count", res.Docs, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
+
+// outputFile 匯出的資料檔：寫入時一併計算 manifest 的 SHA-256（加密時為加密後的內容），有金鑰時加密
+type outputFile struct {
+	io.Writer
+	hash *manifest.Hasher
+	enc  *crypt.Writer // 沒有加密時為 nil
+	file *os.File
+}
+
+func createOutput(filePath string, key []byte) (*outputFile, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	f := &outputFile{hash: manifest.NewHasher(file), file: file}
+	f.Writer = f.hash
+	if key != nil {
+		if f.enc, err = crypt.NewWriter(f.hash, key); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to encrypt: %v", err)
+		}
+		f.Writer = f.enc
+	}
+	return f, nil
+}
+
+// Close 先寫出加密的最後一段再關閉檔案
+func (f *outputFile) Close() error {
+	var err error
+	if f.enc != nil {
+		err = f.enc.Close()
+	}
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// framing JSON 輸出的外框：開頭、第一筆與之後每筆前面的分隔、結尾；bson 沒有外框
+type framing struct {
+	head, first, sep, tail string
+}
+
+// framingOf array / pretty：整份是一個 JSON Array；ndjson：每筆一行，沒有外層括號
+func framingOf(format string) framing {
+	switch format {
+	case FormatNDJSON:
+		return framing{sep: "\n", tail: "\n"}
+	case FormatPretty:
+		return framing{head: "[", first: "\n  ", sep: ",\n  ", tail: "\n]\n"}
+	case FormatBSON:
+		return framing{}
+	}
+	return framing{head: "[", first: "\n", sep: ",\n", tail: "\n]\n"}
+}
+
+// writeExtendedJSON 以 canonical Extended JSON 輸出；array 每筆一行，方便 diff 也能直接被 import 讀回。
+// cp 不為 nil 時接在上次寫出的文件之後，並在每個 cursor batch 後記錄進度
+func writeExtendedJSON(ctx context.Context, cursor *mongo.Cursor, out io.Writer, format string, cp *checkpoint) (int, error) {
+	return writeJSON(ctx, cursor, out, format, framingOf(format), cp)
+}
+
+// writeJSON 以 fr 為外框寫出文件；平行匯出合併前的分段只寫出以 sep 分隔的文件
+func writeJSON(ctx context.Context, cursor *mongo.Cursor, out io.Writer, format string, fr framing, cp *checkpoint) (int, error) {
+	w := bufio.NewWriter(out)
+	count := cp.docs()
+
+	marshal := func(doc bson.Raw) ([]byte, error) {
+		return bson.MarshalExtJSON(doc, true, false)
+	}
+	if format == FormatPretty {
+		marshal = func(doc bson.Raw) ([]byte, error) {
+			return bson.MarshalExtJSONIndent(doc, true, false, "  ", "  ")
+		}
+	}
+	if count == 0 {
+		if _, err := w.WriteString(fr.head); err != nil {
+			return 0, err
+		}
+	}
+	for cursor.Next(ctx) {
+		if cp.skip(cursor.Current) {
+			continue
+		}
+		doc, err := marshal(cursor.Current)
+		if err != nil {
+			return count, fmt.Errorf("failed to marshal document: %v", err)
+		}
+		prefix := fr.sep
+		if count == 0 {
+			prefix = fr.first
+		}
+		if _, err := w.WriteString(prefix); err != nil {
+			return count, err
+		}
+		if _, err := w.Write(doc); err != nil {
+			return count, err
+		}
+		count++
+		if cursor.RemainingBatchLength() == 0 {
+			if err := cp.batchDone(w, count, cursor.Current); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	tail := fr.tail
+	if format == FormatNDJSON && count == 0 {
+		tail = ""
+	}
+	if _, err := w.WriteString(tail); err != nil {
+		return count, err
+	}
+	return count, w.Flush()
+}
+
+// extJSON 用於記錄查詢條件
+func extJSON(d bson.D) string {
+	b, err := bson.MarshalExtJSON(d, false, false)
+	if err != nil {
+		return fmt.Sprint(d)
+	}
+	return string(b)
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
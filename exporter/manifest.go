@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/manifest"
+)
+
+// addToManifest 記下 res 的資料檔（寫入時已算好 SHA-256；保留分段時為每一段）與 sidecar
+func addToManifest(m *manifest.Manifest, res Result, sidecars []string) error {
+	files := []Result{res}
+	if len(res.Shards) > 0 {
+		files = res.Shards
+	}
+	for _, f := range files {
+		docs := f.Docs
+		m.Files = append(m.Files, manifest.Entry{
+			File: filepath.Base(f.File), Collection: res.Collection, Docs: &docs,
+			Bytes: f.Bytes, SHA256: f.SHA256, ExportedAt: time.Now().UTC(),
+		})
+	}
+	for _, path := range sidecars {
+		sum, n, err := manifest.HashFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to hash %s: %v", path, err)
+		}
+		m.Files = append(m.Files, manifest.Entry{
+			File: filepath.Base(path), Collection: res.Collection, Bytes: n, SHA256: sum, ExportedAt: time.Now().UTC(),
+		})
+	}
+	return nil
+}
+
+// writeManifest 寫出 <outDir>/manifest.json；有 collection 失敗時也寫出（complete 為 false），import 才會拒絕不完整的資料
+func (e *Exporter) writeManifest(outDir string, m manifest.Manifest) error {
+	path := filepath.Join(outDir, manifest.File)
+	if _, ok := m.Lookup(manifest.File); ok {
+		// 名為 manifest 的 collection 匯出成 manifest.json 時不能覆蓋它
+		e.log.Warn(fmt.Sprintf("⚠️  Not writing %s: it is the data file of collection manifest", path), "file", path)
+		return nil
+	}
+	if err := manifest.Write(outDir, m); err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to write %s: %v", path, err), "file", path, errAttr(err))
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	e.log.Info(fmt.Sprintf("🔏 Wrote %s (%d files)", path, len(m.Files)), "file", path, "files", len(m.Files), "complete", m.Complete)
+	return nil
+}
@@ -0,0 +1,149 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// metadata mongodump 的 <collection>.metadata.json：mongorestore 以它重建 collection 的選項與索引
+type metadata struct {
+	Options        bson.Raw `bson:"options"`
+	Indexes        []bson.D `bson:"indexes"`
+	UUID           string   `bson:"uuid,omitempty"`
+	CollectionName string   `bson:"collectionName"`
+	Type           string   `bson:"type"`
+}
+
+// listIndexes 目前的索引定義，metadata.json 與 indexes.json 共用
+func (e *Exporter) listIndexes(ctx context.Context, coll string) ([]bson.D, error) {
+	listCtx, cancel := e.opContext(ctx)
+	defer cancel()
+	cursor, err := e.client.Database(e.opts.DB).Collection(coll).Indexes().List(listCtx)
+	var indexes []bson.D
+	if err == nil {
+		err = cursor.All(listCtx, &indexes)
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to list indexes: %v", err)
+		e.log.Error(fmt.Sprintf("❌ Failed to list indexes of %s: %v", coll, err), "collection", coll, errAttr(err))
+		return nil, err
+	}
+	return indexes, nil
+}
+
+// writeMetadata 把 spec 的選項、UUID 與 indexes 寫成 filePath（canonical Extended JSON，跟 mongodump 一樣）
+func (e *Exporter) writeMetadata(spec *mongo.CollectionSpecification, indexes []bson.D, filePath string) error {
+	meta := metadata{Options: spec.Options, Indexes: indexes, CollectionName: spec.Name, Type: spec.Type}
+	if meta.Options == nil {
+		meta.Options = bson.Raw{5, 0, 0, 0, 0} // {}
+	}
+	if spec.UUID != nil {
+		meta.UUID = hex.EncodeToString(spec.UUID.Data)
+	}
+	data, err := bson.MarshalExtJSON(meta, true, false)
+	if err == nil {
+		err = os.WriteFile(filePath, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to write metadata of %s: %v", spec.Name, err), "collection", spec.Name, "file", filePath, errAttr(err))
+		return err
+	}
+	e.log.Info(fmt.Sprintf("🗂️  Wrote metadata of %s (%d indexes) → %s", spec.Name, len(indexes), filePath),
+		"collection", spec.Name, "file", filePath, "indexes", len(indexes))
+	return nil
+}
+
+// writeIndexes 把 indexes 寫成 importer 讀取的 <collection>.indexes.json；_id 索引由 importer 略過
+func (e *Exporter) writeIndexes(coll string, indexes []bson.D, filePath string) error {
+	if indexes == nil {
+		indexes = []bson.D{}
+	}
+	data, err := bson.MarshalExtJSONIndent(bson.D{{Key: "indexes", Value: indexes}}, true, false, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filePath, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to write indexes of %s: %v", coll, err), "collection", coll, "file", filePath, errAttr(err))
+		return err
+	}
+	e.log.Debug(fmt.Sprintf("🗂️  Wrote %d index definitions of %s → %s", len(indexes), coll, filePath),
+		"collection", coll, "file", filePath, "indexes", len(indexes))
+	return nil
+}
+
+// writeBSON 跟 mongodump 的 .bson 一樣，把文件原樣一筆接一筆寫出；cp 的用法同 writeExtendedJSON
+func writeBSON(ctx context.Context, cursor *mongo.Cursor, out io.Writer, cp *checkpoint) (int, error) {
+	w := bufio.NewWriter(out)
+	count := cp.docs()
+	for cursor.Next(ctx) {
+		if cp.skip(cursor.Current) {
+			continue
+		}
+		if _, err := w.Write(cursor.Current); err != nil {
+			return count, err
+		}
+		count++
+		if cursor.RemainingBatchLength() == 0 {
+			if err := cp.batchDone(w, count, cursor.Current); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	return count, w.Flush()
+}
+
+// view importer 的 views.json 的一筆
+type view struct {
+	Name      string   `bson:"name"`
+	Source    string   `bson:"source"`
+	Pipeline  bson.A   `bson:"pipeline"`
+	Collation bson.Raw `bson:"collation,omitempty"`
+}
+
+// ExportViews 把資料庫內所有 view 的定義寫成 filePath（importer 的 ViewsFile 格式），依 listCollections 的順序；
+// 回傳 view 的名稱，沒有 view 時仍寫出空的清單
+func (e *Exporter) ExportViews(ctx context.Context, filePath string) ([]string, error) {
+	listCtx, cancel := e.opContext(ctx)
+	defer cancel()
+	specs, err := e.client.Database(e.opts.DB).ListCollectionSpecifications(listCtx, bson.M{"type": "view"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %v", err)
+	}
+	views := make([]view, 0, len(specs))
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		v := view{Name: spec.Name, Pipeline: bson.A{}}
+		if s, ok := spec.Options.Lookup("viewOn").StringValueOK(); ok {
+			v.Source = s
+		}
+		if p := spec.Options.Lookup("pipeline"); p.Type == bson.TypeArray {
+			if err := p.Unmarshal(&v.Pipeline); err != nil {
+				return nil, fmt.Errorf("view %s: invalid pipeline: %v", spec.Name, err)
+			}
+		}
+		if c, ok := spec.Options.Lookup("collation").DocumentOK(); ok {
+			v.Collation = c
+		}
+		views = append(views, v)
+		names = append(names, v.Name)
+	}
+	data, err := bson.MarshalExtJSONIndent(bson.D{{Key: "views", Value: views}}, true, false, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filePath, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", filePath, err)
+	}
+	e.log.Info(fmt.Sprintf("🔭 Wrote %d view definitions → %s", len(views), filePath), "file", filePath, "views", len(views))
+	return names, nil
+}
@@ -0,0 +1,260 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	samplesPerRange = 100  // 每段 _id 範圍以多少筆 $sample 估計切點
+	minDocsPerRange = 1000 // 估計文件數少於 Parallel × minDocsPerRange 時不切分
+)
+
+// canSplit 平行匯出以 _id 索引的 min / max 限定範圍；time-series 與 clustered collection 沒有 _id 索引
+func canSplit(spec *mongo.CollectionSpecification) bool {
+	if spec.Type != "collection" {
+		return false
+	}
+	_, err := spec.Options.LookupErr("clusteredIndex")
+	return err != nil
+}
+
+// shardPath 第 n 段的檔名，例如 users.json → users.shard-0001.json；importer 會把它匯入 users
+func shardPath(outDir, coll string, n int, ext string) string {
+	return filepath.Join(outDir, fmt.Sprintf("%s.shard-%04d%s", coll, n, ext))
+}
+
+// splitPoints 以 $sample 抽樣的 _id 估出最多 n-1 個切點，把 _id 空間分成文件數大致相同的範圍；collection 太小時回傳 nil
+func (e *Exporter) splitPoints(ctx context.Context, coll string, n int) ([]bson.RawValue, error) {
+	opCtx, cancel := e.opContext(ctx)
+	defer cancel()
+	c := e.client.Database(e.opts.DB).Collection(coll)
+	count, err := c.EstimatedDocumentCount(opCtx)
+	if err != nil {
+		return nil, err
+	}
+	if count < int64(n*minDocsPerRange) {
+		return nil, nil
+	}
+	// $sort 與 _id 索引的順序相同，切點才能直接當作 min / max
+	cursor, err := c.Aggregate(opCtx, mongo.Pipeline{
+		{{Key: "$sample", Value: bson.D{{Key: "size", Value: n * samplesPerRange}}}},
+		{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(opCtx)
+	var ids []bson.RawValue
+	for cursor.Next(opCtx) {
+		ids = append(ids, cursor.Current.Lookup("_id"))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	var points []bson.RawValue
+	for i := 1; i < n && len(ids) > 0; i++ {
+		p := ids[len(ids)*i/n]
+		// 重複的 _id 會切出空的範圍
+		if len(points) > 0 && points[len(points)-1].Equal(p) {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// exportParallel 把 _id 空間切成 Options.Parallel 段，由同樣數量的 worker 分別寫成 <collection>.shard-0001.json 等檔案；
+// 沒有 KeepShards 時依 _id 順序合併成 filePath 並刪除分段檔。collection 太小時改為一般的匯出
+func (e *Exporter) exportParallel(ctx context.Context, outDir, coll, filePath string) (res Result) {
+	points, err := e.splitPoints(ctx, coll, e.opts.Parallel)
+	if err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to split %s into _id ranges: %v", coll, err), "collection", coll, errAttr(err))
+		return Result{Collection: coll, File: filePath, Err: err}
+	}
+	if len(points) == 0 {
+		e.log.Debug(fmt.Sprintf("↪️  %s is too small to split; exporting it in one piece", coll), "collection", coll)
+		return e.ExportCollection(ctx, coll, filePath)
+	}
+
+	res = Result{Collection: coll, File: filePath}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+	ranges := len(points) + 1
+	e.log.Info(fmt.Sprintf("🔀 Exporting %s in %d _id ranges → %s", coll, ranges, filePath),
+		"collection", coll, "file", filePath, "ranges", ranges)
+
+	q := e.queryFor(coll)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	shards := make([]Result, ranges)
+	var wg sync.WaitGroup
+	for n := range shards {
+		path := shardPath(outDir, coll, n+1, e.dataExt())
+		if !e.opts.KeepShards {
+			// 合併前的暫存檔，importer 不認得 .part
+			path += ".part"
+		}
+		var lo, hi bson.RawValue
+		if n > 0 {
+			lo = points[n-1]
+		}
+		if n < len(points) {
+			hi = points[n]
+		}
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			shards[n] = e.exportRange(ctx, coll, path, q, lo, hi)
+			if shards[n].Err != nil {
+				// 其他 worker 不必再繼續
+				cancel()
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	for _, s := range shards {
+		res.Docs += s.Docs
+		if s.Err != nil && res.Err == nil {
+			res.Err = fmt.Errorf("%s: %v", filepath.Base(s.File), s.Err)
+		}
+	}
+	switch {
+	case res.Err != nil:
+		if !e.opts.KeepShards {
+			removeShards(shards)
+		}
+	case e.opts.KeepShards:
+		res.Shards = shards
+		for _, s := range shards {
+			res.Bytes += s.Bytes
+		}
+	default:
+		res.Bytes, res.SHA256, res.Err = e.mergeShards(filePath, shards)
+		removeShards(shards)
+	}
+	if res.Err != nil {
+		e.log.Error(fmt.Sprintf("❌ Failed to export %s: %v", coll, res.Err), "collection", coll, "file", filePath, errAttr(res.Err))
+		return res
+	}
+	e.log.Info(fmt.Sprintf("✅ Exported %d docs from %s in %d _id ranges", res.Docs, coll, ranges),
+		"collection", coll, "file", filePath, "count", res.Docs, "ranges", ranges, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
+
+// exportRange 匯出 lo <= _id < hi 的文件；lo / hi 為零值表示不限制。合併前的分段只寫出文件本身，由 mergeShards 補上外框
+func (e *Exporter) exportRange(ctx context.Context, coll, filePath string, q Query, lo, hi bson.RawValue) Result {
+	res := Result{Collection: coll, File: filePath}
+	filter, findOpts := q.find()
+	findOpts.SetHint(bson.D{{Key: "_id", Value: 1}}).SetSort(bson.D{{Key: "_id", Value: 1}})
+	if lo.Type != 0 {
+		findOpts.SetMin(bson.D{{Key: "_id", Value: lo}})
+	}
+	if hi.Type != 0 {
+		findOpts.SetMax(bson.D{{Key: "_id", Value: hi}})
+	}
+	if e.opts.BatchSize > 0 {
+		findOpts.SetBatchSize(int32(e.opts.BatchSize))
+	}
+
+	findCtx, cancel := e.opContext(ctx)
+	defer cancel()
+	cursor, err := e.client.Database(e.opts.DB).Collection(coll).Find(findCtx, filter, findOpts)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer cursor.Close(ctx)
+
+	f, err := createOutput(filePath, e.opts.EncryptKey)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	switch {
+	case e.opts.Format == FormatBSON:
+		res.Docs, err = writeBSON(ctx, cursor, f, nil)
+	case e.opts.KeepShards:
+		res.Docs, err = writeExtendedJSON(ctx, cursor, f, e.opts.Format, nil)
+	default:
+		res.Docs, err = writeJSON(ctx, cursor, f, e.opts.Format, framing{sep: framingOf(e.opts.Format).sep}, nil)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	res.SHA256, res.Bytes, res.Err = f.hash.Sum(), f.hash.Bytes(), err
+	if err == nil {
+		e.log.Debug(fmt.Sprintf("🧩 Exported %d docs of %s → %s", res.Docs, coll, filePath), "collection", coll, "file", filePath, "count", res.Docs)
+	}
+	return res
+}
+
+// mergeShards 依 _id 順序把各段接成 filePath，補上格式的外框；回傳合併後的大小與 SHA-256
+func (e *Exporter) mergeShards(filePath string, shards []Result) (int64, string, error) {
+	f, err := createOutput(filePath, e.opts.EncryptKey)
+	if err != nil {
+		return 0, "", err
+	}
+	fr := framingOf(e.opts.Format)
+	err = func() error {
+		if _, err := io.WriteString(f, fr.head); err != nil {
+			return err
+		}
+		docs := 0
+		for _, s := range shards {
+			if s.Docs == 0 {
+				continue
+			}
+			prefix := fr.sep
+			if docs == 0 {
+				prefix = fr.first
+			}
+			if _, err := io.WriteString(f, prefix); err != nil {
+				return err
+			}
+			part, err := os.Open(s.File)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, part)
+			part.Close()
+			if err != nil {
+				return err
+			}
+			docs += s.Docs
+		}
+		tail := fr.tail
+		if e.opts.Format == FormatNDJSON && docs == 0 {
+			tail = ""
+		}
+		_, err := io.WriteString(f, tail)
+		return err
+	}()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to merge into %s: %v", filePath, err)
+	}
+	return f.hash.Bytes(), f.hash.Sum(), nil
+}
+
+// removeShards 刪除合併前的暫存檔
+func removeShards(shards []Result) {
+	for _, s := range shards {
+		if strings.HasSuffix(s.File, ".part") {
+			os.Remove(s.File)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"io"
+
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// parquetWriter 以 Apache Arrow 的 Parquet writer 寫出，每次 flush 一個 row group（snappy 壓縮）；
+// 檔案內另外存一份 Arrow schema，讀回時時區與 decimal 的精度不變
+type parquetWriter struct {
+	buf *recordBuffer
+	fw  *pqarrow.FileWriter
+}
+
+func newParquetWriter(out io.Writer) func(cols []*column) (columnarWriter, error) {
+	return func(cols []*column) (columnarWriter, error) {
+		schema := arrowSchema(cols)
+		fw, err := pqarrow.NewFileWriter(schema, out,
+			parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy), parquet.WithMaxRowGroupLength(columnarRowGroupSize)),
+			pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+		if err != nil {
+			return nil, err
+		}
+		return &parquetWriter{buf: newRecordBuffer(schema, cols), fw: fw}, nil
+	}
+}
+
+func (p *parquetWriter) add(doc bson.Raw) int {
+	return p.buf.add(doc)
+}
+
+func (p *parquetWriter) flush() error {
+	rec := p.buf.take()
+	defer rec.Release()
+	return p.fw.Write(rec)
+}
+
+func (p *parquetWriter) close() error {
+	p.buf.release()
+	return p.fw.Close()
+}
@@ -0,0 +1,180 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/apache/arrow-go/v18/parquet/schema"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	testID      = primitive.NewObjectID()
+	testCreated = time.Date(2024, 5, 6, 7, 8, 9, 123e6, time.UTC)
+)
+
+// testDocs 涵蓋每一種欄位型別、缺少的欄位、null 與混合的 int / double
+func testDocs(t *testing.T) []interface{} {
+	t.Helper()
+	price, err := primitive.ParseDecimal128("12.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, err := primitive.ParseDecimal128("-3.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []interface{}{
+		bson.D{
+			{Key: "_id", Value: testID},
+			{Key: "name", Value: "alice"},
+			{Key: "count", Value: int32(3)},
+			{Key: "score", Value: int64(7)},
+			{Key: "price", Value: price},
+			{Key: "created", Value: primitive.NewDateTimeFromTime(testCreated)},
+			{Key: "active", Value: true},
+			{Key: "tags", Value: bson.A{"a", "b"}},
+		},
+		bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "name", Value: nil},
+			{Key: "count", Value: int64(1) << 40},
+			{Key: "score", Value: 2.5},
+			{Key: "price", Value: small},
+			{Key: "active", Value: false},
+		},
+	}
+}
+
+// writeTestFile 以 newWriter 寫出 testDocs，回傳檔案內容
+func writeTestFile(t *testing.T, newWriter func(out *bytes.Buffer) func(cols []*column) (columnarWriter, error)) []byte {
+	t.Helper()
+	docs := testDocs(t)
+	cursor := func() (*mongo.Cursor, error) { return mongo.NewCursorFromDocuments(docs, nil, nil) }
+	first, err := cursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	rows, nulled, err := writeColumnar(context.Background(), first, cursor, newWriter(&out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != len(docs) || nulled != 0 {
+		t.Fatalf("wrote %d rows with %d nulled values, want %d and 0", rows, nulled, len(docs))
+	}
+	return out.Bytes()
+}
+
+// checkTestTable 比對讀回的 Arrow table 與 testDocs
+func checkTestTable(t *testing.T, tbl arrow.Table) {
+	t.Helper()
+	if tbl.NumRows() != 2 {
+		t.Fatalf("read %d rows, want 2", tbl.NumRows())
+	}
+	col := func(name string) arrow.Array {
+		t.Helper()
+		idx := tbl.Schema().FieldIndices(name)
+		if len(idx) != 1 {
+			t.Fatalf("no column %s in %s", name, tbl.Schema())
+		}
+		chunks := tbl.Column(idx[0]).Data().Chunks()
+		if len(chunks) != 1 {
+			t.Fatalf("column %s has %d chunks", name, len(chunks))
+		}
+		return chunks[0]
+	}
+
+	if got := col("_id").(*array.String).Value(0); got != testID.Hex() {
+		t.Errorf("_id = %s, want %s", got, testID.Hex())
+	}
+	name := col("name").(*array.String)
+	if name.Value(0) != "alice" || !name.IsNull(1) {
+		t.Errorf("name = %s", name)
+	}
+	count := col("count").(*array.Int64)
+	if count.Value(0) != 3 || count.Value(1) != 1<<40 {
+		t.Errorf("count = %s", count)
+	}
+	score := col("score").(*array.Float64)
+	if score.Value(0) != 7 || score.Value(1) != 2.5 {
+		t.Errorf("score = %s", score)
+	}
+	price := col("price").(*array.Decimal128)
+	typ := price.DataType().(*arrow.Decimal128Type)
+	if typ.Precision != 4 || typ.Scale != 2 {
+		t.Errorf("price is decimal(%d, %d), want decimal(4, 2)", typ.Precision, typ.Scale)
+	}
+	if got := price.Value(0).BigInt(); got.Cmp(big.NewInt(1250)) != 0 {
+		t.Errorf("price[0] = %s, want 1250", got)
+	}
+	if got := price.Value(1).BigInt(); got.Cmp(big.NewInt(-310)) != 0 {
+		t.Errorf("price[1] = %s, want -310", got)
+	}
+	created := col("created").(*array.Timestamp)
+	ts := created.DataType().(*arrow.TimestampType)
+	if ts.Unit != arrow.Millisecond || ts.TimeZone != "UTC" {
+		t.Errorf("created is %s, want timestamp[ms, UTC]", ts)
+	}
+	if got := created.Value(0).ToTime(arrow.Millisecond); !got.Equal(testCreated) || !created.IsNull(1) {
+		t.Errorf("created = %s, want %s and null", created, testCreated)
+	}
+	active := col("active").(*array.Boolean)
+	if !active.Value(0) || active.Value(1) {
+		t.Errorf("active = %s", active)
+	}
+	tags := col("tags").(*array.String)
+	if tags.Value(0) != `["a","b"]` || !tags.IsNull(1) {
+		t.Errorf("tags = %s", tags)
+	}
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	data := writeTestFile(t, func(out *bytes.Buffer) func(cols []*column) (columnarWriter, error) {
+		return newParquetWriter(out)
+	})
+
+	// 不依賴檔案內的 Arrow schema，直接檢查 Parquet 的 logical type
+	rdr, err := file.NewParquetReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := rdr.MetaData().Schema
+	logical := func(name string) schema.LogicalType {
+		t.Helper()
+		idx := sc.ColumnIndexByName(name)
+		if idx < 0 {
+			t.Fatalf("no column %s", name)
+		}
+		return sc.Column(idx).LogicalType()
+	}
+	if _, ok := logical("_id").(schema.StringLogicalType); !ok {
+		t.Errorf("_id is %s, want String", logical("_id"))
+	}
+	if d, ok := logical("price").(schema.DecimalLogicalType); !ok || d.Precision() != 4 || d.Scale() != 2 {
+		t.Errorf("price is %s, want Decimal(4, 2)", logical("price"))
+	}
+	if ts, ok := logical("created").(schema.TimestampLogicalType); !ok || !ts.IsAdjustedToUTC() || ts.TimeUnit() != schema.TimeUnitMillis {
+		t.Errorf("created is %s, want Timestamp(UTC, millis)", logical("created"))
+	}
+	rdr.Close()
+
+	tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(data), parquet.NewReaderProperties(memory.DefaultAllocator),
+		pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.Release()
+	checkTestTable(t, tbl)
+}
@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/yaml.v3"
+)
+
+// Query 匯出時套用的查詢條件與 projection；nil 表示不限制
+type Query struct {
+	Filter     bson.D
+	Projection bson.D
+}
+
+// QueryRule 查詢檔的一條規則，Collection 可以是 glob
+type QueryRule struct {
+	Collection string
+	Query
+}
+
+// queryFile query 檔的格式；query / projection 以 Extended JSON 字串表示，$date、$oid 等型別才不會失真
+type queryFile struct {
+	Queries []struct {
+		Collection string `yaml:"collection" json:"collection"`
+		Query      string `yaml:"query" json:"query"`
+		Projection string `yaml:"projection" json:"projection"`
+	} `yaml:"queries" json:"queries"`
+}
+
+// ParseDocument 解析 --query / --projection 的 Extended JSON 文件；空字串回傳 nil
+func ParseDocument(s string) (bson.D, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var d bson.D
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// LoadQueries 讀取 YAML（或 .json）格式的 query 檔，規則依檔案內的順序比對
+func LoadQueries(path string) ([]QueryRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var qf queryFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &qf)
+	} else {
+		err = yaml.Unmarshal(data, &qf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query file %s: %v", path, err)
+	}
+
+	rules := make([]QueryRule, 0, len(qf.Queries))
+	for n, raw := range qf.Queries {
+		if raw.Collection == "" {
+			return nil, fmt.Errorf("query %d: collection is required", n+1)
+		}
+		if _, err := filepath.Match(raw.Collection, ""); err != nil {
+			return nil, fmt.Errorf("query %d: invalid pattern %q: %v", n+1, raw.Collection, err)
+		}
+		r := QueryRule{Collection: raw.Collection}
+		if r.Filter, err = ParseDocument(raw.Query); err != nil {
+			return nil, fmt.Errorf("query %d: invalid query: %v", n+1, err)
+		}
+		if r.Projection, err = ParseDocument(raw.Projection); err != nil {
+			return nil, fmt.Errorf("query %d: invalid projection: %v", n+1, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// queryFor 第一條符合 coll 的規則；規則沒有給的 query / projection 沿用 Options.Query
+func (e *Exporter) queryFor(coll string) Query {
+	q := e.opts.Query
+	for _, r := range e.opts.Queries {
+		if ok, _ := filepath.Match(r.Collection, coll); !ok {
+			continue
+		}
+		if r.Filter != nil {
+			q.Filter = r.Filter
+		}
+		if r.Projection != nil {
+			q.Projection = r.Projection
+		}
+		break
+	}
+	return q
+}
+
+// find 依 queryFor 的結果組出 Find 的參數
+func (q Query) find() (interface{}, *options.FindOptions) {
+	var filter interface{} = bson.D{}
+	if q.Filter != nil {
+		filter = q.Filter
+	}
+	opts := options.Find()
+	if q.Projection != nil {
+		opts.SetProjection(q.Projection)
+	}
+	return filter, opts
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TailStats Tail 寫出的筆數
+type TailStats struct {
+	Written int // 寫出的事件或文件
+	Skipped int // FullDocuments 時沒有文件內容的事件（delete、drop 等）
+}
+
+// Tail 監看 Options.Collection 的 change stream，把每個事件以 relaxed Extended JSON 每行一筆寫到 w，直到 ctx 被取消。
+// fullDocuments 時改寫出變更後的完整文件（insert / update / replace），可以直接再 import；
+// 每筆寫出後立即 flush，下游（tail -f、ETL）不需要等待緩衝。只從開始監看後的變更寫起，不含既有的文件
+func (e *Exporter) Tail(ctx context.Context, w io.Writer, fullDocuments bool) (TailStats, error) {
+	var stats TailStats
+	if e.opts.Collection == "" {
+		return stats, errors.New("missing collection")
+	}
+	coll := e.client.Database(e.opts.DB).Collection(e.opts.Collection)
+	ns := e.opts.DB + "." + e.opts.Collection
+
+	watchCtx, cancel := e.opContext(ctx)
+	stream, err := coll.Watch(watchCtx, mongo.Pipeline{}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	cancel()
+	if err != nil {
+		return stats, fmt.Errorf("failed to open a change stream on %s (requires a replica set or sharded cluster): %v", ns, err)
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	what := "change events"
+	if fullDocuments {
+		what = "full documents"
+	}
+	e.log.Info(fmt.Sprintf("👀 Tailing %s of %s until stopped", what, ns), "collection", ns, "full_documents", fullDocuments)
+
+	out := bufio.NewWriter(w)
+	for stream.Next(ctx) {
+		raw := stream.Current
+		op, _ := raw.Lookup("operationType").StringValueOK()
+		if t, _, ok := raw.Lookup("clusterTime").TimestampOK(); ok {
+			e.opts.Metrics.Lag(time.Since(time.Unix(int64(t), 0)))
+		}
+		if fullDocuments {
+			doc, ok := raw.Lookup("fullDocument").DocumentOK()
+			if !ok {
+				stats.Skipped++
+				continue
+			}
+			raw = doc
+		}
+		line, err := bson.MarshalExtJSON(raw, false, false)
+		if err != nil {
+			e.opts.Metrics.Error(ns)
+			return stats, fmt.Errorf("failed to marshal change event: %v", err)
+		}
+		written := time.Now()
+		out.Write(line)
+		out.WriteByte('\n')
+		if err := out.Flush(); err != nil {
+			e.opts.Metrics.Error(ns)
+			return stats, err
+		}
+		e.opts.Metrics.Batch(time.Since(written))
+		e.opts.Metrics.Docs(ns, op, 1)
+		stats.Written++
+		if op == "invalidate" {
+			return stats, fmt.Errorf("the change stream was invalidated (%s was dropped or renamed)", ns)
+		}
+	}
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		return stats, fmt.Errorf("change stream failed: %v", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hayletdomybest/mongo-tools/generate"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runGenerate 依 --schema 產生假資料：有 --out 時寫成 <collection>.json（client 為 nil），
+// 否則寫入 --uri / --db，沒有 --append 時先清空目標 collection
+func runGenerate(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config) int {
+	cfg.Generate.Logger = logger
+	g, err := generate.New(cfg.GenerateSchema, cfg.Generate)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid generate options: %v", err), errAttr(err))
+	}
+	logger.Info(fmt.Sprintf("🎲 Using seed %d", g.Seed()), "seed", g.Seed())
+
+	var results []generate.Result
+	if cfg.GenerateOut != "" {
+		results, err = g.WriteAll(ctx, cfg.GenerateOut)
+		if err != nil {
+			fatal(fmt.Sprintf("❌ Generate failed: %v", err), errAttr(err))
+		}
+	} else {
+		if !cfg.Generate.Append {
+			var targets []namespace
+			for _, coll := range g.Collections() {
+				targets = append(targets, namespace{cfg.DB, coll})
+			}
+			confirmDestructive(ctx, client, clientOpts, cfg, "delete every document in these collections and fill them with generated data", targets)
+		}
+		results = g.InsertAll(ctx, client)
+	}
+
+	total := len(cfg.GenerateSchema.Collections)
+	docs, failed := 0, 0
+	for _, r := range results {
+		docs += r.Docs
+		if r.Err != nil {
+			failed++
+		}
+	}
+	logger.Info(fmt.Sprintf("📊 %d collections, %d docs generated, %d failed, %d not started", len(results), docs, failed, total-len(results)),
+		"collections", len(results), "count", docs, "failed", failed, "not_run", total-len(results))
+	if failed > 0 || len(results) < total {
+		return exitFailure
+	}
+	logger.Info("✅ Generate completed.")
+	return exitOK
+}
@@ -0,0 +1,339 @@
+// Package generate 依 schema 檔產生假資料（姓名、email、ObjectID、範圍內的日期等），
+// 直接寫入 MongoDB 或寫成可以再匯入的 NDJSON 檔，用來建立壓測與 demo 用的資料集。
+package generate
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultBatchSize 每次 InsertMany 的文件數
+const DefaultBatchSize = 1000
+
+// Options 產生設定
+type Options struct {
+	DB        string
+	Count     int   // 大於 0 時取代 schema 內每個 collection 的 count
+	Seed      int64 // 相同的 seed 與 schema 產生相同的資料（ObjectID 與 now 除外）；0 表示依時間隨機
+	Append    bool  // 不先清空目標 collection
+	BatchSize int
+	OpTimeout time.Duration // 清空與每批寫入的 timeout；0 表示不限制
+	Logger    *slog.Logger  // nil 時使用 slog.Default()
+}
+
+// Generator 依 schema 產生文件；不可同時在多個 goroutine 使用
+type Generator struct {
+	schema *Schema
+	opts   Options
+	rnd    *rand.Rand
+	now    time.Time
+	log    *slog.Logger
+}
+
+// Result 單一 collection 的結果
+type Result struct {
+	Collection string
+	Target     string // namespace 或輸出檔
+	Docs       int
+	Duration   time.Duration
+	Err        error
+}
+
+// New 檢查 opts 並補齊預設值
+func New(schema *Schema, opts Options) (*Generator, error) {
+	if opts.Count < 0 {
+		return nil, fmt.Errorf("invalid count: %d", opts.Count)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Generator{schema: schema, opts: opts, rnd: rand.New(rand.NewSource(opts.Seed)), now: time.Now(), log: opts.Logger}, nil
+}
+
+// Seed 實際使用的 seed，沒有指定時記錄下來才能重現同一份資料
+func (g *Generator) Seed() int64 {
+	return g.opts.Seed
+}
+
+// count collection 要產生的文件數
+func (g *Generator) count(c Collection) int {
+	if g.opts.Count > 0 {
+		return g.opts.Count
+	}
+	return c.Count
+}
+
+// Document 產生 c 的第 n 筆文件（n 從 0 開始，seq 以此計算）
+func (g *Generator) Document(c Collection, n int) bson.D {
+	return g.document(c.fields, n)
+}
+
+func (g *Generator) document(fields []field, n int) bson.D {
+	doc := make(bson.D, 0, len(fields))
+	for _, f := range fields {
+		doc = append(doc, bson.E{Key: f.name, Value: f.gen(g, n)})
+	}
+	return doc
+}
+
+// InsertAll 依序把每個 collection 的文件寫入 client；ctx 被取消後不再開始新的 collection
+func (g *Generator) InsertAll(ctx context.Context, client *mongo.Client) []Result {
+	if g.opts.DB == "" {
+		return []Result{{Err: errors.New("missing database")}}
+	}
+	results := make([]Result, 0, len(g.schema.Collections))
+	for _, c := range g.schema.Collections {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, g.insert(ctx, client.Database(g.opts.DB).Collection(c.Name), c))
+	}
+	return results
+}
+
+func (g *Generator) insert(ctx context.Context, target *mongo.Collection, c Collection) (res Result) {
+	res = Result{Collection: c.Name, Target: g.opts.DB + "." + c.Name}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+	total := g.count(c)
+	g.log.Info(fmt.Sprintf("🎲 Generating %d docs into %s", total, res.Target), "collection", c.Name, "target", res.Target, "count", total)
+
+	if !g.opts.Append {
+		err := g.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.DeleteMany(ctx, bson.M{})
+			return err
+		})
+		if err != nil {
+			g.log.Error(fmt.Sprintf("❌ Failed to clear collection %s: %v", res.Target, err), "target", res.Target, errAttr(err))
+			res.Err = err
+			return res
+		}
+	}
+
+	batch := make([]interface{}, 0, g.opts.BatchSize)
+	var err error
+	for n := 0; n < total && err == nil; n++ {
+		batch = append(batch, g.Document(c, n))
+		if len(batch) < g.opts.BatchSize && n < total-1 {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		err = g.withOpTimeout(ctx, func(ctx context.Context) error {
+			_, err := target.InsertMany(ctx, batch)
+			return err
+		})
+		if err == nil {
+			res.Docs += len(batch)
+			batch = make([]interface{}, 0, g.opts.BatchSize)
+		}
+	}
+	if err != nil {
+		g.log.Error(fmt.Sprintf("❌ Failed to generate %s after %d docs: %v", res.Target, res.Docs, err),
+			"target", res.Target, "count", res.Docs, errAttr(err))
+		res.Err = err
+		return res
+	}
+	g.log.Info(fmt.Sprintf("✅ Generated %d docs into %s", res.Docs, res.Target),
+		"target", res.Target, "count", res.Docs, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
+
+// WriteAll 把每個 collection 寫成 dir/<collection>.json（每行一筆 Extended JSON），可以直接再 import
+func (g *Generator) WriteAll(ctx context.Context, dir string) ([]Result, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory %s: %v", dir, err)
+	}
+	results := make([]Result, 0, len(g.schema.Collections))
+	for _, c := range g.schema.Collections {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, g.write(ctx, filepath.Join(dir, c.Name+".json"), c))
+	}
+	return results, nil
+}
+
+func (g *Generator) write(ctx context.Context, path string, c Collection) (res Result) {
+	res = Result{Collection: c.Name, Target: path}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+	total := g.count(c)
+	g.log.Info(fmt.Sprintf("🎲 Generating %d docs into %s", total, path), "collection", c.Name, "file", path, "count", total)
+
+	err := func() error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		for n := 0; n < total; n++ {
+			if n%g.opts.BatchSize == 0 {
+				if err := ctx.Err(); err != nil {
+					f.Close()
+					return err
+				}
+			}
+			line, err := bson.MarshalExtJSON(g.Document(c, n), false, false)
+			if err != nil {
+				f.Close()
+				return err
+			}
+			w.Write(line)
+			w.WriteByte('\n')
+			res.Docs++
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}()
+	if err != nil {
+		g.log.Error(fmt.Sprintf("❌ Failed to write %s: %v", path, err), "file", path, "count", res.Docs, errAttr(err))
+		res.Err = err
+		return res
+	}
+	g.log.Info(fmt.Sprintf("✅ Wrote %d docs to %s", res.Docs, path),
+		"file", path, "count", res.Docs, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
+
+// Collections schema 內的 collection 名稱，供確認破壞性操作時列出
+func (g *Generator) Collections() []string {
+	names := make([]string, len(g.schema.Collections))
+	for n, c := range g.schema.Collections {
+		names[n] = c.Name
+	}
+	return names
+}
+
+// simpleGenerators 不需要參數的產生器
+var simpleGenerators = map[string]valueFunc{
+	"objectId":  func(*Generator, int) interface{} { return primitive.NewObjectID() },
+	"uuid":      func(g *Generator, _ int) interface{} { return g.uuid() },
+	"name":      func(g *Generator, _ int) interface{} { return g.pick(firstNames) + " " + g.pick(lastNames) },
+	"firstName": func(g *Generator, _ int) interface{} { return g.pick(firstNames) },
+	"lastName":  func(g *Generator, _ int) interface{} { return g.pick(lastNames) },
+	"email":     func(g *Generator, _ int) interface{} { return g.email() },
+	"phone": func(g *Generator, _ int) interface{} {
+		return fmt.Sprintf("+1-%03d-%03d-%04d", 200+g.rnd.Intn(800), g.rnd.Intn(1000), g.rnd.Intn(10000))
+	},
+	"city":     func(g *Generator, _ int) interface{} { return g.pick(cities) },
+	"country":  func(g *Generator, _ int) interface{} { return g.pick(countries) },
+	"company":  func(g *Generator, _ int) interface{} { return g.pick(lastNames) + " " + g.pick(companySuffixes) },
+	"word":     func(g *Generator, _ int) interface{} { return g.pick(words) },
+	"sentence": func(g *Generator, _ int) interface{} { return g.sentence() },
+	"bool":     func(g *Generator, _ int) interface{} { return g.rnd.Intn(2) == 1 },
+	"int":      func(g *Generator, _ int) interface{} { return g.rnd.Int63n(1000) },
+	"float":    func(g *Generator, _ int) interface{} { return g.rnd.Float64() },
+	"seq":      func(_ *Generator, n int) interface{} { return int64(n + 1) },
+	"now":      func(g *Generator, _ int) interface{} { return primitive.NewDateTimeFromTime(g.now) },
+	// date 沒有範圍時為過去一年內
+	"date": func(g *Generator, _ int) interface{} { return g.dateBetween(g.now.AddDate(-1, 0, 0), g.now) },
+}
+
+func (g *Generator) pick(list []string) string {
+	return list[g.rnd.Intn(len(list))]
+}
+
+// email 以姓名組成，加上數字降低重複的機率
+func (g *Generator) email() string {
+	return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(g.pick(firstNames)), strings.ToLower(g.pick(lastNames)),
+		g.rnd.Intn(1000), g.pick(emailDomains))
+}
+
+func (g *Generator) sentence() string {
+	n := 4 + g.rnd.Intn(8)
+	parts := make([]string, n)
+	for k := range parts {
+		parts[k] = g.pick(words)
+	}
+	s := strings.Join(parts, " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// uuid 以 seed 產生的 UUID v4 字串，同一個 seed 可以重現
+func (g *Generator) uuid() string {
+	var b [16]byte
+	g.rnd.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// dateBetween [from, to] 之間的隨機時間，精確到毫秒（BSON date 的精度）
+func (g *Generator) dateBetween(from, to time.Time) primitive.DateTime {
+	span := to.Sub(from).Milliseconds()
+	return primitive.NewDateTimeFromTime(from.Add(time.Duration(g.rnd.Int63n(span+1)) * time.Millisecond))
+}
+
+// opContext OpTimeout 為 0 時不限制
+func (g *Generator) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.opts.OpTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.opts.OpTimeout)
+}
+
+func (g *Generator) withOpTimeout(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := g.opContext(ctx)
+	defer cancel()
+	return fn(ctx)
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
+
+var firstNames = []string{
+	"Alex", "Blake", "Casey", "Dana", "Eli", "Frankie", "Gale", "Harper", "Indy", "Jamie",
+	"Kai", "Lee", "Morgan", "Noel", "Oakley", "Parker", "Quinn", "Riley", "Sage", "Taylor",
+	"Avery", "Cameron", "Drew", "Emerson", "Jordan", "Logan", "Micah", "Reese", "Rowan", "Skyler",
+}
+
+var lastNames = []string{
+	"Anderson", "Brooks", "Chen", "Diaz", "Evans", "Fischer", "Garcia", "Huang", "Ito", "Jensen",
+	"Kim", "Lopez", "Martin", "Nguyen", "Olsen", "Patel", "Rossi", "Smith", "Tanaka", "Wang",
+}
+
+var emailDomains = []string{"example.com", "example.org", "example.net", "mail.test"}
+
+var cities = []string{
+	"Amsterdam", "Austin", "Berlin", "Buenos Aires", "Cape Town", "Chicago", "Dublin", "Kaohsiung", "Lisbon", "London",
+	"Madrid", "Melbourne", "Montreal", "Osaka", "Paris", "Seoul", "Singapore", "Taipei", "Tokyo", "Toronto",
+}
+
+var countries = []string{
+	"Argentina", "Australia", "Brazil", "Canada", "France", "Germany", "India", "Ireland", "Japan", "Mexico",
+	"Netherlands", "Portugal", "Singapore", "South Africa", "South Korea", "Spain", "Taiwan", "United Kingdom", "United States",
+}
+
+var companySuffixes = []string{"Inc", "LLC", "Ltd", "Group", "Labs", "Systems", "Holdings"}
+
+var words = []string{
+	"alpha", "amber", "anchor", "apple", "arrow", "basket", "beacon", "breeze", "canvas", "cedar",
+	"cloud", "copper", "delta", "echo", "ember", "falcon", "forest", "galaxy", "harbor", "island",
+	"jade", "kernel", "lantern", "maple", "meadow", "nebula", "orbit", "pepper", "quartz", "river",
+	"saffron", "signal", "summit", "thunder", "timber", "velvet", "willow", "zephyr",
+}
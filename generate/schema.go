@@ -0,0 +1,283 @@
+package generate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema generate 的設定檔，collection 依檔案內的順序產生
+type Schema struct {
+	Collections []Collection
+}
+
+// Collection 一個 collection 要產生的文件數與欄位
+type Collection struct {
+	Name   string
+	Count  int
+	fields []field
+}
+
+// Fields 最上層的欄位名稱，依設定檔的順序
+func (c Collection) Fields() []string {
+	names := make([]string, len(c.fields))
+	for n, f := range c.fields {
+		names[n] = f.name
+	}
+	return names
+}
+
+type field struct {
+	name string
+	gen  valueFunc
+}
+
+// valueFunc 產生欄位的值；n 為文件在 collection 中的序號（從 0 開始）
+type valueFunc func(g *Generator, n int) interface{}
+
+// LoadSchema 讀取 YAML（JSON 也是合法的 YAML）格式的 schema，例如
+//
+//	collections:
+//	  users:
+//	    count: 1000
+//	    fields:
+//	      _id: objectId
+//	      name: name
+//	      email: email
+//	      age: { int: [18, 90] }
+//	      createdAt: { date: ["2024-01-01", "2024-12-31"] }
+//	      status: { oneOf: [active, active, inactive] }
+//	      address: { city: city, country: country }
+//	      tags: { array: { of: word, min: 0, max: 3 } }
+//
+// 欄位的值為產生器名稱，或只有一個 key 的產生器設定；其他 mapping 是子文件
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		Collections yaml.Node `yaml:"collections"`
+	}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if root.Collections.Kind != yaml.MappingNode || len(root.Collections.Content) == 0 {
+		return nil, fmt.Errorf("%s: collections must be a non-empty mapping of collection names", path)
+	}
+
+	s := &Schema{}
+	for k := 0; k+1 < len(root.Collections.Content); k += 2 {
+		name := root.Collections.Content[k].Value
+		c, err := compileCollection(name, root.Collections.Content[k+1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+		s.Collections = append(s.Collections, c)
+	}
+	return s, nil
+}
+
+func compileCollection(name string, n *yaml.Node) (Collection, error) {
+	var raw struct {
+		Count  int       `yaml:"count"`
+		Fields yaml.Node `yaml:"fields"`
+	}
+	if err := n.Decode(&raw); err != nil {
+		return Collection{}, err
+	}
+	if raw.Count < 0 {
+		return Collection{}, fmt.Errorf("invalid count %d", raw.Count)
+	}
+	if raw.Fields.Kind != yaml.MappingNode {
+		return Collection{}, fmt.Errorf("fields must be a mapping")
+	}
+	fields, err := compileFields(&raw.Fields)
+	if err != nil {
+		return Collection{}, err
+	}
+	return Collection{Name: name, Count: raw.Count, fields: fields}, nil
+}
+
+func compileFields(n *yaml.Node) ([]field, error) {
+	fields := make([]field, 0, len(n.Content)/2)
+	for k := 0; k+1 < len(n.Content); k += 2 {
+		name := n.Content[k].Value
+		gen, err := compileValue(n.Content[k+1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		fields = append(fields, field{name: name, gen: gen})
+	}
+	return fields, nil
+}
+
+// compileValue 字串為產生器名稱；只有一個 key 且是產生器名稱的 mapping 帶有參數；其他 mapping 為子文件
+func compileValue(n *yaml.Node) (valueFunc, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return compileValue(n.Alias)
+	case yaml.ScalarNode:
+		gen, ok := simpleGenerators[n.Value]
+		if !ok {
+			return nil, fmt.Errorf("unknown generator %q (use { const: ... } for a fixed value)", n.Value)
+		}
+		return gen, nil
+	case yaml.MappingNode:
+		if len(n.Content) == 2 {
+			if compile, ok := argGenerators[n.Content[0].Value]; ok {
+				return compile(n.Content[1])
+			}
+		}
+		fields, err := compileFields(n)
+		if err != nil {
+			return nil, err
+		}
+		return func(g *Generator, i int) interface{} { return g.document(fields, i) }, nil
+	}
+	return nil, fmt.Errorf("expected a generator name or mapping, got a %s", nodeKind(n))
+}
+
+// argGenerators 帶參數的產生器；array 會遞迴呼叫 compileValue，所以在 init 中設定
+var argGenerators map[string]func(n *yaml.Node) (valueFunc, error)
+
+func init() {
+	argGenerators = map[string]func(n *yaml.Node) (valueFunc, error){
+		"const": func(n *yaml.Node) (valueFunc, error) {
+			var v interface{}
+			if err := n.Decode(&v); err != nil {
+				return nil, err
+			}
+			return func(*Generator, int) interface{} { return v }, nil
+		},
+		"int": func(n *yaml.Node) (valueFunc, error) {
+			lo, hi, err := intRange(n)
+			if err != nil {
+				return nil, fmt.Errorf("int: %v", err)
+			}
+			return func(g *Generator, _ int) interface{} { return g.int64Between(lo, hi) }, nil
+		},
+		"float": func(n *yaml.Node) (valueFunc, error) {
+			var r []float64
+			if err := n.Decode(&r); err != nil || len(r) != 2 || r[0] > r[1] {
+				return nil, fmt.Errorf("float expects [min, max]")
+			}
+			return func(g *Generator, _ int) interface{} { return r[0] + g.rnd.Float64()*(r[1]-r[0]) }, nil
+		},
+		"date": func(n *yaml.Node) (valueFunc, error) {
+			var r []string
+			if err := n.Decode(&r); err != nil || len(r) != 2 {
+				return nil, fmt.Errorf(`date expects ["from", "to"]`)
+			}
+			from, err := parseTime(r[0])
+			if err != nil {
+				return nil, err
+			}
+			to, err := parseTime(r[1])
+			if err != nil {
+				return nil, err
+			}
+			if to.Before(from) {
+				return nil, fmt.Errorf("date: %s is before %s", r[1], r[0])
+			}
+			return func(g *Generator, _ int) interface{} { return g.dateBetween(from, to) }, nil
+		},
+		"oneOf": func(n *yaml.Node) (valueFunc, error) {
+			var values []interface{}
+			if err := n.Decode(&values); err != nil || len(values) == 0 {
+				return nil, fmt.Errorf("oneOf expects a non-empty list")
+			}
+			return func(g *Generator, _ int) interface{} { return values[g.rnd.Intn(len(values))] }, nil
+		},
+		"seq": func(n *yaml.Node) (valueFunc, error) {
+			start, err := strconv.ParseInt(n.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("seq expects the first number")
+			}
+			return func(_ *Generator, i int) interface{} { return start + int64(i) }, nil
+		},
+		"array": func(n *yaml.Node) (valueFunc, error) {
+			var raw struct {
+				Of  yaml.Node `yaml:"of"`
+				Min int       `yaml:"min"`
+				Max int       `yaml:"max"`
+			}
+			if err := n.Decode(&raw); err != nil {
+				return nil, err
+			}
+			if raw.Of.Kind == 0 {
+				return nil, fmt.Errorf("array requires of")
+			}
+			if raw.Max == 0 && raw.Min == 0 {
+				raw.Max = 3
+			}
+			if raw.Min < 0 || raw.Max < raw.Min {
+				return nil, fmt.Errorf("array: invalid min %d / max %d", raw.Min, raw.Max)
+			}
+			of, err := compileValue(&raw.Of)
+			if err != nil {
+				return nil, fmt.Errorf("array: %v", err)
+			}
+			return func(g *Generator, i int) interface{} {
+				a := make(bson.A, raw.Min+g.rnd.Intn(raw.Max-raw.Min+1))
+				for k := range a {
+					a[k] = of(g, i)
+				}
+				return a
+			}, nil
+		},
+	}
+}
+
+func intRange(n *yaml.Node) (int64, int64, error) {
+	var r []int64
+	if err := n.Decode(&r); err != nil || len(r) != 2 {
+		return 0, 0, fmt.Errorf("expects [min, max]")
+	}
+	if r[0] > r[1] {
+		return 0, 0, fmt.Errorf("min %d is greater than max %d", r[0], r[1])
+	}
+	return r[0], r[1], nil
+}
+
+// int64Between lo 到 hi（含）之間的均勻亂數；hi-lo 超過 int64 時（例如 [-9223372036854775808, 9223372036854775807]）改以 Uint64 取樣
+func (g *Generator) int64Between(lo, hi int64) int64 {
+	span := uint64(hi) - uint64(lo)
+	if span < math.MaxInt64 {
+		return lo + g.rnd.Int63n(int64(span)+1)
+	}
+	for {
+		// span 至少是 2^63-1，每次取樣至少一半的機率落在範圍內
+		if v := g.rnd.Uint64(); v <= span {
+			return int64(uint64(lo) + v)
+		}
+	}
+}
+
+// parseTime 接受 RFC 3339、2006-01-02 或 now
+func parseTime(s string) (time.Time, error) {
+	if s == "now" {
+		return time.Now(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (expected now, an RFC 3339 time or 2006-01-02)", s)
+}
+
+func nodeKind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.MappingNode:
+		return "mapping"
+	}
+	return "scalar"
+}
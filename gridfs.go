@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hayletdomybest/mongo-tools/gridfs"
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runGridFS --gridfs：import 把 --path 目錄的檔案上傳到 bucket（truncate 時先刪除整個 bucket，
+// 否則只取代同名的檔案），export 把 bucket 的檔案下載到 --path
+func runGridFS(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, cmd string) int {
+	opts := gridfs.Options{
+		DB:        cfg.DB,
+		Bucket:    cfg.GridFS,
+		Drop:      cmd == "import" && cfg.Import.Strategy == importer.StrategyTruncate,
+		OpTimeout: cfg.OpTimeout,
+		Logger:    logger,
+	}
+	t, err := gridfs.New(client, opts)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid GridFS options: %v", err), errAttr(err))
+	}
+
+	var results []gridfs.Result
+	verb := "uploaded"
+	if cmd == "export" {
+		verb = "downloaded"
+		results, err = t.Export(ctx, cfg.Path)
+	} else {
+		if opts.Drop {
+			var targets []namespace
+			for _, coll := range t.Namespaces() {
+				targets = append(targets, namespace{cfg.DB, coll})
+			}
+			confirmDestructive(ctx, client, clientOpts, cfg, "drop the GridFS bucket "+cfg.GridFS+" and upload the files again", targets)
+		}
+		results, err = t.Import(ctx, cfg.Path)
+	}
+	if err != nil {
+		fatal(fmt.Sprintf("❌ GridFS %s failed: %v", cmd, err), "bucket", cfg.GridFS, errAttr(err))
+	}
+
+	var bytes int64
+	failed := 0
+	for _, r := range results {
+		bytes += r.Bytes
+		if r.Err != nil {
+			failed++
+		}
+	}
+	logger.Info(fmt.Sprintf("📊 %d files, %d bytes %s, %d failed", len(results), bytes, verb, failed),
+		"bucket", cfg.GridFS, "files", len(results), "bytes", bytes, "failed", failed)
+	if failed > 0 {
+		return exitFailure
+	}
+	logger.Info(fmt.Sprintf("✅ GridFS %s completed.", cmd))
+	return exitOK
+}
@@ -0,0 +1,126 @@
+// Package gridfs 把目錄下的二進位檔（圖片、附件等 fixture）匯入 GridFS bucket，或從 bucket 匯出回目錄；
+// 檔名以外的屬性（_id、metadata）記錄在目錄內的 manifest 檔，匯出再匯入後內容相同。
+package gridfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mgridfs "go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultBucket 沒有指定 bucket 時使用 GridFS 預設的 fs（fs.files / fs.chunks）
+const DefaultBucket = "fs"
+
+// Options 匯入 / 匯出設定
+type Options struct {
+	DB        string
+	Bucket    string        // 空字串表示 DefaultBucket
+	ChunkSize int32         // 每個 chunk 的大小，0 表示 driver 預設的 255 KiB
+	Drop      bool          // 匯入前刪除整個 bucket；否則只取代同名（或同 _id）的檔案
+	OpTimeout time.Duration // 刪除、查詢等操作的 timeout，不含上傳 / 下載檔案內容的時間；0 表示不限制
+	Logger    *slog.Logger  // nil 時使用 slog.Default()
+}
+
+// Transfer 在目錄與 GridFS bucket 之間搬移檔案
+type Transfer struct {
+	bucket *mgridfs.Bucket
+	opts   Options
+	log    *slog.Logger
+}
+
+// Result 單一檔案的結果
+type Result struct {
+	Path     string // 相對於目錄的路徑，以 / 分隔
+	Filename string // GridFS 的 filename
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+// New 檢查 opts 並補齊預設值
+func New(client *mongo.Client, opts Options) (*Transfer, error) {
+	if opts.DB == "" {
+		return nil, errors.New("missing database")
+	}
+	if opts.Bucket == "" {
+		opts.Bucket = DefaultBucket
+	}
+	if opts.ChunkSize < 0 {
+		return nil, fmt.Errorf("invalid chunk size: %d", opts.ChunkSize)
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	bucketOpts := options.GridFSBucket().SetName(opts.Bucket)
+	if opts.ChunkSize > 0 {
+		bucketOpts.SetChunkSizeBytes(opts.ChunkSize)
+	}
+	bucket, err := mgridfs.NewBucket(client.Database(opts.DB), bucketOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &Transfer{bucket: bucket, opts: opts, log: opts.Logger}, nil
+}
+
+// Namespaces bucket 底下的兩個 collection，供確認破壞性操作時列出
+func (t *Transfer) Namespaces() []string {
+	return []string{t.opts.Bucket + ".files", t.opts.Bucket + ".chunks"}
+}
+
+// opContext OpTimeout 為 0 時不限制
+func (t *Transfer) opContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.opts.OpTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, t.opts.OpTimeout)
+}
+
+// storedFile fs.files 內的一筆
+type storedFile struct {
+	ID         interface{} `bson:"_id"`
+	Filename   string      `bson:"filename"`
+	Length     int64       `bson:"length"`
+	UploadDate time.Time   `bson:"uploadDate"`
+	Metadata   bson.D      `bson:"metadata,omitempty"`
+}
+
+// find 依 filter 列出 fs.files，同一個 filename 的新版本排在前面
+func (t *Transfer) find(ctx context.Context, filter interface{}) ([]storedFile, error) {
+	ctx, cancel := t.opContext(ctx)
+	defer cancel()
+	cursor, err := t.bucket.FindContext(ctx, filter, options.GridFSFind().SetSort(bson.D{{Key: "filename", Value: 1}, {Key: "uploadDate", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	var files []storedFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// ctxReader 讀取前檢查 ctx；driver 的上傳 / 下載串流只接受 deadline，取消時靠這裡中斷
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
@@ -0,0 +1,84 @@
+package gridfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ManifestFile 目錄內記錄每個檔案 GridFS 屬性的 manifest，本身不會被匯入
+const ManifestFile = "gridfs.manifest.json"
+
+// Entry manifest 的一筆，例如
+// {"path": "avatars/alice.png", "_id": {"$oid": "..."}, "metadata": {"contentType": "image/png", "owner": "alice"}}
+type Entry struct {
+	Path     string      `bson:"path"`               // 相對於目錄的路徑，以 / 分隔
+	Filename string      `bson:"filename,omitempty"` // GridFS 的 filename，預設與 Path 相同
+	ID       interface{} `bson:"_id,omitempty"`      // 沒有時由 driver 產生 ObjectID
+	Metadata bson.D      `bson:"metadata,omitempty"`
+}
+
+// filename GridFS 內使用的名稱
+func (e Entry) filename() string {
+	if e.Filename != "" {
+		return e.Filename
+	}
+	return e.Path
+}
+
+type manifest struct {
+	Files []Entry `bson:"files"`
+}
+
+// loadManifest 讀取 <dir>/gridfs.manifest.json（Extended JSON），以 Path 為索引；沒有 manifest 時回傳空的 map
+func loadManifest(dir string) (map[string]Entry, error) {
+	path := filepath.Join(dir, ManifestFile)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := bson.UnmarshalExtJSON(bytes.TrimSpace(data), false, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	entries := make(map[string]Entry, len(m.Files))
+	for n, e := range m.Files {
+		if e.Path == "" {
+			return nil, fmt.Errorf("%s: file %d: path is required", path, n+1)
+		}
+		if _, err := localPath(dir, e.Path); err != nil {
+			return nil, fmt.Errorf("%s: file %d: %v", path, n+1, err)
+		}
+		if _, dup := entries[e.Path]; dup {
+			return nil, fmt.Errorf("%s: duplicate path %q", path, e.Path)
+		}
+		entries[e.Path] = e
+	}
+	return entries, nil
+}
+
+// writeManifest 以 canonical Extended JSON 寫出 manifest，_id 與 metadata 的型別才能完整保留
+func writeManifest(dir string, entries []Entry) error {
+	data, err := bson.MarshalExtJSONIndent(manifest{Files: entries}, true, false, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644)
+}
+
+// localPath 把以 / 分隔的相對路徑轉成 dir 底下的路徑；拒絕絕對路徑與跳出 dir 的 ".."
+func localPath(dir, rel string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(rel))
+	if filepath.IsAbs(clean) || !filepath.IsLocal(clean) {
+		return "", fmt.Errorf("path %q is outside the directory", rel)
+	}
+	return filepath.Join(dir, clean), nil
+}
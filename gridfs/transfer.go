@@ -0,0 +1,301 @@
+package gridfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Import 把 dir 底下（含子目錄，略過隱藏檔與 manifest）的檔案上傳到 bucket，屬性取自 manifest；
+// 只有無法讀取目錄 / manifest 或刪除 bucket 時才回傳 error，個別檔案的錯誤記錄在 Result.Err
+func (t *Transfer) Import(ctx context.Context, dir string) ([]Result, error) {
+	entries, err := loadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %v", err)
+	}
+
+	if t.opts.Drop {
+		dropCtx, cancel := t.opContext(ctx)
+		err := t.bucket.DropContext(dropCtx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to drop bucket %s: %v", t.opts.Bucket, err)
+		}
+		t.log.Info(fmt.Sprintf("🗑️  Dropped GridFS bucket %s", t.opts.Bucket), "bucket", t.opts.Bucket)
+	}
+
+	var results []Result
+	for _, rel := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		e, ok := entries[rel]
+		if !ok {
+			e = Entry{Path: rel}
+		}
+		delete(entries, rel)
+		results = append(results, t.importFile(ctx, dir, e))
+	}
+	missing := make([]string, 0, len(entries))
+	for rel := range entries {
+		missing = append(missing, rel)
+	}
+	sort.Strings(missing)
+	for _, rel := range missing {
+		t.log.Warn(fmt.Sprintf("⚠️  %s is listed in %s but does not exist", rel, ManifestFile), "path", rel)
+	}
+	return results, nil
+}
+
+func (t *Transfer) importFile(ctx context.Context, dir string, e Entry) (res Result) {
+	res = Result{Path: e.Path, Filename: e.filename()}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+	attrs := []any{"bucket", t.opts.Bucket, "path", res.Path, "filename", res.Filename}
+	fail := func(msg string, err error) Result {
+		t.log.Error(fmt.Sprintf("❌ %s %s: %v", msg, res.Path, err), append(attrs, errAttr(err))...)
+		res.Err = err
+		return res
+	}
+
+	t.log.Info(fmt.Sprintf("📥 Uploading %s → %s", res.Path, t.opts.Bucket), attrs...)
+	local, err := localPath(dir, e.Path)
+	if err != nil {
+		return fail("Failed to open", err)
+	}
+	f, err := os.Open(local)
+	if err != nil {
+		return fail("Failed to open", err)
+	}
+	defer f.Close()
+
+	// 舊版本在新版本上傳完成後才刪除，上傳失敗時 bucket 仍保有原本的檔案
+	var existing []storedFile
+	taken := false
+	if !t.opts.Drop {
+		if existing, taken, err = t.existing(ctx, res.Filename, e.ID); err != nil {
+			return fail("Failed to replace", err)
+		}
+	}
+	uploadOpts := options.GridFSUpload()
+	if meta := withContentType(e.Metadata, e.Path); meta != nil {
+		uploadOpts.SetMetadata(meta)
+	}
+	id := e.ID
+	if id == nil || taken {
+		// 指定的 _id 還被舊版本佔用時先以暫時的 _id 上傳
+		id = primitive.NewObjectID()
+	}
+	n, err := t.upload(ctx, f, id, res.Filename, uploadOpts)
+	if err != nil {
+		return fail("Failed to upload", err)
+	}
+	if err := t.remove(ctx, existing); err != nil {
+		return fail("Failed to replace", err)
+	}
+	if taken {
+		// 舊版本刪除後再以指定的 _id 上傳一次，最後刪除暫時的版本
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return fail("Failed to upload", err)
+		}
+		if _, err := t.upload(ctx, f, e.ID, res.Filename, uploadOpts); err != nil {
+			return fail("Failed to upload", err)
+		}
+		if err := t.remove(ctx, []storedFile{{ID: id}}); err != nil {
+			return fail("Failed to replace", err)
+		}
+	}
+	res.Bytes = n
+	t.log.Info(fmt.Sprintf("✅ Uploaded %s (%d bytes)", res.Filename, n), append(attrs, "bytes", n)...)
+	return res
+}
+
+// upload 以 id 上傳 r 的內容，回傳寫入的位元組數；失敗時刪除已經寫入的 chunk
+func (t *Transfer) upload(ctx context.Context, r io.Reader, id interface{}, filename string, opts *options.UploadOptions) (int64, error) {
+	upload, err := t.bucket.OpenUploadStreamWithID(id, filename, opts)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(upload, ctxReader{ctx: ctx, r: r})
+	if err != nil {
+		upload.Abort()
+		return 0, err
+	}
+	return n, upload.Close()
+}
+
+// existing 同名的所有版本與同 _id 的檔案，新版本上傳後刪除，重複匯入時不會累積舊版或因 _id 重複而失敗；
+// taken 表示 id 已經被其中一個檔案使用
+func (t *Transfer) existing(ctx context.Context, filename string, id interface{}) (files []storedFile, taken bool, err error) {
+	filter := bson.D{{Key: "filename", Value: filename}}
+	if id != nil {
+		filter = bson.D{{Key: "$or", Value: bson.A{filter, bson.D{{Key: "_id", Value: id}}}}}
+	}
+	if files, err = t.find(ctx, filter); err != nil || id == nil {
+		return files, false, err
+	}
+	same, err := t.find(ctx, bson.D{{Key: "_id", Value: id}})
+	return files, len(same) > 0, err
+}
+
+// remove 刪除 files 與它們的 chunk
+func (t *Transfer) remove(ctx context.Context, files []storedFile) error {
+	for _, f := range files {
+		delCtx, cancel := t.opContext(ctx)
+		err := t.bucket.DeleteContext(delCtx, f.ID)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withContentType metadata 沒有 contentType 時依副檔名補上，例如 .png → image/png
+func withContentType(meta bson.D, rel string) bson.D {
+	for _, e := range meta {
+		if e.Key == "contentType" {
+			return meta
+		}
+	}
+	ct := mime.TypeByExtension(path.Ext(rel))
+	if ct == "" {
+		return meta
+	}
+	return append(append(bson.D{}, meta...), bson.E{Key: "contentType", Value: ct})
+}
+
+// listFiles dir 底下的一般檔案，以 / 分隔的相對路徑排序；略過隱藏的檔案與目錄以及 manifest
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel = filepath.ToSlash(rel); rel != ManifestFile {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Export 把 bucket 內的檔案寫到 dir（路徑即 filename）並寫出 manifest；同名的多個版本只匯出最新的一個。
+// 只有無法列出檔案、建立目錄或寫出 manifest 時才回傳 error，個別檔案的錯誤記錄在 Result.Err
+func (t *Transfer) Export(ctx context.Context, dir string) ([]Result, error) {
+	files, err := t.find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in bucket %s: %v", t.opts.Bucket, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory %s: %v", dir, err)
+	}
+
+	var results []Result
+	entries := []Entry{}
+	seen := map[string]bool{}
+	older := 0
+	for _, f := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		if seen[f.Filename] {
+			older++
+			continue
+		}
+		seen[f.Filename] = true
+		res := t.exportFile(ctx, dir, f)
+		results = append(results, res)
+		if res.Err == nil {
+			e := Entry{Path: res.Path, ID: f.ID, Metadata: f.Metadata}
+			if res.Path != f.Filename {
+				e.Filename = f.Filename
+			}
+			entries = append(entries, e)
+		}
+	}
+	if older > 0 {
+		t.log.Warn(fmt.Sprintf("⚠️  Skipped %d older revisions; only the latest upload of each filename is exported", older),
+			"bucket", t.opts.Bucket, "count", older)
+	}
+	if err := writeManifest(dir, entries); err != nil {
+		return results, fmt.Errorf("failed to write %s: %v", ManifestFile, err)
+	}
+	return results, nil
+}
+
+func (t *Transfer) exportFile(ctx context.Context, dir string, f storedFile) (res Result) {
+	res = Result{Filename: f.Filename}
+	started := time.Now()
+	defer func() { res.Duration = time.Since(started) }()
+	attrs := []any{"bucket", t.opts.Bucket, "filename", f.Filename}
+	fail := func(msg string, err error) Result {
+		t.log.Error(fmt.Sprintf("❌ %s %s: %v", msg, f.Filename, err), append(attrs, errAttr(err))...)
+		res.Err = err
+		return res
+	}
+
+	local, err := localPath(dir, f.Filename)
+	if err != nil {
+		return fail("Cannot export", err)
+	}
+	rel, _ := filepath.Rel(dir, local)
+	res.Path = filepath.ToSlash(rel)
+	t.log.Info(fmt.Sprintf("📤 Downloading %s → %s", f.Filename, local), append(attrs, "file", local)...)
+
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return fail("Failed to create directory for", err)
+	}
+	download, err := t.bucket.OpenDownloadStream(f.ID)
+	if err != nil {
+		return fail("Failed to download", err)
+	}
+	defer download.Close()
+	out, err := os.Create(local)
+	if err != nil {
+		return fail("Failed to create file for", err)
+	}
+	n, err := io.Copy(out, ctxReader{ctx: ctx, r: download})
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fail("Failed to download", err)
+	}
+	res.Bytes = n
+	t.log.Info(fmt.Sprintf("✅ Downloaded %s (%d bytes)", f.Filename, n), append(attrs, "bytes", n)...)
+	return res
+}
@@ -0,0 +1,450 @@
+package importer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc64"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mongodump --archive 的串流格式：magic number、header 文件、每個 namespace 一筆 metadata 文件、terminator（prelude），
+// 之後是一個個區塊：namespace header 文件、該 namespace 的文件、terminator。多個 collection 同時 dump 時區塊會交錯，
+// 每個 namespace 最後以 EOF 為 true（帶 CRC）的 header 結束。
+
+// archiveMagic 串流開頭的 int32（little-endian）
+const archiveMagic = 0x8199e26d
+
+// archiveTerminator 出現在 BSON 長度位置的 -1，結束 prelude 或一個區塊
+const archiveTerminator = 0xffffffff
+
+// archiveExt mongodump --archive=<file> 慣用的副檔名；--gzip 時內容整個以 gzip 壓縮，副檔名不一定會變
+const archiveExt = ".archive"
+
+// archiveCRC mongodump 以 CRC-64（ECMA）檢查每個 namespace 的文件
+var archiveCRC = crc64.MakeTable(crc64.ECMA)
+
+// archiveHeader prelude 的第一筆文件
+type archiveHeader struct {
+	Version               string `bson:"version"`
+	ServerVersion         string `bson:"server_version"`
+	ToolVersion           string `bson:"tool_version"`
+	ConcurrentCollections int32  `bson:"concurrent_collections"`
+}
+
+// archiveCollection prelude 內每個 namespace 的 metadata；Metadata 是 <collection>.metadata.json 的內容
+type archiveCollection struct {
+	DB         string `bson:"db"`
+	Collection string `bson:"collection"`
+	Metadata   string `bson:"metadata"`
+	Size       int64  `bson:"size"`
+	Type       string `bson:"type"` // collection、view 或 timeseries；舊版 mongodump 沒有這個欄位
+}
+
+func (c archiveCollection) namespace() string {
+	return c.DB + "." + c.Collection
+}
+
+// archiveBlockHeader 每個區塊開頭的 namespace header
+type archiveBlockHeader struct {
+	DB         string `bson:"db"`
+	Collection string `bson:"collection"`
+	EOF        bool   `bson:"EOF"`
+	CRC        int64  `bson:"CRC"`
+}
+
+// archiveReader 依序讀出 archive 內以長度開頭的 BSON 文件與 terminator
+type archiveReader struct {
+	r   *bufio.Reader
+	pos int64 // 已讀取的位元組，錯誤訊息用
+}
+
+// newArchiveReader 檢查 magic number；r 以 gzip 開頭（mongodump --gzip --archive）時先解壓
+func newArchiveReader(r io.Reader) (*archiveReader, io.Closer, error) {
+	br := bufio.NewReader(r)
+	var closer io.Closer = io.NopCloser(nil)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		br, closer = bufio.NewReader(gz), gz
+	}
+	var header [4]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		closer.Close()
+		return nil, nil, fmt.Errorf("not a mongodump archive: %v", err)
+	}
+	if binary.LittleEndian.Uint32(header[:]) != archiveMagic {
+		closer.Close()
+		return nil, nil, errors.New("not a mongodump archive (bad magic number)")
+	}
+	return &archiveReader{r: br, pos: 4}, closer, nil
+}
+
+// next 回傳下一筆文件；遇到 terminator 時回傳 nil, nil，串流結束時回傳 io.EOF
+func (a *archiveReader) next() (bson.Raw, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(a.r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("truncated archive at byte %d: %v", a.pos, err)
+	}
+	a.pos += 4
+	length := binary.LittleEndian.Uint32(header[:])
+	if length == archiveTerminator {
+		return nil, nil
+	}
+	if length < 5 || length > maxBSONDocSize {
+		return nil, fmt.Errorf("invalid archive at byte %d: BSON document size %d", a.pos-4, int32(length))
+	}
+	raw := make([]byte, length)
+	copy(raw, header[:])
+	if _, err := io.ReadFull(a.r, raw[4:]); err != nil {
+		return nil, fmt.Errorf("truncated archive at byte %d: %v", a.pos, err)
+	}
+	// 損壞的文件交給 bson.Unmarshal 可能會 panic，先檢查結構
+	if err := bson.Raw(raw).Validate(); err != nil {
+		return nil, fmt.Errorf("corrupt archive at byte %d: %v", a.pos-4, err)
+	}
+	a.pos += int64(length) - 4
+	return raw, nil
+}
+
+// readPrelude 讀取 header 與所有 namespace 的 metadata
+func (a *archiveReader) readPrelude() (archiveHeader, []archiveCollection, error) {
+	var header archiveHeader
+	raw, err := a.next()
+	if err != nil || raw == nil {
+		return header, nil, fmt.Errorf("missing archive header: %v", err)
+	}
+	if err := bson.Unmarshal(raw, &header); err != nil {
+		return header, nil, fmt.Errorf("invalid archive header: %v", err)
+	}
+	var colls []archiveCollection
+	for {
+		raw, err := a.next()
+		if err != nil {
+			return header, nil, fmt.Errorf("failed to read archive metadata: %v", err)
+		}
+		if raw == nil {
+			return header, colls, nil
+		}
+		var c archiveCollection
+		if err := bson.Unmarshal(raw, &c); err != nil {
+			return header, nil, fmt.Errorf("invalid archive metadata %d: %v", len(colls)+1, err)
+		}
+		colls = append(colls, c)
+	}
+}
+
+// spooledNamespace 已從 archive 拆出到暫存目錄的 namespace
+type spooledNamespace struct {
+	archiveCollection
+	file  string // <db>.<collection>.bson，旁邊是由 metadata 產生的 .indexes.json / .options.json
+	view  *View  // type 為 view 時的定義，沒有資料檔
+	crc   hash.Hash64
+	out   *os.File
+	w     *bufio.Writer
+	ended bool
+}
+
+// spoolArchive 把 archive 拆成 dir 下每個 namespace 一個 .bson 檔：區塊是交錯的，
+// 而且 stdin 只能讀一次，所以先寫到磁碟，再用一般的 BSON 匯入流程逐一匯入
+func spoolArchive(r io.Reader, dir string) (archiveHeader, []*spooledNamespace, error) {
+	a, closer, err := newArchiveReader(r)
+	if err != nil {
+		return archiveHeader{}, nil, err
+	}
+	defer closer.Close()
+	header, colls, err := a.readPrelude()
+	if err != nil {
+		return header, nil, err
+	}
+
+	byName := map[string]*spooledNamespace{}
+	var spooled []*spooledNamespace
+	defer func() {
+		for _, ns := range spooled {
+			if ns.out != nil {
+				ns.out.Close()
+			}
+		}
+	}()
+	for _, c := range colls {
+		ns := &spooledNamespace{archiveCollection: c, crc: crc64.New(archiveCRC)}
+		base := filepath.Join(dir, url.PathEscape(c.DB)+"."+url.PathEscape(c.Collection))
+		if err := ns.writeSidecars(base); err != nil {
+			return header, nil, fmt.Errorf("%s: %v", c.namespace(), err)
+		}
+		if ns.view == nil {
+			ns.file = base + ".bson"
+			if ns.out, err = os.Create(ns.file); err != nil {
+				return header, nil, err
+			}
+			ns.w = bufio.NewWriter(ns.out)
+		}
+		byName[c.namespace()] = ns
+		spooled = append(spooled, ns)
+	}
+
+	for {
+		raw, err := a.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return header, nil, err
+		}
+		if raw == nil {
+			return header, nil, fmt.Errorf("invalid archive at byte %d: block without a namespace header", a.pos-4)
+		}
+		var block archiveBlockHeader
+		if err := bson.Unmarshal(raw, &block); err != nil {
+			return header, nil, fmt.Errorf("invalid archive namespace header at byte %d: %v", a.pos-int64(len(raw)), err)
+		}
+		ns := byName[block.DB+"."+block.Collection]
+		if ns == nil && block.DB == "" && block.Collection == "oplog" {
+			// --oplog 的 entry 不是 collection 的資料，只檢查 CRC
+			ns = &spooledNamespace{crc: crc64.New(archiveCRC)}
+			byName[".oplog"] = ns
+		}
+		if ns == nil {
+			return header, nil, fmt.Errorf("invalid archive: %s.%s is not listed in the archive metadata", block.DB, block.Collection)
+		}
+		for {
+			doc, err := a.next()
+			if err != nil {
+				if err == io.EOF {
+					err = fmt.Errorf("truncated archive: %s.%s ends without a terminator", block.DB, block.Collection)
+				}
+				return header, nil, err
+			}
+			if doc == nil {
+				break
+			}
+			ns.crc.Write(doc)
+			if ns.w != nil {
+				if _, err := ns.w.Write(doc); err != nil {
+					return header, nil, err
+				}
+			}
+		}
+		if block.EOF {
+			if block.CRC != int64(ns.crc.Sum64()) {
+				return header, nil, fmt.Errorf("corrupt archive: checksum of %s.%s does not match", block.DB, block.Collection)
+			}
+			ns.ended = true
+			if err := ns.close(); err != nil {
+				return header, nil, err
+			}
+		}
+	}
+	for _, ns := range spooled {
+		if ns.view == nil && !ns.ended {
+			return header, nil, fmt.Errorf("truncated archive: %s has no end marker", ns.namespace())
+		}
+	}
+	return header, spooled, nil
+}
+
+func (ns *spooledNamespace) close() error {
+	if ns.out == nil {
+		return nil
+	}
+	err := ns.w.Flush()
+	if cerr := ns.out.Close(); err == nil {
+		err = cerr
+	}
+	ns.out = nil
+	return err
+}
+
+// writeSidecars 把 metadata 的 indexes 與 options 寫成 base.indexes.json / base.options.json，
+// 由 applyIndexSidecar 與 prepareCollection 跟目錄匯入時一樣套用；view 只記下定義
+func (ns *spooledNamespace) writeSidecars(base string) error {
+	if ns.Metadata == "" {
+		return nil
+	}
+	var meta struct {
+		Options bson.D   `bson:"options"`
+		Indexes []bson.D `bson:"indexes"`
+	}
+	if err := bson.UnmarshalExtJSON([]byte(ns.Metadata), false, &meta); err != nil {
+		return fmt.Errorf("invalid metadata: %v", err)
+	}
+	if ns.Type == "view" || viewOn(meta.Options) != "" {
+		v := View{DB: ns.DB, Name: ns.Collection, Source: viewOn(meta.Options)}
+		for _, e := range meta.Options {
+			switch e.Key {
+			case "pipeline":
+				if err := remarshal(e.Value, &v.Pipeline); err != nil {
+					return fmt.Errorf("invalid view pipeline: %v", err)
+				}
+			case "collation":
+				v.Collation, _ = e.Value.(bson.D)
+			}
+		}
+		ns.view = &v
+		return nil
+	}
+	if len(meta.Indexes) > 0 {
+		if err := writeExtJSON(base+".indexes.json", bson.D{{Key: "indexes", Value: meta.Indexes}}); err != nil {
+			return err
+		}
+	}
+	if len(meta.Options) > 0 {
+		if err := writeExtJSON(base+".options.json", bson.D{{Key: "options", Value: meta.Options}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func viewOn(opts bson.D) string {
+	for _, e := range opts {
+		if e.Key == "viewOn" {
+			s, _ := e.Value.(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// remarshal 經由 BSON 把 bson.A 之類的值轉成 out 的型別
+func remarshal(v interface{}, out interface{}) error {
+	data, err := bson.Marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return err
+	}
+	return bson.Raw(data).Lookup("v").Unmarshal(out)
+}
+
+func writeExtJSON(path string, doc bson.D) error {
+	data, err := bson.MarshalExtJSON(doc, true, false)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// importArchive 還原 mongodump archive（檔案或 stdin，可以是 gzip）內的所有 collection：每個 collection 匯入原本的 database，
+// 依 metadata 建立選項與索引，view 在 collection 之後建立。Options.Collection 與 Include / Exclude 以 collection 名稱過濾
+func (i *Importer) importArchive(ctx context.Context, filePath string) []FileResult {
+	fail := func(err error) []FileResult {
+		i.log.Error(fmt.Sprintf("❌ Failed to read archive %s: %v", baseName(filePath), err), "file", filePath, errAttr(err))
+		return []FileResult{{File: filePath, Err: err}}
+	}
+	dir, err := os.MkdirTemp("", "mongo-tools-archive-")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, err := openInput(ctx, filePath, i.opts.DecryptKey)
+	if err != nil {
+		return fail(err)
+	}
+	header, spooled, err := spoolArchive(in, dir)
+	in.Close()
+	if err != nil {
+		return fail(err)
+	}
+	i.log.Info(fmt.Sprintf("📦 Read %d namespaces from %s (mongodump %s, server %s)", len(spooled), baseName(filePath), header.ToolVersion, header.ServerVersion),
+		"file", filePath, "namespaces", len(spooled), "tool_version", header.ToolVersion, "server_version", header.ServerVersion)
+
+	var files []string
+	var views []View
+	namespaces := map[string]*spooledNamespace{}
+	for _, ns := range spooled {
+		if i.opts.Collection != "" && ns.Collection != i.opts.Collection {
+			continue
+		}
+		if !i.selected(ns.Collection) {
+			i.log.Info(fmt.Sprintf("⏭️  Skipping %s of %s: excluded by the collection filters", ns.namespace(), baseName(filePath)),
+				"file", filePath, "collection", ns.namespace())
+			continue
+		}
+		if ns.view != nil {
+			views = append(views, *ns.view)
+			continue
+		}
+		i.subdirs.Store(ns.file, ns.DB)
+		namespaces[ns.file] = ns
+		files = append(files, ns.file)
+	}
+	if len(files) == 0 && len(views) == 0 {
+		res := FileResult{File: filePath, Skipped: true}
+		res.warn(i.log, fmt.Sprintf("⚠️  No collections to import in %s", baseName(filePath)), "file", filePath)
+		return []FileResult{res}
+	}
+	if i.dates != nil {
+		if err := i.prepareDateShift(ctx, files); err != nil {
+			return fail(err)
+		}
+	}
+
+	results := runWorkers(ctx, files, i.opts.Concurrency, i.opts.FailFast, func(file string) FileResult {
+		sub := *i
+		sub.opts.Collection = namespaces[file].Collection
+		res, _ := sub.ImportFile(ctx, file)
+		return res
+	})
+	for n := range results {
+		ns := namespaces[files[n]]
+		results[n].File = filePath
+		results[n].DB, results[n].Collection = ns.DB, i.targetName(ns.Collection)
+	}
+
+	for _, v := range views {
+		if ctx.Err() != nil || (i.opts.FailFast && failedAny(results)) {
+			break
+		}
+		res := FileResult{File: filePath, DB: v.DB, Collection: i.targetName(v.Name)}
+		started := time.Now()
+		if err := i.createView(ctx, v); err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to create view %s.%s: %v", v.DB, v.Name, err), "view", v.Name, "source", v.Source, errAttr(err))
+			res.Err = err
+		}
+		res.Duration = time.Since(started)
+		results = append(results, res)
+	}
+	return results
+}
+
+// archiveTargets 只讀 prelude，列出 archive 內會匯入的 collection（不含 view）
+func (i *Importer) archiveTargets(ctx context.Context, filePath string) ([]Target, error) {
+	in, err := openInput(ctx, filePath, i.opts.DecryptKey)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	a, closer, err := newArchiveReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+	_, colls, err := a.readPrelude()
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	for _, c := range colls {
+		if c.Type == "view" || (i.opts.Collection != "" && c.Collection != i.opts.Collection) || !i.selected(c.Collection) {
+			continue
+		}
+		targets = append(targets, Target{File: filePath, DB: c.DB, Collection: i.targetName(c.Collection), Strategy: i.opts.strategyFor(c.Collection)})
+	}
+	return targets, nil
+}
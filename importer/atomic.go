@@ -0,0 +1,163 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// stagingSuffix 不支援 transaction 時先載入到 <collection>.__staging 再 rename
+const stagingSuffix = ".__staging"
+
+// writeAtomically 讓整個檔案的「清空 + 插入」要嘛全部生效、要嘛都不生效：
+// 支援 transaction 時包在單一 transaction 內，否則改用暫存 collection + rename
+func (i *Importer) writeAtomically(ctx context.Context, collection *mongo.Collection, docs docReader, prog *progress, res *FileResult) error {
+	if i.targetServer.Transactions() {
+		return i.writeInTransaction(ctx, collection, docs, prog, res)
+	}
+	if res.strategy != StrategyTruncate {
+		res.warn(i.log, fmt.Sprintf("⚠️  Transactions are not supported by the server; %s strategy on %s is not atomic", res.strategy, collection.Name()),
+			"collection", collection.Name(), "strategy", res.strategy)
+		return i.writeDocuments(ctx, collection, docs, prog, res)
+	}
+	if len(i.opts.Scope) > 0 {
+		res.warn(i.log, fmt.Sprintf("⚠️  Transactions are not supported by the server; the scoped refresh of %s is not atomic", collection.Name()),
+			"collection", collection.Name())
+		return i.writeDocuments(ctx, collection, docs, prog, res)
+	}
+	return i.writeViaStaging(ctx, collection, docs, prog, res)
+}
+
+// writeInTransaction 不使用 WithTransaction：docs 是串流只能讀一次，整個 transaction 無法重跑
+func (i *Importer) writeInTransaction(ctx context.Context, collection *mongo.Collection, docs docReader, prog *progress, res *FileResult) error {
+	session, err := collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	if err := session.StartTransaction(); err != nil {
+		return err
+	}
+	sc := mongo.NewSessionContext(ctx, session)
+
+	// transaction 內單一操作失敗後不能重試，只能整個 abort；同一個 session 也不能同時執行多個操作
+	tx := *i
+	tx.opts.Retry.Attempts = 1
+	tx.opts.InsertWorkers = 1
+	if err := tx.writeDocuments(sc, collection, docs, prog, res); err != nil {
+		res.Docs = 0
+		actx, cancel := cleanupContext(ctx)
+		defer cancel()
+		if aerr := session.AbortTransaction(actx); aerr != nil {
+			i.log.Warn(fmt.Sprintf("⚠️  Failed to abort transaction on %s: %v", collection.Name(), aerr),
+				"collection", collection.Name(), errAttr(aerr))
+		}
+		return err
+	}
+	if err := session.CommitTransaction(ctx); err != nil {
+		i.log.Error(fmt.Sprintf("❌ Failed to commit transaction on %s: %v", collection.Name(), err),
+			"collection", collection.Name(), errAttr(err))
+		res.Docs = 0
+		return err
+	}
+	i.log.Info(fmt.Sprintf("🔒 Committed transaction on %s", collection.Name()), "collection", collection.Name(), "count", res.Docs)
+	return nil
+}
+
+// writeViaStaging 先把資料寫進暫存 collection，建好目標原有的索引與 sidecar 索引，
+// 最後 rename 蓋過目標（dropTarget），讀取端不會看到載入到一半的 collection
+func (i *Importer) writeViaStaging(ctx context.Context, collection *mongo.Collection, docs docReader, prog *progress, res *FileResult) error {
+	db := collection.Database()
+	staging := db.Collection(collection.Name() + stagingSuffix)
+	// 失敗或中斷時刪掉載入到一半的暫存 collection
+	dropStaging := func() {
+		ctx, cancel := cleanupContext(ctx)
+		defer cancel()
+		staging.Drop(ctx)
+	}
+
+	if err := staging.Drop(ctx); err != nil {
+		return fmt.Errorf("failed to drop staging collection %s: %v", staging.Name(), err)
+	}
+	if res.createOptions != nil {
+		if err := createCollection(ctx, staging, res.createOptions); err != nil {
+			return err
+		}
+	}
+	if err := i.writeDocuments(ctx, staging, docs, prog, res); err != nil {
+		dropStaging()
+		return err
+	}
+	if err := copyIndexes(ctx, collection, staging); err != nil {
+		dropStaging()
+		return fmt.Errorf("failed to copy indexes to %s: %v", staging.Name(), err)
+	}
+	if err := i.applyIndexSidecar(ctx, staging, res.File); err != nil {
+		dropStaging()
+		return fmt.Errorf("failed to create indexes on %s: %v", staging.Name(), err)
+	}
+	if err := renameCollection(ctx, staging, collection.Name()); err != nil {
+		dropStaging()
+		return err
+	}
+	res.staged = true
+	i.log.Info(fmt.Sprintf("🔀 Renamed %s → %s", staging.Name(), collection.Name()),
+		"collection", collection.Name(), "staging", staging.Name())
+	return nil
+}
+
+// copyIndexes 把 from 現有的索引（_id 以外）建到 to 上；from 不存在時什麼都不做
+func copyIndexes(ctx context.Context, from, to *mongo.Collection) error {
+	specs, err := listIndexSpecs(ctx, from)
+	if err != nil || len(specs) == 0 {
+		return err
+	}
+	return createIndexes(ctx, to, specs)
+}
+
+// listIndexSpecs 回傳 coll 現有索引（_id 以外）的 spec；coll 不存在時回傳 nil
+func listIndexSpecs(ctx context.Context, coll *mongo.Collection) ([]bson.D, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		// NamespaceNotFound：collection 還不存在
+		var se mongo.ServerError
+		if errors.As(err, &se) && se.HasErrorCode(26) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var specs []bson.D
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+
+	var out []bson.D
+	for _, spec := range specs {
+		spec, err := normalizeIndexSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		if spec != nil {
+			out = append(out, spec)
+		}
+	}
+	return out, nil
+}
+
+// renameCollection 用 admin 的 renameCollection 指令原子地取代目標 collection
+func renameCollection(ctx context.Context, from *mongo.Collection, to string) error {
+	db := from.Database()
+	cmd := bson.D{
+		{Key: "renameCollection", Value: db.Name() + "." + from.Name()},
+		{Key: "to", Value: db.Name() + "." + to},
+		{Key: "dropTarget", Value: true},
+	}
+	if err := db.Client().Database("admin").RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("failed to rename %s to %s: %v", from.Name(), to, err)
+	}
+	return nil
+}
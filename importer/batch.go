@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const DefaultBatchSize = 1000
+
+// forEachBatch 從 r 逐筆讀取，每湊滿 size 筆呼叫一次 fn 並回報進度；
+// workers <= 1 時同一時間只有一批文件在記憶體中。workers > 1 時最多 workers 批同時呼叫 fn，
+// 完成的順序不固定，fn 自己負責保護共用的狀態；任一批失敗後不再派發新的批次
+func forEachBatch(r docReader, size, workers int, prog *progress, fn func(batch []interface{}) error) error {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	next := nextFunc(r)
+	if workers > 1 {
+		return forEachBatchParallel(next, size, workers, prog, fn)
+	}
+
+	batch := make([]interface{}, 0, size)
+	n := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n++
+		if err := fn(batch); err != nil {
+			return fmt.Errorf("batch %d: %v", n, err)
+		}
+		prog.batch(len(batch))
+		batch = make([]interface{}, 0, size)
+		return nil
+	}
+
+	for {
+		doc, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, doc)
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// nextFunc PreserveOrder 時改寫入 orderedReader 排好順序的 bson.D
+func nextFunc(r docReader) func() (interface{}, error) {
+	if o, ok := r.(*orderedReader); ok {
+		return func() (interface{}, error) { return o.NextOrdered() }
+	}
+	return func() (interface{}, error) { return r.Next() }
+}
+
+// prefetchReader 先讀出前幾筆文件，之後照原本的順序交出；truncate 在清空 collection 前確認檔案開頭可以解析
+type prefetchReader struct {
+	docReader
+	buf []bson.M
+	err error // 預讀時遇到的錯誤（包括 io.EOF），交出 buf 之後回傳
+}
+
+// prefetch 從 r 預讀最多 n 筆，complete 表示整個檔案都已讀完；r 是 orderedReader 時預讀它底下的 reader，欄位順序照常還原
+func prefetch(r docReader, n int) (_ docReader, complete bool, err error) {
+	p := &prefetchReader{}
+	o, ordered := r.(*orderedReader)
+	if ordered {
+		p.docReader = o.docReader
+	} else {
+		p.docReader = r
+	}
+	for len(p.buf) < n {
+		doc, err := p.docReader.Next()
+		if err != nil {
+			p.err = err
+			break
+		}
+		p.buf = append(p.buf, doc)
+	}
+	if p.err != nil && p.err != io.EOF {
+		return nil, false, p.err
+	}
+	if ordered {
+		o.docReader = p
+		return o, p.err == io.EOF, nil
+	}
+	return p, p.err == io.EOF, nil
+}
+
+func (p *prefetchReader) Next() (bson.M, error) {
+	if len(p.buf) > 0 {
+		doc := p.buf[0]
+		p.buf = p.buf[1:]
+		return doc, nil
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.docReader.Next()
+}
+
+// forEachBatchParallel 由呼叫端的 goroutine 解析文件，workers 個 goroutine 寫入
+func forEachBatchParallel(next func() (interface{}, error), size, workers int, prog *progress, fn func(batch []interface{}) error) error {
+	type job struct {
+		n     int
+		batch []interface{}
+	}
+	jobs := make(chan job, workers)
+	stop := make(chan struct{})
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			close(stop)
+		}
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := fn(j.batch); err != nil {
+					fail(fmt.Errorf("batch %d: %v", j.n, err))
+					continue
+				}
+				mu.Lock()
+				prog.batch(len(j.batch))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	send := func(j job) bool {
+		select {
+		case jobs <- j:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	var readErr error
+	batch := make([]interface{}, 0, size)
+	n := 0
+	for {
+		doc, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+		batch = append(batch, doc)
+		if len(batch) == size {
+			n++
+			if !send(job{n, batch}) {
+				break
+			}
+			batch = make([]interface{}, 0, size)
+		}
+	}
+	if readErr == nil && len(batch) > 0 {
+		n++
+		send(job{n, batch})
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return readErr
+}
+
+// duplicateKeyErrors err 只包含 duplicate key（E11000）錯誤時回傳失敗的筆數，否則回傳 0；
+// unordered InsertMany 遇到重複的文件時其他文件仍會寫入
+func duplicateKeyErrors(err error) int {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return 0
+	}
+	for _, we := range bwe.WriteErrors {
+		if we.Code != 11000 {
+			return 0
+		}
+	}
+	return len(bwe.WriteErrors)
+}
+
+// assignIDs 替批次內沒有 _id 的文件補上 ObjectID，重試時送出的 _id 與第一次相同
+func assignIDs(batch []interface{}) {
+	for n, doc := range batch {
+		switch d := doc.(type) {
+		case bson.M:
+			if _, ok := d["_id"]; !ok {
+				d["_id"] = primitive.NewObjectID()
+			}
+		case bson.D:
+			if !hasKey(d, "_id") {
+				batch[n] = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, d...)
+			}
+		}
+	}
+}
+
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// markSent 記下一次失敗的嘗試可能已經寫入的文件：錯誤明確列出的文件沒有寫入，其餘的（包括網路錯誤時整批）都可能已經寫入
+func markSent(sent map[int]bool, n int, err error) {
+	if err == nil {
+		return
+	}
+	failed := map[int]bool{}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, we := range bwe.WriteErrors {
+			failed[we.Index] = true
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !failed[i] {
+			sent[i] = true
+		}
+	}
+}
+
+// dropRetriedDuplicates 重試時，之前的嘗試可能已經寫入的文件（見 markSent）遇到 duplicate key 表示已經寫入，不算錯誤；
+// _id 來自檔案或由 assignIDs 補上都一樣。其餘錯誤照常回傳
+func dropRetriedDuplicates(err error, sent map[int]bool) error {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || bwe.WriteConcernError != nil {
+		return err
+	}
+	var rest []mongo.BulkWriteError
+	for _, we := range bwe.WriteErrors {
+		if we.Code != 11000 || !sent[we.Index] {
+			rest = append(rest, we)
+		}
+	}
+	if len(rest) == 0 {
+		return nil
+	}
+	bwe.WriteErrors = rest
+	return bwe
+}
@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func duplicateAt(idx ...int) error {
+	var bwe mongo.BulkWriteException
+	for _, i := range idx {
+		bwe.WriteErrors = append(bwe.WriteErrors, mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Code: 11000}})
+	}
+	return bwe
+}
+
+// 只有之前的嘗試可能已經寫入的文件遇到 duplicate key 才不算錯誤
+func TestDropRetriedDuplicates(t *testing.T) {
+	sent := map[int]bool{}
+	markSent(sent, 3, duplicateAt(1))
+	if !sent[0] || sent[1] || !sent[2] {
+		t.Fatalf("sent = %v, want 0 and 2", sent)
+	}
+
+	err := dropRetriedDuplicates(duplicateAt(0, 1), sent)
+	bwe, ok := err.(mongo.BulkWriteException)
+	if !ok || len(bwe.WriteErrors) != 1 || bwe.WriteErrors[0].Index != 1 {
+		t.Fatalf("got %v, want only the duplicate at index 1", err)
+	}
+	if err := dropRetriedDuplicates(duplicateAt(0, 2), sent); err != nil {
+		t.Fatalf("got %v for documents sent before", err)
+	}
+}
+
+// 檔案內明確指定 _id 的文件：第一次 insert 可能已經寫入但回應遺失，重試時的 duplicate key 不算錯誤
+func TestRetryToleratesExplicitIDs(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock).ClientOptions(options.Client().SetRetryWrites(false)))
+	mt.Run("explicit ids", func(mt *mtest.T) {
+		file := filepath.Join(mt.TempDir(), "users.json")
+		if err := os.WriteFile(file, []byte(`[{"_id": 1, "name": "a"}, {"_id": 2, "name": "b"}]`), 0o644); err != nil {
+			mt.Fatal(err)
+		}
+		imp, err := New(context.Background(), mt.Client, Options{
+			DB:       "shop",
+			Strategy: StrategyAppend,
+			Retry:    RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+			Server:   &ServerInfo{Version: "7.0.0", WireVersion: 21, Topology: TopologyStandalone},
+		})
+		if err != nil {
+			mt.Fatal(err)
+		}
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 0}, {Key: "code", Value: 91}, {Key: "errmsg", Value: "shutting down"},
+				{Key: "errorLabels", Value: bson.A{"RetryableWriteError"}}},
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "writeErrors", Value: bson.A{
+				bson.D{{Key: "index", Value: 0}, {Key: "code", Value: 11000}, {Key: "errmsg", Value: "E11000 duplicate key error"}},
+			}}),
+		)
+		res, err := imp.ImportFile(context.Background(), file)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		inserts := 0
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "insert" {
+				inserts++
+			}
+		}
+		if inserts != 2 {
+			mt.Errorf("sent %d inserts, want 2", inserts)
+		}
+		if res.Docs != 2 {
+			mt.Errorf("imported %d documents, want 2", res.Docs)
+		}
+	})
+}
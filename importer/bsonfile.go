@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxBSONDocSize mongodump 的單筆文件上限（16MB）再加上一點 overhead
+const maxBSONDocSize = 16*1024*1024 + 16*1024
+
+// bsonReader 讀取 mongodump 產生的 .bson：一筆接一筆、以 int32 長度開頭的 BSON 文件
+type bsonReader struct {
+	r      *bufio.Reader
+	strict strictness
+	order  *orderTracker // 不為 nil 時先解析成 bson.D 記下欄位順序
+	index  int
+}
+
+func newBSONReader(r io.Reader, strict strictness, order *orderTracker) *bsonReader {
+	return &bsonReader{r: bufio.NewReader(r), strict: strict, order: order}
+}
+
+func (b *bsonReader) Next() (bson.M, error) {
+	raw, err := b.nextRaw()
+	if err != nil {
+		return nil, err
+	}
+	if b.order != nil || b.strict.enabled() {
+		var d bson.D
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, &parseError{Pos: fmt.Sprintf("document %d", b.index), Err: err}
+		}
+		m, err := b.strict.finish(d, b.order)
+		if err != nil {
+			return nil, &parseError{Pos: fmt.Sprintf("document %d", b.index), Err: err}
+		}
+		return m, nil
+	}
+	var m bson.M
+	if err := bson.Unmarshal(raw, &m); err != nil {
+		return nil, &parseError{Pos: fmt.Sprintf("document %d", b.index), Err: err}
+	}
+	return m, nil
+}
+
+func (b *bsonReader) nextRaw() (bson.Raw, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(b.r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read BSON document %d: %v", b.index+1, err)
+	}
+	b.index++
+
+	size := int32(binary.LittleEndian.Uint32(header[:]))
+	if size < 5 || size > maxBSONDocSize {
+		return nil, fmt.Errorf("invalid BSON document %d: size %d", b.index, size)
+	}
+	raw := make([]byte, size)
+	copy(raw, header[:])
+	if _, err := io.ReadFull(b.r, raw[4:]); err != nil {
+		return nil, fmt.Errorf("failed to read BSON document %d: %v", b.index, err)
+	}
+	return raw, nil
+}
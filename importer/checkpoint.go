@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// checkpointCollection Resume 時每批寫入後記錄進度，放在目標 database；檔案匯入成功後刪除
+const checkpointCollection = "_import_checkpoints"
+
+// checkpointDoc _import_checkpoints 的文件；Fingerprint 不同（檔案被換掉）時不接續
+type checkpointDoc struct {
+	ID          metaKey            `bson:"_id"`
+	Fingerprint string             `bson:"fingerprint"`
+	Settings    string             `bson:"settings"`
+	Docs        int                `bson:"docs"`    // 已寫入的文件數（經過轉換、filter、略過無效文件之後）
+	Batches     int                `bson:"batches"` // 已寫入的批次數
+	IDSeed      primitive.ObjectID `bson:"idSeed"`  // 沒有 _id 的文件依序號由此產生 _id，接續時重寫的文件 _id 相同
+	UpdatedAt   time.Time          `bson:"updatedAt"`
+}
+
+// checkpoint 單一檔案匯入時的進度記錄
+type checkpoint struct {
+	coll    *mongo.Collection
+	doc     checkpointDoc
+	resumed int  // 這次從第幾筆文件接續，0 表示從頭開始
+	pending bool // 接續後的第一批可能在中斷前已經寫入
+}
+
+// insertsIDs 只有 append / truncate 以 InsertMany 寫入，需要預先指定 _id；upsert 等以 key 欄位比對，補上的 _id 會與既有文件衝突
+func insertsIDs(strategy string) bool {
+	return strategy == StrategyAppend || strategy == StrategyTruncate
+}
+
+// fileFingerprint 以大小與修改時間辨識檔案；多 GB 的檔案不值得為此先完整讀一次
+func fileFingerprint(filePath string) (string, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d", fi.Size(), fi.ModTime().UnixNano()), nil
+}
+
+// startCheckpoint 讀取上次中斷時的進度；stdin 與 URL 來源無法辨識是否為同一個檔案，不記錄進度
+func (i *Importer) startCheckpoint(ctx context.Context, collection *mongo.Collection, filePath string, res *FileResult) (*checkpoint, error) {
+	if filePath == Stdin || remote.IsURL(filePath) {
+		return nil, nil
+	}
+	fp, err := fileFingerprint(filePath)
+	if err != nil {
+		return nil, err
+	}
+	cp := &checkpoint{
+		coll: collection.Database().Collection(checkpointCollection),
+		doc: checkpointDoc{
+			ID:          metaKey{collection.Name(), baseName(filePath)},
+			Fingerprint: fp,
+			Settings:    i.settings,
+			IDSeed:      primitive.NewObjectID(),
+		},
+	}
+
+	var prev checkpointDoc
+	err = cp.coll.FindOne(ctx, bson.M{"_id": cp.doc.ID}).Decode(&prev)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		// 第一批寫入前先記下 IDSeed，第一批之後就中斷也能接續
+		return cp, cp.write(ctx)
+	case err != nil:
+		return nil, err
+	case prev.Fingerprint != fp || prev.Settings != i.settings:
+		res.warn(i.log, fmt.Sprintf("⚠️  %s changed since the last checkpoint; starting over", baseName(filePath)), "file", filePath)
+		return cp, cp.write(ctx)
+	}
+	// 沿用上次的 IDSeed：中斷前已寫入但還沒記錄進度的文件，重寫時 _id 相同而以 duplicate key 略過
+	if !prev.IDSeed.IsZero() {
+		cp.doc.IDSeed = prev.IDSeed
+	}
+	cp.pending = true
+	if prev.Docs > 0 {
+		cp.doc.Docs, cp.doc.Batches = prev.Docs, prev.Batches
+		cp.resumed = prev.Docs
+		i.log.Info(fmt.Sprintf("⏯️  Resuming %s after %d docs (%d batches)", baseName(filePath), prev.Docs, prev.Batches),
+			"file", filePath, "collection", collection.Name(), "count", prev.Docs)
+	}
+	return cp, cp.write(ctx)
+}
+
+// save 每批寫入成功後更新進度
+func (c *checkpoint) save(ctx context.Context, docs int) error {
+	c.doc.Docs = c.resumed + docs
+	c.doc.Batches++
+	return c.write(ctx)
+}
+
+func (c *checkpoint) write(ctx context.Context) error {
+	c.doc.UpdatedAt = time.Now().UTC()
+	_, err := c.coll.ReplaceOne(ctx, bson.M{"_id": c.doc.ID}, c.doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// idReader 替沒有 _id 的文件補上由 IDSeed 與文件序號決定的 ObjectID；放在 skipDocsReader 之內，略過的文件也計入序號
+type idReader struct {
+	docReader
+	seed primitive.ObjectID
+	n    uint64
+}
+
+func (r *idReader) Next() (bson.M, error) {
+	doc, err := r.docReader.Next()
+	if err != nil {
+		return nil, err
+	}
+	r.n++
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = seededID(r.seed, r.n)
+	}
+	return doc, nil
+}
+
+// seededID seed 的後 8 個位元組加上 n；時間戳記沿用 seed
+func seededID(seed primitive.ObjectID, n uint64) primitive.ObjectID {
+	id := seed
+	binary.BigEndian.PutUint64(id[4:], binary.BigEndian.Uint64(seed[4:])+n)
+	return id
+}
+
+// clear 檔案完整匯入後刪除進度，下次從頭開始
+func (c *checkpoint) clear(ctx context.Context) error {
+	_, err := c.coll.DeleteOne(ctx, bson.M{"_id": c.doc.ID})
+	return err
+}
+
+// skipDocsReader 丟掉前 n 筆文件；檔案仍然要從頭解析，但不會重新寫入
+type skipDocsReader struct {
+	docReader
+	n int
+}
+
+func (s *skipDocsReader) Next() (bson.M, error) {
+	for s.n > 0 {
+		if _, err := s.docReader.Next(); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("file has fewer documents than the checkpoint")
+			}
+			return nil, err
+		}
+		s.n--
+	}
+	return s.docReader.Next()
+}
@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/crypt"
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// metaCollection 記錄每個檔案最後一次成功匯入時的 checksum，放在目標 database
+const metaCollection = "_import_meta"
+
+// importMeta _import_meta 的文件；_id 為 collection + 檔名
+type importMeta struct {
+	ID         metaKey   `bson:"_id"`
+	SHA256     string    `bson:"sha256"`
+	Settings   string    `bson:"settings"`
+	Docs       int       `bson:"docs"`
+	ImportedAt time.Time `bson:"importedAt"`
+}
+
+type metaKey struct {
+	Collection string `bson:"collection"`
+	File       string `bson:"file"`
+}
+
+// fileChecksum 計算檔案（壓縮檔不解壓）的 SHA-256；加密的 SQL 暫存檔每次的 nonce 都不同，改以 key 解密後的內容計算
+func fileChecksum(filePath string, key []byte) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(filePath, sqlSpoolExt+crypt.Ext) {
+		if r, err = crypt.NewReader(f, key); err != nil {
+			return "", err
+		}
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// settingsChecksum 會影響寫入結果的設定；改了轉換規則或 filter 之後即使檔案沒變也要重新匯入
+func settingsChecksum(opts Options) string {
+	var tenant bson.M
+	if opts.TenantField != "" {
+		tenant = bson.M{opts.TenantField: opts.TenantValue}
+	}
+	data, _ := json.Marshal(struct {
+		Strategy      string
+		Strategies    []StrategyRule `json:",omitempty"`
+		KeyField      string
+		MergeUpdate   bool
+		Transforms    []Transform
+		Filter        bson.M
+		Mask          *MaskConfig
+		CSV           CSVOptions
+		PreserveOrder bool
+		Templates     bool       `json:",omitempty"` // 沒有使用時不改變既有的 checksum
+		DateShift     *DateShift `json:",omitempty"`
+		DedupeBy      string     `json:",omitempty"`
+		DedupeKeep    string     `json:",omitempty"`
+		Stamp         []string   `json:",omitempty"`
+		Scope         bson.M     `json:",omitempty"`
+		Tenant        bson.M     `json:",omitempty"`
+		OnlyFields    []string   `json:",omitempty"`
+		DropFields    []string   `json:",omitempty"`
+		Coerce        *Coercion  `json:",omitempty"`
+		Duplicates    bool       `json:",omitempty"`
+		NonFinite     string     `json:",omitempty"`
+	}{opts.Strategy, opts.Strategies, opts.KeyField, opts.MergeUpdate, opts.Transforms, opts.Filter, opts.Mask, opts.CSV, opts.PreserveOrder, opts.Templates, opts.DateShift, opts.DedupeBy, dedupeKeep(opts), opts.StampFields(), opts.Scope, tenant, opts.OnlyFields, opts.DropFields, opts.Coerce, opts.RejectDuplicateFields, nonFinite(opts)})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkUnchanged 檔案與設定都和上次成功匯入時相同就回傳 true，同時回傳這次的 checksum 供匯入後記錄；
+// stdin 與 URL 來源無法事先計算，一律匯入
+func (i *Importer) checkUnchanged(ctx context.Context, db *mongo.Database, coll, filePath string) (bool, string, error) {
+	if src := i.sourceOf(filePath); src == Stdin || remote.IsURL(src) {
+		return false, "", nil
+	}
+	sum, err := fileChecksum(filePath, i.opts.DecryptKey)
+	if err != nil {
+		return false, "", err
+	}
+	if i.opts.Force {
+		return false, sum, nil
+	}
+
+	var meta importMeta
+	err = db.Collection(metaCollection).FindOne(ctx, bson.M{"_id": metaKey{coll, baseName(i.sourceOf(filePath))}}).Decode(&meta)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, sum, nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return meta.SHA256 == sum && meta.Settings == i.settings, sum, nil
+}
+
+// recordChecksum 匯入成功後更新 _import_meta
+func (i *Importer) recordChecksum(ctx context.Context, db *mongo.Database, coll, filePath, sum string, docs int) error {
+	meta := importMeta{
+		ID:         metaKey{coll, baseName(i.sourceOf(filePath))},
+		SHA256:     sum,
+		Settings:   i.settings,
+		Docs:       docs,
+		ImportedAt: time.Now().UTC(),
+	}
+	return i.withRetry(ctx, "record checksum of "+coll, func(ctx context.Context) error {
+		_, err := db.Collection(metaCollection).ReplaceOne(ctx, bson.M{"_id": meta.ID}, meta, options.Replace().SetUpsert(true))
+		return err
+	})
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 型別修正規則，給非 MongoDB 工具產生、型別都變成字串的 dump 使用
+const (
+	CoerceObjectID = "objectid" // 名稱符合 Coercion.IDFields 的欄位中，24 個 hex 字元的字串 → ObjectID
+	CoerceDate     = "date"     // ISO-8601 日期時間字串（必須有時間）→ date
+	CoerceNumber   = "number"   // 數字字串 → int（超過範圍時 long）或 double；有前導零的字串（郵遞區號等）不轉換
+)
+
+// DefaultCoerceIDFields objectid 規則預設比對的欄位名稱
+var DefaultCoerceIDFields = []string{"_id", "*Id"}
+
+// Coercion 解析後、寫入前修正的字串型別，見 ParseCoercion
+type Coercion struct {
+	ObjectID bool
+	Date     bool
+	Number   bool
+	IDFields []string // objectid 規則比對的欄位名稱 glob（只比對最後一段，例如 userId、author.userId 都符合 *Id）
+}
+
+// ParseCoercion 解析以逗號分隔的規則（objectid、date、number）；idFields 為空時使用 DefaultCoerceIDFields；
+// rules 為空時回傳 nil
+func ParseCoercion(rules, idFields string) (*Coercion, error) {
+	var c Coercion
+	for _, r := range strings.Split(rules, ",") {
+		switch strings.ToLower(strings.TrimSpace(r)) {
+		case "":
+		case CoerceObjectID:
+			c.ObjectID = true
+		case CoerceDate:
+			c.Date = true
+		case CoerceNumber:
+			c.Number = true
+		default:
+			return nil, fmt.Errorf("unknown coercion %q (expected objectid, date or number)", strings.TrimSpace(r))
+		}
+	}
+	if !c.ObjectID && !c.Date && !c.Number {
+		if strings.TrimSpace(idFields) != "" {
+			return nil, fmt.Errorf("id fields require the objectid coercion")
+		}
+		return nil, nil
+	}
+	patterns, err := ParsePatterns(idFields)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		patterns = DefaultCoerceIDFields
+	}
+	c.IDFields = patterns
+	return &c, nil
+}
+
+var (
+	objectIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{24}$`)
+	// 負號可有可無，整數部分沒有前導零，小數部分可有可無；1e5、.5、+1 之類的寫法不轉換
+	numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+	// 一定要有日期與時間，只有日期的 2024-01-02 可能只是字串
+	isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?$`)
+)
+
+// isoDateLayouts isoDatePattern 接受的格式；沒有時區時視為 UTC
+var isoDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04Z0700",
+	"2006-01-02T15:04",
+}
+
+// coerce 就地修改 doc，回傳轉換的值數量
+func (c *Coercion) coerce(doc bson.M) int {
+	n := 0
+	for k, v := range doc {
+		if out, changed := c.value(k, v, &n); changed {
+			doc[k] = out
+		}
+	}
+	return n
+}
+
+// value 回傳轉換後的值；name 是值所在欄位的名稱，陣列元素沿用陣列的欄位名稱
+func (c *Coercion) value(name string, v interface{}, n *int) (interface{}, bool) {
+	switch x := v.(type) {
+	case bson.M:
+		*n += c.coerce(x)
+	case bson.A:
+		for i, e := range x {
+			if out, changed := c.value(name, e, n); changed {
+				x[i] = out
+			}
+		}
+	case []interface{}:
+		for i, e := range x {
+			if out, changed := c.value(name, e, n); changed {
+				x[i] = out
+			}
+		}
+	case string:
+		if out, ok := c.convert(name, x); ok {
+			*n++
+			return out, true
+		}
+	}
+	return v, false
+}
+
+func (c *Coercion) convert(name, s string) (interface{}, bool) {
+	if c.ObjectID && objectIDPattern.MatchString(s) && c.isIDField(name) {
+		if id, err := primitive.ObjectIDFromHex(s); err == nil {
+			return id, true
+		}
+	}
+	if c.Date && isoDatePattern.MatchString(s) {
+		for _, layout := range isoDateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return primitive.NewDateTimeFromTime(t), true
+			}
+		}
+	}
+	if c.Number && numberPattern.MatchString(s) {
+		if !strings.Contains(s, ".") {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				if i >= math.MinInt32 && i <= math.MaxInt32 {
+					return int32(i), true
+				}
+				return i, true
+			}
+			// 超過 int64 的整數保留字串，轉成 double 會失去精確度
+			return nil, false
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func (c *Coercion) isIDField(name string) bool {
+	for _, p := range c.IDFields {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// coerceReader 對每筆文件套用 Options.Coerce，並計算轉換的值數量
+type coerceReader struct {
+	docReader
+	coercion *Coercion
+	coerced  int
+}
+
+func (c *coerceReader) Next() (bson.M, error) {
+	doc, err := c.docReader.Next()
+	if err == nil {
+		c.coerced += c.coercion.coerce(doc)
+	}
+	return doc, err
+}
@@ -0,0 +1,210 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// loadCollectionOptions 讀取 <collection>.options.json：create 指令的選項，例如
+// {"capped": true, "size": 1048576, "collation": {"locale": "en"}, "validator": {...}}；
+// 也接受 mongodump metadata.json 那種 {"options": {...}}。沒有 sidecar 檔（或從 stdin 讀取）時回傳 nil
+func loadCollectionOptions(ctx context.Context, filePath string) (bson.D, string, error) {
+	if filePath == Stdin {
+		return nil, "", nil
+	}
+	path := sidecarPath(filePath, ".options.json")
+	var data []byte
+	var err error
+	if remote.IsURL(path) {
+		data, err = remote.ReadFile(ctx, path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, path, err
+	}
+
+	var opts bson.D
+	if err := bson.UnmarshalExtJSON(bytes.TrimSpace(data), false, &opts); err != nil {
+		return nil, path, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if len(opts) == 1 && opts[0].Key == "options" {
+		inner, ok := opts[0].Value.(bson.D)
+		if !ok {
+			return nil, path, fmt.Errorf("%s: options must be a document", path)
+		}
+		opts = inner
+	}
+	out := make(bson.D, 0, len(opts))
+	for _, e := range opts {
+		switch e.Key {
+		case "create":
+			return nil, path, fmt.Errorf("%s: create is set from the target collection", path)
+		case "uuid": // mongodump metadata 內的 collection UUID，不能沿用
+			continue
+		}
+		out = append(out, e)
+	}
+	if err := checkTimeSeries(out); err != nil {
+		return nil, path, fmt.Errorf("%s: %v", path, err)
+	}
+	return out, path, nil
+}
+
+// timeSeriesOf 回傳 options 內的 timeseries 設定，例如 {"timeField": "ts", "metaField": "sensor", "granularity": "minutes"}；
+// 不是 time-series collection 時回傳 nil
+func timeSeriesOf(opts bson.D) bson.D {
+	for _, e := range opts {
+		if e.Key == "timeseries" {
+			d, _ := e.Value.(bson.D)
+			return d
+		}
+	}
+	return nil
+}
+
+// checkTimeSeries 先檢查 timeseries 設定，而不是等 create 指令失敗；timeField 必填，metaField 不能與 timeField 相同
+func checkTimeSeries(opts bson.D) error {
+	for _, e := range opts {
+		if e.Key == "timeseries" {
+			if _, ok := e.Value.(bson.D); !ok {
+				return errors.New("timeseries must be a document")
+			}
+		}
+	}
+	ts := timeSeriesOf(opts)
+	if ts == nil {
+		return nil
+	}
+	fields := ts.Map()
+	timeField, _ := fields["timeField"].(string)
+	if timeField == "" {
+		return errors.New("timeseries.timeField is required")
+	}
+	if v, ok := fields["metaField"]; ok {
+		if meta, _ := v.(string); meta == "" || meta == timeField {
+			return fmt.Errorf("timeseries.metaField must be a field name other than the timeField, got %v", v)
+		}
+	}
+	if v, ok := fields["granularity"]; ok {
+		switch v {
+		case "seconds", "minutes", "hours":
+		default:
+			return fmt.Errorf("invalid timeseries.granularity %v (expected seconds, minutes or hours)", v)
+		}
+	}
+	return nil
+}
+
+// createCollection 以 opts 明確建立 collection，而不是在第一次寫入時隱含建立
+func createCollection(ctx context.Context, coll *mongo.Collection, opts bson.D) error {
+	cmd := append(bson.D{{Key: "create", Value: coll.Name()}}, opts...)
+	if err := coll.Database().RunCommand(ctx, cmd).Err(); err != nil {
+		return fmt.Errorf("failed to create %s: %v", coll.Name(), err)
+	}
+	return nil
+}
+
+// collectionType collection、timeseries 或 view；不存在時回傳空字串
+func collectionType(ctx context.Context, coll *mongo.Collection) (string, error) {
+	specs, err := coll.Database().ListCollectionSpecifications(ctx, bson.M{"name": coll.Name()})
+	if err != nil || len(specs) == 0 {
+		return "", err
+	}
+	return specs[0].Type, nil
+}
+
+// prepareCollection 依 <collection>.options.json 建立 collection：不存在時直接建立；存在且要清空重來
+// （truncate，且不是 transaction / atomic swap / 接續中斷的匯入）時記下要重建，由 writeDocuments 在解析過第一批後
+// 以 recreateCollection 取代清空，檔案無法解析時舊資料還在。atomic swap 的暫存 collection 由 writeViaStaging 以相同選項建立
+func (i *Importer) prepareCollection(ctx context.Context, collection *mongo.Collection, filePath string, res *FileResult) error {
+	opts, path, err := loadCollectionOptions(ctx, filePath)
+	if err != nil || opts == nil {
+		return err
+	}
+	opts = i.degradeCreateOptions(opts, path, res)
+	res.createOptions = opts
+	coll := collection.Name()
+
+	ts := timeSeriesOf(opts)
+	if ts != nil && (i.opts.Transactional || i.opts.AtomicSwap) {
+		// time-series collection 不能在 transaction 內寫入，也不能 rename
+		return fmt.Errorf("%s is a time-series collection (%s) and cannot be imported transactionally or with an atomic swap", coll, path)
+	}
+	kind := "collection"
+	attrs := []any{"collection", coll, "file", path}
+	if ts != nil {
+		kind = "time-series collection"
+		fields := ts.Map()
+		for _, f := range [][2]string{{"timeField", "time_field"}, {"metaField", "meta_field"}, {"granularity", "granularity"}} {
+			if v, ok := fields[f[0]]; ok {
+				attrs = append(attrs, f[1], v)
+			}
+		}
+	}
+
+	existing, err := collectionType(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if existing == "" {
+		if err := createCollection(ctx, collection, opts); err != nil {
+			return err
+		}
+		i.log.Info(fmt.Sprintf("🧱 Created %s %s with options from %s", kind, coll, path), attrs...)
+		return nil
+	}
+
+	resumed := res.checkpoint != nil && res.checkpoint.resumed > 0
+	if res.strategy != StrategyTruncate || len(i.opts.Scope) > 0 || i.opts.Transactional || i.opts.AtomicSwap || resumed {
+		if ts != nil && existing != "timeseries" {
+			res.warn(i.log, fmt.Sprintf("⚠️  %s already exists as a regular collection; %s describes a time-series collection, which only takes effect when the truncate strategy recreates it", coll, path), attrs...)
+			return nil
+		}
+		i.log.Debug(fmt.Sprintf("%s already exists; keeping its options", coll), attrs...)
+		return nil
+	}
+
+	res.recreate = &recreation{path: path, kind: kind, attrs: attrs}
+	return nil
+}
+
+// recreation prepareCollection 決定重建時記下的內容，選項在 FileResult.createOptions
+type recreation struct {
+	path  string
+	kind  string
+	attrs []any
+}
+
+// recreateCollection drop 後以 options.json 的選項重建並補回原有的索引，選項（capped、collation…）才會生效
+func (i *Importer) recreateCollection(ctx context.Context, collection *mongo.Collection, res *FileResult) error {
+	r, coll := res.recreate, collection.Name()
+	specs, err := listIndexSpecs(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if err := collection.Drop(ctx); err != nil {
+		return fmt.Errorf("failed to drop %s: %v", coll, err)
+	}
+	if err := createCollection(ctx, collection, res.createOptions); err != nil {
+		return err
+	}
+	if len(specs) > 0 {
+		if err := createIndexes(ctx, collection, specs); err != nil {
+			return fmt.Errorf("failed to restore indexes on %s: %v", coll, err)
+		}
+	}
+	i.log.Info(fmt.Sprintf("🧱 Recreated %s %s with options from %s", r.kind, coll, r.path), append(r.attrs, "indexes", len(specs))...)
+	return nil
+}
@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// importWithOptions 在已經存在、有 users.options.json 的 users collection 上以 truncate 匯入 data，回傳送出的指令
+func importWithOptions(mt *mtest.T, data string) ([]string, error) {
+	dir := mt.TempDir()
+	file := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+		mt.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "users.options.json"), []byte(`{"capped": true, "size": 4096}`), 0o644); err != nil {
+		mt.Fatal(err)
+	}
+	imp, err := New(context.Background(), mt.Client, Options{
+		DB:     "shop",
+		Server: &ServerInfo{Version: "7.0.0", WireVersion: 21, Topology: TopologyStandalone},
+	})
+	if err != nil {
+		mt.Fatal(err)
+	}
+	// collection 已經存在；其餘的回應讓 listIndexes、drop、create 與 insert 在被呼叫時都會成功
+	mt.AddMockResponses(
+		mtest.CreateCursorResponse(0, "shop.$cmd.listCollections", mtest.FirstBatch,
+			bson.D{{Key: "name", Value: "users"}, {Key: "type", Value: "collection"}}),
+		mtest.CreateCursorResponse(0, "shop.users", mtest.FirstBatch,
+			bson.D{{Key: "v", Value: 2}, {Key: "key", Value: bson.D{{Key: "_id", Value: 1}}}, {Key: "name", Value: "_id_"}}),
+		mtest.CreateSuccessResponse(),
+		mtest.CreateSuccessResponse(),
+		mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+	)
+	_, err = imp.ImportFile(context.Background(), file)
+	var cmds []string
+	for _, e := range mt.GetAllStartedEvents() {
+		cmds = append(cmds, e.CommandName)
+	}
+	return cmds, err
+}
+
+// 有 options.json 的 collection 以 truncate 匯入無法解析的檔案時，不能在解析前就 drop 重建
+func TestRecreateKeepsCollectionOnParseError(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("unparseable", func(mt *mtest.T) {
+		cmds, err := importWithOptions(mt, `[{"_id": 1, "name": "a"}, {"_id": 2, "name": `)
+		if err == nil {
+			mt.Fatal("importing a truncated file succeeded")
+		}
+		for _, c := range cmds {
+			switch c {
+			case "drop", "create", "delete", "insert":
+				mt.Errorf("sent %s for a file that cannot be parsed (commands: %v)", c, cmds)
+			}
+		}
+	})
+	mt.Run("valid", func(mt *mtest.T) {
+		cmds, err := importWithOptions(mt, `[{"_id": 1, "name": "a"}, {"_id": 2, "name": "b"}]`)
+		if err != nil {
+			mt.Fatal(err)
+		}
+		if got := strings.Join(cmds, " "); got != "listCollections listIndexes drop create insert" {
+			mt.Errorf("sent %s, want listCollections listIndexes drop create insert", got)
+		}
+	})
+}
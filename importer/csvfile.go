@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CSVOptions CSV / TSV 的解析設定
+type CSVOptions struct {
+	Delimiter rune              // 0 表示依副檔名（.csv 用逗號、.tsv 用 tab）
+	Fields    map[string]string // 欄位型別提示，例如 age → int
+}
+
+var csvFieldTypes = []string{"auto", "string", "int", "long", "double", "decimal", "bool", "date", "objectid"}
+
+// ParseFieldHints 解析 --fields name:string,age:int,created:date
+func ParseFieldHints(spec string) (map[string]string, error) {
+	hints := map[string]string{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, typ, ok := strings.Cut(item, ":")
+		if !ok {
+			typ = "auto"
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		if !contains(csvFieldTypes, typ) {
+			return nil, fmt.Errorf("unknown type %q for field %s (expected one of %s)", typ, name, strings.Join(csvFieldTypes, ", "))
+		}
+		hints[strings.TrimSpace(name)] = typ
+	}
+	return hints, nil
+}
+
+// ParseDelimiter 接受單一字元，或 "tab" / "\t"
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character: %q", s)
+	}
+	return r[0], nil
+}
+
+// csvReader 第一列是欄位名稱，之後每列轉成一筆文件；空白欄位直接略過；
+// 欄位名稱含 "." 時會寫成子文件（address.city → {address: {city: ...}}）
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+	hints  map[string]string
+	strict strictness
+	line   int
+}
+
+func newCSVReader(r io.Reader, delimiter rune, opts CSVOptions, strict strictness) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = delimiter
+	if opts.Delimiter != 0 {
+		cr.Comma = opts.Delimiter
+	}
+	if cr.Comma == '\t' {
+		cr.LazyQuotes = true
+	}
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return &csvReader{r: cr}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	header = append([]string(nil), header...)
+	for i, h := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		if header[i] == "" {
+			return nil, fmt.Errorf("CSV header column %d is empty", i+1)
+		}
+		if strict.duplicates && contains(header[:i], header[i]) {
+			return nil, fmt.Errorf("CSV header column %d duplicates field %q", i+1, header[i])
+		}
+	}
+	return &csvReader{r: cr, header: header, hints: opts.Fields, strict: strict}, nil
+}
+
+func (c *csvReader) Next() (bson.M, error) {
+	if c.header == nil {
+		return nil, io.EOF
+	}
+	record, err := c.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	// 解析失敗時沒有欄位可以查位置，FieldPos 會 panic；行號取自 ParseError
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		c.line = pe.Line
+		return nil, &parseError{Pos: fmt.Sprintf("line %d", pe.Line), Raw: strings.Join(record, string(c.r.Comma)), Err: pe.Err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.line, _ = c.r.FieldPos(0)
+	pos := fmt.Sprintf("line %d", c.line)
+
+	doc := bson.M{}
+	for i, field := range record {
+		if field == "" {
+			continue
+		}
+		name := c.header[i]
+		v, err := convertCSVValue(field, c.hints[name])
+		if err != nil {
+			return nil, &parseError{Pos: pos, Raw: strings.Join(record, string(c.r.Comma)), Err: fmt.Errorf("field %s: %v", name, err)}
+		}
+		setPath(doc, name, v)
+	}
+	if c.strict.enabled() {
+		if err := c.strict.checkMap(doc); err != nil {
+			return nil, &parseError{Pos: pos, Raw: strings.Join(record, string(c.r.Comma)), Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// convertCSVValue 依型別提示轉換；沒有提示（auto）時推斷 int / double / bool，否則保留字串
+func convertCSVValue(s, typ string) (interface{}, error) {
+	switch typ {
+	case "string":
+		return s, nil
+	case "int":
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+		return int32(n), err
+	case "long":
+		return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case "double":
+		return strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "decimal":
+		return primitive.ParseDecimal128(strings.TrimSpace(s))
+	case "bool":
+		return strconv.ParseBool(strings.TrimSpace(s))
+	case "date":
+		t, err := parseDate(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		return primitive.NewDateTimeFromTime(t), nil
+	case "objectid":
+		return primitive.ObjectIDFromHex(strings.TrimSpace(s))
+	}
+
+	// auto
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if n >= math.MinInt32 && n <= math.MaxInt32 {
+			return int32(n), nil
+		}
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f, nil
+	}
+	if b, err := strconv.ParseBool(s); err == nil && (s == "true" || s == "false") {
+		return b, nil
+	}
+	return s, nil
+}
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseDate 支援 RFC3339 與常見的日期格式（無時區時視為 UTC）
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DateShift 把檔案內所有 date 平移同一段時間，讓資料集中最新的日期變成 Anchor、彼此的間隔不變，
+// 展示用的資料集因此永遠像是最近的資料。只平移檔案內原本就是 date 的值，{{NOW}} 與轉換產生的 date 不受影響
+type DateShift struct {
+	Anchor time.Time // 零值表示匯入開始的時間
+	Field  string    // 只以這個欄位（a.b 路徑）找最新的日期，避免 expiresAt: 2099 之類的值干擾；空字串表示所有 date
+}
+
+// ParseDateShift 解析 --shift-dates 的錨點：now，或 RFC 3339 / 2006-01-02 格式的時間；空字串回傳 nil
+func ParseDateShift(anchor, field string) (*DateShift, error) {
+	switch anchor {
+	case "":
+		return nil, nil
+	case "now":
+		return &DateShift{Field: field}, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, anchor); err == nil {
+			return &DateShift{Anchor: t, Field: field}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid anchor %q (expected now, an RFC 3339 time or 2006-01-02)", anchor)
+}
+
+// dateShifter 整次執行共用的平移量：目錄模式在開始匯入前掃過所有檔案計算一次，watch 重新匯入時沿用
+type dateShifter struct {
+	opts   DateShift
+	mu     sync.Mutex
+	ready  bool
+	offset time.Duration
+}
+
+// prepareDateShift 尚未計算平移量時，讀過 files 找出最新的日期
+func (i *Importer) prepareDateShift(ctx context.Context, files []string) error {
+	s := i.dates
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ready {
+		return nil
+	}
+
+	var newest time.Time
+	for _, file := range files {
+		if file == Stdin {
+			return errors.New("shifting dates needs to read the data twice and cannot be used with stdin")
+		}
+		t, err := i.newestDate(ctx, file)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s for dates: %v", file, err)
+		}
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	s.ready = true
+	if newest.IsZero() {
+		i.log.Warn("⚠️  No dates found; shifting dates has no effect")
+		return nil
+	}
+
+	anchor := s.opts.Anchor
+	if anchor.IsZero() {
+		anchor = i.started
+	}
+	s.offset = anchor.Sub(newest)
+	i.log.Info(fmt.Sprintf("🕒 Shifting dates by %s: the newest date %s becomes %s",
+		s.offset.Round(time.Second), newest.UTC().Format(time.RFC3339), anchor.UTC().Format(time.RFC3339)),
+		"offset", s.offset.Round(time.Second).String(), "newest", newest, "anchor", anchor)
+	return nil
+}
+
+// newestDate 檔案內（或 DateShift.Field 欄位中）最新的日期；沒有任何 date 時回傳零值
+func (i *Importer) newestDate(ctx context.Context, file string) (time.Time, error) {
+	docs, err := OpenDocuments(ctx, file, i.opts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer docs.Close()
+
+	var newest time.Time
+	visit := func(v interface{}) {
+		if t, ok := toTime(v); ok && t.After(newest) {
+			newest = t
+		}
+	}
+	for {
+		doc, err := docs.Next()
+		if err == io.EOF {
+			return newest, nil
+		}
+		if err != nil {
+			var pe *parseError
+			if i.opts.SkipInvalid && errors.As(err, &pe) {
+				continue
+			}
+			return time.Time{}, err
+		}
+		if i.dates.opts.Field == "" {
+			walkValues(doc, visit)
+			continue
+		}
+		for _, v := range lookupPath(doc, i.dates.opts.Field) {
+			walkValues(v, visit)
+		}
+	}
+}
+
+// walkValues 對 v 以及其中所有子文件與陣列的值呼叫 fn
+func walkValues(v interface{}, fn func(interface{})) {
+	switch x := v.(type) {
+	case bson.M:
+		for _, e := range x {
+			walkValues(e, fn)
+		}
+	case bson.D:
+		for _, e := range x {
+			walkValues(e.Value, fn)
+		}
+	case bson.A:
+		for _, e := range x {
+			walkValues(e, fn)
+		}
+	case []interface{}:
+		for _, e := range x {
+			walkValues(e, fn)
+		}
+	default:
+		fn(v)
+	}
+}
+
+// shiftReader 把每筆文件內的 date 加上平移量
+type shiftReader struct {
+	docReader
+	offset time.Duration
+}
+
+func (s *shiftReader) Next() (bson.M, error) {
+	doc, err := s.docReader.Next()
+	if err != nil || s.offset == 0 {
+		return doc, err
+	}
+	shiftDates(doc, s.offset)
+	return doc, nil
+}
+
+// shiftDates 就地平移子文件與陣列中的 date
+func shiftDates(v interface{}, offset time.Duration) interface{} {
+	switch x := v.(type) {
+	case primitive.DateTime:
+		return primitive.NewDateTimeFromTime(x.Time().Add(offset))
+	case time.Time:
+		return x.Add(offset)
+	case bson.M:
+		for k, e := range x {
+			x[k] = shiftDates(e, offset)
+		}
+	case bson.D:
+		for n := range x {
+			x[n].Value = shiftDates(x[n].Value, offset)
+		}
+	case bson.A:
+		for n := range x {
+			x[n] = shiftDates(x[n], offset)
+		}
+	case []interface{}:
+		for n := range x {
+			x[n] = shiftDates(x[n], offset)
+		}
+	}
+	return v
+}
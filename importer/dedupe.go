@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Options.DedupeKeep 的值
+const (
+	DedupeLast  = "last"  // 保留最後一筆（預設），整個檔案會先讀進記憶體
+	DedupeFirst = "first" // 保留第一筆，只記住出現過的 key
+)
+
+// maxDedupeSamples 警告中列出的重複 key 數量上限
+const maxDedupeSamples = 10
+
+// dedupeReader 依 key（a.b 路徑）丟掉同一個檔案內重複的文件；沒有這個欄位的文件一律保留。
+// 保留最後一筆時先讀完整個檔案，再依原本的先後回傳留下來的文件
+type dedupeReader struct {
+	docReader
+	key     string
+	first   bool
+	seen    map[string]int // key → 保留的文件在 buffered 中的位置（first 時只用來判斷是否出現過）
+	buffer  []bson.M
+	read    bool // last：已經讀完整個檔案
+	dropped int
+	samples []string
+}
+
+func newDedupeReader(r docReader, key, keep string) *dedupeReader {
+	return &dedupeReader{docReader: r, key: key, first: keep == DedupeFirst, seen: map[string]int{}}
+}
+
+func (d *dedupeReader) Next() (bson.M, error) {
+	if d.first {
+		for {
+			doc, err := d.docReader.Next()
+			if err != nil {
+				return doc, err
+			}
+			k, ok := d.keyOf(doc)
+			if !ok {
+				return doc, nil
+			}
+			if _, dup := d.seen[k]; !dup {
+				d.seen[k] = 0
+				return doc, nil
+			}
+			d.drop(doc)
+		}
+	}
+
+	// 解析錯誤直接回傳，SkipInvalid 時會再呼叫 Next 繼續讀
+	for !d.read {
+		doc, err := d.docReader.Next()
+		if err == io.EOF {
+			d.read = true
+			break
+		}
+		if err != nil {
+			return doc, err
+		}
+		if k, ok := d.keyOf(doc); ok {
+			if n, dup := d.seen[k]; dup {
+				d.drop(d.buffer[n])
+				d.buffer[n] = nil
+			}
+			d.seen[k] = len(d.buffer)
+		}
+		d.buffer = append(d.buffer, doc)
+	}
+	for len(d.buffer) > 0 {
+		doc := d.buffer[0]
+		d.buffer = d.buffer[1:]
+		if doc != nil {
+			return doc, nil
+		}
+	}
+	return nil, io.EOF
+}
+
+func (d *dedupeReader) keyOf(doc bson.M) (string, bool) {
+	v, ok := getPath(doc, d.key)
+	if !ok {
+		return "", false
+	}
+	return keyString(v), true
+}
+
+// keyString key 的比對用字串，見 normalizeKey
+func keyString(v interface{}) string {
+	t, data, err := bson.MarshalValue(normalizeKey(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(rune(t)) + string(data)
+}
+
+func (d *dedupeReader) drop(doc bson.M) {
+	d.dropped++
+	if len(d.samples) < maxDedupeSamples {
+		v, _ := getPath(doc, d.key)
+		d.samples = append(d.samples, fmt.Sprint(v))
+	}
+}
+
+// summary 丟掉的重複 key，最多列出 maxDedupeSamples 個
+func (d *dedupeReader) summary() string {
+	s := strings.Join(d.samples, ", ")
+	if d.dropped > len(d.samples) {
+		s += ", ..."
+	}
+	return s
+}
+
+// normalizeKey 讓 server 視為相同的 key 比對結果也相同：整數值的數字不分型別，
+// 子文件依欄位名稱排序（解析成 bson.M 後本來就沒有順序）
+func normalizeKey(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int32:
+		return int64(x)
+	case int:
+		return int64(x)
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<63 {
+			return int64(x)
+		}
+	case bson.M:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := make(bson.D, 0, len(x))
+		for _, k := range keys {
+			d = append(d, bson.E{Key: k, Value: normalizeKey(x[k])})
+		}
+		return d
+	case bson.D:
+		d := make(bson.D, len(x))
+		for n, e := range x {
+			d[n] = bson.E{Key: e.Key, Value: normalizeKey(e.Value)}
+		}
+		return d
+	case bson.A:
+		a := make(bson.A, len(x))
+		for n, e := range x {
+			a[n] = normalizeKey(e)
+		}
+		return a
+	}
+	return v
+}
+
+// dedupeKeep 沒有 DedupeBy 時 DedupeKeep 不影響結果
+func dedupeKeep(opts Options) string {
+	if opts.DedupeBy == "" {
+		return ""
+	}
+	return opts.DedupeKeep
+}
@@ -0,0 +1,116 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseDependencies 解析以逗號分隔的 <collection>:<dependency>，例如 "users:tenants,orders:users"；
+// 一個 collection 依賴多個 collection 時重複列出
+func ParseDependencies(s string) (map[string][]string, error) {
+	deps := map[string][]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		coll, dep, ok := strings.Cut(pair, ":")
+		coll, dep = strings.TrimSpace(coll), strings.TrimSpace(dep)
+		if !ok || coll == "" || dep == "" {
+			return nil, fmt.Errorf("invalid dependency %q (expected <collection>:<dependency>)", pair)
+		}
+		if coll == dep {
+			return nil, fmt.Errorf("collection %s cannot depend on itself", coll)
+		}
+		deps[coll] = append(deps[coll], dep)
+	}
+	if err := checkDependencies(deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// checkDependencies 確認依賴關係沒有循環
+func checkDependencies(deps map[string][]string) error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(coll string, path []string) error
+	visit = func(coll string, path []string) error {
+		switch state[coll] {
+		case visiting:
+			return fmt.Errorf("circular dependency: %s", strings.Join(append(path, coll), " -> "))
+		case visited:
+			return nil
+		}
+		state[coll] = visiting
+		for _, dep := range deps[coll] {
+			if err := visit(dep, append(path, coll)); err != nil {
+				return err
+			}
+		}
+		state[coll] = visited
+		return nil
+	}
+	colls := make([]string, 0, len(deps))
+	for coll := range deps {
+		colls = append(colls, coll)
+	}
+	sort.Strings(colls)
+	for _, coll := range colls {
+		if err := visit(coll, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencyWaves 依 Options.DependsOn 把目錄的檔案分成依序執行的幾批，每批內維持原本的順序；
+// 依賴的 collection 沒有檔案時不影響順序
+func (i *Importer) dependencyWaves(files []string) [][]string {
+	if len(i.opts.DependsOn) == 0 {
+		return [][]string{files}
+	}
+	levels := map[string]int{}
+	var level func(coll string) int
+	level = func(coll string) int {
+		if l, ok := levels[coll]; ok {
+			return l
+		}
+		l := 0
+		for _, dep := range i.opts.DependsOn[coll] {
+			l = max(l, level(dep)+1)
+		}
+		levels[coll] = l
+		return l
+	}
+
+	byLevel := map[int][]string{}
+	for _, file := range files {
+		_, coll := i.resolveTarget(file)
+		l := level(coll)
+		byLevel[l] = append(byLevel[l], file)
+	}
+	keys := make([]int, 0, len(byLevel))
+	for l := range byLevel {
+		keys = append(keys, l)
+	}
+	sort.Ints(keys)
+	waves := make([][]string, 0, len(keys))
+	for _, l := range keys {
+		waves = append(waves, byLevel[l])
+	}
+	return waves
+}
+
+// failedDependency 回傳 coll 依賴的 collection 中第一個失敗（或沒有執行）的，沒有時回傳空字串
+func (i *Importer) failedDependency(coll string, failed map[string]bool) string {
+	for _, dep := range i.opts.DependsOn[coll] {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MaxDocumentSize MongoDB 單筆文件的 BSON 上限（16MB）
+const MaxDocumentSize = 16 * 1024 * 1024
+
+// positioner 讀取器回報最後讀出的文件在檔案中的位置，例如 "line 12"
+type positioner interface {
+	position() string
+}
+
+func (n *ndjsonReader) position() string { return fmt.Sprintf("line %d", n.line) }
+func (a *arrayReader) position() string  { return fmt.Sprintf("element %d", a.index) }
+func (b *bsonReader) position() string   { return fmt.Sprintf("document %d", b.index) }
+func (c *csvReader) position() string    { return fmt.Sprintf("line %d", c.line) }
+func (y *yamlReader) position() string   { return fmt.Sprintf("document %d element %d", y.doc, y.index) }
+func (r *sqlReader) position() string    { return fmt.Sprintf("line %d row %d", r.ins.line, r.row) }
+
+// sizeReader 解析後立刻量每筆文件的 BSON 大小，記錄警告後略過超過 MaxDocumentSize 的文件，不讓整批寫入失敗；
+// 每筆文件都要多 marshal 一次，所以只在 SkipOversized 時使用，否則由寫入時的錯誤回報。之後的轉換、stamp 等新增的欄位不算在內
+type sizeReader struct {
+	docReader
+	pos       positioner // 讀取器不支援時為 nil，以文件序號表示位置
+	file      string
+	log       *errorLog
+	logger    *slog.Logger
+	n         int
+	oversized int
+}
+
+func newSizeReader(r, parser docReader, file string, log *errorLog, logger *slog.Logger) *sizeReader {
+	pos, _ := parser.(positioner)
+	return &sizeReader{docReader: r, pos: pos, file: file, log: log, logger: logger}
+}
+
+func (s *sizeReader) Next() (bson.M, error) {
+	for {
+		doc, err := s.docReader.Next()
+		if err != nil {
+			return doc, err
+		}
+		s.n++
+		raw, err := bson.Marshal(doc)
+		if err != nil || len(raw) <= MaxDocumentSize {
+			// 無法轉成 BSON 的文件交給寫入時回報
+			return doc, nil
+		}
+		pe := &parseError{Pos: s.position(), Raw: idOf(doc), Err: fmt.Errorf("document is %.1fMB of BSON, over MongoDB's 16MB limit", float64(len(raw))/(1024*1024))}
+		s.oversized++
+		s.logger.Warn(fmt.Sprintf("⚠️  Skipping oversized document in %s %s: %v", s.file, pe.Pos, pe.Err),
+			"file", s.file, "position", pe.Pos, "bytes", len(raw))
+		if s.log != nil {
+			if err := s.log.Record(s.file, pe); err != nil {
+				return nil, fmt.Errorf("failed to write errors file: %v", err)
+			}
+		}
+	}
+}
+
+func (s *sizeReader) position() string {
+	if s.pos != nil {
+		return s.pos.position()
+	}
+	return fmt.Sprintf("document %d", s.n)
+}
+
+// idOf 錯誤檔裡代替整份（超過 16MB 的）文件，只記 _id
+func idOf(doc bson.M) string {
+	id, ok := doc["_id"]
+	if !ok {
+		return ""
+	}
+	raw, err := bson.MarshalExtJSON(bson.M{"_id": id}, false, false)
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
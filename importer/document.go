@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// setPath 以 "a.b.c" 路徑寫入欄位，中間層不存在時自動建立子文件
+func setPath(doc bson.M, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(bson.M)
+		if !ok {
+			next = bson.M{}
+			cur[p] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = v
+}
+
+// getPath 以 "a.b.c" 路徑讀取欄位；中間層不是子文件時視為不存在
+func getPath(doc bson.M, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(bson.M)
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	v, ok := cur[parts[len(parts)-1]]
+	return v, ok
+}
+
+// deletePath 以 "a.b.c" 路徑刪除欄位，不存在時什麼都不做
+func deletePath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	cur := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := cur[p].(bson.M)
+		if !ok {
+			return
+		}
+		cur = next
+	}
+	delete(cur, parts[len(parts)-1])
+}
@@ -0,0 +1,563 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ParseFilter 解析 --filter 的 Extended JSON 查詢，並先檢查是否使用了不支援的運算子
+func ParseFilter(s string) (bson.M, error) {
+	var filter bson.M
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &filter); err != nil {
+		return nil, err
+	}
+	if _, err := compileFilter(filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// predicate 判斷整份文件是否符合查詢
+type predicate func(doc bson.M) bool
+
+// valuePredicate 判斷欄位的值是否符合條件；vals 是路徑上找到的值（陣列尚未展開），欄位不存在時為空
+type valuePredicate func(vals []interface{}) bool
+
+// filterReader 只回傳符合 Options.Filter 的文件（在 client 端比對，不會送到 server）
+type filterReader struct {
+	docReader
+	match    predicate
+	filtered int
+}
+
+func (f *filterReader) Next() (bson.M, error) {
+	for {
+		doc, err := f.docReader.Next()
+		if err != nil {
+			return doc, err
+		}
+		if f.match(doc) {
+			return doc, nil
+		}
+		f.filtered++
+	}
+}
+
+// compileFilter 把查詢轉成 predicate；支援常用的比較、元素、陣列與邏輯運算子（與 find 的語意相同）
+func compileFilter(filter bson.M) (predicate, error) {
+	var preds []predicate
+	for key, val := range filter {
+		var p predicate
+		var err error
+		switch key {
+		case "$and", "$or", "$nor":
+			p, err = compileLogical(key, val)
+		case "$comment":
+			continue
+		default:
+			if strings.HasPrefix(key, "$") {
+				return nil, fmt.Errorf("unsupported top-level operator %s", key)
+			}
+			var vp valuePredicate
+			vp, err = compileCondition(val)
+			path := key
+			p = func(doc bson.M) bool { return vp(lookupPath(doc, path)) }
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		preds = append(preds, p)
+	}
+	return func(doc bson.M) bool {
+		for _, p := range preds {
+			if !p(doc) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileLogical(op string, val interface{}) (predicate, error) {
+	clauses, ok := val.(bson.A)
+	if !ok || len(clauses) == 0 {
+		return nil, errors.New("expects a non-empty array")
+	}
+	preds := make([]predicate, 0, len(clauses))
+	for _, c := range clauses {
+		m, ok := c.(bson.M)
+		if !ok {
+			return nil, errors.New("array elements must be documents")
+		}
+		p, err := compileFilter(m)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, p)
+	}
+	return func(doc bson.M) bool {
+		for _, p := range preds {
+			matched := p(doc)
+			switch {
+			case op == "$and" && !matched:
+				return false
+			case op == "$or" && matched:
+				return true
+			case op == "$nor" && matched:
+				return false
+			}
+		}
+		return op != "$or"
+	}, nil
+}
+
+// compileCondition 處理 {field: value} 或 {field: {$op: ...}}
+func compileCondition(cond interface{}) (valuePredicate, error) {
+	m, ok := cond.(bson.M)
+	if !ok || !isOperatorDoc(m) {
+		return equals(cond), nil
+	}
+
+	var preds []valuePredicate
+	for op, arg := range m {
+		p, err := compileOperator(op, arg, m)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			preds = append(preds, p)
+		}
+	}
+	return func(vals []interface{}) bool {
+		for _, p := range preds {
+			if !p(vals) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func compileOperator(op string, arg interface{}, cond bson.M) (valuePredicate, error) {
+	switch op {
+	case "$eq":
+		return equals(arg), nil
+	case "$ne":
+		eq := equals(arg)
+		return func(vals []interface{}) bool { return !eq(vals) }, nil
+	case "$gt", "$gte", "$lt", "$lte":
+		return compare(op, arg), nil
+	case "$in", "$nin":
+		list, ok := arg.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("%s expects an array", op)
+		}
+		preds := make([]valuePredicate, len(list))
+		for n, v := range list {
+			preds[n] = equals(v)
+		}
+		in := func(vals []interface{}) bool {
+			for _, p := range preds {
+				if p(vals) {
+					return true
+				}
+			}
+			return false
+		}
+		if op == "$nin" {
+			return func(vals []interface{}) bool { return !in(vals) }, nil
+		}
+		return in, nil
+	case "$exists":
+		want := truthy(arg)
+		return func(vals []interface{}) bool { return (len(vals) > 0) == want }, nil
+	case "$type":
+		return typeIs(arg)
+	case "$regex":
+		opts, _ := cond["$options"].(string)
+		var pattern string
+		switch r := arg.(type) {
+		case string:
+			pattern = r
+		case primitive.Regex:
+			pattern = r.Pattern
+			if opts == "" {
+				opts = r.Options
+			}
+		default:
+			return nil, errors.New("$regex expects a string")
+		}
+		re, err := compileRegex(pattern, opts)
+		if err != nil {
+			return nil, err
+		}
+		return matchRegex(re), nil
+	case "$options":
+		if _, ok := cond["$regex"]; !ok {
+			return nil, errors.New("$options requires $regex")
+		}
+		return nil, nil
+	case "$size":
+		n, ok := toFloat(arg)
+		if !ok {
+			return nil, errors.New("$size expects a number")
+		}
+		return func(vals []interface{}) bool {
+			for _, v := range vals {
+				if a, ok := v.(bson.A); ok && float64(len(a)) == n {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "$all":
+		list, ok := arg.(bson.A)
+		if !ok {
+			return nil, errors.New("$all expects an array")
+		}
+		preds := make([]valuePredicate, len(list))
+		for n, v := range list {
+			preds[n] = equals(v)
+		}
+		return func(vals []interface{}) bool {
+			if len(preds) == 0 {
+				return false
+			}
+			for _, p := range preds {
+				if !p(vals) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case "$elemMatch":
+		return elemMatch(arg)
+	case "$mod":
+		list, ok := arg.(bson.A)
+		if !ok || len(list) != 2 {
+			return nil, errors.New("$mod expects [divisor, remainder]")
+		}
+		d, ok1 := toFloat(list[0])
+		r, ok2 := toFloat(list[1])
+		if !ok1 || !ok2 || d == 0 {
+			return nil, errors.New("$mod expects a non-zero divisor and a remainder")
+		}
+		return anyValue(func(v interface{}) bool {
+			n, ok := toFloat(v)
+			return ok && math.Mod(math.Trunc(n), math.Trunc(d)) == math.Trunc(r)
+		}), nil
+	case "$not":
+		var inner valuePredicate
+		switch a := arg.(type) {
+		case bson.M:
+			if !isOperatorDoc(a) {
+				return nil, errors.New("$not expects an operator document or a regex")
+			}
+			p, err := compileCondition(a)
+			if err != nil {
+				return nil, err
+			}
+			inner = p
+		case primitive.Regex:
+			re, err := compileRegex(a.Pattern, a.Options)
+			if err != nil {
+				return nil, err
+			}
+			inner = matchRegex(re)
+		default:
+			return nil, errors.New("$not expects an operator document or a regex")
+		}
+		return func(vals []interface{}) bool { return !inner(vals) }, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", op)
+}
+
+// elemMatch 陣列中至少有一個元素符合：條件是運算子時比對元素本身，否則把元素當成子文件比對
+func elemMatch(arg interface{}) (valuePredicate, error) {
+	m, ok := arg.(bson.M)
+	if !ok {
+		return nil, errors.New("$elemMatch expects a document")
+	}
+	var match func(elem interface{}) bool
+	if isOperatorDoc(m) {
+		p, err := compileCondition(m)
+		if err != nil {
+			return nil, err
+		}
+		match = func(elem interface{}) bool { return p([]interface{}{elem}) }
+	} else {
+		p, err := compileFilter(m)
+		if err != nil {
+			return nil, err
+		}
+		match = func(elem interface{}) bool {
+			doc, ok := elem.(bson.M)
+			return ok && p(doc)
+		}
+	}
+	return func(vals []interface{}) bool {
+		for _, v := range vals {
+			a, ok := v.(bson.A)
+			if !ok {
+				continue
+			}
+			for _, elem := range a {
+				if match(elem) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+// isOperatorDoc {$gt: 1} 是條件，{a: 1} 是要完全相等的子文件
+func isOperatorDoc(m bson.M) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") || k == "$and" || k == "$or" || k == "$nor" {
+			return false
+		}
+	}
+	return true
+}
+
+// equals 與 find 相同：陣列欄位只要有一個元素相等即可；null 也符合欄位不存在；regex 比對字串
+func equals(want interface{}) valuePredicate {
+	if re, ok := want.(primitive.Regex); ok {
+		if compiled, err := compileRegex(re.Pattern, re.Options); err == nil {
+			return matchRegex(compiled)
+		}
+	}
+	return func(vals []interface{}) bool {
+		if want == nil && len(vals) == 0 {
+			return true
+		}
+		for _, v := range vals {
+			if valuesEqual(v, want) {
+				return true
+			}
+			if a, ok := v.(bson.A); ok {
+				for _, elem := range a {
+					if valuesEqual(elem, want) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+}
+
+// compare 只比較同一類的型別（數字、字串、日期、ObjectId、布林），不同類一律不符合
+func compare(op string, want interface{}) valuePredicate {
+	return anyValue(func(v interface{}) bool {
+		c, ok := compareValues(v, want)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "$gt":
+			return c > 0
+		case "$gte":
+			return c >= 0
+		case "$lt":
+			return c < 0
+		}
+		return c <= 0
+	})
+}
+
+func compareValues(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		return strings.Compare(x, y), ok
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case x == y:
+			return 0, true
+		case !x:
+			return -1, true
+		}
+		return 1, true
+	case primitive.ObjectID:
+		y, ok := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]), ok
+	case primitive.DateTime, time.Time:
+		tx, _ := toTime(a)
+		ty, ok := toTime(b)
+		return tx.Compare(ty), ok
+	}
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case primitive.DateTime:
+		return t.Time(), true
+	case time.Time:
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// typeIs 支援 $type 的名稱（含 number）或數字代碼，也可以是陣列
+func typeIs(arg interface{}) (valuePredicate, error) {
+	var names []string
+	list, ok := arg.(bson.A)
+	if !ok {
+		list = bson.A{arg}
+	}
+	for _, t := range list {
+		if n, ok := toFloat(t); ok {
+			name, ok := bsonTypeCodes[int(n)]
+			if !ok {
+				return nil, fmt.Errorf("unknown $type code %v", n)
+			}
+			names = append(names, name)
+			continue
+		}
+		s, ok := t.(string)
+		if !ok {
+			return nil, errors.New("$type expects a type name or code")
+		}
+		names = append(names, s)
+	}
+	return anyValue(func(v interface{}) bool {
+		typ := bsonTypeOf(v)
+		return matchesType(append([]string{typ}, bsonTypeAliases[typ]...), names)
+	}), nil
+}
+
+// bsonTypeCodes $type 的數字代碼
+var bsonTypeCodes = map[int]string{
+	1: "double", 2: "string", 3: "object", 4: "array", 5: "binData", 6: "undefined",
+	7: "objectId", 8: "bool", 9: "date", 10: "null", 11: "regex", 12: "dbPointer",
+	13: "javascript", 14: "symbol", 15: "javascriptWithScope", 16: "int", 17: "timestamp",
+	18: "long", 19: "decimal", -1: "minKey", 127: "maxKey",
+}
+
+// compileRegex 把 MongoDB 的 $options（i、m、s）轉成 Go 的 flag；Go 不支援 x
+func compileRegex(pattern, opts string) (*regexp.Regexp, error) {
+	flags := ""
+	for _, o := range opts {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		default:
+			return nil, fmt.Errorf("unsupported regex option %q", o)
+		}
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func matchRegex(re *regexp.Regexp) valuePredicate {
+	return anyValue(func(v interface{}) bool {
+		s, ok := v.(string)
+		return ok && re.MatchString(s)
+	})
+}
+
+// anyValue 欄位值本身或（欄位是陣列時）其中任一元素符合即可
+func anyValue(fn func(v interface{}) bool) valuePredicate {
+	return func(vals []interface{}) bool {
+		for _, v := range vals {
+			if fn(v) {
+				return true
+			}
+			if a, ok := v.(bson.A); ok {
+				for _, elem := range a {
+					if fn(elem) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+}
+
+// lookupPath 依 "a.b.c" 取出值；中途遇到陣列時會對每個子文件繼續往下找（數字段落則取該位置的元素）
+func lookupPath(doc bson.M, path string) []interface{} {
+	return lookupParts(doc, strings.Split(path, "."))
+}
+
+func lookupParts(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch x := v.(type) {
+	case bson.M:
+		child, ok := x[parts[0]]
+		if !ok {
+			return nil
+		}
+		return lookupParts(child, parts[1:])
+	case bson.A:
+		var out []interface{}
+		if idx, ok := arrayIndex(parts[0]); ok && idx < len(x) {
+			out = append(out, lookupParts(x[idx], parts[1:])...)
+		}
+		for _, elem := range x {
+			if _, ok := elem.(bson.M); ok {
+				out = append(out, lookupParts(elem, parts)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func arrayIndex(s string) (int, bool) {
+	if s == "" {
+		return 0, false
+	}
+	n := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
+// truthy $exists 接受布林或數字
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	n, ok := toFloat(v)
+	return ok && n != 0
+}
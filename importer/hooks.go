@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+// Hook 匯入符合 Collection / File（名稱或 glob，空字串表示不限）的檔案前後要執行的動作
+type Hook struct {
+	Collection string
+	File       string
+	Pre        []HookAction // 開始寫入前；失敗時不匯入這個檔案
+	Post       []HookAction // 寫入並建好索引後；失敗時這個檔案視為失敗（資料已經寫入）
+}
+
+// HookAction Shell、Command、Aggregate 三者擇一
+type HookAction struct {
+	Shell     string   // 以 sh -c 執行，可用 IMPORT_DB、IMPORT_COLLECTION、IMPORT_FILE、IMPORT_DOCS 環境變數
+	Command   bson.D   // 在目標 database 執行的 server command
+	Aggregate []bson.D // 在 On（預設為目標 collection）執行的 aggregation pipeline，例如 $merge 更新 materialized view
+	On        string
+}
+
+// hookFile YAML 的欄位以 yaml.Node 讀取，command / pipeline 才能保留 key 的順序（command 名稱必須在第一個）
+type hookFile struct {
+	Hooks []struct {
+		Collection string          `yaml:"collection"`
+		File       string          `yaml:"file"`
+		Pre        []hookActionRaw `yaml:"pre"`
+		Post       []hookActionRaw `yaml:"post"`
+	} `yaml:"hooks"`
+}
+
+type hookActionRaw struct {
+	Shell     string      `yaml:"shell"`
+	Command   yaml.Node   `yaml:"command"`
+	Aggregate []yaml.Node `yaml:"aggregate"`
+	On        string      `yaml:"on"`
+}
+
+// LoadHooks 讀取 YAML 格式的 hook 設定；JSON 也是合法的 YAML
+func LoadHooks(path string) ([]Hook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hf hookFile
+	if err := yaml.Unmarshal(data, &hf); err != nil {
+		return nil, fmt.Errorf("failed to parse hook file %s: %v", path, err)
+	}
+
+	hooks := make([]Hook, 0, len(hf.Hooks))
+	for n, raw := range hf.Hooks {
+		h := Hook{Collection: raw.Collection, File: raw.File}
+		if h.Collection == "" && h.File == "" {
+			return nil, fmt.Errorf("hook %d: collection or file is required", n+1)
+		}
+		for _, pattern := range []string{h.Collection, h.File} {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("hook %d: invalid pattern %q: %v", n+1, pattern, err)
+			}
+		}
+		if h.Pre, err = convertHookActions(raw.Pre); err != nil {
+			return nil, fmt.Errorf("hook %d pre: %v", n+1, err)
+		}
+		if h.Post, err = convertHookActions(raw.Post); err != nil {
+			return nil, fmt.Errorf("hook %d post: %v", n+1, err)
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+func convertHookActions(raws []hookActionRaw) ([]HookAction, error) {
+	actions := make([]HookAction, 0, len(raws))
+	for n, raw := range raws {
+		a := HookAction{Shell: raw.Shell, On: raw.On}
+		kinds := 0
+		if a.Shell != "" {
+			kinds++
+		}
+		if raw.Command.Kind != 0 {
+			kinds++
+			v, err := nodeToBSON(&raw.Command)
+			if err != nil {
+				return nil, fmt.Errorf("action %d: %v", n+1, err)
+			}
+			d, ok := v.(bson.D)
+			if !ok || len(d) == 0 {
+				return nil, fmt.Errorf("action %d: command must be a non-empty document", n+1)
+			}
+			a.Command = d
+		}
+		if len(raw.Aggregate) > 0 {
+			kinds++
+			for s := range raw.Aggregate {
+				v, err := nodeToBSON(&raw.Aggregate[s])
+				if err != nil {
+					return nil, fmt.Errorf("action %d: %v", n+1, err)
+				}
+				stage, ok := v.(bson.D)
+				if !ok {
+					return nil, fmt.Errorf("action %d: pipeline stage %d must be a document", n+1, s+1)
+				}
+				a.Aggregate = append(a.Aggregate, stage)
+			}
+		}
+		if kinds != 1 {
+			return nil, fmt.Errorf("action %d: expected exactly one of shell, command or aggregate", n+1)
+		}
+		if a.On != "" && a.Aggregate == nil {
+			return nil, fmt.Errorf("action %d: on only applies to aggregate", n+1)
+		}
+		actions = append(actions, a)
+	}
+	return actions, nil
+}
+
+// nodeToBSON mapping → bson.D（保留順序）、sequence → bson.A、scalar 依 YAML 的型別解碼
+func nodeToBSON(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return nodeToBSON(n.Content[0])
+	case yaml.AliasNode:
+		return nodeToBSON(n.Alias)
+	case yaml.MappingNode:
+		d := make(bson.D, 0, len(n.Content)/2)
+		for k := 0; k+1 < len(n.Content); k += 2 {
+			v, err := nodeToBSON(n.Content[k+1])
+			if err != nil {
+				return nil, err
+			}
+			d = append(d, bson.E{Key: n.Content[k].Value, Value: v})
+		}
+		return d, nil
+	case yaml.SequenceNode:
+		a := make(bson.A, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := nodeToBSON(c)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	default:
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
+
+// hooksFor 回傳符合這個 collection 與檔案的 hook，依設定檔的順序
+func hooksFor(hooks []Hook, coll, filePath string) []Hook {
+	var out []Hook
+	for _, h := range hooks {
+		if h.Collection != "" {
+			if ok, _ := filepath.Match(h.Collection, coll); !ok {
+				continue
+			}
+		}
+		if h.File != "" {
+			if ok, _ := filepath.Match(h.File, baseName(filePath)); !ok {
+				continue
+			}
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// runHooks 依序執行 hooks 的 pre 或 post 動作，第一個失敗就停止
+func (i *Importer) runHooks(ctx context.Context, hooks []Hook, phase string, collection *mongo.Collection, filePath string, docs int) error {
+	for _, h := range hooks {
+		actions := h.Pre
+		if phase == "post" {
+			actions = h.Post
+		}
+		for _, a := range actions {
+			if err := i.runHookAction(ctx, a, phase, collection, filePath, docs); err != nil {
+				return fmt.Errorf("%s hook failed: %v", phase, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (i *Importer) runHookAction(ctx context.Context, a HookAction, phase string, collection *mongo.Collection, filePath string, docs int) error {
+	coll := collection.Name()
+	switch {
+	case a.Shell != "":
+		i.log.Info(fmt.Sprintf("🪝 Running %s hook for %s: %s", phase, coll, a.Shell), "collection", coll, "hook", phase, "shell", a.Shell)
+		cmd := exec.CommandContext(ctx, "sh", "-c", a.Shell)
+		cmd.Env = append(os.Environ(),
+			"IMPORT_DB="+collection.Database().Name(),
+			"IMPORT_COLLECTION="+coll,
+			"IMPORT_FILE="+filePath,
+			"IMPORT_DOCS="+strconv.Itoa(docs),
+		)
+		out, err := cmd.CombinedOutput()
+		output := strings.TrimSpace(string(out))
+		if err != nil {
+			if output != "" {
+				return fmt.Errorf("%s: %v: %s", a.Shell, err, output)
+			}
+			return fmt.Errorf("%s: %v", a.Shell, err)
+		}
+		if output != "" {
+			i.log.Debug(output, "collection", coll, "hook", phase)
+		}
+		return nil
+
+	case a.Command != nil:
+		name := a.Command[0].Key
+		i.log.Info(fmt.Sprintf("🪝 Running %s hook for %s: %s command", phase, coll, name), "collection", coll, "hook", phase, "command", name)
+		if err := collection.Database().RunCommand(ctx, a.Command).Err(); err != nil {
+			return fmt.Errorf("%s command: %v", name, err)
+		}
+		return nil
+
+	case a.Aggregate != nil:
+		target := collection
+		if a.On != "" {
+			target = collection.Database().Collection(a.On)
+		}
+		i.log.Info(fmt.Sprintf("🪝 Running %s hook for %s: aggregate on %s", phase, coll, target.Name()), "collection", coll, "hook", phase, "on", target.Name())
+		cursor, err := target.Aggregate(ctx, a.Aggregate)
+		if err != nil {
+			return fmt.Errorf("aggregate on %s: %v", target.Name(), err)
+		}
+		// $merge / $out 在 Aggregate 回傳前就已完成，結果不需要讀取
+		return cursor.Close(ctx)
+	}
+	return errors.New("empty hook action")
+}
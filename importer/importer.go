@@ -0,0 +1,1104 @@
+// Package importer 把 Extended JSON / BSON / CSV 檔案匯入 MongoDB，CLI 與其他 Go 服務共用同一份邏輯。
+//
+//	imp, err := importer.New(ctx, client, importer.Options{DB: "dex"})
+//	if err != nil { ... }
+//	defer imp.Close()
+//	results, err := imp.ImportDir(ctx, "./dump")
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/metrics"
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Options 控制匯入時如何寫入既有的 collection
+type Options struct {
+	DB               string            // 預設的 database
+	Strategy         string            // truncate（預設，清空後插入；沒有 Transactional / AtomicSwap 時不是原子的）、upsert、merge、append 或 delete
+	KeyField         string            // upsert / merge / delete 比對用的欄位，預設 _id；逗號分隔多個欄位（a.b 路徑）時以全部欄位比對
+	MergeUpdate      bool              // merge 時以 $set 更新既有文件中檔案有的欄位；否則既有文件完全不動
+	Collection       string            // 指定目標 collection，覆蓋檔名推斷；目錄模式下只匯入這個 collection
+	Renames          map[string]string // 來源 collection → 實際寫入的 collection，見 ParseRenames
+	CollectionPrefix string            // 加在每個寫入的 collection（含 view）名稱前面，例如 staging_；在 Renames 之後套用
+	Strategies       []StrategyRule    // 依 collection 覆蓋 Strategy，第一個符合的規則生效，見 ParseStrategies
+	BatchSize        int               // 每次 InsertMany / BulkWrite 的文件數
+	Concurrency      int               // 目錄模式同時匯入的檔案數
+
+	InsertWorkers    int  // 單一檔案同時寫入的批次數；大於 1 時改用 unordered InsertMany
+	Unordered        bool // InsertMany 遇到錯誤時繼續寫入同一批的其他文件，檔案仍然視為失敗
+	IgnoreDuplicates bool // 略過 duplicate key 的文件並計入 FileResult.Duplicates，隱含 Unordered
+
+	DedupeBy   string // 寫入前丟掉同一個檔案內這個 key（a.b 路徑）重複的文件，並計入 FileResult.Deduped
+	DedupeKeep string // DedupeLast（預設）或 DedupeFirst
+
+	StampHashField string // 非空時在每筆文件加上內容雜湊；upsert / merge 時略過雜湊與既有文件相同的文件，見 Drift
+	StampTimeField string // 非空時在每筆文件加上這次匯入的時間
+
+	Include []string // 目錄模式只匯入 collection 名稱符合其中一個 glob 的檔案，空的表示全部，見 ParsePatterns
+	Exclude []string // 目錄模式略過 collection 名稱符合其中一個 glob 的檔案，優先於 Include
+
+	DependsOn map[string][]string // 目錄模式下 collection → 必須先匯入的 collection，依賴的 collection 失敗時不匯入，見 ParseDependencies
+
+	Mappings       []Mapping // 對應檔規則，優先於檔名推斷
+	DBFromFilename bool      // 檔名為 <db>.<collection>.json 時匯入對應的 database
+	Recursive      bool      // 目錄模式包含子目錄；子目錄下的檔案匯入以第一層子目錄命名的 database（dumps/mydb/users.json → mydb.users）
+	Archive        bool      // 路徑（包括 stdin）是 mongodump --archive 的串流，見 importArchive；.archive 檔不需要設定
+
+	OnlyFields []string  // 非空時解析後只保留這些欄位（a.b 路徑）與 _id，見 projectReader
+	DropFields []string  // 解析後移除這些欄位（a.b 路徑），例如內嵌的大型 binary
+	Coerce     *Coercion // 把字串型別的 ObjectID、日期與數字轉回原本的型別，在轉換之前套用，見 ParseCoercion
+
+	DateShift   *DateShift  // 平移檔案內的 date，讓最新的日期落在指定的時間，見 ParseDateShift
+	Templates   bool        // 展開字串值裡的 {{NOW}}、{{UUID}}、{{ENV:NAME}}，在轉換之前套用，見 templateReader
+	Transforms  []Transform // 插入前依 collection 套用的欄位轉換，見 LoadTransforms
+	Filter      bson.M      // 只匯入符合這個查詢的文件（client 端比對），見 ParseFilter
+	Scope       bson.M      // truncate 時只刪除符合這個查詢的文件（例如單一 tenant），而不是清空整個 collection
+	TenantField string      // 非空時在每筆文件寫入 TenantValue（a.b 路徑），沒有 Scope 時 truncate 只刪除這個 tenant 的文件，見 TruncateScope
+	TenantValue interface{} // TenantField 的值
+	Mask        *MaskConfig // 寫入前遮罩個資欄位，見 LoadMaskConfig
+	Hooks       []Hook      // 匯入前後執行的 shell / server command / aggregation，見 LoadHooks
+
+	CSV                   CSVOptions    // .csv / .tsv 的分隔字元與欄位型別
+	ExtJSONMode           string        // .json 的解析模式：relaxed（預設）、canonical 或 auto
+	DecryptKey            []byte        // 解密 .enc 檔（export 加密的輸出）的 AES-256 金鑰，nil 時 .enc 檔無法匯入
+	RejectDuplicateFields bool          // 文件（含子文件）或 CSV 標題有重複的欄位時視為解析錯誤；預設保留最後一個值
+	NonFinite             string        // NaN / Infinity 的處理：keep（預設）、null 或 reject
+	PreserveOrder         bool          // JSON / BSON 檔的欄位依檔案內的順序寫入（解析成 bson.D）；轉換新增的欄位依名稱排在最後
+	Retry                 RetryPolicy   // 暫時性錯誤的重試設定
+	OpTimeout             time.Duration // 每個資料庫操作（清空、每批寫入）的 timeout，每次重試重新計算；0 表示不限制
+	RateLimit             RateLimit     // 寫入速度上限，所有檔案共用
+	Quiet                 bool          // 不印每批的進度
+
+	FailFast bool // 第一個檔案失敗後就不再開始新的檔案
+
+	SkipUnchanged bool // 檔案的 SHA-256 與設定都和上次成功匯入（記錄在 _import_meta）相同時略過
+	Force         bool // SkipUnchanged 時仍然重新匯入，並更新 checksum
+	NoVerify      bool // 目錄內有 export 寫出的 manifest.json 時不檢查，被修改或不完整的資料也照樣匯入
+
+	Resume bool // 每批寫入後記錄進度（_import_checkpoints），中斷後重新執行時從上次的位置接續
+
+	Transactional bool // 每個檔案的清空 + 插入要嘛全部生效、要嘛都不生效
+	AtomicSwap    bool // 一律載入到 <collection>.__staging、建好索引後再 rename 蓋過目標
+
+	ValidateSchema bool   // 插入前以目標 collection 的 $jsonSchema validator 檢查每筆文件
+	SchemaFile     string // 改用本地的 JSON Schema 檔，隱含 ValidateSchema
+
+	PreSplit int // 目標是空的 sharded collection 時，插入前依檔案內 shard key 的分布切成這麼多個 chunk 並分散到各個 shard，見 preSplit
+
+	ViewsFile string // 匯入後由 CreateViews 建立的 view 定義（Extended JSON），見 loadViews；放在資料目錄內時不會被當成資料檔
+
+	SkipInvalid   bool   // 略過無法解析的文件而不是整個檔案失敗
+	SkipOversized bool   // 略過 BSON 超過 16MB 的文件，見 sizeReader；否則整批寫入失敗
+	ErrorsFile    string // 記錄被略過的文件，空字串表示不記錄
+
+	Server  *ServerInfo      // 連線的 server 版本與拓撲，nil 時由 New 偵測，見 DetectServer
+	Metrics *metrics.Metrics // --metrics-addr 的計數，nil 表示不記錄
+	Logger  *slog.Logger     // nil 時使用 slog.Default()
+}
+
+// Importer 匯入檔案到 MongoDB；可以同時在多個 goroutine 使用
+type Importer struct {
+	client       *mongo.Client
+	opts         Options
+	log          *slog.Logger
+	errorLog     *errorLog
+	targetServer ServerInfo // Options.Server，或啟動時由 DetectServer 偵測
+	schema       bson.M     // Options.SchemaFile 的內容
+	views        []View     // Options.ViewsFile 的內容
+	started      time.Time  // {{NOW}} 的值，整次執行的所有檔案都相同
+	dates        *dateShifter
+	limiter      *rateLimiter // Options.RateLimit，nil 表示不限制
+	match        predicate
+	scope        predicate // Options.Scope
+	masker       *masker
+	settings     string    // settingsChecksum，SkipUnchanged 比對用
+	subdirs      *sync.Map // 檔案路徑 → Recursive 時由子目錄推斷的 database
+	source       string    // 匯入 importSQLDump 拆出的暫存檔時為原本的 dump；對應目標、checkpoint 與 checksum 都以它為準
+}
+
+// sourceOf 由 .sql dump 拆出的暫存檔對應到原本的 dump，其他檔案就是自己
+func (i *Importer) sourceOf(filePath string) string {
+	if i.source != "" {
+		return i.source
+	}
+	return filePath
+}
+
+// New 檢查並補齊 opts 的預設值；沒有 Options.Server 時先詢問 server 的版本與拓撲，Transactional 時偵測失敗視為錯誤
+func New(ctx context.Context, client *mongo.Client, opts Options) (*Importer, error) {
+	if opts.DB == "" {
+		return nil, errors.New("missing database")
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = StrategyTruncate
+	}
+	if err := checkStrategy(opts.Strategy); err != nil {
+		return nil, err
+	}
+	for _, r := range opts.Strategies {
+		if err := checkStrategy(r.Strategy); err != nil {
+			return nil, fmt.Errorf("collection %s: %v", r.Collection, err)
+		}
+		if err := checkPatterns([]string{r.Collection}); err != nil {
+			return nil, err
+		}
+	}
+	if opts.AtomicSwap && opts.UsesStrategy(StrategyUpsert, StrategyMerge, StrategyAppend, StrategyDelete) {
+		return nil, errors.New("atomic swap replaces the whole collection and requires the truncate strategy")
+	}
+	if opts.AtomicSwap && opts.TenantField != "" {
+		return nil, errors.New("atomic swap replaces the whole collection and cannot be combined with a tenant field")
+	}
+	opts.Scope = opts.TruncateScope()
+	if opts.AtomicSwap && len(opts.Scope) > 0 {
+		return nil, errors.New("atomic swap replaces the whole collection and cannot be combined with a scope")
+	}
+	if opts.Resume && (opts.Transactional || opts.AtomicSwap) {
+		return nil, errors.New("resume cannot be combined with transactional or atomic swap imports, which restart from scratch")
+	}
+	if opts.PreSplit > 1 && (opts.Transactional || opts.AtomicSwap) {
+		return nil, errors.New("pre-split cannot be combined with transactional or atomic swap imports")
+	}
+	if opts.Resume && opts.InsertWorkers > 1 {
+		return nil, errors.New("resume records progress batch by batch and cannot be combined with parallel insert workers")
+	}
+	switch opts.ExtJSONMode {
+	case "":
+		opts.ExtJSONMode = ExtJSONRelaxed
+	case ExtJSONRelaxed, ExtJSONCanonical, ExtJSONAuto:
+	default:
+		return nil, fmt.Errorf("invalid Extended JSON mode: %s (expected canonical, relaxed or auto)", opts.ExtJSONMode)
+	}
+	if opts.CollectionPrefix != "" && checkCollectionName(opts.CollectionPrefix) != nil {
+		return nil, fmt.Errorf("invalid collection prefix %q", opts.CollectionPrefix)
+	}
+	switch opts.NonFinite {
+	case "":
+		opts.NonFinite = NonFiniteKeep
+	case NonFiniteKeep, NonFiniteNull, NonFiniteReject:
+	default:
+		return nil, fmt.Errorf("invalid NaN/Infinity handling: %s (expected keep, null or reject)", opts.NonFinite)
+	}
+	if err := checkPatterns(append(append([]string{}, opts.Include...), opts.Exclude...)); err != nil {
+		return nil, fmt.Errorf("invalid collection filter: %v", err)
+	}
+	if err := checkDependencies(opts.DependsOn); err != nil {
+		return nil, err
+	}
+	if opts.DedupeKeep == "" {
+		opts.DedupeKeep = DedupeLast
+	}
+	if opts.DedupeKeep != DedupeLast && opts.DedupeKeep != DedupeFirst {
+		return nil, fmt.Errorf("invalid dedupe keep: %s (expected first or last)", opts.DedupeKeep)
+	}
+	if opts.KeyField == "" {
+		opts.KeyField = "_id"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.InsertWorkers <= 0 {
+		opts.InsertWorkers = 1
+	}
+	if opts.Retry.Attempts <= 0 {
+		opts.Retry.Attempts = 1
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	i := &Importer{client: client, opts: opts, log: opts.Logger, settings: settingsChecksum(opts), subdirs: &sync.Map{}, started: time.Now()}
+
+	if len(opts.Filter) > 0 {
+		match, err := compileFilter(opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %v", err)
+		}
+		i.match = match
+	}
+	if len(opts.Scope) > 0 {
+		match, err := compileFilter(opts.Scope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope: %v", err)
+		}
+		i.scope = match
+	}
+	if opts.Mask != nil {
+		m, deterministic := newMasker(opts.Mask.Salt)
+		if !deterministic {
+			i.log.Warn("⚠️  No mask salt configured; masked values will differ between runs (set salt or MASK_SALT)")
+		}
+		i.masker = m
+	}
+	if opts.SchemaFile != "" {
+		schema, err := loadSchemaFile(opts.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema file: %v", err)
+		}
+		i.schema = schema
+		i.opts.ValidateSchema = true
+	}
+
+	if opts.DateShift != nil {
+		i.dates = &dateShifter{opts: *opts.DateShift}
+	}
+	limiter, err := newRateLimiter(opts.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limiter != nil {
+		i.limiter = limiter
+		i.log.Info(fmt.Sprintf("🐢 Limiting writes to %s", opts.RateLimit),
+			"max_docs_per_sec", opts.RateLimit.DocsPerSec, "max_batches_per_sec", opts.RateLimit.BatchesPerSec)
+	}
+	if opts.ViewsFile != "" {
+		views, err := loadViews(opts.ViewsFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid views file: %v", err)
+		}
+		i.views = views
+	}
+
+	if opts.Server != nil {
+		i.targetServer = *opts.Server
+	} else if server, err := DetectServer(ctx, client); err == nil {
+		i.targetServer = server
+	} else if opts.Transactional {
+		return nil, fmt.Errorf("failed to detect transaction support: %v", err)
+	} else {
+		i.log.Warn(fmt.Sprintf("⚠️  Could not detect the server version: %v; assuming the server supports every requested feature", err), errAttr(err))
+	}
+	if opts.Transactional && !i.targetServer.Transactions() {
+		i.log.Warn(fmt.Sprintf("⚠️  %s does not support transactions; falling back to staging collection + rename", i.targetServer),
+			"server_version", i.targetServer.Version, "topology", i.targetServer.Topology)
+	}
+	if (opts.SkipInvalid || opts.SkipOversized) && opts.ErrorsFile != "" {
+		i.errorLog = newErrorLog(opts.ErrorsFile)
+	}
+	return i, nil
+}
+
+// Close 關閉錯誤檔
+func (i *Importer) Close() error {
+	if i.errorLog == nil {
+		return nil
+	}
+	return i.errorLog.Close()
+}
+
+// ImportPath 匯入單一檔案或整個目錄；path 也可以是 http(s):// 或 s3:// URL（s3 以 "/" 結尾時為 prefix）
+func (i *Importer) ImportPath(ctx context.Context, path string) ([]FileResult, error) {
+	if path == Stdin && i.opts.Archive {
+		return i.importArchive(ctx, path), nil
+	}
+	if path == Stdin {
+		if i.opts.Collection == "" {
+			return nil, errors.New("reading from stdin requires a collection")
+		}
+		res, _ := i.ImportFile(ctx, path)
+		return []FileResult{res}, nil
+	}
+	if remote.IsURL(path) {
+		if remote.IsPrefix(path) {
+			return i.ImportDir(ctx, path)
+		}
+		return i.importOne(ctx, path), nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return i.ImportDir(ctx, path)
+	}
+	return i.importOne(ctx, path), nil
+}
+
+// importOne 匯入單一檔案；.sql dump 每個 table、archive 每個 collection 各有一個結果
+func (i *Importer) importOne(ctx context.Context, filePath string) []FileResult {
+	if i.opts.Archive || dataExt(filePath) == archiveExt {
+		return i.importArchive(ctx, filePath)
+	}
+	if dataExt(filePath) == ".sql" {
+		return i.importSQLDump(ctx, filePath)
+	}
+	res, _ := i.ImportFile(ctx, filePath)
+	return []FileResult{res}
+}
+
+// ImportDir 依 Options.Concurrency 平行匯入目錄下的資料檔；
+// 只有無法讀取目錄時才回傳 error，個別檔案的錯誤記錄在 FileResult.Err
+func (i *Importer) ImportDir(ctx context.Context, dir string) ([]FileResult, error) {
+	if err := i.verifyManifest(ctx, dir); err != nil {
+		return nil, err
+	}
+	files, err := i.dirFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.checkSharedTargets(files); err != nil {
+		return nil, err
+	}
+	// 整個目錄以同一個平移量，collection 之間的日期才對得上；archive 要先拆開才能讀，由 importArchive 處理
+	if i.dates != nil {
+		var scan []string
+		for _, file := range files {
+			if dataExt(file) != archiveExt {
+				scan = append(scan, file)
+			}
+		}
+		if len(scan) > 0 {
+			if err := i.prepareDateShift(ctx, scan); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// .sql dump 的 table 與 archive 的 collection 在其他檔案之後依序匯入
+	var dumps []string
+	for n := 0; n < len(files); {
+		if ext := dataExt(files[n]); ext == ".sql" || ext == archiveExt {
+			dumps = append(dumps, files[n])
+			files = append(files[:n], files[n+1:]...)
+			continue
+		}
+		n++
+	}
+	var results []FileResult
+	failed := map[string]bool{} // 有檔案失敗或沒有執行的 collection
+	for _, wave := range i.dependencyWaves(files) {
+		if ctx.Err() != nil || (i.opts.FailFast && failedAny(results)) {
+			for _, file := range wave {
+				results = append(results, FileResult{File: file, NotRun: true})
+			}
+			continue
+		}
+		var run []string
+		for _, file := range wave {
+			db, coll := i.resolveTarget(file)
+			if dep := i.failedDependency(coll, failed); dep != "" {
+				i.log.Warn(fmt.Sprintf("⏭️  Skipping %s: collection %s depends on %s, which did not import", baseName(file), coll, dep),
+					"file", file, "collection", coll, "depends_on", dep)
+				failed[coll] = true
+				results = append(results, FileResult{File: file, DB: db, Collection: coll, NotRun: true})
+				continue
+			}
+			run = append(run, file)
+		}
+		waveResults := runWorkers(ctx, run, i.opts.Concurrency, i.opts.FailFast, func(file string) FileResult {
+			res, _ := i.ImportFile(ctx, file)
+			return res
+		})
+		for n, r := range waveResults {
+			if r.Err != nil || r.NotRun {
+				_, coll := i.resolveTarget(run[n])
+				failed[coll] = true
+			}
+		}
+		results = append(results, waveResults...)
+	}
+	for _, dump := range dumps {
+		if ctx.Err() != nil || (i.opts.FailFast && failedAny(results)) {
+			break
+		}
+		if dataExt(dump) == archiveExt {
+			results = append(results, i.importArchive(ctx, dump)...)
+			continue
+		}
+		results = append(results, i.importSQLDump(ctx, dump)...)
+	}
+	return results, nil
+}
+
+func failedAny(results []FileResult) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// dirFiles 目錄下要匯入的資料檔；目錄模式下 Collection 只挑出對應的檔案，Include / Exclude 過濾 collection 名稱
+func (i *Importer) dirFiles(ctx context.Context, dir string) ([]string, error) {
+	matches, err := listDataFiles(ctx, dir, i.opts.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory: %v", err)
+	}
+	var files []string
+	for _, file := range matches {
+		if i.isViewsFile(file) || isManifestFile(dir, file) {
+			continue
+		}
+		i.noteSubdir(dir, file)
+		if ext := dataExt(file); ext == ".sql" || ext == archiveExt {
+			// dump 內的 table 由 importSQLDump 過濾，archive 內的 collection 由 importArchive 過濾
+			files = append(files, file)
+			continue
+		}
+		_, coll := i.resolveTarget(file)
+		if i.opts.Collection != "" && coll != i.opts.Collection {
+			continue
+		}
+		if !i.selected(coll) {
+			i.log.Info(fmt.Sprintf("⏭️  Skipping %s: collection %s is excluded by the collection filters", baseName(file), coll),
+				"file", file, "collection", coll)
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// ErrSharedTarget 目錄內有多個檔案寫入同一個 collection，而它的 strategy 不能這樣匯入
+var ErrSharedTarget = errors.New("several files import into one collection")
+
+// checkSharedTargets 多個檔案（例如 export --keep-shards 的分段檔）寫入同一個 collection 時，truncate 會在每個檔案開始時
+// 再清空一次、只留下最後一個檔案，upsert 則讓並行匯入的檔案互相覆蓋；這兩種 strategy 拒絕匯入
+func (i *Importer) checkSharedTargets(files []string) error {
+	seen := map[string]string{}
+	for _, file := range files {
+		if ext := dataExt(file); ext == ".sql" || ext == archiveExt {
+			continue
+		}
+		db, name := i.resolveTarget(file)
+		coll := i.targetName(name)
+		strategy := i.opts.strategyFor(name)
+		if coll == "" || (strategy != StrategyTruncate && strategy != StrategyUpsert) {
+			continue
+		}
+		if db == "" {
+			db = i.opts.DB
+		}
+		ns := db + "." + coll
+		if prev, ok := seen[ns]; ok {
+			return fmt.Errorf("%w: %s and %s both import into %s, which the %s strategy does not support; use --strategy append (or merge) for that collection",
+				ErrSharedTarget, baseName(prev), baseName(file), ns, strategy)
+		}
+		seen[ns] = file
+	}
+	return nil
+}
+
+// Target ImportPath 會寫入的檔案與 collection
+type Target struct {
+	File       string
+	DB         string // 已套用預設的 database
+	Collection string
+	Strategy   string // 這個 collection 套用的 strategy
+}
+
+// Targets 列出 ImportPath(ctx, path) 會匯入的檔案與目標 collection，不寫入資料庫；推斷不出 collection 的檔案不列出
+func (i *Importer) Targets(ctx context.Context, path string) ([]Target, error) {
+	files := []string{path}
+	if path != Stdin {
+		dir := remote.IsPrefix(path)
+		if !remote.IsURL(path) {
+			fi, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			dir = fi.IsDir()
+		}
+		if dir {
+			var err error
+			if files, err = i.dirFiles(ctx, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var targets []Target
+	for _, file := range files {
+		if dataExt(file) == archiveExt || (i.opts.Archive && file == path) {
+			if file == Stdin {
+				// stdin 只能讀一次，不能先看 prelude
+				continue
+			}
+			archived, err := i.archiveTargets(ctx, file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read archive %s: %v", file, err)
+			}
+			targets = append(targets, archived...)
+			continue
+		}
+		db, coll := i.resolveTarget(file)
+		if db == "" {
+			db = i.opts.DB
+		}
+		if dataExt(file) == ".sql" {
+			tables, err := i.sqlTablesFor(ctx, file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read SQL dump %s: %v", file, err)
+			}
+			for _, table := range tables {
+				targets = append(targets, Target{File: file, DB: db, Collection: i.targetName(table), Strategy: i.opts.strategyFor(table)})
+			}
+			continue
+		}
+		if coll == "" {
+			continue
+		}
+		targets = append(targets, Target{File: file, DB: db, Collection: i.targetName(coll), Strategy: i.opts.strategyFor(coll)})
+	}
+	return targets, nil
+}
+
+// ImportFile 匯入單一檔案，回傳的 error 與 FileResult.Err 相同
+func (i *Importer) ImportFile(ctx context.Context, filePath string) (res FileResult, err error) {
+	// .sql dump 拆出的暫存檔只用來讀取，記錄、hook 與結果都以原本的 dump 為準
+	dataPath := filePath
+	filePath = i.sourceOf(filePath)
+	res = FileResult{File: filePath}
+	started := time.Now()
+	defer func() {
+		res.Duration = time.Since(started)
+		res.Interrupted = res.Err != nil && ctx.Err() != nil
+		if res.Err != nil && !res.Interrupted {
+			i.opts.Metrics.Error(res.Namespace())
+		}
+		err = res.Err
+	}()
+
+	db, name := i.resolveTarget(filePath)
+	res.DB, res.Collection = db, i.targetName(name)
+	coll := res.Collection
+	if coll == "" {
+		res.warn(i.log, fmt.Sprintf("⚠️  Skipping unrecognized file: %s", filePath), "file", filePath)
+		res.Skipped = true
+		return res, nil
+	}
+	res.strategy = i.opts.strategyFor(name)
+
+	i.log.Info(fmt.Sprintf("📥 Importing %s → collection: %s", baseName(filePath), res.Namespace()),
+		"file", filePath, "collection", res.Namespace())
+
+	in, err := openInput(ctx, dataPath, i.opts.DecryptKey)
+	if err != nil {
+		i.log.Error(fmt.Sprintf("❌ Failed to read file: %s (%v)", filePath, err), "file", filePath, errAttr(err))
+		res.Err = err
+		return res, err
+	}
+	defer in.Close()
+
+	if db == "" {
+		db = i.opts.DB
+	}
+	collection := i.client.Database(db).Collection(coll)
+
+	if i.opts.SkipUnchanged {
+		unchanged, checksum, err := i.checkUnchanged(ctx, collection.Database(), coll, dataPath)
+		if err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to check %s against %s: %v", filePath, metaCollection, err), "file", filePath, errAttr(err))
+			res.Err = err
+			return res, err
+		}
+		if unchanged {
+			i.log.Info(fmt.Sprintf("⏭️  Skipping unchanged file: %s", baseName(filePath)), "file", filePath, "collection", res.Namespace())
+			res.Unchanged = true
+			return res, nil
+		}
+		defer func() { i.finishImport(ctx, collection, filePath, checksum, &res) }()
+	}
+
+	if i.opts.Resume {
+		cp, err := i.startCheckpoint(ctx, collection, filePath, &res)
+		if err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to read checkpoint of %s: %v", filePath, err), "file", filePath, errAttr(err))
+			res.Err = err
+			return res, err
+		}
+		res.checkpoint = cp
+		defer func() {
+			if cp == nil || res.Err != nil {
+				return
+			}
+			if err := cp.clear(ctx); err != nil {
+				res.warn(i.log, fmt.Sprintf("⚠️  Failed to clear checkpoint of %s: %v", filePath, err), "file", filePath, errAttr(err))
+			}
+		}()
+	}
+
+	hooks := hooksFor(i.opts.Hooks, name, filePath)
+	if err := i.runHooks(ctx, hooks, "pre", collection, filePath, 0); err != nil {
+		i.log.Error(fmt.Sprintf("❌ %s: %v", baseName(filePath), err), "file", filePath, "collection", coll, errAttr(err))
+		res.Err = err
+		return res, err
+	}
+
+	err = i.withOpTimeout(ctx, "prepare "+coll, func(ctx context.Context) error {
+		return i.prepareCollection(ctx, collection, filePath, &res)
+	})
+	if err != nil {
+		i.log.Error(fmt.Sprintf("❌ Failed to prepare %s: %v", coll, err), "file", filePath, "collection", coll, errAttr(err))
+		res.Err = err
+		return res, err
+	}
+
+	var order *orderTracker
+	if i.opts.PreserveOrder {
+		order = &orderTracker{}
+	}
+	var docs docReader
+	docs, err = newDocReader(dataPath, in, i.opts, order)
+	if err != nil {
+		i.log.Error(fmt.Sprintf("❌ Failed to parse %s: %v", filePath, err), "file", filePath, errAttr(err))
+		res.Err = err
+		return res, err
+	}
+	parsed := &countReader{docReader: docs}
+	defer func() { res.Parsed = parsed.n }()
+	if i.opts.SkipOversized {
+		sized := newSizeReader(parsed, docs, filePath, i.errorLog, i.log)
+		defer func() {
+			res.Oversized = sized.oversized
+			if sized.oversized > 0 {
+				res.warnf("Skipped %d documents over 16MB in %s", sized.oversized, baseName(filePath))
+			}
+		}()
+		docs = sized
+	} else {
+		docs = parsed
+	}
+	if len(i.opts.OnlyFields) > 0 || len(i.opts.DropFields) > 0 {
+		docs = &projectReader{docReader: docs, only: i.opts.OnlyFields, drop: i.opts.DropFields}
+	}
+	if i.opts.Coerce != nil {
+		coerce := &coerceReader{docReader: docs, coercion: i.opts.Coerce}
+		defer func() {
+			if coerce.coerced > 0 {
+				i.log.Info(fmt.Sprintf("🔧 Coerced %d string values in %s", coerce.coerced, baseName(filePath)),
+					"file", filePath, "collection", coll, "coerced", coerce.coerced)
+			}
+		}()
+		docs = coerce
+	}
+	if i.dates != nil {
+		if err := i.prepareDateShift(ctx, []string{dataPath}); err != nil {
+			i.log.Error(fmt.Sprintf("❌ %v", err), "file", filePath, errAttr(err))
+			res.Err = err
+			return res, err
+		}
+		docs = &shiftReader{docReader: docs, offset: i.dates.offset}
+	}
+	if i.opts.Templates {
+		docs = &templateReader{docReader: docs, now: i.started}
+	}
+	if transforms := transformsFor(i.opts.Transforms, name); len(transforms) > 0 {
+		docs = &transformReader{docReader: docs, transforms: transforms}
+	}
+	if i.opts.TenantField != "" {
+		docs = &tenantReader{docReader: docs, field: i.opts.TenantField, value: i.opts.TenantValue}
+	}
+	if i.match != nil {
+		filter := &filterReader{docReader: docs, match: i.match}
+		defer func() {
+			res.Filtered = filter.filtered
+			if filter.filtered > 0 {
+				i.log.Info(fmt.Sprintf("🔎 Filtered out %d docs from %s", filter.filtered, baseName(filePath)),
+					"file", filePath, "collection", coll, "filtered", filter.filtered)
+			}
+		}()
+		docs = filter
+	}
+	if i.opts.DedupeBy != "" {
+		dedupe := newDedupeReader(docs, i.opts.DedupeBy, i.opts.DedupeKeep)
+		defer func() {
+			res.Deduped = dedupe.dropped
+			if dedupe.dropped > 0 {
+				res.warn(i.log, fmt.Sprintf("⚠️  Dropped %d duplicate docs from %s by %s, keeping the %s occurrence: %s",
+					dedupe.dropped, baseName(filePath), i.opts.DedupeBy, i.opts.DedupeKeep, dedupe.summary()),
+					"file", filePath, "collection", coll, "deduped", dedupe.dropped, "dedupe_by", i.opts.DedupeBy)
+			}
+		}()
+		docs = dedupe
+	}
+	if i.masker != nil {
+		if fields := i.opts.Mask.fieldsFor(name); len(fields) > 0 {
+			docs = newMaskReader(docs, i.masker, fields)
+		}
+	}
+	if i.opts.StampHashField != "" || i.opts.StampTimeField != "" {
+		docs = i.newStampReader(docs)
+	}
+	if i.scope != nil && res.strategy == StrategyTruncate {
+		scope := &scopeReader{docReader: docs, match: i.scope}
+		defer func() {
+			if scope.outside > 0 {
+				res.warn(i.log, fmt.Sprintf("⚠️  %d docs of %s do not match the scope; the next scoped refresh will not replace them", scope.outside, baseName(filePath)),
+					"file", filePath, "collection", coll, "outside_scope", scope.outside)
+			}
+		}()
+		docs = scope
+	}
+	// 缺少 shard key 的文件全部落在 null 的 chunk；只有 sharded cluster（或偵測不到拓撲）才讀取 config.collections，讀不到（例如沒有權限）時不檢查。
+	// 要依 options.json 重建的 collection 重建後不是 sharded
+	if res.recreate == nil && (!i.targetServer.known() || i.targetServer.Topology == TopologySharded) {
+		if key, err := shardKeyFor(ctx, collection); err != nil {
+			i.log.Debug(fmt.Sprintf("Could not read the shard key of %s: %v", coll, err), "collection", coll, errAttr(err))
+		} else if len(key) > 0 {
+			i.log.Info(fmt.Sprintf("🧩 %s is sharded on %s", res.Namespace(), shardKeyString(key)), "collection", res.Namespace(), "shard_key", shardKeyString(key))
+			res.shardKey = key
+			docs = &shardKeyReader{docReader: docs, key: key}
+		}
+	}
+	if i.opts.ValidateSchema {
+		schema, err := i.schemaFor(ctx, collection, &res)
+		if err != nil {
+			i.log.Error(fmt.Sprintf("❌ %v", err), "file", filePath, "collection", coll, errAttr(err))
+			res.Err = err
+			return res, err
+		}
+		if schema != nil {
+			docs = &schemaReader{docReader: docs, schema: newSchemaValidator(schema)}
+		}
+	}
+	if i.opts.SkipInvalid {
+		skipper := &skipInvalidReader{docReader: docs, file: filePath, log: i.errorLog, logger: i.log}
+		defer func() {
+			res.Invalid = skipper.skipped
+			if skipper.skipped > 0 {
+				res.warnf("Skipped %d invalid documents in %s", skipper.skipped, baseName(filePath))
+			}
+		}()
+		docs = skipper
+	}
+
+	if cp := res.checkpoint; cp != nil && insertsIDs(res.strategy) {
+		docs = &idReader{docReader: docs, seed: cp.doc.IDSeed}
+	}
+	if cp := res.checkpoint; cp != nil && cp.resumed > 0 {
+		docs = &skipDocsReader{docReader: docs, n: cp.resumed}
+	}
+	if order != nil {
+		docs = &orderedReader{docReader: docs, order: order}
+	}
+
+	prog := newProgress(i.log, coll, in, i.opts.Quiet)
+	write := i.writeDocuments
+	switch {
+	case i.opts.AtomicSwap:
+		write = i.writeViaStaging
+	case i.opts.Transactional:
+		write = i.writeAtomically
+	}
+	if err := write(ctx, collection, docs, prog, &res); err != nil {
+		res.Err = err
+		return res, err
+	}
+
+	// 資料載入後建立 sidecar 定義的索引（staging 流程已在 rename 前建好）
+	if !res.staged {
+		if err := i.applyIndexSidecar(ctx, collection, filePath); err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to create indexes on %s: %v", coll, err), "file", filePath, "collection", coll, errAttr(err))
+			res.Err = err
+			return res, err
+		}
+	}
+
+	if err := i.runHooks(ctx, hooks, "post", collection, filePath, res.Docs); err != nil {
+		i.log.Error(fmt.Sprintf("❌ %s: %v", baseName(filePath), err), "file", filePath, "collection", coll, errAttr(err))
+		res.Err = err
+		return res, err
+	}
+	return res, nil
+}
+
+// saveCheckpoint 記錄失敗只是中斷後無法接續，不中止匯入
+func (i *Importer) saveCheckpoint(ctx context.Context, res *FileResult) {
+	if res.checkpoint == nil {
+		return
+	}
+	// 中斷時最後一批的進度也要記下，下次才能從這裡接續
+	ctx, cancel := cleanupContext(ctx)
+	defer cancel()
+	if err := res.checkpoint.save(ctx, res.Docs); err != nil {
+		res.warn(i.log, fmt.Sprintf("⚠️  Failed to save checkpoint of %s: %v", res.File, err), "file", res.File, errAttr(err))
+	}
+}
+
+// finishImport 成功匯入後記錄 checksum；記錄失敗只是下次不能略過，不影響這次的結果
+func (i *Importer) finishImport(ctx context.Context, collection *mongo.Collection, filePath, checksum string, res *FileResult) {
+	if checksum == "" || res.Err != nil {
+		return
+	}
+	if err := i.recordChecksum(ctx, collection.Database(), collection.Name(), filePath, checksum, res.Docs); err != nil {
+		res.warn(i.log, fmt.Sprintf("⚠️  Failed to record checksum of %s: %v", filePath, err), "file", filePath, errAttr(err))
+	}
+}
+
+// writeDocuments 依檔案的 strategy 把 docs 寫進 collection，並累計 res.Docs
+func (i *Importer) writeDocuments(ctx context.Context, collection *mongo.Collection, docs docReader, prog *progress, res *FileResult) error {
+	coll := collection.Name()
+
+	if res.strategy == StrategyUpsert || res.strategy == StrategyMerge || res.strategy == StrategyDelete {
+		verb, done := "upsert", "Upserted"
+		write := func(ctx context.Context, batch []interface{}) (*mongo.BulkWriteResult, error) {
+			return upsertDocuments(ctx, collection, batch, i.opts.KeyField)
+		}
+		if res.strategy == StrategyMerge {
+			verb, done = "merge", "Merged"
+			write = func(ctx context.Context, batch []interface{}) (*mongo.BulkWriteResult, error) {
+				return mergeDocuments(ctx, collection, batch, i.opts.KeyField, i.opts.MergeUpdate)
+			}
+		}
+		if res.strategy == StrategyDelete {
+			verb, done = "delete", "Deleted"
+			write = func(ctx context.Context, batch []interface{}) (*mongo.BulkWriteResult, error) {
+				return deleteDocuments(ctx, collection, batch, i.opts.KeyField)
+			}
+		}
+
+		bw := &mongo.BulkWriteResult{}
+		var mu sync.Mutex
+		err := forEachBatch(docs, i.opts.BatchSize, i.opts.InsertWorkers, prog, func(batch []interface{}) error {
+			if err := i.limiter.wait(ctx, len(batch)); err != nil {
+				return err
+			}
+			var r *mongo.BulkWriteResult
+			same := 0
+			started := time.Now()
+			err := i.withRetry(ctx, verb+" into "+coll, func(ctx context.Context) (err error) {
+				pending := batch
+				if i.opts.StampHashField != "" && res.strategy != StrategyDelete {
+					if pending, same, err = i.dropUnchanged(ctx, collection, batch); err != nil {
+						return err
+					}
+				}
+				r, err = write(ctx, pending)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			i.opts.Metrics.Batch(time.Since(started))
+			i.opts.Metrics.Docs(res.Namespace(), verb, len(batch)-same)
+			mu.Lock()
+			defer mu.Unlock()
+			res.UnchangedDocs += same
+			bw.InsertedCount += r.InsertedCount
+			bw.UpsertedCount += r.UpsertedCount
+			bw.MatchedCount += r.MatchedCount
+			bw.ModifiedCount += r.ModifiedCount
+			bw.DeletedCount += r.DeletedCount
+			res.Docs += len(batch)
+			i.saveCheckpoint(ctx, res)
+			return nil
+		})
+		if err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to %s into %s: %v", verb, coll, err), "collection", coll, errAttr(err))
+			return err
+		}
+		if res.strategy == StrategyDelete {
+			i.log.Info(fmt.Sprintf("✅ Deleted %d of %d docs from %s (%d had no matching document, %s)",
+				bw.DeletedCount, res.Docs, coll, int64(res.Docs)-bw.DeletedCount, prog.rate()),
+				"collection", coll, "count", res.Docs, "deleted", bw.DeletedCount, "docs_per_sec", prog.docsPerSec())
+			return nil
+		}
+		if res.UnchangedDocs > 0 {
+			i.log.Info(fmt.Sprintf("⏭️  Skipped %d unchanged docs in %s", res.UnchangedDocs, coll),
+				"collection", coll, "unchanged", res.UnchangedDocs)
+		}
+		i.log.Info(fmt.Sprintf("✅ %s %d docs into %s (inserted %d, matched %d, modified %d, %s)",
+			done, res.Docs, coll, bw.InsertedCount+bw.UpsertedCount, bw.MatchedCount, bw.ModifiedCount, prog.rate()),
+			"collection", coll, "count", res.Docs, "inserted", bw.InsertedCount+bw.UpsertedCount,
+			"matched", bw.MatchedCount, "modified", bw.ModifiedCount, "docs_per_sec", prog.docsPerSec())
+		return nil
+	}
+
+	// 清空舊資料（append 不清空，有 Scope 時只刪除範圍內的文件）；接續中斷的匯入時保留已寫入的部分，
+	// 有 options.json 時改為 drop 後以新的選項重建
+	cp := res.checkpoint
+	partial := false // 清空時還沒解析完整個檔案，而且不在 transaction / 暫存 collection 內
+	if res.strategy == StrategyTruncate && (cp == nil || cp.resumed == 0) {
+		// 先解析第一批，檔案開頭就無法解析（格式錯誤、金鑰錯誤）時保留舊資料；之後的批次解析失敗時只有
+		// transaction 或 atomic swap 能保留舊資料
+		size := i.opts.BatchSize
+		if size <= 0 {
+			size = DefaultBatchSize
+		}
+		var err error
+		var complete bool
+		if docs, complete, err = prefetch(docs, size); err != nil {
+			i.log.Error(fmt.Sprintf("❌ Not clearing %s: %v", coll, err), "collection", coll, errAttr(err))
+			return err
+		}
+		if !complete && !i.opts.Transactional && !i.opts.AtomicSwap {
+			partial = true
+			i.log.Info(fmt.Sprintf("🔓 Clearing %s after parsing its first %d docs; truncate is not atomic, so an error later in the file leaves it partially loaded (use --atomic-swap to keep the old data until the whole file is imported)", coll, size),
+				"collection", coll, "parsed", size)
+		}
+		if res.recreate != nil {
+			err = i.withOpTimeout(ctx, "recreate "+coll, func(ctx context.Context) error {
+				return i.recreateCollection(ctx, collection, res)
+			})
+		} else {
+			err = i.withRetry(ctx, "clear "+coll, func(ctx context.Context) error {
+				_, err := collection.DeleteMany(ctx, i.clearFilter())
+				return err
+			})
+		}
+		if err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to clear collection %s: %v", coll, err), "collection", coll, errAttr(err))
+			return err
+		}
+	}
+
+	if i.opts.PreSplit > 1 && len(res.shardKey) > 0 {
+		if err := i.preSplit(ctx, collection, res.File, res.shardKey, res); err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to pre-split %s: %v", coll, err), "collection", coll, errAttr(err))
+			return err
+		}
+	}
+
+	// 插入新資料（分批，避免單次超過 16MB）；平行寫入時批次之間本來就沒有順序，批次內也不需要
+	insertOpts := options.InsertMany()
+	if i.opts.Unordered || i.opts.IgnoreDuplicates || i.opts.InsertWorkers > 1 {
+		insertOpts.SetOrdered(false)
+	}
+	var mu sync.Mutex
+	err := forEachBatch(docs, i.opts.BatchSize, i.opts.InsertWorkers, prog, func(batch []interface{}) error {
+		if err := i.limiter.wait(ctx, len(batch)); err != nil {
+			return err
+		}
+		dups, attempt := 0, 0
+		assignIDs(batch)
+		sent := map[int]bool{} // 之前失敗的嘗試可能已經寫入的文件
+		started := time.Now()
+		err := i.withRetry(ctx, "insert into "+coll, func(ctx context.Context) error {
+			dups = 0
+			attempt++
+			if cp != nil && cp.pending {
+				_, err := collection.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+				if duplicateKeyErrors(err) > 0 {
+					return nil
+				}
+				return err
+			}
+			opts := insertOpts
+			if attempt > 1 {
+				// 上一次嘗試可能已經寫入一部分：其餘的文件照樣寫入，已經寫入的以 duplicate key 略過
+				opts = options.InsertMany().SetOrdered(false)
+			}
+			_, sendErr := collection.InsertMany(ctx, batch, opts)
+			err := sendErr
+			if attempt > 1 {
+				err = dropRetriedDuplicates(err, sent)
+			}
+			markSent(sent, len(batch), sendErr)
+			if i.opts.IgnoreDuplicates {
+				if dups = duplicateKeyErrors(err); dups > 0 {
+					return nil
+				}
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		i.opts.Metrics.Batch(time.Since(started))
+		i.opts.Metrics.Docs(res.Namespace(), "insert", len(batch)-dups)
+		mu.Lock()
+		defer mu.Unlock()
+		if cp != nil {
+			cp.pending = false
+		}
+		res.Docs += len(batch) - dups
+		res.Duplicates += dups
+		i.saveCheckpoint(ctx, res)
+		return nil
+	})
+	if res.Duplicates > 0 {
+		i.log.Info(fmt.Sprintf("⏭️  Skipped %d duplicate docs in %s", res.Duplicates, coll),
+			"collection", coll, "duplicates", res.Duplicates)
+	}
+	if err != nil {
+		i.log.Error(fmt.Sprintf("❌ Failed to insert into %s: %v", coll, err), "collection", coll, errAttr(err))
+		if partial && ctx.Err() == nil { // 中斷時由 summary 提示
+			res.warn(i.log, fmt.Sprintf("⚠️  %s was cleared and now holds only the %d docs imported before the error; fix the file and run again, or use --atomic-swap to keep the old data on failure", coll, res.Docs),
+				"collection", coll, "count", res.Docs)
+		}
+		return err
+	}
+	i.log.Info(fmt.Sprintf("✅ Inserted %d docs into %s (%s)", res.Docs, coll, prog.rate()),
+		"collection", coll, "count", res.Docs, "docs_per_sec", prog.docsPerSec())
+	return nil
+}
+
+// resolveTarget 決定檔案要匯入哪個 database / collection；db 為空字串表示使用 Options.DB
+func (i *Importer) resolveTarget(filePath string) (db, coll string) {
+	if m, ok := matchMapping(i.opts.Mappings, filePath); ok {
+		db, coll = m.DB, m.Collection
+	}
+	if db == "" {
+		if v, ok := i.subdirs.Load(filePath); ok {
+			db = v.(string)
+		}
+	}
+	if db == "" && i.opts.DBFromFilename {
+		var c string
+		db, c = splitNamespaceFilename(filePath)
+		if coll == "" {
+			coll = c
+		}
+	}
+	if i.opts.Collection != "" {
+		coll = i.opts.Collection
+	}
+	if coll == "" {
+		coll = extractCollectionName(filePath)
+	}
+	return db, coll
+}
+
+// noteSubdir Recursive 時記下 root 子目錄下的檔案要匯入的 database，供 resolveTarget 使用
+func (i *Importer) noteSubdir(root, file string) {
+	if !i.opts.Recursive {
+		return
+	}
+	if db := subdirDB(root, file); db != "" {
+		i.subdirs.Store(file, db)
+	}
+}
+
+// splitNamespaceFilename 依 mongodump 慣例拆出 <db>.<collection>.json（或其他資料格式）；collection 本身可以含有 "."
+func splitNamespaceFilename(filePath string) (db, coll string) {
+	name := trimCompressionExt(baseName(filePath))
+	name = trimShardSuffix(strings.TrimSuffix(name, dataExt(filePath)))
+	db, coll, ok := strings.Cut(name, ".")
+	if !ok || db == "" || coll == "" {
+		return "", ""
+	}
+	return db, coll
+}
+
+// extractCollectionName 檔名去掉副檔名與平行匯出的分段編號後的最後一段，例如 dex.users.json、users.shard-0001.json → users
+func extractCollectionName(filePath string) string {
+	ext := dataExt(filePath)
+	if ext == "" {
+		return ""
+	}
+	name := trimShardSuffix(strings.TrimSuffix(trimCompressionExt(baseName(filePath)), ext))
+	parts := strings.Split(name, ".")
+	return parts[len(parts)-1]
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
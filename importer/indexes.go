@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// applyIndexSidecar 讀取 <collection>.indexes.json 並建立索引；沒有 sidecar 檔（或從 stdin 讀取）時什麼都不做
+func (i *Importer) applyIndexSidecar(ctx context.Context, coll *mongo.Collection, filePath string) error {
+	if filePath == Stdin {
+		return nil
+	}
+	path := sidecarPath(filePath, ".indexes.json")
+	specs, err := loadIndexSpecs(ctx, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+	specs = i.degradeIndexSpecs(specs, path)
+	if err := createIndexes(ctx, coll, specs); err != nil {
+		return err
+	}
+	i.log.Info(fmt.Sprintf("🔑 Created %d indexes on %s from %s", len(specs), coll.Name(), path),
+		"collection", coll.Name(), "file", path, "count", len(specs))
+	return nil
+}
+
+// loadIndexSpecs 格式為 createIndexes 的 index spec 陣列，例如
+// [{"key": {"email": 1}, "name": "email_1", "unique": true}]；
+// 也接受 mongodump metadata.json 那種 {"indexes": [...]}
+func loadIndexSpecs(ctx context.Context, path string) ([]bson.D, error) {
+	var data []byte
+	var err error
+	if remote.IsURL(path) {
+		data, err = remote.ReadFile(ctx, path)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		// bson 不能直接解析最外層是 array 的 JSON，包一層再拆
+		data = append(append([]byte(`{"indexes":`), data...), '}')
+	}
+	var wrapper struct {
+		Indexes []bson.D `bson:"indexes"`
+	}
+	if err := bson.UnmarshalExtJSON(data, false, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	specs := wrapper.Indexes
+
+	out := make([]bson.D, 0, len(specs))
+	for i, spec := range specs {
+		spec, err := normalizeIndexSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: index %d: %v", path, i+1, err)
+		}
+		if spec != nil {
+			out = append(out, spec)
+		}
+	}
+	return out, nil
+}
+
+// normalizeIndexSpec 檢查 key、補上預設名稱並移除 v / ns；_id 索引回傳 nil（server 會自動建立）
+func normalizeIndexSpec(spec bson.D) (bson.D, error) {
+	var key bson.D
+	var name string
+	out := bson.D{}
+	for _, e := range spec {
+		switch e.Key {
+		case "v", "ns":
+			continue
+		case "key":
+			k, ok := e.Value.(bson.D)
+			if !ok || len(k) == 0 {
+				return nil, fmt.Errorf("key must be a non-empty document")
+			}
+			key = k
+		case "name":
+			name, _ = e.Value.(string)
+		}
+		out = append(out, e)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("missing key")
+	}
+	if name == "_id_" || (len(key) == 1 && key[0].Key == "_id" && name == "") {
+		return nil, nil
+	}
+	if name == "" {
+		out = append(out, bson.E{Key: "name", Value: defaultIndexName(key)})
+	}
+	return out, nil
+}
+
+// defaultIndexName 跟 server 預設規則一樣：email_1_createdAt_-1
+func defaultIndexName(key bson.D) string {
+	parts := make([]string, 0, len(key)*2)
+	for _, e := range key {
+		parts = append(parts, e.Key, fmt.Sprint(e.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// createIndexes 直接下 createIndexes 指令，spec 內的所有選項（unique、partialFilterExpression、collation…）原樣傳給 server
+func createIndexes(ctx context.Context, coll *mongo.Collection, specs []bson.D) error {
+	cmd := bson.D{
+		{Key: "createIndexes", Value: coll.Name()},
+		{Key: "indexes", Value: specs},
+	}
+	return coll.Database().RunCommand(ctx, cmd).Err()
+}
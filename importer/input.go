@@ -0,0 +1,305 @@
+package importer
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hayletdomybest/mongo-tools/internal/crypt"
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionExts 可自動解壓的副檔名
+var compressionExts = []string{".gz", ".zst", ".zstd"}
+
+// trimCompressionExt 去掉加密與壓縮副檔名，例如 users.json.gz → users.json、users.bson.enc → users.bson
+func trimCompressionExt(name string) string {
+	name = strings.TrimSuffix(name, crypt.Ext)
+	for _, ext := range compressionExts {
+		if strings.HasSuffix(name, ext) {
+			return strings.TrimSuffix(name, ext)
+		}
+	}
+	return name
+}
+
+// shardMarker export --parallel --keep-shards 分段檔名的編號前綴，例如 users.shard-0001.json
+const shardMarker = ".shard-"
+
+// trimShardSuffix 去掉分段編號，例如 users.shard-0001 → users；logs.2024 之類的一般檔名不變
+func trimShardSuffix(name string) string {
+	i := strings.LastIndex(name, shardMarker)
+	if i <= 0 {
+		return name
+	}
+	if n := name[i+len(shardMarker):]; len(n) != 4 || strings.Trim(n, "0123456789") != "" {
+		return name
+	}
+	return name[:i]
+}
+
+// Stdin 作為路徑時從標準輸入讀取 Extended JSON（array 或 NDJSON），需要指定 Options.Collection
+const Stdin = "-"
+
+// baseName 檔名；URL 時去掉 query string
+func baseName(filePath string) string {
+	if filePath == Stdin {
+		return "stdin"
+	}
+	if remote.IsURL(filePath) {
+		return remote.Base(filePath)
+	}
+	return filepath.Base(filePath)
+}
+
+// openInput 開啟輸入檔（本機檔案或 http(s):// / s3:// URL），依副檔名透明解密（.enc，使用 key）並解壓 gzip / zstd
+func openInput(ctx context.Context, filePath string, key []byte) (*inputFile, error) {
+	var f io.ReadCloser
+	var size int64
+	if filePath == Stdin {
+		f = io.NopCloser(os.Stdin)
+	} else if remote.IsURL(filePath) {
+		body, n, err := remote.Open(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		f, size = body, n
+	} else {
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if fi, err := file.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+		f = file
+	}
+	in := &inputFile{counter: &countingReader{r: f}, closers: []io.Closer{f}, size: max(size, 0)}
+
+	var raw io.Reader = in.counter
+	name := baseName(filePath)
+	if strings.HasSuffix(name, crypt.Ext) {
+		if key == nil {
+			f.Close()
+			return nil, errors.New("file is encrypted and no decryption key was given")
+		}
+		dec, err := crypt.NewReader(in.counter, key)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		raw, name = dec, strings.TrimSuffix(name, crypt.Ext)
+	}
+
+	switch filepath.Ext(name) {
+	case ".gz":
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		in.Reader = gz
+		in.closers = append([]io.Closer{gz}, in.closers...)
+	case ".zst", ".zstd":
+		zr, err := zstd.NewReader(raw)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		in.Reader = zr
+		in.closers = append([]io.Closer{zr.IOReadCloser()}, in.closers...)
+	default:
+		in.Reader = raw
+	}
+	return in, nil
+}
+
+// inputFile 關閉時一併關閉解壓器與底層檔案；Progress 以（壓縮前的）檔案位元組估算進度
+type inputFile struct {
+	io.Reader
+	size    int64
+	counter *countingReader
+	closers []io.Closer
+}
+
+func (f *inputFile) Close() error {
+	var first error
+	for _, c := range f.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Progress 回傳已讀取與總共的位元組數；size 為 0 表示無法得知
+func (f *inputFile) Progress() (read, size int64) {
+	return f.counter.n.Load(), f.size
+}
+
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// dataExts 可匯入的資料格式
+var dataExts = []string{".json", ".bson", ".csv", ".tsv", ".yaml", ".yml", ".sql", archiveExt}
+
+// dataExt 回傳去掉壓縮副檔名後的資料格式副檔名，不認得時回傳空字串
+func dataExt(filePath string) string {
+	ext := filepath.Ext(trimCompressionExt(baseName(filePath)))
+	for _, e := range dataExts {
+		if ext == e {
+			return e
+		}
+	}
+	return ""
+}
+
+// sidecarSuffixes 跟資料檔放在一起的附屬檔（mongodump 的 metadata、索引定義、collection 選項），不是資料檔
+var sidecarSuffixes = []string{".metadata.json", ".indexes.json", ".options.json"}
+
+func isSidecarFile(filePath string) bool {
+	name := trimCompressionExt(baseName(filePath))
+	for _, suffix := range sidecarSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sidecarPath 資料檔對應的附屬檔路徑，例如 users.json.gz、users.shard-0001.json → users.indexes.json
+func sidecarPath(filePath, suffix string) string {
+	name := trimCompressionExt(baseName(filePath))
+	name = trimShardSuffix(strings.TrimSuffix(name, dataExt(filePath)))
+	if remote.IsURL(filePath) {
+		return remote.Dir(filePath) + name + suffix
+	}
+	return filepath.Join(filepath.Dir(filePath), name+suffix)
+}
+
+// newDocReader 依副檔名選擇解析器；order 不為 nil 時 JSON、BSON、YAML 與 SQL 檔記下每筆文件的欄位順序
+func newDocReader(filePath string, r io.Reader, opts Options, order *orderTracker) (docReader, error) {
+	if strings.HasSuffix(trimCompressionExt(filePath), sqlSpoolExt) {
+		// importSQLDump 拆出的 table
+		return newSQLSpoolReader(r, opts.Collection, order), nil
+	}
+	switch dataExt(filePath) {
+	case ".bson":
+		return newBSONReader(r, strictnessOf(opts), order), nil
+	case archiveExt:
+		return nil, fmt.Errorf("%s is a mongodump archive holding several collections; only import can restore it", baseName(filePath))
+	case ".csv":
+		return newCSVReader(r, ',', opts.CSV, strictnessOf(opts))
+	case ".tsv":
+		return newCSVReader(r, '\t', opts.CSV, strictnessOf(opts))
+	case ".yaml", ".yml":
+		return newYAMLReader(r, strictnessOf(opts), order), nil
+	case ".sql":
+		// dump 內與 collection 同名的 table
+		table := opts.Collection
+		if table == "" {
+			table = extractCollectionName(filePath)
+		}
+		return newSQLReader(r, table, order), nil
+	}
+	return newExtJSONReader(r, opts.ExtJSONMode, strictnessOf(opts), order)
+}
+
+// listDataFiles 列出目錄（或 s3:// prefix）下可匯入的檔案（含壓縮與加密檔），依路徑排序；recursive 時包含子目錄
+func listDataFiles(ctx context.Context, dir string, recursive bool) ([]string, error) {
+	var files []string
+	if remote.IsURL(dir) {
+		objects, err := remote.List(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(dir, "/") + "/"
+		for _, o := range objects {
+			// 與本機目錄一樣，recursive 時才往子目錄找
+			rest := strings.TrimPrefix(o, base)
+			if (!recursive && strings.Contains(rest, "/")) || dataExt(o) == "" || isSidecarFile(o) {
+				continue
+			}
+			files = append(files, o)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	if recursive {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				// 略過 .git 之類的隱藏目錄
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() && dataExt(path) != "" && !isSidecarFile(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+	for _, ext := range dataExts {
+		for _, suffix := range append([]string{""}, compressionExts...) {
+			for _, enc := range []string{"", crypt.Ext} {
+				matches, err := filepath.Glob(filepath.Join(dir, "*"+ext+suffix+enc))
+				if err != nil {
+					return nil, err
+				}
+				for _, m := range matches {
+					if !isSidecarFile(m) {
+						files = append(files, m)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// subdirDB file 在 root 的子目錄下時回傳第一層子目錄的名稱，對應 mongodump 的 <out>/<db>/<collection>.bson；
+// 直接放在 root 下的檔案回傳空字串
+func subdirDB(root, file string) string {
+	var rel string
+	if remote.IsURL(root) {
+		rel = strings.TrimPrefix(file, strings.TrimSuffix(root, "/")+"/")
+	} else {
+		r, err := filepath.Rel(root, file)
+		if err != nil {
+			return ""
+		}
+		rel = filepath.ToSlash(r)
+	}
+	db, _, ok := strings.Cut(rel, "/")
+	if !ok || db == ".." {
+		return ""
+	}
+	return db
+}
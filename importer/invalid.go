@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// parseError 單筆文件解析失敗；讀取器可以略過它繼續往下讀
+type parseError struct {
+	Pos string // 例如 "line 12" 或 "element 3"
+	Raw string
+	Err error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *parseError) Unwrap() error { return e.Err }
+
+// errorLog 把無效的文件寫入錯誤檔，多個 worker 共用
+type errorLog struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+func newErrorLog(path string) *errorLog {
+	return &errorLog{path: path}
+}
+
+// Record 第一次寫入時才建立檔案，沒有錯誤就不會留下空檔
+func (l *errorLog) Record(file string, pe *parseError) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		f, err := os.Create(l.path)
+		if err != nil {
+			return err
+		}
+		l.f = f
+	}
+	raw := pe.Raw
+	if len(raw) > 200 {
+		raw = raw[:200] + "..."
+	}
+	_, err := fmt.Fprintf(l.f, "%s:%s: %v\t%s\n", file, pe.Pos, pe.Err, raw)
+	return err
+}
+
+func (l *errorLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	return l.f.Close()
+}
+
+// skipInvalidReader 略過解析失敗的文件，記錄到 errorLog 並計數
+type skipInvalidReader struct {
+	docReader
+	file    string
+	log     *errorLog
+	logger  *slog.Logger
+	skipped int
+}
+
+func (s *skipInvalidReader) Next() (bson.M, error) {
+	for {
+		doc, err := s.docReader.Next()
+		var pe *parseError
+		if !errors.As(err, &pe) {
+			return doc, err
+		}
+		s.skipped++
+		s.logger.Warn(fmt.Sprintf("⚠️  Skipping invalid document in %s %s: %v", s.file, pe.Pos, pe.Err),
+			"file", s.file, "position", pe.Pos, errAttr(pe.Err))
+		if s.log != nil {
+			if err := s.log.Record(s.file, pe); err != nil {
+				return nil, fmt.Errorf("failed to write errors file: %v", err)
+			}
+		}
+	}
+}
+
+// countReader 計算從檔案讀出的文件數；解析失敗的文件也算一筆
+type countReader struct {
+	docReader
+	n int
+}
+
+func (c *countReader) Next() (bson.M, error) {
+	doc, err := c.docReader.Next()
+	var pe *parseError
+	if err == nil || errors.As(err, &pe) {
+		c.n++
+	}
+	return doc, err
+}
@@ -0,0 +1,87 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/hayletdomybest/mongo-tools/internal/manifest"
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+)
+
+// ErrManifest 目錄的 manifest.json 與檔案不符（被修改、缺少檔案、多出檔案或 export 沒有完成）
+var ErrManifest = errors.New("the directory does not match its manifest")
+
+// verifyManifest 本機目錄有 export 寫出的 manifest.json 時，匯入前檢查每個檔案的大小與 SHA-256、
+// 缺少或多出的資料檔與 sidecar（含子目錄），以及 export 是否完整；NoVerify 時只記錄警告
+func (i *Importer) verifyManifest(ctx context.Context, dir string) error {
+	if remote.IsURL(dir) {
+		return nil
+	}
+	m, err := manifest.Load(dir)
+	if err == nil && m == nil {
+		return nil
+	}
+	path := filepath.Join(dir, manifest.File)
+	if i.opts.NoVerify {
+		i.log.Warn(fmt.Sprintf("⚠️  Not verifying %s", path), "manifest", path)
+		return nil
+	}
+	if err == nil {
+		err = m.Verify(dir)
+		files, lerr := manifestCandidates(dir)
+		if lerr != nil {
+			return lerr
+		}
+		var extra []error
+		for _, rel := range files {
+			if _, ok := m.Lookup(rel); !ok && !isManifestFile(dir, filepath.Join(dir, filepath.FromSlash(rel))) {
+				extra = append(extra, fmt.Errorf("%s is not in %s", rel, manifest.File))
+			}
+		}
+		err = errors.Join(append([]error{err}, extra...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrManifest, err)
+	}
+	i.log.Info(fmt.Sprintf("🔏 Verified %d files against %s", len(m.Files), path), "manifest", path, "files", len(m.Files))
+	return nil
+}
+
+// manifestCandidates 目錄下（含子目錄）import 可能讀取的資料檔與 sidecar，回傳以 / 分隔的相對路徑；與 --recursive 一樣略過隱藏目錄
+func manifestCandidates(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || (dataExt(path) == "" && !isSidecarFile(path)) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+// isManifestFile 目錄最上層 export 寫出的 manifest.json 不是資料檔；同名但不是 manifest 的檔案照常匯入
+func isManifestFile(dir, file string) bool {
+	if filepath.Base(file) != manifest.File || filepath.Dir(file) != filepath.Clean(dir) {
+		return false
+	}
+	m, err := manifest.Load(dir)
+	return err == nil && m != nil
+}
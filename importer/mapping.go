@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping 把符合 Match（路徑或 glob）的檔案導到指定的 collection / database
+type Mapping struct {
+	Match      string `json:"match" yaml:"match"`
+	Collection string `json:"collection" yaml:"collection"`
+	DB         string `json:"db" yaml:"db"`
+}
+
+type mappingFile struct {
+	Mappings []Mapping `json:"mappings" yaml:"mappings"`
+}
+
+// LoadMappings 讀取 YAML 或 JSON 格式的對應檔（依副檔名判斷）
+func LoadMappings(path string) ([]Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mf mappingFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &mf)
+	} else {
+		err = yaml.Unmarshal(data, &mf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mapping file %s: %v", path, err)
+	}
+
+	for i, m := range mf.Mappings {
+		if m.Match == "" {
+			return nil, fmt.Errorf("mapping %d: match is required", i+1)
+		}
+		if m.Collection == "" && m.DB == "" {
+			return nil, fmt.Errorf("mapping %d (%s): collection or db is required", i+1, m.Match)
+		}
+		if _, err := filepath.Match(m.Match, ""); err != nil {
+			return nil, fmt.Errorf("mapping %d: invalid pattern %q: %v", i+1, m.Match, err)
+		}
+	}
+	return mf.Mappings, nil
+}
+
+// matchMapping 依序比對，第一條符合的規則生效；pattern 可比對完整路徑或檔名
+func matchMapping(mappings []Mapping, filePath string) (Mapping, bool) {
+	base := baseName(filePath)
+	for _, m := range mappings {
+		if ok, _ := filepath.Match(m.Match, filePath); ok {
+			return m, true
+		}
+		if ok, _ := filepath.Match(m.Match, base); ok {
+			return m, true
+		}
+	}
+	return Mapping{}, false
+}
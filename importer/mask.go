@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// 遮罩方式
+const (
+	MaskHash     = "hash"     // HMAC-SHA256 的 hex，同樣的值得到同樣的結果，可以保留關聯
+	MaskRedact   = "redact"   // 字串換成 [REDACTED]，其他型別換成 null
+	MaskEmail    = "email"    // 假的 email：user_<hash>@example.com
+	MaskName     = "name"     // 假的姓名
+	MaskPreserve = "preserve" // 保留格式：數字換數字、字母換字母（大小寫不變），其他字元不變
+)
+
+var maskKinds = []string{MaskHash, MaskRedact, MaskEmail, MaskName, MaskPreserve}
+
+// MaskRule 對符合 Collection（名稱或 glob）的文件，把 Fields 的每個欄位以指定方式遮罩
+type MaskRule struct {
+	Collection string            `json:"collection" yaml:"collection"`
+	Fields     map[string]string `json:"fields" yaml:"fields"` // 欄位路徑 → 遮罩方式
+}
+
+// MaskConfig 遮罩設定；Salt 相同時每次匯入的遮罩結果都一樣
+type MaskConfig struct {
+	Salt  string     `json:"salt" yaml:"salt"`
+	Rules []MaskRule `json:"masks" yaml:"masks"`
+}
+
+// LoadMaskConfig 讀取 YAML 或 JSON 格式的遮罩設定（依副檔名判斷）；salt 可以用 MASK_SALT 環境變數覆蓋
+func LoadMaskConfig(path string) (*MaskConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mc MaskConfig
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &mc)
+	} else {
+		err = yaml.Unmarshal(data, &mc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mask file %s: %v", path, err)
+	}
+	if s := os.Getenv("MASK_SALT"); s != "" {
+		mc.Salt = s
+	}
+
+	for i, r := range mc.Rules {
+		if r.Collection == "" {
+			return nil, fmt.Errorf("mask %d: collection is required", i+1)
+		}
+		if _, err := filepath.Match(r.Collection, ""); err != nil {
+			return nil, fmt.Errorf("mask %d: invalid pattern %q: %v", i+1, r.Collection, err)
+		}
+		for field, kind := range r.Fields {
+			kind = strings.ToLower(kind)
+			if !contains(maskKinds, kind) {
+				return nil, fmt.Errorf("mask %d (%s): unknown mask %q for %s (expected one of %s)",
+					i+1, r.Collection, kind, field, strings.Join(maskKinds, ", "))
+			}
+			r.Fields[field] = kind
+		}
+	}
+	return &mc, nil
+}
+
+// masker 依 salt 產生可重現的遮罩值
+type masker struct {
+	key []byte
+}
+
+// newMasker salt 為空時使用隨機值：同一次匯入內仍然一致，但每次執行結果不同
+func newMasker(salt string) (*masker, bool) {
+	if salt != "" {
+		return &masker{key: []byte(salt)}, true
+	}
+	key := make([]byte, 32)
+	rand.Read(key)
+	return &masker{key: key}, false
+}
+
+// fieldsFor 合併所有符合 coll 的規則，後面的規則覆蓋前面的
+func (mc *MaskConfig) fieldsFor(coll string) map[string]string {
+	fields := map[string]string{}
+	for _, r := range mc.Rules {
+		if ok, _ := filepath.Match(r.Collection, coll); ok {
+			for f, kind := range r.Fields {
+				fields[f] = kind
+			}
+		}
+	}
+	return fields
+}
+
+// digest 以 field 作為 domain，同樣的值在不同欄位會得到不同的結果
+func (m *masker) digest(field, value string, n int) []byte {
+	out := make([]byte, 0, n)
+	for counter := uint32(0); len(out) < n; counter++ {
+		mac := hmac.New(sha256.New, m.key)
+		var c [4]byte
+		binary.BigEndian.PutUint32(c[:], counter)
+		mac.Write(c[:])
+		mac.Write([]byte(field))
+		mac.Write([]byte{0})
+		mac.Write([]byte(value))
+		out = mac.Sum(out)
+	}
+	return out[:n]
+}
+
+// mask 遮罩單一值；陣列與子文件逐一遮罩其中每個值，只有 null 原樣保留
+func (m *masker) mask(field, kind string, v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return v
+	case bson.A:
+		out := make(bson.A, len(x))
+		for i, e := range x {
+			out[i] = m.mask(field, kind, e)
+		}
+		return out
+	case []interface{}:
+		return m.mask(field, kind, bson.A(x))
+	case bson.M:
+		out := make(bson.M, len(x))
+		for k, e := range x {
+			out[k] = m.mask(field, kind, e)
+		}
+		return out
+	case bson.D:
+		out := make(bson.D, len(x))
+		for i, e := range x {
+			out[i] = bson.E{Key: e.Key, Value: m.mask(field, kind, e.Value)}
+		}
+		return out
+	}
+
+	s, isString := v.(string)
+	if !isString {
+		s = fmt.Sprint(v)
+	}
+
+	switch kind {
+	case MaskHash:
+		return hex.EncodeToString(m.digest(field, s, 32))
+	case MaskRedact:
+		if isString {
+			return "[REDACTED]"
+		}
+		return nil
+	case MaskEmail:
+		return "user_" + hex.EncodeToString(m.digest(field, s, 5)) + "@example.com"
+	case MaskName:
+		d := m.digest(field, s, 4)
+		first := fakeFirstNames[int(binary.BigEndian.Uint16(d[:2]))%len(fakeFirstNames)]
+		last := fakeLastNames[int(binary.BigEndian.Uint16(d[2:]))%len(fakeLastNames)]
+		return first + " " + last
+	case MaskPreserve:
+		out := m.preserve(field, s)
+		if isString {
+			return out
+		}
+		// 整數保留原本的型別與位數
+		switch v.(type) {
+		case int32:
+			if n, err := strconv.ParseInt(out, 10, 32); err == nil {
+				return int32(n)
+			}
+		case int64:
+			if n, err := strconv.ParseInt(out, 10, 64); err == nil {
+				return n
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// preserve 保留長度與字元類別，適合電話、身分證號、卡號之類有格式檢查的欄位
+func (m *masker) preserve(field, s string) string {
+	runes := []rune(s)
+	d := m.digest(field, s, len(runes))
+	for i, r := range runes {
+		switch {
+		case unicode.IsDigit(r):
+			// 開頭的數字不換成 0，數值才不會少一位
+			if i == 0 {
+				runes[i] = rune('1' + d[i]%9)
+			} else {
+				runes[i] = rune('0' + d[i]%10)
+			}
+		case unicode.IsUpper(r):
+			runes[i] = rune('A' + d[i]%26)
+		case unicode.IsLower(r):
+			runes[i] = rune('a' + d[i]%26)
+		case unicode.IsLetter(r):
+			// 非拉丁字母（例如中文姓名）換成 *
+			runes[i] = '*'
+		}
+	}
+	return string(runes)
+}
+
+// maskReader 在寫入前遮罩指定欄位，原始值不會送到目標資料庫
+type maskReader struct {
+	docReader
+	masker *masker
+	fields map[string]string
+	paths  []string
+}
+
+func newMaskReader(r docReader, m *masker, fields map[string]string) *maskReader {
+	return &maskReader{docReader: r, masker: m, fields: fields, paths: sortedKeys(fields)}
+}
+
+func (r *maskReader) Next() (bson.M, error) {
+	doc, err := r.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	for _, path := range r.paths {
+		r.masker.maskAt(doc, path, r.fields[path], strings.Split(path, "."))
+	}
+	return doc, nil
+}
+
+// maskAt 遮罩 cur 之下 parts 路徑的值；路徑經過陣列時逐一處理陣列內的子文件，例如 contacts.email
+func (m *masker) maskAt(cur interface{}, field, kind string, parts []string) {
+	switch x := cur.(type) {
+	case bson.M:
+		v, ok := x[parts[0]]
+		if !ok {
+			return
+		}
+		if len(parts) == 1 {
+			x[parts[0]] = m.mask(field, kind, v)
+			return
+		}
+		m.maskAt(v, field, kind, parts[1:])
+	case bson.D:
+		for i := range x {
+			if x[i].Key != parts[0] {
+				continue
+			}
+			if len(parts) == 1 {
+				x[i].Value = m.mask(field, kind, x[i].Value)
+			} else {
+				m.maskAt(x[i].Value, field, kind, parts[1:])
+			}
+		}
+	case bson.A:
+		for _, e := range x {
+			m.maskAt(e, field, kind, parts)
+		}
+	case []interface{}:
+		m.maskAt(bson.A(x), field, kind, parts)
+	}
+}
+
+var fakeFirstNames = []string{
+	"Alex", "Blake", "Casey", "Dana", "Eli", "Frankie", "Gale", "Harper", "Indy", "Jamie",
+	"Kai", "Lee", "Morgan", "Noel", "Oakley", "Parker", "Quinn", "Riley", "Sage", "Taylor",
+}
+
+var fakeLastNames = []string{
+	"Anderson", "Brooks", "Chen", "Diaz", "Evans", "Fischer", "Garcia", "Huang", "Ito", "Jensen",
+	"Kim", "Lopez", "Martin", "Nguyen", "Olsen", "Patel", "Rossi", "Smith", "Tanaka", "Wang",
+}
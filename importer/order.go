@@ -0,0 +1,121 @@
+package importer
+
+import (
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// orderTracker PreserveOrder 時記錄每筆文件解析時的欄位順序，寫入前依此把 bson.M 排回 bson.D；
+// 轉換、filter、遮罩等 stages 都以 bson.M 處理文件，只會就地修改或丟掉文件、不會改變先後，
+// 所以依讀取的先後排隊，以 map 本身對應即可
+type orderTracker struct {
+	queue []orderedDoc
+}
+
+type orderedDoc struct {
+	m      bson.M
+	layout bson.D
+}
+
+// track 把解析出的 bson.D 轉成 stages 使用的 bson.M，並記下原本的順序
+func (t *orderTracker) track(d bson.D) bson.M {
+	m := docToMap(d)
+	t.queue = append(t.queue, orderedDoc{m: m, layout: d})
+	return m
+}
+
+// restore 依 track 時的順序把 m 排成 bson.D；排在 m 之前、沒有被寫入的文件（被 filter 等丟掉）一併移出
+func (t *orderTracker) restore(m bson.M) bson.D {
+	p := reflect.ValueOf(m).UnsafePointer()
+	for k, e := range t.queue {
+		if reflect.ValueOf(e.m).UnsafePointer() == p {
+			t.queue = t.queue[k+1:]
+			return reorder(m, e.layout)
+		}
+	}
+	return reorder(m, nil)
+}
+
+// orderedReader pipeline 最外層的 reader；forEachBatch 改用 NextOrdered 取得要寫入的文件
+type orderedReader struct {
+	docReader
+	order *orderTracker
+}
+
+func (o *orderedReader) NextOrdered() (bson.D, error) {
+	m, err := o.docReader.Next()
+	if err != nil {
+		return nil, err
+	}
+	return o.order.restore(m), nil
+}
+
+// reorder 依 layout 的順序排列 m 的欄位；layout 沒有的欄位（轉換新增的）依名稱排在最後
+func reorder(m bson.M, layout bson.D) bson.D {
+	out := make(bson.D, 0, len(m))
+	seen := make(map[string]bool, len(layout))
+	for _, e := range layout {
+		v, ok := m[e.Key]
+		if !ok || seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+		out = append(out, bson.E{Key: e.Key, Value: reorderValue(v, e.Value)})
+	}
+	for _, k := range sortedKeys(m) {
+		if !seen[k] {
+			out = append(out, bson.E{Key: k, Value: reorderValue(m[k], nil)})
+		}
+	}
+	return out
+}
+
+func reorderValue(v, layout interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.M:
+		d, _ := layout.(bson.D)
+		return reorder(v, d)
+	case bson.A:
+		return reorderArray(v, layout)
+	case []interface{}:
+		return reorderArray(v, layout)
+	}
+	return v
+}
+
+func reorderArray(a []interface{}, layout interface{}) bson.A {
+	l, _ := layout.(bson.A)
+	out := make(bson.A, len(a))
+	for i, v := range a {
+		var li interface{}
+		if i < len(l) {
+			li = l[i]
+		}
+		out[i] = reorderValue(v, li)
+	}
+	return out
+}
+
+// docToMap 把 bson.D（含子文件與陣列內的子文件）轉成 bson.M
+func docToMap(d bson.D) bson.M {
+	m := make(bson.M, len(d))
+	for _, e := range d {
+		m[e.Key] = valueToMap(e.Value)
+	}
+	return m
+}
+
+func valueToMap(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.D:
+		return docToMap(v)
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, x := range v {
+			out[i] = valueToMap(x)
+		}
+		return out
+	}
+	return v
+}
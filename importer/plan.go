@@ -0,0 +1,231 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hayletdomybest/mongo-tools/internal/remote"
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// Plan import-plan.yaml 描述的一組匯入步驟；Steps 已依 depends_on 排好執行順序
+type Plan struct {
+	File  string
+	Steps []PlanStep
+}
+
+// PlanStep 一個來源（檔案、目錄或 URL）的匯入設定；沒有設定的欄位沿用 plan 開頭的預設值，再沿用命令列與環境變數
+type PlanStep struct {
+	Name        string
+	Source      string
+	DB          string
+	Collection  string // 空字串表示依檔名推斷
+	Strategy    string
+	KeyField    string
+	MergeUpdate bool
+	Transforms  []Transform
+	Mask        *MaskConfig
+	Hooks       []Hook
+	Filter      bson.M
+	DependsOn   []string // 必須先成功的步驟名稱
+}
+
+type planFile struct {
+	DB       string         `yaml:"db"`
+	Strategy string         `yaml:"strategy"`
+	KeyField string         `yaml:"key"`
+	Steps    []planStepFile `yaml:"steps"`
+}
+
+type planStepFile struct {
+	Name        string   `yaml:"name"`
+	Source      string   `yaml:"source"`
+	DB          string   `yaml:"db"`
+	Collection  string   `yaml:"collection"`
+	Strategy    string   `yaml:"strategy"`
+	KeyField    string   `yaml:"key"`
+	MergeUpdate bool     `yaml:"merge_update"`
+	Transform   string   `yaml:"transform"`
+	Mask        string   `yaml:"mask"`
+	Hooks       string   `yaml:"hooks"`
+	Filter      string   `yaml:"filter"`
+	DependsOn   []string `yaml:"depends_on"`
+}
+
+// LoadPlan 讀取 YAML 格式的 import plan；source 與 transform / mask / hooks 檔的相對路徑以 plan 檔所在的目錄為準
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pf planFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %v", path, err)
+	}
+	if len(pf.Steps) == 0 {
+		return nil, fmt.Errorf("plan file %s has no steps", path)
+	}
+	dir := filepath.Dir(path)
+	rel := func(p string) string {
+		if p == "" || remote.IsURL(p) || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	steps := make([]PlanStep, 0, len(pf.Steps))
+	names := map[string]bool{}
+	for n, raw := range pf.Steps {
+		s := PlanStep{
+			Name:        raw.Name,
+			Source:      rel(raw.Source),
+			DB:          firstNonEmpty(raw.DB, pf.DB),
+			Collection:  raw.Collection,
+			Strategy:    firstNonEmpty(raw.Strategy, pf.Strategy),
+			KeyField:    firstNonEmpty(raw.KeyField, pf.KeyField),
+			MergeUpdate: raw.MergeUpdate,
+			DependsOn:   raw.DependsOn,
+		}
+		if s.Name == "" {
+			s.Name = firstNonEmpty(s.Collection, baseName(raw.Source))
+		}
+		where := fmt.Sprintf("step %d (%s)", n+1, s.Name)
+		if raw.Source == "" {
+			return nil, fmt.Errorf("%s: source is required", where)
+		}
+		if raw.Source == Stdin {
+			return nil, fmt.Errorf("%s: a plan cannot read from stdin", where)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("%s: duplicate step name; set a distinct name", where)
+		}
+		names[s.Name] = true
+		if s.Strategy != "" {
+			if err := checkStrategy(s.Strategy); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		}
+		if s.MergeUpdate && s.Strategy != StrategyMerge {
+			return nil, fmt.Errorf("%s: merge_update requires the merge strategy", where)
+		}
+		if raw.Transform != "" {
+			if s.Transforms, err = LoadTransforms(rel(raw.Transform)); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		}
+		if raw.Mask != "" {
+			if s.Mask, err = LoadMaskConfig(rel(raw.Mask)); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		}
+		if raw.Hooks != "" {
+			if s.Hooks, err = LoadHooks(rel(raw.Hooks)); err != nil {
+				return nil, fmt.Errorf("%s: %v", where, err)
+			}
+		}
+		if raw.Filter != "" {
+			if s.Filter, err = ParseFilter(raw.Filter); err != nil {
+				return nil, fmt.Errorf("%s: invalid filter: %v", where, err)
+			}
+		}
+		steps = append(steps, s)
+	}
+
+	ordered, err := orderPlanSteps(steps)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{File: path, Steps: ordered}, nil
+}
+
+// orderPlanSteps 依 depends_on 排序；沒有依賴關係的步驟維持檔案內的順序
+func orderPlanSteps(steps []PlanStep) ([]PlanStep, error) {
+	byName := map[string]bool{}
+	for _, s := range steps {
+		byName[s.Name] = true
+	}
+	for _, s := range steps {
+		for _, dep := range s.DependsOn {
+			if !byName[dep] {
+				return nil, fmt.Errorf("step %s depends on unknown step %s", s.Name, dep)
+			}
+		}
+	}
+
+	done := map[string]bool{}
+	ordered := make([]PlanStep, 0, len(steps))
+	for len(ordered) < len(steps) {
+		progressed := false
+		for _, s := range steps {
+			if done[s.Name] || !allDone(s.DependsOn, done) {
+				continue
+			}
+			done[s.Name] = true
+			ordered = append(ordered, s)
+			progressed = true
+			break
+		}
+		if !progressed {
+			var cycle []string
+			for _, s := range steps {
+				if !done[s.Name] {
+					cycle = append(cycle, s.Name)
+				}
+			}
+			return nil, fmt.Errorf("circular depends_on between steps: %s", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, n := range names {
+		if !done[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Options 以這個步驟的設定覆蓋 base；目標 collection 只來自步驟本身
+func (s PlanStep) Options(base Options) Options {
+	opts := base
+	opts.Collection = s.Collection
+	if s.DB != "" {
+		opts.DB = s.DB
+	}
+	if s.Strategy != "" {
+		opts.Strategy = s.Strategy
+	}
+	if s.KeyField != "" {
+		opts.KeyField = s.KeyField
+	}
+	if s.MergeUpdate {
+		opts.MergeUpdate = true
+	}
+	if s.Transforms != nil {
+		opts.Transforms = s.Transforms
+	}
+	if s.Mask != nil {
+		opts.Mask = s.Mask
+	}
+	if s.Hooks != nil {
+		opts.Hooks = s.Hooks
+	}
+	if s.Filter != nil {
+		opts.Filter = s.Filter
+	}
+	return opts
+}
@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const progressBarWidth = 20
+
+// progress 每批寫入後印出已寫入筆數、百分比（可得知檔案大小時）與 docs/sec
+type progress struct {
+	logger  *slog.Logger
+	coll    string
+	input   *inputFile
+	quiet   bool
+	started time.Time
+	docs    int
+}
+
+func newProgress(logger *slog.Logger, coll string, input *inputFile, quiet bool) *progress {
+	return &progress{logger: logger, coll: coll, input: input, quiet: quiet, started: time.Now()}
+}
+
+func (p *progress) batch(n int) {
+	p.docs += n
+	if p.quiet {
+		return
+	}
+	if pct, ok := p.percent(); ok {
+		p.logger.Info(fmt.Sprintf("   ↳ %s %s %5.1f%%  %d docs  %s", p.coll, progressBar(pct), pct, p.docs, p.rate()),
+			"collection", p.coll, "count", p.docs, "percent", pct, "docs_per_sec", p.docsPerSec())
+		return
+	}
+	p.logger.Info(fmt.Sprintf("   ↳ %s %d docs  %s", p.coll, p.docs, p.rate()),
+		"collection", p.coll, "count", p.docs, "docs_per_sec", p.docsPerSec())
+}
+
+func (p *progress) percent() (float64, bool) {
+	if p.input == nil {
+		return 0, false
+	}
+	read, size := p.input.Progress()
+	if size <= 0 {
+		return 0, false
+	}
+	return min(100, float64(read)*100/float64(size)), true
+}
+
+// docsPerSec 目前為止的平均寫入速度
+func (p *progress) docsPerSec() float64 {
+	secs := time.Since(p.started).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(p.docs) / secs
+}
+
+func (p *progress) rate() string {
+	return fmt.Sprintf("%.0f docs/s", p.docsPerSec())
+}
+
+func progressBar(pct float64) string {
+	filled := int(pct / 100 * progressBarWidth)
+	return "[" + strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled) + "]"
+}
@@ -0,0 +1,60 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ParseFieldList 解析以逗號分隔的欄位（a.b 路徑），例如 "name,profile.email"
+func ParseFieldList(s string) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if strings.HasPrefix(f, ".") || strings.HasSuffix(f, ".") || strings.Contains(f, "..") || strings.HasPrefix(f, "$") {
+			return nil, fmt.Errorf("invalid field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// projectReader 在解析後立刻套用 Options.OnlyFields / DropFields，之後的轉換、filter 等只看到保留下來的欄位；
+// OnlyFields 一律保留 _id（除非列在 DropFields），跟 MongoDB 的 projection 一樣
+type projectReader struct {
+	docReader
+	only []string
+	drop []string
+}
+
+func (p *projectReader) Next() (bson.M, error) {
+	doc, err := p.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	if len(p.only) > 0 {
+		kept := bson.M{}
+		if id, ok := doc["_id"]; ok {
+			kept["_id"] = id
+		}
+		for _, field := range p.only {
+			if v, ok := getPath(doc, field); ok {
+				setPath(kept, field, v)
+			}
+		}
+		// 就地替換欄位而不是換一個 map，PreserveOrder 的 orderTracker 以 map 本身對應原本的順序
+		for k := range doc {
+			delete(doc, k)
+		}
+		for k, v := range kept {
+			doc[k] = v
+		}
+	}
+	for _, field := range p.drop {
+		deletePath(doc, field)
+	}
+	return doc, nil
+}
@@ -0,0 +1,97 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit 限制整個 Importer 的寫入速度（所有檔案與 InsertWorkers 合計），避免匯入佔滿共用 cluster 的資源
+type RateLimit struct {
+	DocsPerSec    float64 // 每秒最多寫入的文件數，0 表示不限制
+	BatchesPerSec float64 // 每秒最多送出的 InsertMany / BulkWrite 次數，0 表示不限制
+}
+
+// rateLimiter RateLimit 的 token bucket；nil 表示不限制
+type rateLimiter struct {
+	docs, batches *tokenBucket
+}
+
+func newRateLimiter(l RateLimit) (*rateLimiter, error) {
+	if l.DocsPerSec < 0 || l.BatchesPerSec < 0 {
+		return nil, fmt.Errorf("invalid rate limit: %v docs/s, %v batches/s", l.DocsPerSec, l.BatchesPerSec)
+	}
+	if l.DocsPerSec == 0 && l.BatchesPerSec == 0 {
+		return nil, nil
+	}
+	return &rateLimiter{docs: newTokenBucket(l.DocsPerSec), batches: newTokenBucket(l.BatchesPerSec)}, nil
+}
+
+// wait 送出一批 n 筆文件之前呼叫，必要時等到兩個 bucket 都有足夠的 token；ctx 被取消時回傳錯誤
+func (r *rateLimiter) wait(ctx context.Context, n int) error {
+	if r == nil {
+		return nil
+	}
+	d := max(r.docs.reserve(float64(n)), r.batches.reserve(1))
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// tokenBucket 每秒補充 rate 個 token，最多累積一秒份；nil 表示不限制
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// reserve 先取走 n 個 token，回傳要等多久才輪到這次；token 可以預支成負數，
+// 所以比一秒份還大的批次（例如 BatchSize 1000、DocsPerSec 100）也能送出，只是之後要等更久
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// String 記錄在 log 裡的限制，例如 500 docs/s, 2 batches/s
+func (l RateLimit) String() string {
+	var parts []string
+	if l.DocsPerSec > 0 {
+		parts = append(parts, fmt.Sprintf("%g docs/s", l.DocsPerSec))
+	}
+	if l.BatchesPerSec > 0 {
+		parts = append(parts, fmt.Sprintf("%g batches/s", l.BatchesPerSec))
+	}
+	if len(parts) == 0 {
+		return "unlimited"
+	}
+	return strings.Join(parts, ", ")
+}
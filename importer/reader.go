@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// docReader 逐筆讀出文件，讀完回傳 io.EOF
+type docReader interface {
+	Next() (bson.M, error)
+}
+
+// Extended JSON 的解析模式，見 Options.ExtJSONMode
+const (
+	ExtJSONRelaxed   = "relaxed"   // 同時接受 canonical 與 relaxed 的寫法（預設）
+	ExtJSONCanonical = "canonical" // 只接受 canonical 的寫法，例如 {"$date": {"$numberLong": "..."}}；其他寫法視為解析錯誤
+	ExtJSONAuto      = "auto"      // 先以 canonical 解析，失敗時改用 relaxed
+)
+
+// unmarshalExtJSON 依 mode 解析一筆 Extended JSON 文件；mode 為空字串時使用 relaxed
+func unmarshalExtJSON[T bson.M | bson.D](data []byte, mode string) (T, error) {
+	var v T
+	err := bson.UnmarshalExtJSON(data, mode == ExtJSONCanonical || mode == ExtJSONAuto, &v)
+	if err != nil && mode == ExtJSONAuto {
+		var relaxed T
+		err = bson.UnmarshalExtJSON(data, false, &relaxed)
+		v = relaxed
+	}
+	return v, err
+}
+
+// decodeExtJSON order 不為 nil 時先解析成 bson.D 記下欄位順序；需要 strict 檢查時也先解析成 bson.D，才看得到重複的欄位
+func decodeExtJSON(data []byte, mode string, strict strictness, order *orderTracker) (bson.M, error) {
+	if order == nil && !strict.enabled() {
+		return unmarshalExtJSON[bson.M](data, mode)
+	}
+	d, err := unmarshalExtJSON[bson.D](data, mode)
+	if err != nil {
+		return nil, err
+	}
+	return strict.finish(d, order)
+}
+
+// newExtJSONReader 支援 整份 JSON Array 或 NDJSON，每笔依 mode 解析 Extended JSON；
+// 以串流方式逐筆解析，不會把整個檔案讀進記憶體
+func newExtJSONReader(r io.Reader, mode string, strict strictness, order *orderTracker) (docReader, error) {
+	br := bufio.NewReader(r)
+
+	// 跳過開頭空白，看第一個字元決定格式
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return emptyReader{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		if err := br.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if b == '[' {
+			return newArrayReader(br, mode, strict, order)
+		}
+		return &ndjsonReader{r: br, mode: mode, strict: strict, order: order}, nil
+	}
+}
+
+type emptyReader struct{}
+
+func (emptyReader) Next() (bson.M, error) { return nil, io.EOF }
+
+// arrayReader 整份 JSON Array：用 json.Decoder 逐個元素讀出
+type arrayReader struct {
+	dec    *json.Decoder
+	mode   string
+	strict strictness
+	order  *orderTracker
+	index  int
+	done   bool
+}
+
+func newArrayReader(r io.Reader, mode string, strict strictness, order *orderTracker) (*arrayReader, error) {
+	dec := json.NewDecoder(r)
+	if _, err := dec.Token(); err != nil { // 吃掉 '['
+		return nil, fmt.Errorf("failed to parse JSON array: %v", err)
+	}
+	return &arrayReader{dec: dec, mode: mode, strict: strict, order: order}, nil
+}
+
+func (a *arrayReader) Next() (bson.M, error) {
+	if a.done {
+		return nil, io.EOF
+	}
+	if !a.dec.More() {
+		a.done = true
+		if _, err := a.dec.Token(); err != nil { // 吃掉 ']'
+			return nil, fmt.Errorf("failed to parse JSON array: %v", err)
+		}
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := a.dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON array: %v", err)
+	}
+	a.index++
+	m, err := decodeExtJSON(raw, a.mode, a.strict, a.order)
+	if err != nil {
+		return nil, &parseError{Pos: fmt.Sprintf("element %d", a.index), Raw: string(raw), Err: err}
+	}
+	return m, nil
+}
+
+// maxLineSize NDJSON 單行的上限；Extended JSON 通常比同一筆文件的 BSON 長（base64、{"$numberLong": ...} 等），
+// 所以放寬到 BSON 上限（16MB）的 4 倍，超過的行不可能是能寫入的文件；真正的 16MB 上限是 BSON 的大小，由寫入時的 server 檢查（SkipOversized 時由 sizeReader 略過）
+const maxLineSize = 64 * 1024 * 1024
+
+// ndjsonReader 否则当作 NDJSON（每行一笔）；不用 bufio.Scanner，單行可以超過它的 64KB 上限
+type ndjsonReader struct {
+	r      *bufio.Reader
+	mode   string
+	strict strictness
+	order  *orderTracker
+	line   int
+	buf    []byte
+}
+
+func (n *ndjsonReader) Next() (bson.M, error) {
+	for {
+		data, size, err := n.readLine()
+		if err == io.EOF && size == 0 {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		n.line++
+		if size > maxLineSize {
+			return nil, &parseError{
+				Pos: fmt.Sprintf("line %d", n.line),
+				Raw: string(data),
+				Err: fmt.Errorf("line is %d bytes, longer than the 64MB limit; a document cannot exceed 16MB of BSON", size),
+			}
+		}
+		line := strings.TrimSpace(string(data))
+		if line == "" {
+			continue
+		}
+		m, err := decodeExtJSON([]byte(line), n.mode, n.strict, n.order)
+		if err != nil {
+			return nil, &parseError{
+				Pos: fmt.Sprintf("line %d", n.line),
+				Raw: line,
+				Err: fmt.Errorf("failed to parse line as Extended JSON: %v", err),
+			}
+		}
+		return m, nil
+	}
+}
+
+// readLine 讀出一行（不含換行）與它的長度；超過 maxLineSize 的行只保留開頭、其餘讀過丟掉，讓下一行還能繼續讀
+func (n *ndjsonReader) readLine() ([]byte, int, error) {
+	n.buf = n.buf[:0]
+	size := 0
+	for {
+		chunk, err := n.r.ReadSlice('\n')
+		size += len(chunk)
+		if len(n.buf) <= maxLineSize {
+			n.buf = append(n.buf, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil {
+			size--
+			n.buf = n.buf[:len(n.buf)-1]
+		}
+		if size > maxLineSize {
+			return n.buf[:200], size, err
+		}
+		return n.buf, size, err
+	}
+}
+
+// Documents 逐筆讀出資料檔（任何可匯入的格式，含壓縮與 URL）的文件，不寫入資料庫；
+// 供 diff 等只需要讀取的指令使用
+type Documents struct {
+	docReader
+	in *inputFile
+}
+
+// OpenDocuments 開啟 filePath；opts 只用到 CSV 與 ExtJSONMode 設定
+func OpenDocuments(ctx context.Context, filePath string, opts Options) (*Documents, error) {
+	in, err := openInput(ctx, filePath, opts.DecryptKey)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newDocReader(filePath, in, opts, nil)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return &Documents{docReader: r, in: in}, nil
+}
+
+// Documents 開啟 filePath，並套用匯入時的欄位選擇、型別修正、placeholder、轉換、tenant 欄位、filter、去重與遮罩，得到會寫入 collection 的文件；
+// 不做 schema 驗證，也不略過無效的文件
+func (i *Importer) Documents(ctx context.Context, filePath string) (*Documents, error) {
+	d, err := OpenDocuments(ctx, filePath, i.opts)
+	if err != nil {
+		return nil, err
+	}
+	_, coll := i.resolveTarget(filePath)
+	if len(i.opts.OnlyFields) > 0 || len(i.opts.DropFields) > 0 {
+		d.docReader = &projectReader{docReader: d.docReader, only: i.opts.OnlyFields, drop: i.opts.DropFields}
+	}
+	if i.opts.Coerce != nil {
+		d.docReader = &coerceReader{docReader: d.docReader, coercion: i.opts.Coerce}
+	}
+	if i.opts.Templates {
+		d.docReader = &templateReader{docReader: d.docReader, now: i.started}
+	}
+	if transforms := transformsFor(i.opts.Transforms, coll); len(transforms) > 0 {
+		d.docReader = &transformReader{docReader: d.docReader, transforms: transforms}
+	}
+	if i.opts.TenantField != "" {
+		d.docReader = &tenantReader{docReader: d.docReader, field: i.opts.TenantField, value: i.opts.TenantValue}
+	}
+	if i.match != nil {
+		d.docReader = &filterReader{docReader: d.docReader, match: i.match}
+	}
+	if i.opts.DedupeBy != "" {
+		d.docReader = newDedupeReader(d.docReader, i.opts.DedupeBy, i.opts.DedupeKeep)
+	}
+	if i.masker != nil {
+		if fields := i.opts.Mask.fieldsFor(coll); len(fields) > 0 {
+			d.docReader = newMaskReader(d.docReader, i.masker, fields)
+		}
+	}
+	return d, nil
+}
+
+// Next 回傳下一筆文件，讀完時回傳 io.EOF
+func (d *Documents) Next() (bson.M, error) {
+	return d.docReader.Next()
+}
+
+func (d *Documents) Close() error {
+	return d.in.Close()
+}
+
+// CollectionName 依檔名推斷 collection，例如 dex.accounts.json.gz → accounts；推斷不出來時回傳空字串
+func CollectionName(filePath string) string {
+	return extractCollectionName(filePath)
+}
@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseRenames 解析 --rename：以逗號分隔的 <來源>=<目標>，例如 users=users_b,orders=orders_b
+func ParseRenames(s string) (map[string]string, error) {
+	renames := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid rename %q (expected <source>=<target>)", pair)
+		}
+		if err := checkCollectionName(to); err != nil {
+			return nil, err
+		}
+		if _, dup := renames[from]; dup {
+			return nil, fmt.Errorf("%s is renamed more than once", from)
+		}
+		renames[from] = to
+	}
+	return renames, nil
+}
+
+func checkCollectionName(name string) error {
+	if strings.ContainsAny(name, "$\x00") || strings.HasPrefix(name, "system.") {
+		return fmt.Errorf("invalid collection name %q", name)
+	}
+	return nil
+}
+
+// targetName 來源 collection 實際寫入的名稱：先套用 Options.Renames，再加上 CollectionPrefix；
+// Strategies、轉換、遮罩、hooks 與 Include / Exclude 仍以來源名稱比對
+func (i *Importer) targetName(coll string) string {
+	if coll == "" {
+		return ""
+	}
+	if to, ok := i.opts.Renames[coll]; ok {
+		coll = to
+	}
+	return i.opts.CollectionPrefix + coll
+}
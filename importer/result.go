@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FileResult 記錄單一檔案的匯入結果
+type FileResult struct {
+	File          string
+	DB            string // 空字串表示預設的 database
+	Collection    string
+	Parsed        int // 從檔案讀出的文件數，包含無效的
+	Docs          int
+	Invalid       int // SkipInvalid 略過的文件數
+	Oversized     int // SkipOversized 略過的超過 16MB 的文件數
+	Filtered      int // 不符合 Options.Filter 而沒有匯入的文件數
+	Duplicates    int // IgnoreDuplicates 略過的重複文件數
+	Deduped       int // DedupeBy 丟掉的同檔案重複文件數
+	UnchangedDocs int // StampHashField：雜湊與既有文件相同而沒有重新寫入的文件數，包含在 Docs 內
+	Duration      time.Duration
+	Skipped       bool // 無法辨識的檔案
+	NotRun        bool // FailFast 或中斷後沒有執行的檔案
+	Interrupted   bool // 匯入到一半時 ctx 被取消（Ctrl+C、--run-timeout）；Err 也會有值
+	Unchanged     bool // SkipUnchanged：checksum 與上次成功匯入相同而略過
+	Err           error
+	Warnings      []string // 匯入過程中記錄的警告
+
+	strategy      string      // 這個檔案的 collection 套用的 strategy，見 Options.Strategies
+	staged        bool        // 經由 staging collection + rename 載入
+	recreate      *recreation // truncate 時要依 <collection>.options.json 重建；由 writeDocuments 取代清空
+	createOptions bson.D      // <collection>.options.json 的內容
+	shardKey      bson.D      // 目標是 sharded collection 時的 shard key
+	checkpoint    *checkpoint // Resume 時的進度記錄
+}
+
+// Namespace 回傳 <db>.<collection>，使用預設 database 時只有 collection
+func (r FileResult) Namespace() string {
+	if r.DB == "" {
+		return r.Collection
+	}
+	return r.DB + "." + r.Collection
+}
+
+// warn 記錄警告並留在結果中，--report 才看得到
+func (r *FileResult) warn(log *slog.Logger, msg string, args ...any) {
+	log.Warn(msg, args...)
+	r.Warnings = append(r.Warnings, strings.TrimSpace(strings.TrimPrefix(msg, "⚠️")))
+}
+
+// warnf 只記在結果中，不另外寫 log
+func (r *FileResult) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Status ok、interrupted、failed、skipped、not run 或 unchanged
+func (r FileResult) Status() string {
+	switch {
+	case r.Interrupted:
+		return "interrupted"
+	case r.Err != nil:
+		return "failed"
+	case r.Skipped:
+		return "skipped"
+	case r.NotRun:
+		return "not run"
+	case r.Unchanged:
+		return "unchanged"
+	default:
+		return "ok"
+	}
+}
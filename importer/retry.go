@@ -0,0 +1,84 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxRetryBackoff 指數退避的上限
+const maxRetryBackoff = 30 * time.Second
+
+// cleanupTimeout 中斷後收尾（記錄進度、abort transaction、刪除暫存 collection）最多等多久
+const cleanupTimeout = 10 * time.Second
+
+// RetryPolicy 暫時性錯誤（網路中斷、主節點切換）時的重試設定
+type RetryPolicy struct {
+	Attempts int           // 總嘗試次數，1 表示不重試
+	Backoff  time.Duration // 第一次重試前的等待時間，之後每次加倍
+	Jitter   float64       // 等待時間的隨機浮動比例（0~1）
+}
+
+// withRetry 依 Options.Retry 執行 fn，只有 isTransient 的錯誤才會重試；每次嘗試各自套用 Options.OpTimeout
+func (i *Importer) withRetry(ctx context.Context, what string, fn func(ctx context.Context) error) error {
+	p := i.opts.Retry
+	wait := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := i.withOpTimeout(ctx, what, fn)
+		if err == nil || attempt >= p.Attempts || !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+
+		d := wait
+		if p.Jitter > 0 {
+			d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(wait))
+		}
+		i.log.Warn(fmt.Sprintf("🔁 %s failed (attempt %d/%d), retrying in %s: %v", what, attempt, p.Attempts, d.Round(time.Millisecond), err),
+			"operation", what, "attempt", attempt, "max_attempts", p.Attempts, "backoff_ms", d.Milliseconds(), errAttr(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d):
+		}
+		wait = min(wait*2, maxRetryBackoff)
+	}
+}
+
+// withOpTimeout 以 Options.OpTimeout 限制單一資料庫操作；逾時的錯誤註明是哪個操作、限制多久
+func (i *Importer) withOpTimeout(ctx context.Context, what string, fn func(ctx context.Context) error) error {
+	if i.opts.OpTimeout <= 0 {
+		return fn(ctx)
+	}
+	opCtx, cancel := context.WithTimeout(ctx, i.opts.OpTimeout)
+	defer cancel()
+	err := fn(opCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%s timed out after %s: %w", what, i.opts.OpTimeout, err)
+	}
+	return err
+}
+
+// cleanupContext 收尾用的 context：ctx 被取消（Ctrl+C、--run-timeout）後仍然可以執行，但限制在 cleanupTimeout 內
+func cleanupContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), cleanupTimeout)
+}
+
+// isTransient 只認 server 標記的 RetryableWriteError / TransientTransactionError 以及網路錯誤
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if mongo.IsNetworkError(err) {
+		return true
+	}
+	var se mongo.ServerError
+	if errors.As(err, &se) {
+		return se.HasErrorLabel("RetryableWriteError") || se.HasErrorLabel("TransientTransactionError")
+	}
+	return false
+}
@@ -0,0 +1,530 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// loadSchemaFile 讀取本地的 JSON Schema；可以是 {"$jsonSchema": {...}}（與 collMod 一樣）或直接是 schema 本身
+func loadSchemaFile(path string) (bson.M, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.UnmarshalExtJSON(data, false, &m); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if s, ok := m["$jsonSchema"].(bson.M); ok {
+		return s, nil
+	}
+	return m, nil
+}
+
+// fetchSchema 從 listCollections 取出 collection 的 $jsonSchema validator；
+// collection 不存在或沒有 $jsonSchema 時回傳 nil
+func fetchSchema(ctx context.Context, collection *mongo.Collection) (schema bson.M, others []string, err error) {
+	cursor, err := collection.Database().ListCollections(ctx, bson.M{"name": collection.Name()})
+	if err != nil {
+		return nil, nil, err
+	}
+	var infos []struct {
+		Options struct {
+			Validator bson.M `bson:"validator"`
+		} `bson:"options"`
+	}
+	if err := cursor.All(ctx, &infos); err != nil {
+		return nil, nil, err
+	}
+	if len(infos) == 0 {
+		return nil, nil, nil
+	}
+	schema, others = splitValidator(infos[0].Options.Validator)
+	return schema, others, nil
+}
+
+// optionsSchema 從 options.json 的 create 選項取出 $jsonSchema validator
+func optionsSchema(opts bson.D) (schema bson.M, others []string, err error) {
+	raw, err := bson.Marshal(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	var o struct {
+		Validator bson.M `bson:"validator"`
+	}
+	if err := bson.Unmarshal(raw, &o); err != nil {
+		return nil, nil, err
+	}
+	schema, others = splitValidator(o.Validator)
+	return schema, others, nil
+}
+
+// splitValidator 分出 $jsonSchema 與其他 query 運算子（只有 server 會檢查）
+func splitValidator(validator bson.M) (schema bson.M, others []string) {
+	for k, v := range validator {
+		if k == "$jsonSchema" {
+			schema, _ = v.(bson.M)
+			continue
+		}
+		others = append(others, k)
+	}
+	sort.Strings(others)
+	return schema, others
+}
+
+// schemaFor 決定這個檔案要用的 schema：本地檔優先，否則向 server 取得目標 collection 的 validator；
+// 要依 options.json 重建的 collection 用 options.json 內的 validator
+func (i *Importer) schemaFor(ctx context.Context, collection *mongo.Collection, res *FileResult) (bson.M, error) {
+	if i.schema != nil {
+		return i.schema, nil
+	}
+	var schema bson.M
+	var others []string
+	var err error
+	if res.recreate != nil {
+		schema, others, err = optionsSchema(res.createOptions)
+	} else {
+		schema, others, err = fetchSchema(ctx, collection)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch validator of %s: %v", collection.Name(), err)
+	}
+	if len(others) > 0 {
+		i.log.Warn(fmt.Sprintf("⚠️  Validator of %s uses %s; only $jsonSchema is checked before insert", collection.Name(), strings.Join(others, ", ")),
+			"collection", collection.Name())
+	}
+	if schema == nil {
+		i.log.Warn(fmt.Sprintf("⚠️  Collection %s has no $jsonSchema validator; skipping schema validation", collection.Name()),
+			"collection", collection.Name())
+	}
+	return schema, nil
+}
+
+// schemaReader 在插入前逐筆以 $jsonSchema 檢查；不符合的文件以 parseError 回傳，
+// 搭配 --skip-invalid 可以略過並寫入錯誤檔
+type schemaReader struct {
+	docReader
+	schema  *schemaValidator
+	scanned int
+}
+
+func (s *schemaReader) Next() (bson.M, error) {
+	doc, err := s.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	s.scanned++
+	if errs := s.schema.validate(doc); len(errs) > 0 {
+		raw, _ := bson.MarshalExtJSON(doc, false, false)
+		return nil, &parseError{
+			Pos: fmt.Sprintf("document %d", s.scanned),
+			Raw: string(raw),
+			Err: fmt.Errorf("schema validation failed: %s", strings.Join(errs, "; ")),
+		}
+	}
+	return doc, nil
+}
+
+// schemaValidator 實作 MongoDB $jsonSchema 常用的關鍵字；不支援的關鍵字忽略，交給 server 檢查
+type schemaValidator struct {
+	root     bson.M
+	patterns map[string]*regexp.Regexp
+}
+
+func newSchemaValidator(schema bson.M) *schemaValidator {
+	return &schemaValidator{root: schema, patterns: map[string]*regexp.Regexp{}}
+}
+
+// validate 回傳每個不符合的欄位，格式為「欄位路徑: 原因」
+func (v *schemaValidator) validate(doc bson.M) []string {
+	var errs []string
+	v.check(v.root, doc, "", &errs)
+	return errs
+}
+
+func (v *schemaValidator) check(schema bson.M, val interface{}, path string, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "(root)"
+		}
+		*errs = append(*errs, p+": "+fmt.Sprintf(format, args...))
+	}
+
+	typ := bsonTypeOf(val)
+	if want, ok := schema["bsonType"]; ok && !matchesType(append([]string{typ}, bsonTypeAliases[typ]...), stringList(want)) {
+		fail("expected bsonType %s, got %s", strings.Join(stringList(want), " or "), typ)
+		return
+	}
+	if want, ok := schema["type"]; ok && !matchesType(jsonTypeAliases[typ], stringList(want)) {
+		fail("expected type %s, got %s", strings.Join(stringList(want), " or "), typ)
+		return
+	}
+	if enum, ok := schema["enum"].(bson.A); ok && !inEnum(val, enum) {
+		fail("value %v is not one of the allowed values", val)
+	}
+
+	if n, ok := toFloat(val); ok {
+		if min, ok := toFloat(schema["minimum"]); ok {
+			if ex, _ := schema["exclusiveMinimum"].(bool); ex && n <= min {
+				fail("%v must be greater than %v", val, min)
+			} else if n < min {
+				fail("%v is less than minimum %v", val, min)
+			}
+		}
+		if max, ok := toFloat(schema["maximum"]); ok {
+			if ex, _ := schema["exclusiveMaximum"].(bool); ex && n >= max {
+				fail("%v must be less than %v", val, max)
+			} else if n > max {
+				fail("%v is greater than maximum %v", val, max)
+			}
+		}
+		if m, ok := toFloat(schema["multipleOf"]); ok && m != 0 && math.Mod(n, m) != 0 {
+			fail("%v is not a multiple of %v", val, m)
+		}
+	}
+
+	if s, ok := val.(string); ok {
+		l := utf8.RuneCountInString(s)
+		if min, ok := toFloat(schema["minLength"]); ok && float64(l) < min {
+			fail("length %d is less than minLength %v", l, min)
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(l) > max {
+			fail("length %d is greater than maxLength %v", l, max)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			re, err := v.pattern(p)
+			if err != nil {
+				fail("invalid pattern %q: %v", p, err)
+			} else if !re.MatchString(s) {
+				fail("%q does not match pattern %q", s, p)
+			}
+		}
+	}
+
+	switch x := val.(type) {
+	case bson.M:
+		v.checkObject(schema, x, path, errs, fail)
+	case bson.A:
+		v.checkArray(schema, x, path, errs, fail)
+	}
+
+	for _, sub := range schemaList(schema["allOf"]) {
+		v.check(sub, val, path, errs)
+	}
+	if anyOf := schemaList(schema["anyOf"]); len(anyOf) > 0 {
+		matched := false
+		for _, sub := range anyOf {
+			if v.matches(sub, val, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("does not match any schema in anyOf")
+		}
+	}
+	if one := schemaList(schema["oneOf"]); len(one) > 0 {
+		matched := 0
+		for _, sub := range one {
+			if v.matches(sub, val, path) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			fail("matches %d schemas in oneOf, expected exactly 1", matched)
+		}
+	}
+	if not, ok := schema["not"].(bson.M); ok && v.matches(not, val, path) {
+		fail("must not match the schema in not")
+	}
+}
+
+func (v *schemaValidator) checkObject(schema bson.M, obj bson.M, path string, errs *[]string, fail func(string, ...interface{})) {
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, joinPath(path, name)+": required field is missing")
+		}
+	}
+	if min, ok := toFloat(schema["minProperties"]); ok && float64(len(obj)) < min {
+		fail("has %d fields, fewer than minProperties %v", len(obj), min)
+	}
+	if max, ok := toFloat(schema["maxProperties"]); ok && float64(len(obj)) > max {
+		fail("has %d fields, more than maxProperties %v", len(obj), max)
+	}
+
+	props, _ := schema["properties"].(bson.M)
+	patternProps, _ := schema["patternProperties"].(bson.M)
+
+	// 依欄位名稱排序，錯誤訊息的順序才固定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := obj[name]
+		known := false
+		if sub, ok := props[name].(bson.M); ok {
+			known = true
+			v.check(sub, field, joinPath(path, name), errs)
+		}
+		for p, sub := range patternProps {
+			re, err := v.pattern(p)
+			if err != nil || !re.MatchString(name) {
+				continue
+			}
+			known = true
+			if sub, ok := sub.(bson.M); ok {
+				v.check(sub, field, joinPath(path, name), errs)
+			}
+		}
+		if known {
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				*errs = append(*errs, joinPath(path, name)+": additional field is not allowed")
+			}
+		case bson.M:
+			v.check(extra, field, joinPath(path, name), errs)
+		}
+	}
+}
+
+func (v *schemaValidator) checkArray(schema bson.M, arr bson.A, path string, errs *[]string, fail func(string, ...interface{})) {
+	if min, ok := toFloat(schema["minItems"]); ok && float64(len(arr)) < min {
+		fail("has %d items, fewer than minItems %v", len(arr), min)
+	}
+	if max, ok := toFloat(schema["maxItems"]); ok && float64(len(arr)) > max {
+		fail("has %d items, more than maxItems %v", len(arr), max)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for a := 0; a < len(arr); a++ {
+			for b := a + 1; b < len(arr); b++ {
+				if valuesEqual(arr[a], arr[b]) {
+					fail("items %d and %d are duplicates", a, b)
+				}
+			}
+		}
+	}
+
+	switch items := schema["items"].(type) {
+	case bson.M:
+		for n, item := range arr {
+			v.check(items, item, fmt.Sprintf("%s[%d]", path, n), errs)
+		}
+	case bson.A:
+		// tuple 形式：每個位置各自的 schema，之後的元素由 additionalItems 決定
+		for n, item := range arr {
+			if n < len(items) {
+				if sub, ok := items[n].(bson.M); ok {
+					v.check(sub, item, fmt.Sprintf("%s[%d]", path, n), errs)
+				}
+				continue
+			}
+			switch extra := schema["additionalItems"].(type) {
+			case bool:
+				if !extra {
+					fail("item %d is not allowed by additionalItems", n)
+				}
+			case bson.M:
+				v.check(extra, item, fmt.Sprintf("%s[%d]", path, n), errs)
+			}
+		}
+	}
+}
+
+// matches 只判斷是否符合，用於 anyOf / oneOf / not
+func (v *schemaValidator) matches(schema bson.M, val interface{}, path string) bool {
+	var errs []string
+	v.check(schema, val, path, &errs)
+	return len(errs) == 0
+}
+
+func (v *schemaValidator) pattern(p string) (*regexp.Regexp, error) {
+	if re, ok := v.patterns[p]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[p] = re
+	return re, nil
+}
+
+// bsonTypeAliases 是 bsonType 額外接受的名稱；jsonTypeAliases 把 bsonTypeOf 的結果對應到 type 的 JSON 型別，
+// 沒列出的 BSON 型別不符合任何 JSON 型別
+var (
+	bsonTypeAliases = map[string][]string{
+		"int":     {"number"},
+		"long":    {"number"},
+		"double":  {"number"},
+		"decimal": {"number"},
+	}
+	jsonTypeAliases = map[string][]string{
+		"object":  {"object"},
+		"array":   {"array"},
+		"string":  {"string"},
+		"bool":    {"boolean"},
+		"null":    {"null"},
+		"int":     {"number"},
+		"long":    {"number"},
+		"double":  {"number"},
+		"decimal": {"number"},
+	}
+)
+
+// matchesType 檢查值的型別名稱是否有任何一個在 want 之內
+func matchesType(names, want []string) bool {
+	for _, w := range want {
+		for _, n := range names {
+			if w == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bsonTypeOf 回傳值對應的 $jsonSchema bsonType 名稱
+func bsonTypeOf(v interface{}) string {
+	switch x := v.(type) {
+	case nil, primitive.Null:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int32:
+		return "int"
+	case int:
+		if x >= math.MinInt32 && x <= math.MaxInt32 {
+			return "int"
+		}
+		return "long"
+	case int64:
+		return "long"
+	case float64, float32:
+		return "double"
+	case primitive.Decimal128:
+		return "decimal"
+	case bson.M, bson.D:
+		return "object"
+	case bson.A:
+		return "array"
+	case primitive.ObjectID:
+		return "objectId"
+	case primitive.DateTime, time.Time:
+		return "date"
+	case primitive.Binary:
+		return "binData"
+	case primitive.Regex:
+		return "regex"
+	case primitive.JavaScript:
+		return "javascript"
+	case primitive.CodeWithScope:
+		return "javascriptWithScope"
+	case primitive.Timestamp:
+		return "timestamp"
+	case primitive.Symbol:
+		return "symbol"
+	case primitive.DBPointer:
+		return "dbPointer"
+	case primitive.Undefined:
+		return "undefined"
+	case primitive.MinKey:
+		return "minKey"
+	case primitive.MaxKey:
+		return "maxKey"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// stringList bsonType / type / required 可以是單一字串或字串陣列
+func stringList(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case bson.A:
+		out := make([]string, 0, len(x))
+		for _, s := range x {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaList(v interface{}) []bson.M {
+	a, _ := v.(bson.A)
+	out := make([]bson.M, 0, len(a))
+	for _, s := range a {
+		if s, ok := s.(bson.M); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case primitive.Decimal128:
+		f, err := strconv.ParseFloat(x.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// valuesEqual 數字不分 int / long / double 比較數值，其他型別要完全相同
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func inEnum(v interface{}, enum bson.A) bool {
+	for _, e := range enum {
+		if valuesEqual(v, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
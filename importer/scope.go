@@ -0,0 +1,26 @@
+package importer
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// scopeReader 計算不符合 Options.Scope 的文件數：這些文件寫入後不在範圍內，下一次限定範圍的重新載入不會刪除它們
+type scopeReader struct {
+	docReader
+	match   predicate
+	outside int
+}
+
+func (s *scopeReader) Next() (bson.M, error) {
+	doc, err := s.docReader.Next()
+	if err == nil && !s.match(doc) {
+		s.outside++
+	}
+	return doc, err
+}
+
+// clearFilter truncate 清空時刪除的文件：有 Scope 時只刪除符合的文件
+func (i *Importer) clearFilter() bson.M {
+	if len(i.opts.Scope) > 0 {
+		return i.opts.Scope
+	}
+	return bson.M{}
+}
@@ -0,0 +1,44 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ParsePatterns 解析以逗號分隔的 collection 名稱 glob，例如 "logs,analytics_*"
+func ParsePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns, checkPatterns(patterns)
+}
+
+func checkPatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+// selected 目錄模式下 collection 是否符合 Include / Exclude；Exclude 優先
+func (i *Importer) selected(coll string) bool {
+	if matchAny(i.opts.Exclude, coll) {
+		return false
+	}
+	return len(i.opts.Include) == 0 || matchAny(i.opts.Include, coll)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ServerInfo.Topology 的值
+const (
+	TopologyStandalone = "standalone"
+	TopologyReplicaSet = "replicaSet"
+	TopologySharded    = "sharded"
+)
+
+// 各功能需要的 wire version
+const (
+	wireCollation         = 5  // MongoDB 3.4
+	wireTransactions      = 7  // MongoDB 4.0，replica set
+	wireShardTransactions = 8  // MongoDB 4.2，mongos
+	wireTimeSeries        = 13 // MongoDB 5.0
+)
+
+// ServerInfo 連線的 server 版本與拓撲，見 DetectServer；零值表示不知道，除了 transaction 之外都假設支援，交給 server 決定
+type ServerInfo struct {
+	Version     string // 例如 "7.0.2"；沒有 buildInfo 的權限時為空字串
+	WireVersion int32
+	Topology    string // TopologyStandalone、TopologyReplicaSet 或 TopologySharded
+}
+
+// DetectServer 以 hello 判斷拓撲與 wire version，再以 buildInfo 取得版本字串
+func DetectServer(ctx context.Context, client *mongo.Client) (ServerInfo, error) {
+	var hello struct {
+		SetName        string `bson:"setName"`
+		Msg            string `bson:"msg"`
+		MaxWireVersion int32  `bson:"maxWireVersion"`
+	}
+	admin := client.Database("admin")
+	if err := admin.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return ServerInfo{}, err
+	}
+	info := ServerInfo{WireVersion: hello.MaxWireVersion, Topology: TopologyStandalone}
+	switch {
+	case hello.SetName != "":
+		info.Topology = TopologyReplicaSet
+	case hello.Msg == "isdbgrid":
+		info.Topology = TopologySharded
+	}
+	var build struct {
+		Version string `bson:"version"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&build); err == nil {
+		info.Version = build.Version
+	}
+	return info, nil
+}
+
+// String 例如 "MongoDB 7.0.2 (replica set)"
+func (s ServerInfo) String() string {
+	version := s.Version
+	if version == "" {
+		version = fmt.Sprintf("wire version %d", s.WireVersion)
+	}
+	topology := map[string]string{TopologyStandalone: "standalone", TopologyReplicaSet: "replica set", TopologySharded: "sharded cluster"}[s.Topology]
+	return fmt.Sprintf("MongoDB %s (%s)", version, topology)
+}
+
+func (s ServerInfo) known() bool {
+	return s.WireVersion > 0
+}
+
+// Transactions replica set（MongoDB 4.0）或 mongos（MongoDB 4.2）才支援多文件 transaction
+func (s ServerInfo) Transactions() bool {
+	switch s.Topology {
+	case TopologyReplicaSet:
+		return s.WireVersion >= wireTransactions
+	case TopologySharded:
+		return s.WireVersion >= wireShardTransactions
+	}
+	return false
+}
+
+// Collation collection、索引與 view 的 collation 選項（MongoDB 3.4）
+func (s ServerInfo) Collation() bool {
+	return !s.known() || s.WireVersion >= wireCollation
+}
+
+// TimeSeries time-series collection（MongoDB 5.0）
+func (s ServerInfo) TimeSeries() bool {
+	return !s.known() || s.WireVersion >= wireTimeSeries
+}
+
+// degradeCreateOptions 移除 server 不支援的 create 選項並記錄警告：沒有 collation 時拿掉 collation，
+// 沒有 time-series 時建立一般的 collection（expireAfterSeconds 只對 time-series 有效，一併拿掉）
+func (i *Importer) degradeCreateOptions(opts bson.D, path string, res *FileResult) bson.D {
+	drop := map[string]bool{}
+	if !i.targetServer.Collation() && hasOption(opts, "collation") {
+		drop["collation"] = true
+		res.warn(i.log, fmt.Sprintf("⚠️  %s does not support collations (requires 3.4); ignoring the collation in %s", i.targetServer, path),
+			"file", path, "server_version", i.targetServer.Version)
+	}
+	if !i.targetServer.TimeSeries() && hasOption(opts, "timeseries") {
+		drop["timeseries"], drop["expireAfterSeconds"] = true, true
+		res.warn(i.log, fmt.Sprintf("⚠️  %s does not support time-series collections (requires 5.0); %s creates a regular collection", i.targetServer, path),
+			"file", path, "server_version", i.targetServer.Version)
+	}
+	if len(drop) == 0 {
+		return opts
+	}
+	out := make(bson.D, 0, len(opts))
+	for _, e := range opts {
+		if !drop[e.Key] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// degradeIndexSpecs server 不支援 collation 時拿掉索引 spec 內的 collation
+func (i *Importer) degradeIndexSpecs(specs []bson.D, path string) []bson.D {
+	if i.targetServer.Collation() {
+		return specs
+	}
+	stripped := 0
+	out := make([]bson.D, len(specs))
+	for n, spec := range specs {
+		out[n] = make(bson.D, 0, len(spec))
+		for _, e := range spec {
+			if e.Key == "collation" {
+				stripped++
+				continue
+			}
+			out[n] = append(out[n], e)
+		}
+	}
+	if stripped > 0 {
+		i.log.Warn(fmt.Sprintf("⚠️  %s does not support collations (requires 3.4); creating %d indexes from %s without them", i.targetServer, stripped, path),
+			"file", path, "indexes", stripped, "server_version", i.targetServer.Version)
+	}
+	return out
+}
+
+func hasOption(opts bson.D, key string) bool {
+	for _, e := range opts {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxSplitSamples 計算 pre-split 切點時最多取樣的 shard key 值
+const maxSplitSamples = 10000
+
+// shardKeyFor 從 config.collections 讀取 collection 的 shard key；沒有分片（或連到的不是 sharded cluster）時回傳 nil
+func shardKeyFor(ctx context.Context, coll *mongo.Collection) (bson.D, error) {
+	var info struct {
+		Key     bson.D `bson:"key"`
+		Dropped bool   `bson:"dropped"` // MongoDB 5.0 之前刪除的 collection 仍留在 config.collections
+	}
+	ns := coll.Database().Name() + "." + coll.Name()
+	err := coll.Database().Client().Database("config").Collection("collections").FindOne(ctx, bson.M{"_id": ns}).Decode(&info)
+	if errors.Is(err, mongo.ErrNoDocuments) || info.Dropped {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return info.Key, nil
+}
+
+// shardKeyString 例如 {tenantId: 1, _id: "hashed"}
+func shardKeyString(key bson.D) string {
+	parts := make([]string, len(key))
+	for n, e := range key {
+		parts[n] = fmt.Sprintf("%s: %v", e.Key, e.Value)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// shardKeyReader 缺少 shard key 欄位的文件視為無效：server 會把缺少的欄位當成 null，全部落在同一個 chunk。
+// _id 由 driver 在插入時產生，不檢查
+type shardKeyReader struct {
+	docReader
+	key     bson.D
+	scanned int
+}
+
+func (s *shardKeyReader) Next() (bson.M, error) {
+	doc, err := s.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	s.scanned++
+	var missing []string
+	for _, e := range s.key {
+		if e.Key == "_id" {
+			continue
+		}
+		if _, ok := getPath(doc, e.Key); !ok {
+			missing = append(missing, e.Key)
+		}
+	}
+	if len(missing) > 0 {
+		raw, _ := bson.MarshalExtJSON(doc, false, false)
+		return nil, &parseError{
+			Pos: fmt.Sprintf("document %d", s.scanned),
+			Raw: string(raw),
+			Err: fmt.Errorf("missing shard key field %s (shard key %s)", strings.Join(missing, ", "), shardKeyString(s.key)),
+		}
+	}
+	return doc, nil
+}
+
+// preSplit 在空的 sharded collection 載入之前，依檔案內 shard key 第一個欄位的分布切成 Options.PreSplit 個 chunk，
+// 再輪流搬到各個 shard，避免大量插入全部落在同一個 shard；hashed shard key 本來就會分散，不處理
+func (i *Importer) preSplit(ctx context.Context, collection *mongo.Collection, filePath string, key bson.D, res *FileResult) error {
+	coll := collection.Name()
+	if key[0].Value == "hashed" {
+		res.warn(i.log, fmt.Sprintf("⚠️  %s has a hashed shard key %s; inserts are already spread across chunks, skipping pre-split", coll, shardKeyString(key)),
+			"collection", coll, "shard_key", shardKeyString(key))
+		return nil
+	}
+	if filePath == Stdin {
+		res.warn(i.log, fmt.Sprintf("⚠️  Cannot pre-split %s from stdin, which can only be read once", coll), "collection", coll)
+		return nil
+	}
+	n, err := collection.CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		i.log.Info(fmt.Sprintf("🧩 %s is not empty; skipping pre-split", coll), "collection", coll)
+		return nil
+	}
+
+	points, err := i.splitPoints(ctx, filePath, key[0].Key)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		res.warn(i.log, fmt.Sprintf("⚠️  No comparable values of %s in %s; skipping pre-split", key[0].Key, baseName(filePath)),
+			"file", filePath, "collection", coll, "field", key[0].Key)
+		return nil
+	}
+
+	admin := i.client.Database("admin")
+	ns := collection.Database().Name() + "." + coll
+	bound := func(first interface{}, rest interface{}) bson.D {
+		d := bson.D{{Key: key[0].Key, Value: first}}
+		for _, e := range key[1:] {
+			d = append(d, bson.E{Key: e.Key, Value: rest})
+		}
+		return d
+	}
+	for _, p := range points {
+		if err := admin.RunCommand(ctx, bson.D{{Key: "split", Value: ns}, {Key: "middle", Value: bound(p, primitive.MinKey{})}}).Err(); err != nil {
+			return fmt.Errorf("failed to split %s at %v: %v", ns, p, err)
+		}
+	}
+
+	var shards struct {
+		Shards []struct {
+			ID string `bson:"_id"`
+		} `bson:"shards"`
+	}
+	if err := admin.RunCommand(ctx, bson.D{{Key: "listShards", Value: 1}}).Decode(&shards); err != nil {
+		return fmt.Errorf("failed to list shards: %v", err)
+	}
+	// chunk 已經在目標 shard 上時 moveChunk 會失敗，只在全部失敗時才警告
+	moved, failed := 0, 0
+	if len(shards.Shards) > 1 {
+		lower := bound(primitive.MinKey{}, primitive.MinKey{})
+		for n := 0; n <= len(points); n++ {
+			upper := bound(primitive.MaxKey{}, primitive.MaxKey{})
+			if n < len(points) {
+				upper = bound(points[n], primitive.MinKey{})
+			}
+			to := shards.Shards[n%len(shards.Shards)].ID
+			cmd := bson.D{{Key: "moveChunk", Value: ns}, {Key: "bounds", Value: bson.A{lower, upper}}, {Key: "to", Value: to}}
+			if err := admin.RunCommand(ctx, cmd).Err(); err != nil {
+				failed++
+			} else {
+				moved++
+			}
+			lower = upper
+		}
+		if moved == 0 {
+			res.warn(i.log, fmt.Sprintf("⚠️  Could not move any chunk of %s; the balancer will distribute them", coll), "collection", coll)
+		}
+	}
+	i.log.Info(fmt.Sprintf("🧩 Pre-split %s into %d chunks on %s across %d shards", coll, len(points)+1, key[0].Key, len(shards.Shards)),
+		"collection", coll, "chunks", len(points)+1, "shards", len(shards.Shards), "moved", moved, "move_failed", failed)
+	return nil
+}
+
+// splitPoints 以 reservoir sampling 取樣檔案內 field 的值，回傳切成 Options.PreSplit 份的切點（遞增、不重複）；
+// 只使用數量最多的一種可比較型別（數字、字串、ObjectId 或 date）
+func (i *Importer) splitPoints(ctx context.Context, filePath, field string) ([]interface{}, error) {
+	docs, err := i.Documents(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer docs.Close()
+
+	samples := map[string][]interface{}{}
+	seen := map[string]int{}
+	for {
+		doc, err := docs.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			// 無效的文件留給匯入時處理，用已經讀到的值計算切點
+			var perr *parseError
+			if errors.As(err, &perr) {
+				break
+			}
+			return nil, err
+		}
+		v, ok := getPath(doc, field)
+		if !ok {
+			continue
+		}
+		kind, v := splitKind(v)
+		if kind == "" {
+			continue
+		}
+		seen[kind]++
+		if s := samples[kind]; len(s) < maxSplitSamples {
+			samples[kind] = append(s, v)
+		} else if r := rand.Intn(seen[kind]); r < maxSplitSamples {
+			s[r] = v
+		}
+	}
+
+	var kind string
+	for k := range samples {
+		if kind == "" || len(samples[k]) > len(samples[kind]) || (len(samples[k]) == len(samples[kind]) && k < kind) {
+			kind = k
+		}
+	}
+	values := samples[kind]
+	if len(values) == 0 {
+		return nil, nil
+	}
+	sort.Slice(values, func(a, b int) bool { return lessSplit(values[a], values[b]) })
+
+	var points []interface{}
+	chunks := i.opts.PreSplit
+	for n := 1; n < chunks; n++ {
+		v := values[n*len(values)/chunks]
+		// 切點必須大於最小值，否則第一個 chunk 是空的
+		if !lessSplit(values[0], v) || (len(points) > 0 && !lessSplit(points[len(points)-1], v)) {
+			continue
+		}
+		points = append(points, v)
+	}
+	return points, nil
+}
+
+// splitKind 可以排序的 shard key 值的型別，整數與浮點數都當成數字比較；整數維持 int64，大於 2^53 的值不會因為轉成 float64 而失真
+func splitKind(v interface{}) (string, interface{}) {
+	switch x := v.(type) {
+	case int32:
+		return "number", int64(x)
+	case int64:
+		return "number", x
+	case int:
+		return "number", int64(x)
+	case float64:
+		if math.IsNaN(x) {
+			return "", nil
+		}
+		return "number", x
+	case string:
+		return "string", x
+	case primitive.ObjectID:
+		return "objectId", x
+	case primitive.DateTime:
+		return "date", x
+	}
+	return "", nil
+}
+
+func lessSplit(a, b interface{}) bool {
+	switch x := a.(type) {
+	case int64, float64:
+		return compareNumbers(x, b) < 0
+	case string:
+		return x < b.(string)
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:]) < 0
+	case primitive.DateTime:
+		return x < b.(primitive.DateTime)
+	}
+	return false
+}
+
+// compareNumbers 比較 int64 與 float64；型別不同時以 big.Float 精確比較
+func compareNumbers(a, b interface{}) int {
+	x, xok := a.(int64)
+	y, yok := b.(int64)
+	if xok && yok {
+		return cmp.Compare(x, y)
+	}
+	if !xok && !yok {
+		return cmp.Compare(a.(float64), b.(float64))
+	}
+	return bigNumber(a).Cmp(bigNumber(b))
+}
+
+func bigNumber(v interface{}) *big.Float {
+	if n, ok := v.(int64); ok {
+		return new(big.Float).SetInt64(n)
+	}
+	return big.NewFloat(v.(float64))
+}
@@ -0,0 +1,1044 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/internal/crypt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// .sql dump（實驗性）：只讀取 INSERT INTO 的資料，每個 table 匯入同名的 collection。
+// CREATE TABLE 的欄位型別用來轉換值（整數、decimal、bool、日期、JSON），INSERT 沒有列出欄位時也以它決定欄位名稱；
+// 其他 statement（索引、constraint、function 等）一律略過。pg_dump 預設的 COPY ... FROM stdin 不支援，需改用 --inserts
+
+// sqlTokenKind statement 內的 token 種類
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // 沒有引號的識別字或關鍵字
+	sqlIdent                      // `name` 或 "name"
+	sqlString                     // '...'、E'...'、$$...$$
+	sqlNumber                     // 數字；b'0101' 也轉成十進位數字
+	sqlHex                        // 0x... 或 X'...'，text 為 hex
+	sqlPunct                      // 單一字元的符號
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// is 不分大小寫比對關鍵字
+func (t sqlToken) is(word string) bool {
+	return t.kind == sqlWord && strings.EqualFold(t.text, word)
+}
+
+func (t sqlToken) punct(c string) bool {
+	return t.kind == sqlPunct && t.text == c
+}
+
+// sqlScanner 把 dump 切成一個個 statement，略過註解（含 MySQL 的 /*!40101 ... */）
+type sqlScanner struct {
+	r         *bufio.Reader
+	line      int
+	delimiter string // mysqldump 在 trigger / routine 前後以 DELIMITER 改變
+	backslash bool   // 字串內的 \ 是跳脫字元：MySQL 如此；PostgreSQL 在 standard_conforming_strings = on 時不是
+}
+
+func newSQLScanner(r io.Reader) *sqlScanner {
+	return &sqlScanner{r: bufio.NewReader(r), line: 1, delimiter: ";", backslash: true}
+}
+
+func (s *sqlScanner) read() (byte, error) {
+	c, err := s.r.ReadByte()
+	if c == '\n' && err == nil {
+		s.line++
+	}
+	return c, err
+}
+
+// peekIs 接下來的 bytes 是否為 p（不讀取）
+func (s *sqlScanner) peekIs(p string) bool {
+	b, _ := s.r.Peek(len(p))
+	return string(b) == p
+}
+
+func (s *sqlScanner) skip(n int) {
+	for ; n > 0; n-- {
+		s.read()
+	}
+}
+
+// next 回傳下一個 statement 的 tokens 與開始的行號；讀完時回傳 io.EOF
+func (s *sqlScanner) next() ([]sqlToken, int, error) {
+	var toks []sqlToken
+	start := 0
+	for {
+		c, err := s.read()
+		if err == io.EOF {
+			if len(toks) > 0 {
+				// 最後一個 statement 沒有結尾的 ;
+				return toks, start, nil
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == '-' && s.peekIs("-"), c == '#':
+			if _, err := s.r.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, 0, err
+			}
+			s.line++
+			continue
+		case c == '/' && s.peekIs("*"):
+			if err := s.skipBlockComment(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if c == s.delimiter[0] && s.peekIs(s.delimiter[1:]) {
+			s.skip(len(s.delimiter) - 1)
+			if len(toks) > 0 {
+				return toks, start, nil
+			}
+			continue
+		}
+		if len(toks) == 0 {
+			start = s.line
+		}
+		tok, err := s.token(c)
+		if err != nil {
+			return nil, 0, fmt.Errorf("line %d: %v", s.line, err)
+		}
+		if len(toks) == 0 && tok.is("DELIMITER") {
+			// DELIMITER 是 mysql client 的指令，到行尾為止
+			rest, err := s.r.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return nil, 0, err
+			}
+			s.line++
+			if d := strings.TrimSpace(rest); d != "" {
+				s.delimiter = d
+			}
+			continue
+		}
+		toks = append(toks, tok)
+	}
+}
+
+func (s *sqlScanner) skipBlockComment() error {
+	s.read()
+	for {
+		c, err := s.read()
+		if err != nil {
+			return fmt.Errorf("unterminated comment: %v", err)
+		}
+		if c == '*' && s.peekIs("/") {
+			s.read()
+			return nil
+		}
+	}
+}
+
+// token 讀出以 c 開頭的 token
+func (s *sqlScanner) token(c byte) (sqlToken, error) {
+	switch {
+	case c == '\'':
+		str, err := s.quoted('\'', s.backslash)
+		return sqlToken{sqlString, str}, err
+	case c == '`' || c == '"':
+		str, err := s.quoted(c, false)
+		return sqlToken{sqlIdent, str}, err
+	case c == '$' && s.dollarTag() != "":
+		return s.dollarQuoted()
+	case c >= '0' && c <= '9', c == '.' && s.peekDigit():
+		return s.number(c), nil
+	case isSQLWordByte(c):
+		word := s.word(c)
+		if s.peekIs("'") {
+			s.read()
+			switch strings.ToUpper(word) {
+			case "E":
+				str, err := s.quoted('\'', true)
+				return sqlToken{sqlString, str}, err
+			case "X":
+				str, err := s.quoted('\'', false)
+				return sqlToken{sqlHex, str}, err
+			case "B":
+				str, err := s.quoted('\'', false)
+				if err != nil {
+					return sqlToken{}, err
+				}
+				n, err := strconv.ParseUint(str, 2, 64)
+				if err != nil {
+					return sqlToken{}, fmt.Errorf("invalid bit string b'%s'", str)
+				}
+				return sqlToken{sqlNumber, strconv.FormatUint(n, 10)}, nil
+			}
+			// N'...' 或 _utf8mb4'...' 之類的前綴
+			str, err := s.quoted('\'', s.backslash)
+			return sqlToken{sqlString, str}, err
+		}
+		return sqlToken{sqlWord, word}, nil
+	}
+	return sqlToken{sqlPunct, string(c)}, nil
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (s *sqlScanner) peekDigit() bool {
+	b, _ := s.r.Peek(1)
+	return len(b) == 1 && b[0] >= '0' && b[0] <= '9'
+}
+
+func (s *sqlScanner) word(c byte) string {
+	w := []byte{c}
+	for {
+		b, err := s.r.Peek(1)
+		if err != nil || !isSQLWordByte(b[0]) {
+			return string(w)
+		}
+		s.read()
+		w = append(w, b[0])
+	}
+}
+
+// number 數字與 0x 開頭的 hex
+func (s *sqlScanner) number(c byte) sqlToken {
+	if c == '0' && (s.peekIs("x") || s.peekIs("X")) {
+		s.read()
+		w := s.word('0')
+		return sqlToken{sqlHex, w[1:]}
+	}
+	n := []byte{c}
+	for {
+		b, err := s.r.Peek(1)
+		if err != nil {
+			break
+		}
+		d, last := b[0], n[len(n)-1]
+		exp := last == 'e' || last == 'E'
+		if (d < '0' || d > '9') && d != '.' && d != 'e' && d != 'E' && !((d == '-' || d == '+') && exp) {
+			break
+		}
+		s.read()
+		n = append(n, d)
+	}
+	return sqlToken{sqlNumber, string(n)}
+}
+
+// quoted 讀到結尾的 quote；連續兩個 quote 表示一個 quote 字元，backslash 時處理 \n、\t、\0 等跳脫
+func (s *sqlScanner) quoted(q byte, backslash bool) (string, error) {
+	var b bytes.Buffer
+	for {
+		c, err := s.read()
+		if err != nil {
+			return "", fmt.Errorf("unterminated %c-quoted string", q)
+		}
+		switch {
+		case c == q:
+			if !s.peekIs(string(q)) {
+				return b.String(), nil
+			}
+			s.read()
+		case c == '\\' && backslash:
+			if c, err = s.read(); err != nil {
+				return "", fmt.Errorf("unterminated %c-quoted string", q)
+			}
+			switch c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			case '0':
+				c = 0
+			case 'b':
+				c = '\b'
+			case 'Z':
+				c = 0x1a
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+// dollarTag PostgreSQL 的 $tag$ 開頭（不讀取）；不是時回傳空字串
+func (s *sqlScanner) dollarTag() string {
+	for n := 1; ; n++ {
+		b, err := s.r.Peek(n)
+		if err != nil {
+			return ""
+		}
+		c := b[n-1]
+		if c == '$' {
+			return "$" + string(b)
+		}
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (n == 1 || c < '0' || c > '9') {
+			return ""
+		}
+	}
+}
+
+func (s *sqlScanner) dollarQuoted() (sqlToken, error) {
+	tag := s.dollarTag()
+	s.skip(len(tag) - 1)
+	var b bytes.Buffer
+	for {
+		c, err := s.read()
+		if err != nil {
+			return sqlToken{}, fmt.Errorf("unterminated %s-quoted string", tag)
+		}
+		if c == '$' && s.peekIs(tag[1:]) {
+			s.skip(len(tag) - 1)
+			return sqlToken{sqlString, b.String()}, nil
+		}
+		b.WriteByte(c)
+	}
+}
+
+// skipCopyData 略過 COPY ... FROM stdin 之後到 \. 為止的資料
+func (s *sqlScanner) skipCopyData() error {
+	for {
+		line, err := s.r.ReadString('\n')
+		if strings.HasSuffix(line, "\n") {
+			s.line++
+		}
+		if strings.TrimRight(line, "\r\n") == `\.` {
+			return nil
+		}
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("unterminated COPY data")
+			}
+			return err
+		}
+	}
+}
+
+// sqlColumn CREATE TABLE 的一個欄位；hint 為轉換值的型別（同 CSVOptions.Fields，另有 json），空字串表示依值推斷
+type sqlColumn struct {
+	name string
+	hint string
+}
+
+// sqlTypeHints SQL 欄位型別 → 轉換的型別
+var sqlTypeHints = map[string]string{
+	"tinyint": "int", "smallint": "int", "mediumint": "int", "int": "int", "integer": "int",
+	"int2": "int", "int4": "int", "serial": "int", "smallserial": "int", "serial4": "int",
+	"bigint": "long", "int8": "long", "bigserial": "long", "serial8": "long",
+	"decimal": "decimal", "numeric": "decimal", "dec": "decimal", "fixed": "decimal",
+	"float": "double", "double": "double", "real": "double", "float4": "double", "float8": "double",
+	"bool": "bool", "boolean": "bool",
+	"date": "date", "datetime": "date", "timestamp": "date", "timestamptz": "date",
+	"json": "json", "jsonb": "json",
+}
+
+// sqlConstraintWords CREATE TABLE 內不是欄位定義的項目
+var sqlConstraintWords = []string{"PRIMARY", "KEY", "INDEX", "UNIQUE", "CONSTRAINT", "FOREIGN", "CHECK", "FULLTEXT", "SPATIAL", "EXCLUDE", "LIKE"}
+
+// sqlInsert 一個 INSERT（或 COPY）statement；rows 在需要時才解析
+type sqlInsert struct {
+	table   string
+	columns []string // nil 表示 INSERT 沒有列出欄位
+	values  []sqlToken
+	copy    bool
+	line    int
+}
+
+// sqlDump 依序讀出 dump 內的 INSERT，並記下之前的 CREATE TABLE
+type sqlDump struct {
+	scan    *sqlScanner
+	columns map[string][]sqlColumn
+}
+
+func newSQLDump(r io.Reader) *sqlDump {
+	return &sqlDump{scan: newSQLScanner(r), columns: map[string][]sqlColumn{}}
+}
+
+// nextInsert 讀完時回傳 io.EOF
+func (d *sqlDump) nextInsert() (*sqlInsert, error) {
+	for {
+		toks, line, err := d.scan.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case toks[0].is("SET") && len(toks) >= 3 && toks[1].is("standard_conforming_strings"):
+			d.scan.backslash = !strings.EqualFold(toks[len(toks)-1].text, "on")
+		case toks[0].is("CREATE"):
+			if table, cols, ok := parseCreateTable(toks); ok {
+				d.columns[table] = cols
+			}
+		case toks[0].is("INSERT") || toks[0].is("REPLACE"):
+			if ins, ok := parseInsert(toks); ok {
+				ins.line = line
+				return ins, nil
+			}
+		case toks[0].is("COPY"):
+			if ins, ok := parseCopy(toks); ok {
+				ins.line = line
+				if err := d.scan.skipCopyData(); err != nil {
+					return nil, err
+				}
+				return ins, nil
+			}
+		}
+	}
+}
+
+// tableName 讀出 db.schema.table 形式的名稱，回傳最後一段與下一個 token 的位置
+func tableName(toks []sqlToken, i int) (string, int) {
+	name := ""
+	for i < len(toks) && (toks[i].kind == sqlWord || toks[i].kind == sqlIdent) {
+		name = toks[i].text
+		i++
+		if i >= len(toks) || !toks[i].punct(".") {
+			break
+		}
+		i++
+	}
+	return name, i
+}
+
+// columnList 讀出 (a, b, c)；toks[i] 不是 ( 時回傳 nil
+func columnList(toks []sqlToken, i int) ([]string, int) {
+	if i >= len(toks) || !toks[i].punct("(") {
+		return nil, i
+	}
+	cols := []string{}
+	for i++; i < len(toks) && !toks[i].punct(")"); i++ {
+		if !toks[i].punct(",") {
+			cols = append(cols, toks[i].text)
+		}
+	}
+	return cols, i + 1
+}
+
+// parseCreateTable CREATE [TEMPORARY] TABLE [IF NOT EXISTS] name (欄位定義, ...)
+func parseCreateTable(toks []sqlToken) (string, []sqlColumn, bool) {
+	i := 1
+	for i < len(toks) && !toks[i].is("TABLE") {
+		if !toks[i].is("TEMPORARY") && !toks[i].is("TEMP") && !toks[i].is("UNLOGGED") {
+			return "", nil, false
+		}
+		i++
+	}
+	if i++; i+2 < len(toks) && toks[i].is("IF") && toks[i+1].is("NOT") && toks[i+2].is("EXISTS") {
+		i += 3
+	}
+	table, i := tableName(toks, i)
+	if table == "" || i >= len(toks) || !toks[i].punct("(") {
+		return "", nil, false
+	}
+	var cols []sqlColumn
+	// 以最外層的逗號分開每個定義
+	var def []sqlToken
+	depth := 0
+	for i++; i < len(toks); i++ {
+		t := toks[i]
+		switch {
+		case t.punct("("):
+			depth++
+		case t.punct(")") && depth == 0, t.punct(",") && depth == 0:
+			if c, ok := parseColumnDef(def); ok {
+				cols = append(cols, c)
+			}
+			def = def[:0]
+			if t.punct(")") {
+				return table, cols, true
+			}
+			continue
+		case t.punct(")"):
+			depth--
+		}
+		def = append(def, t)
+	}
+	return table, cols, true
+}
+
+func parseColumnDef(def []sqlToken) (sqlColumn, bool) {
+	if len(def) == 0 || (def[0].kind != sqlWord && def[0].kind != sqlIdent) {
+		return sqlColumn{}, false
+	}
+	if def[0].kind == sqlWord {
+		for _, w := range sqlConstraintWords {
+			if def[0].is(w) {
+				return sqlColumn{}, false
+			}
+		}
+	}
+	c := sqlColumn{name: def[0].text}
+	if len(def) > 1 && def[1].kind != sqlPunct {
+		typ := strings.ToLower(def[1].text)
+		c.hint = sqlTypeHints[typ]
+		// MySQL 的 BOOLEAN 是 tinyint(1)，bit(1) 也當作 bool
+		if (typ == "tinyint" || typ == "bit") && len(def) > 4 && def[2].punct("(") && def[3].text == "1" && def[4].punct(")") {
+			c.hint = "bool"
+		}
+	}
+	return c, true
+}
+
+// parseInsert INSERT [IGNORE] INTO name [(columns)] [OVERRIDING ... VALUE] VALUES (...), (...) [ON ...]；
+// INSERT ... SELECT 與 INSERT ... SET 不支援，當作一般的 statement 略過
+func parseInsert(toks []sqlToken) (*sqlInsert, bool) {
+	i := 1
+	for i < len(toks) && toks[i].kind == sqlWord && !toks[i].is("INTO") && !toks[i].is("VALUES") {
+		// LOW_PRIORITY、IGNORE 等修飾詞；MySQL 的 INTO 可省略
+		if i+1 < len(toks) && (toks[i+1].punct("(") || toks[i+1].punct(".") || toks[i+1].is("VALUES") || toks[i+1].is("VALUE")) {
+			break
+		}
+		i++
+	}
+	if i < len(toks) && toks[i].is("INTO") {
+		i++
+	}
+	table, i := tableName(toks, i)
+	if table == "" {
+		return nil, false
+	}
+	ins := &sqlInsert{table: table}
+	ins.columns, i = columnList(toks, i)
+	if i+2 < len(toks) && toks[i].is("OVERRIDING") {
+		// PostgreSQL identity 欄位：OVERRIDING SYSTEM VALUE
+		i += 3
+	}
+	if i >= len(toks) || !(toks[i].is("VALUES") || toks[i].is("VALUE")) {
+		return nil, false
+	}
+	ins.values = toks[i+1:]
+	return ins, true
+}
+
+// parseCopy COPY name [(columns)] FROM stdin；從檔案 COPY 的 statement 不含資料，略過
+func parseCopy(toks []sqlToken) (*sqlInsert, bool) {
+	table, i := tableName(toks, 1)
+	cols, i := columnList(toks, i)
+	if table == "" || i+1 >= len(toks) || !toks[i].is("FROM") || !toks[i+1].is("stdin") {
+		return nil, false
+	}
+	return &sqlInsert{table: table, columns: cols, copy: true}, true
+}
+
+// rows 解析 VALUES 之後的 (v, ...), (v, ...)；每個值是一個 token
+func (ins *sqlInsert) rows() ([][]sqlToken, error) {
+	var rows [][]sqlToken
+	toks := ins.values
+	i := 0
+	for i < len(toks) && toks[i].punct("(") {
+		var row []sqlToken
+		i++
+		for {
+			v, next, err := sqlValue(toks, i)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", len(rows)+1, err)
+			}
+			row = append(row, v)
+			// 略過 ::type 之類的 cast 與 COLLATE
+			depth := 0
+			for i = next; i < len(toks) && (depth > 0 || (!toks[i].punct(",") && !toks[i].punct(")"))); i++ {
+				if toks[i].punct("(") {
+					depth++
+				} else if toks[i].punct(")") {
+					depth--
+				}
+			}
+			if i >= len(toks) {
+				return nil, fmt.Errorf("row %d: missing )", len(rows)+1)
+			}
+			i++
+			if toks[i-1].punct(")") {
+				break
+			}
+		}
+		rows = append(rows, row)
+		if i >= len(toks) || !toks[i].punct(",") {
+			break
+		}
+		i++
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("expected VALUES (...)")
+	}
+	return rows, nil
+}
+
+// sqlValue 一個值：常數、帶正負號的數字、NULL / TRUE / FALSE / DEFAULT；函式呼叫與運算式不支援
+func sqlValue(toks []sqlToken, i int) (sqlToken, int, error) {
+	if i >= len(toks) {
+		return sqlToken{}, i, fmt.Errorf("missing value")
+	}
+	t := toks[i]
+	switch {
+	case (t.punct("-") || t.punct("+")) && i+1 < len(toks) && toks[i+1].kind == sqlNumber:
+		n := toks[i+1]
+		if t.text == "-" {
+			n.text = "-" + n.text
+		}
+		return n, i + 2, nil
+	case t.kind == sqlString, t.kind == sqlNumber, t.kind == sqlHex:
+		return t, i + 1, nil
+	case t.kind == sqlIdent:
+		// 手寫的 MySQL 常用雙引號包字串
+		return sqlToken{sqlString, t.text}, i + 1, nil
+	case t.is("NULL"), t.is("TRUE"), t.is("FALSE"), t.is("DEFAULT"):
+		if i+1 < len(toks) && toks[i+1].punct("(") {
+			break
+		}
+		return t, i + 1, nil
+	case t.kind == sqlWord && strings.HasPrefix(t.text, "_") && i+1 < len(toks) && toks[i+1].kind == sqlString:
+		// _binary '...' 之類的字元集前綴
+		return toks[i+1], i + 2, nil
+	}
+	return sqlToken{}, i, fmt.Errorf("unsupported value %s (only constants are supported)", t.text)
+}
+
+// convertSQLValue 依欄位型別轉換；轉換失敗時字串保留原樣，數字依值推斷
+func convertSQLValue(t sqlToken, hint string) (interface{}, error) {
+	switch t.kind {
+	case sqlWord:
+		switch strings.ToUpper(t.text) {
+		case "TRUE":
+			return true, nil
+		case "FALSE":
+			return false, nil
+		}
+		return nil, nil
+	case sqlHex:
+		data, err := hex.DecodeString(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value %s", t.text)
+		}
+		return primitive.Binary{Data: data}, nil
+	case sqlNumber:
+		switch hint {
+		case "bool":
+			return t.text != "0", nil
+		case "int", "long", "double", "decimal":
+			if v, err := convertCSVValue(t.text, hint); err == nil {
+				return v, nil
+			}
+		}
+		return convertCSVValue(t.text, "auto")
+	}
+
+	s := t.text
+	switch hint {
+	case "bool":
+		switch strings.ToLower(s) {
+		case "t", "true", "y", "yes", "on", "1", "\x01":
+			return true, nil
+		case "f", "false", "n", "no", "off", "0", "\x00":
+			// mysqldump 把 bit(1) 寫成 _binary '\0' / '\1'
+			return false, nil
+		}
+	case "int", "long", "double", "decimal":
+		if v, err := convertCSVValue(s, hint); err == nil {
+			return v, nil
+		}
+	case "date":
+		if strings.HasPrefix(s, "0000-00-00") {
+			// MySQL 的零日期
+			return nil, nil
+		}
+		if t, err := parseSQLDate(s); err == nil {
+			return primitive.NewDateTimeFromTime(t), nil
+		}
+	case "json":
+		var d bson.D
+		if err := bson.UnmarshalExtJSON([]byte(`{"v":`+s+`}`), false, &d); err == nil && len(d) == 1 {
+			return d[0].Value, nil
+		}
+	}
+	return s, nil
+}
+
+// sqlDateLayouts PostgreSQL timestamptz 的寫法，例如 2024-01-02 10:00:00+08
+var sqlDateLayouts = []string{
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05Z07:00",
+}
+
+func parseSQLDate(s string) (time.Time, error) {
+	for _, layout := range sqlDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return parseDate(s)
+}
+
+// sqlInserts INSERT 的來源：直接掃描 dump 的 sqlDump，或 spoolSQLDump 拆出的暫存檔
+type sqlInserts interface {
+	nextInsert() (*sqlInsert, error)
+	columnsFor(ins *sqlInsert) ([]sqlColumn, error)
+}
+
+// sqlReader 讀出 dump 內 table 的每一列
+type sqlReader struct {
+	dump  sqlInserts
+	table string
+	order *orderTracker
+	ins   *sqlInsert
+	cols  []sqlColumn
+	rows  [][]sqlToken
+	row   int
+}
+
+func newSQLReader(r io.Reader, table string, order *orderTracker) *sqlReader {
+	return &sqlReader{dump: newSQLDump(r), table: table, order: order}
+}
+
+func (r *sqlReader) Next() (bson.M, error) {
+	for r.row >= len(r.rows) {
+		ins, err := r.dump.nextInsert()
+		if err != nil {
+			return nil, err
+		}
+		if ins.table != r.table {
+			continue
+		}
+		r.ins, r.rows, r.row = ins, nil, 0
+		pos := fmt.Sprintf("line %d", ins.line)
+		if ins.copy {
+			return nil, &parseError{Pos: pos, Err: fmt.Errorf("COPY %s FROM stdin is not supported; dump with pg_dump --inserts or --column-inserts", ins.table)}
+		}
+		if r.cols, err = r.dump.columnsFor(ins); err != nil {
+			return nil, &parseError{Pos: pos, Err: err}
+		}
+		if r.rows, err = ins.rows(); err != nil {
+			return nil, &parseError{Pos: pos, Err: err}
+		}
+	}
+	values := r.rows[r.row]
+	r.row++
+	pos := fmt.Sprintf("line %d row %d", r.ins.line, r.row)
+	if len(values) != len(r.cols) {
+		return nil, &parseError{Pos: pos, Err: fmt.Errorf("%d values for %d columns", len(values), len(r.cols))}
+	}
+	d := make(bson.D, 0, len(values))
+	for j, v := range values {
+		if v.is("DEFAULT") {
+			continue
+		}
+		val, err := convertSQLValue(v, r.cols[j].hint)
+		if err != nil {
+			return nil, &parseError{Pos: pos, Err: fmt.Errorf("column %s: %v", r.cols[j].name, err)}
+		}
+		d = append(d, bson.E{Key: r.cols[j].name, Value: val})
+	}
+	if r.order != nil {
+		return r.order.track(d), nil
+	}
+	m := make(bson.M, len(d))
+	for _, e := range d {
+		m[e.Key] = e.Value
+	}
+	return m, nil
+}
+
+// columnsFor INSERT 的欄位；沒有列出欄位時依之前的 CREATE TABLE
+func (d *sqlDump) columnsFor(ins *sqlInsert) ([]sqlColumn, error) {
+	defined := d.columns[ins.table]
+	if ins.columns == nil {
+		if defined == nil {
+			return nil, fmt.Errorf("INSERT into %s without a column list and no CREATE TABLE before it", ins.table)
+		}
+		return defined, nil
+	}
+	cols := make([]sqlColumn, len(ins.columns))
+	for i, name := range ins.columns {
+		cols[i] = sqlColumn{name: name}
+		for _, c := range defined {
+			if c.name == name {
+				cols[i].hint = c.hint
+			}
+		}
+	}
+	return cols, nil
+}
+
+// sqlTables dump 內有資料的 table，依第一次出現的順序
+func sqlTables(ctx context.Context, filePath string, key []byte) ([]string, error) {
+	in, err := openInput(ctx, filePath, key)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	dump := newSQLDump(in)
+	var tables []string
+	seen := map[string]bool{}
+	for {
+		ins, err := dump.nextInsert()
+		if err == io.EOF {
+			return tables, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !seen[ins.table] {
+			seen[ins.table] = true
+			tables = append(tables, ins.table)
+		}
+	}
+}
+
+// importSQLDump 把 .sql dump 內每個 table 依序匯入同名的 collection：先掃描一次 dump，把每個 table 的 INSERT 拆到暫存檔，
+// 再逐一以一般的匯入流程匯入；有 Options.Collection 時只匯入那個 table，Include / Exclude 以 table 名稱過濾。
+// 暫存檔佔用的磁碟空間約等於 dump（解壓後）的大小；加密的 dump 以同一把金鑰加密暫存檔，中斷後留下的暫存檔也不是明文
+func (i *Importer) importSQLDump(ctx context.Context, filePath string) []FileResult {
+	fail := func(err error) []FileResult {
+		i.log.Error(fmt.Sprintf("❌ Failed to read SQL dump %s: %v", filePath, err), "file", filePath, errAttr(err))
+		return []FileResult{{File: filePath, Err: err}}
+	}
+	dir, err := os.MkdirTemp("", "mongo-tools-sql-")
+	if err != nil {
+		return fail(err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, err := openInput(ctx, filePath, i.opts.DecryptKey)
+	if err != nil {
+		return fail(err)
+	}
+	var key []byte
+	if strings.HasSuffix(baseName(filePath), crypt.Ext) {
+		key = i.opts.DecryptKey
+	}
+	tables, err := spoolSQLDump(in, dir, key, func(table string) bool { return i.sqlTableWanted(filePath, table) })
+	in.Close()
+	if err != nil {
+		return fail(err)
+	}
+	if len(tables) == 0 {
+		res := FileResult{File: filePath, Skipped: true}
+		res.warn(i.log, fmt.Sprintf("⚠️  No INSERT statements to import in %s", baseName(filePath)), "file", filePath)
+		return []FileResult{res}
+	}
+	// 所有 table 以同一個平移量
+	if i.dates != nil {
+		files := make([]string, len(tables))
+		for n, t := range tables {
+			files[n] = t.file
+		}
+		if err := i.prepareDateShift(ctx, files); err != nil {
+			return fail(err)
+		}
+	}
+	var results []FileResult
+	for _, t := range tables {
+		if ctx.Err() != nil {
+			break
+		}
+		sub := *i
+		sub.opts.Collection, sub.source = t.table, filePath
+		res, err := sub.ImportFile(ctx, t.file)
+		res.File = filePath
+		results = append(results, res)
+		if err != nil && i.opts.FailFast {
+			break
+		}
+	}
+	return results
+}
+
+// sqlTablesFor sqlTables 再套用 Options.Collection 與 Include / Exclude
+func (i *Importer) sqlTablesFor(ctx context.Context, filePath string) ([]string, error) {
+	all, err := sqlTables(ctx, filePath, i.opts.DecryptKey)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, table := range all {
+		if i.sqlTableWanted(filePath, table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// sqlTableWanted 套用 Options.Collection 與 Include / Exclude；被過濾掉的 table 記錄一次
+func (i *Importer) sqlTableWanted(filePath, table string) bool {
+	if i.opts.Collection != "" && table != i.opts.Collection {
+		return false
+	}
+	if !i.selected(table) {
+		i.log.Info(fmt.Sprintf("⏭️  Skipping table %s of %s: excluded by the collection filters", table, baseName(filePath)),
+			"file", filePath, "collection", table)
+		return false
+	}
+	return true
+}
+
+// sqlSpoolExt spoolSQLDump 寫出的暫存檔：一個 table 已經切好 token 的 INSERT，讀回時不必再掃描整個 dump；
+// 加密的暫存檔再加上 crypt.Ext，由 openInput 解密
+const sqlSpoolExt = ".sqlrows"
+
+// spooledInsert 暫存檔內的一個 INSERT；欄位依 dump 中在它之前的 CREATE TABLE 決定，Line 為在 dump 內的行號
+type spooledInsert struct {
+	Line       int
+	Copy       bool
+	Columns    []spooledColumn
+	ColumnsErr string
+	Values     []spooledToken
+}
+
+type spooledColumn struct {
+	Name, Hint string
+}
+
+type spooledToken struct {
+	Kind sqlTokenKind
+	Text string
+}
+
+// spooledTable 已從 dump 拆出到暫存目錄的 table
+type spooledTable struct {
+	table string
+	file  string
+	out   *os.File
+	seal  *crypt.Writer // 不加密時為 nil
+	w     *bufio.Writer
+	enc   *gob.Encoder
+}
+
+// spoolSQLDump 掃描一次 dump，把 keep 的 table 的 INSERT 依序寫到 dir 下每個 table 一個暫存檔；table 依第一次出現的順序。
+// key 不為 nil 時暫存檔以 key 加密
+func spoolSQLDump(r io.Reader, dir string, key []byte, keep func(table string) bool) ([]*spooledTable, error) {
+	dump := newSQLDump(r)
+	byName := map[string]*spooledTable{}
+	var tables []*spooledTable
+	defer func() {
+		for _, t := range tables {
+			if t.out != nil {
+				t.out.Close()
+			}
+		}
+	}()
+	for {
+		ins, err := dump.nextInsert()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t, seen := byName[ins.table]
+		if !seen {
+			if keep(ins.table) {
+				t = &spooledTable{table: ins.table, file: filepath.Join(dir, url.PathEscape(ins.table)+sqlSpoolExt)}
+				if key != nil {
+					t.file += crypt.Ext
+				}
+				if t.out, err = os.OpenFile(t.file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600); err != nil {
+					return nil, err
+				}
+				tables = append(tables, t)
+				var w io.Writer = t.out
+				if key != nil {
+					if t.seal, err = crypt.NewWriter(t.out, key); err != nil {
+						return nil, err
+					}
+					w = t.seal
+				}
+				t.w = bufio.NewWriter(w)
+				t.enc = gob.NewEncoder(t.w)
+			}
+			byName[ins.table] = t
+		}
+		if t == nil {
+			continue
+		}
+		rec := spooledInsert{Line: ins.line, Copy: ins.copy}
+		if !ins.copy {
+			cols, err := dump.columnsFor(ins)
+			if err != nil {
+				rec.ColumnsErr = err.Error()
+			}
+			for _, c := range cols {
+				rec.Columns = append(rec.Columns, spooledColumn{Name: c.name, Hint: c.hint})
+			}
+			rec.Values = make([]spooledToken, len(ins.values))
+			for n, v := range ins.values {
+				rec.Values[n] = spooledToken{Kind: v.kind, Text: v.text}
+			}
+		}
+		if err := t.enc.Encode(&rec); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range tables {
+		err := t.w.Flush()
+		if t.seal != nil && err == nil {
+			err = t.seal.Close()
+		}
+		if cerr := t.out.Close(); err == nil {
+			err = cerr
+		}
+		t.out = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// sqlSpool 讀回 spoolSQLDump 寫出的暫存檔
+type sqlSpool struct {
+	table   string
+	dec     *gob.Decoder
+	cols    []sqlColumn
+	colsErr error
+}
+
+func newSQLSpoolReader(r io.Reader, table string, order *orderTracker) *sqlReader {
+	return &sqlReader{dump: &sqlSpool{table: table, dec: gob.NewDecoder(r)}, table: table, order: order}
+}
+
+func (s *sqlSpool) nextInsert() (*sqlInsert, error) {
+	var rec spooledInsert
+	if err := s.dec.Decode(&rec); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errors.New("truncated SQL spool file")
+		}
+		return nil, err
+	}
+	ins := &sqlInsert{table: s.table, copy: rec.Copy, line: rec.Line, values: make([]sqlToken, len(rec.Values))}
+	for n, v := range rec.Values {
+		ins.values[n] = sqlToken{kind: v.Kind, text: v.Text}
+	}
+	s.cols, s.colsErr = make([]sqlColumn, len(rec.Columns)), nil
+	for n, c := range rec.Columns {
+		s.cols[n] = sqlColumn{name: c.Name, hint: c.Hint}
+	}
+	if rec.ColumnsErr != "" {
+		s.colsErr = errors.New(rec.ColumnsErr)
+	}
+	return ins, nil
+}
+
+func (s *sqlSpool) columnsFor(*sqlInsert) ([]sqlColumn, error) {
+	return s.cols, s.colsErr
+}
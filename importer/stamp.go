@@ -0,0 +1,207 @@
+package importer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// 匯入中繼欄位的預設名稱
+const (
+	DefaultStampHashField = "_importHash"
+	DefaultStampTimeField = "_importedAt"
+)
+
+// stampReader 在每筆文件加上內容雜湊（Options.StampHashField）與匯入時間（Options.StampTimeField）
+type stampReader struct {
+	docReader
+	hashField, timeField string
+	now                  primitive.DateTime
+}
+
+func (s *stampReader) Next() (bson.M, error) {
+	doc, err := s.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	if s.hashField != "" {
+		h, err := stampHash(doc, s.hashField, s.timeField)
+		if err != nil {
+			return nil, err
+		}
+		doc[s.hashField] = h
+	}
+	if s.timeField != "" {
+		doc[s.timeField] = s.now
+	}
+	return doc, nil
+}
+
+func (i *Importer) newStampReader(r docReader) *stampReader {
+	return &stampReader{docReader: r, hashField: i.opts.StampHashField, timeField: i.opts.StampTimeField, now: primitive.NewDateTimeFromTime(i.started)}
+}
+
+// stampHash 不含 _id 與中繼欄位的內容雜湊：欄位依名稱排序後以 BSON 編碼再取 SHA-256，
+// 檔案解析出的文件與從 server 讀回的同一筆文件（_id 可能由 server 產生）會得到相同的結果
+func stampHash(doc bson.M, skip ...string) (string, error) {
+	d := canonicalDoc(doc)
+	for _, field := range append([]string{"_id"}, skip...) {
+		for k, e := range d {
+			if e.Key == field {
+				d = append(d[:k:k], d[k+1:]...)
+				break
+			}
+		}
+	}
+	b, err := bson.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalDoc 子文件轉成依 key 排序的 bson.D，陣列保留原本的順序
+func canonicalDoc(doc bson.M) bson.D {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := make(bson.D, 0, len(doc))
+	for _, k := range keys {
+		d = append(d, bson.E{Key: k, Value: canonicalValue(doc[k])})
+	}
+	return d
+}
+
+func canonicalValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case bson.M:
+		return canonicalDoc(x)
+	case bson.D:
+		return canonicalDoc(x.Map())
+	case bson.A:
+		out := make(bson.A, len(x))
+		for n, e := range x {
+			out[n] = canonicalValue(e)
+		}
+		return out
+	case []interface{}:
+		return canonicalValue(bson.A(x))
+	}
+	return v
+}
+
+// dropUnchanged upsert / merge 時丟掉 batch 中雜湊與既有文件相同的文件，回傳剩下的文件與丟掉的數量
+func (i *Importer) dropUnchanged(ctx context.Context, coll *mongo.Collection, batch []interface{}) ([]interface{}, int, error) {
+	fields, hashField := keyFields(i.opts.KeyField), i.opts.StampHashField
+	filters := make(bson.A, 0, len(batch))
+	for _, d := range batch {
+		if f, ok, _ := keyFilter(d, fields); ok {
+			filters = append(filters, f)
+		}
+	}
+	if len(filters) == 0 {
+		return batch, 0, nil
+	}
+
+	// 單一 key 時以 $in 查詢，多個欄位組成的 key 以 $or
+	query := bson.M{"$or": filters, hashField: bson.M{"$exists": true}}
+	if len(fields) == 1 {
+		keys := make(bson.A, len(filters))
+		for n, f := range filters {
+			keys[n] = f.(bson.D)[0].Value
+		}
+		query = bson.M{fields[0]: bson.M{"$in": keys}, hashField: bson.M{"$exists": true}}
+	}
+	projection := bson.M{hashField: 1}
+	for _, f := range fields {
+		projection[f] = 1
+	}
+	cursor, err := coll.Find(ctx, query, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+	existing := map[string]interface{}{}
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, 0, err
+		}
+		f, _, _ := keyFilter(doc, fields)
+		existing[keyString(f)] = doc[hashField]
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	kept := make([]interface{}, 0, len(batch))
+	for _, d := range batch {
+		f, ok, _ := keyFilter(d, fields)
+		h, _, _ := topField(d, hashField)
+		if ok && h != nil && existing[keyString(f)] == h {
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, len(batch) - len(kept), nil
+}
+
+// DriftResult Drift 的結果
+type DriftResult struct {
+	Checked int           // 有雜湊欄位的文件數
+	Drifted int           // 內容與匯入時的雜湊不同的文件數
+	IDs     []interface{} // 前幾筆變動過的 _id
+}
+
+// Drift 重新計算 collection 中有 Options.StampHashField 的文件的雜湊，找出匯入之後在資料庫內被修改過的文件
+func (i *Importer) Drift(ctx context.Context, coll *mongo.Collection) (DriftResult, error) {
+	var res DriftResult
+	hashField := i.opts.StampHashField
+	if hashField == "" {
+		return res, fmt.Errorf("drift detection requires a stamp hash field")
+	}
+	cursor, err := coll.Find(ctx, bson.M{hashField: bson.M{"$exists": true}})
+	if err != nil {
+		return res, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return res, err
+		}
+		res.Checked++
+		h, err := stampHash(doc, hashField, i.opts.StampTimeField)
+		if err != nil {
+			return res, err
+		}
+		if h != doc[hashField] {
+			res.Drifted++
+			if len(res.IDs) < maxDedupeSamples {
+				res.IDs = append(res.IDs, doc["_id"])
+			}
+		}
+	}
+	return res, cursor.Err()
+}
+
+// StampFields 匯入時加上的中繼欄位，verify 與 diff 比較時應忽略；沒有使用時為 nil
+func (o Options) StampFields() []string {
+	var fields []string
+	for _, f := range []string{o.StampHashField, o.StampTimeField} {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
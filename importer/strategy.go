@@ -0,0 +1,76 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StrategyRule 名稱符合 Collection（glob）的 collection 改用 Strategy
+type StrategyRule struct {
+	Collection string
+	Strategy   string
+}
+
+// ParseStrategies 解析以逗號分隔的 <collection>:<strategy>，例如 "lookup_codes:truncate,users:merge,audit_*:append"
+func ParseStrategies(s string) ([]StrategyRule, error) {
+	var rules []StrategyRule
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		coll, strategy, ok := strings.Cut(pair, ":")
+		coll, strategy = strings.TrimSpace(coll), strings.TrimSpace(strategy)
+		if !ok || coll == "" || strategy == "" {
+			return nil, fmt.Errorf("invalid strategy override %q (expected <collection>:<strategy>)", pair)
+		}
+		if err := checkStrategy(strategy); err != nil {
+			return nil, fmt.Errorf("collection %s: %v", coll, err)
+		}
+		rules = append(rules, StrategyRule{Collection: coll, Strategy: strategy})
+	}
+	if err := checkPatterns(collectionPatterns(rules)); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func checkStrategy(strategy string) error {
+	switch strategy {
+	case StrategyTruncate, StrategyUpsert, StrategyMerge, StrategyAppend, StrategyDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid strategy: %s (expected truncate, upsert, merge, append or delete)", strategy)
+}
+
+func collectionPatterns(rules []StrategyRule) []string {
+	patterns := make([]string, len(rules))
+	for n, r := range rules {
+		patterns[n] = r.Collection
+	}
+	return patterns
+}
+
+// strategyFor coll 套用的 strategy：第一個符合的 Strategies 規則，否則為 Strategy
+func (o Options) strategyFor(coll string) string {
+	for _, r := range o.Strategies {
+		if matchAny([]string{r.Collection}, coll) {
+			return r.Strategy
+		}
+	}
+	return o.Strategy
+}
+
+// UsesStrategy 預設或任何一條規則使用其中一個 strategy 時回傳 true，例如在列出 Targets 之前判斷是否有 collection 會被清空
+func (o Options) UsesStrategy(strategies ...string) bool {
+	for _, s := range strategies {
+		if o.Strategy == s {
+			return true
+		}
+		for _, r := range o.Strategies {
+			if r.Strategy == s {
+				return true
+			}
+		}
+	}
+	return false
+}
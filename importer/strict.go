@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NaN / Infinity（double 與 decimal）的處理，見 Options.NonFinite
+const (
+	NonFiniteKeep   = "keep"   // 原樣寫入（預設）
+	NonFiniteNull   = "null"   // 換成 null
+	NonFiniteReject = "reject" // 視為解析錯誤
+)
+
+// strictness 解析時對單筆文件的檢查，由 Options.RejectDuplicateFields 與 NonFinite 決定
+type strictness struct {
+	duplicates bool
+	nonFinite  string
+}
+
+func strictnessOf(opts Options) strictness {
+	return strictness{duplicates: opts.RejectDuplicateFields, nonFinite: opts.NonFinite}
+}
+
+func (s strictness) enabled() bool {
+	return s.duplicates || (s.nonFinite != "" && s.nonFinite != NonFiniteKeep)
+}
+
+// finish 檢查 d 後轉成 stages 使用的 bson.M；order 不為 nil 時一併記下欄位順序
+func (s strictness) finish(d bson.D, order *orderTracker) (bson.M, error) {
+	if s.enabled() {
+		if err := s.check(d); err != nil {
+			return nil, err
+		}
+	}
+	if order != nil {
+		return order.track(d), nil
+	}
+	return docToMap(d), nil
+}
+
+// check 檢查 d 與其中的子文件、陣列；nonFinite 為 null 時就地把 NaN / Infinity 換成 nil
+func (s strictness) check(d bson.D) error {
+	return s.checkDoc(d, "")
+}
+
+func (s strictness) checkDoc(d bson.D, prefix string) error {
+	var seen map[string]bool
+	if s.duplicates {
+		seen = make(map[string]bool, len(d))
+	}
+	for i, e := range d {
+		path := prefix + e.Key
+		if seen != nil {
+			if seen[e.Key] {
+				return fmt.Errorf("duplicate field %q", path)
+			}
+			seen[e.Key] = true
+		}
+		out, err := s.checkValue(e.Value, path)
+		if err != nil {
+			return err
+		}
+		d[i].Value = out
+	}
+	return nil
+}
+
+func (s strictness) checkValue(v interface{}, path string) (interface{}, error) {
+	switch x := v.(type) {
+	case bson.D:
+		return x, s.checkDoc(x, path+".")
+	case bson.M:
+		for k, e := range x {
+			out, err := s.checkValue(e, path+"."+k)
+			if err != nil {
+				return nil, err
+			}
+			x[k] = out
+		}
+	case bson.A:
+		return x, s.checkArray(x, path)
+	case []interface{}:
+		return x, s.checkArray(x, path)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return s.nonFiniteValue(v, path, formatNonFinite(x))
+		}
+	case primitive.Decimal128:
+		if x.IsNaN() || x.IsInf() != 0 {
+			return s.nonFiniteValue(v, path, x.String())
+		}
+	}
+	return v, nil
+}
+
+func (s strictness) checkArray(a []interface{}, path string) error {
+	for i, e := range a {
+		out, err := s.checkValue(e, fmt.Sprintf("%s.%d", path, i))
+		if err != nil {
+			return err
+		}
+		a[i] = out
+	}
+	return nil
+}
+
+func (s strictness) nonFiniteValue(v interface{}, path, text string) (interface{}, error) {
+	switch s.nonFinite {
+	case NonFiniteNull:
+		return nil, nil
+	case NonFiniteReject:
+		return nil, fmt.Errorf("field %q is %s", path, text)
+	}
+	return v, nil
+}
+
+func formatNonFinite(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "Infinity"
+	}
+	return "-Infinity"
+}
+
+// checkMap 與 check 相同，給直接產生 bson.M 的讀取器（CSV）使用；bson.M 不會有重複的欄位
+func (s strictness) checkMap(m bson.M) error {
+	for k, v := range m {
+		out, err := s.checkValue(v, k)
+		if err != nil {
+			return err
+		}
+		m[k] = out
+	}
+	return nil
+}
+
+// nonFinite 給 settingsChecksum 使用；keep 是預設行為，不改變既有的 checksum
+func nonFinite(opts Options) string {
+	if opts.NonFinite == NonFiniteKeep {
+		return ""
+	}
+	return opts.NonFinite
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// placeholderPattern {{NOW}}、{{UUID}}、{{ENV:TENANT_ID}}；名稱前後可以有空白
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z]+)(?::([^}]*?))?\s*\}\}`)
+
+// templateReader Options.Templates 時展開字串值（含子文件與陣列）裡的 placeholder：
+//
+//	{{NOW}}       匯入開始的時間；整個字串只有 {{NOW}} 時寫成 date，否則以 RFC 3339 代入
+//	{{UUID}}      每次出現都產生新的 UUID v4 字串
+//	{{ENV:NAME}}  環境變數 NAME 的值；沒有設定時這筆文件視為錯誤，不會寫入空字串
+type templateReader struct {
+	docReader
+	now time.Time
+	n   int
+}
+
+func (t *templateReader) Next() (bson.M, error) {
+	doc, err := t.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	t.n++
+	if _, err := t.expand(doc); err != nil {
+		raw, _ := bson.MarshalExtJSON(doc, false, false)
+		return nil, &parseError{Pos: fmt.Sprintf("document %d", t.n), Raw: string(raw), Err: err}
+	}
+	return doc, nil
+}
+
+// expand 回傳展開後的值；子文件與陣列就地修改
+func (t *templateReader) expand(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return t.expandString(v)
+	case bson.M:
+		for k, x := range v {
+			e, err := t.expand(x)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			v[k] = e
+		}
+	case bson.D:
+		for n := range v {
+			e, err := t.expand(v[n].Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", v[n].Key, err)
+			}
+			v[n].Value = e
+		}
+	case bson.A:
+		for n := range v {
+			e, err := t.expand(v[n])
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", n, err)
+			}
+			v[n] = e
+		}
+	case []interface{}:
+		return t.expand(bson.A(v))
+	}
+	return v, nil
+}
+
+func (t *templateReader) expandString(s string) (interface{}, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	if m := placeholderPattern.FindStringSubmatch(s); m != nil && m[0] == s && strings.EqualFold(m[1], "NOW") && m[2] == "" {
+		return primitive.NewDateTimeFromTime(t.now), nil
+	}
+	var err error
+	out := placeholderPattern.ReplaceAllStringFunc(s, func(p string) string {
+		m := placeholderPattern.FindStringSubmatch(p)
+		v, perr := t.value(strings.ToUpper(m[1]), strings.TrimSpace(m[2]))
+		if perr != nil && err == nil {
+			err = perr
+		}
+		return v
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (t *templateReader) value(name, arg string) (string, error) {
+	switch name {
+	case "NOW":
+		if arg != "" {
+			return "", fmt.Errorf("{{NOW}} takes no argument, got %q", arg)
+		}
+		return t.now.UTC().Format(time.RFC3339), nil
+	case "UUID":
+		return newUUID()
+	case "ENV":
+		if arg == "" {
+			return "", fmt.Errorf("{{ENV:NAME}} requires a variable name")
+		}
+		v, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s used by {{ENV:%s}} is not set", arg, arg)
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown placeholder {{%s}} (expected NOW, UUID or ENV:NAME)", name)
+}
+
+// newUUID 隨機產生的 UUID v4，例如 0b8e6f0a-6c1d-4f57-9a41-3c2e1d5b7a90
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
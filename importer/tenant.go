@@ -0,0 +1,30 @@
+package importer
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// tenantReader 在每筆文件寫入 Options.TenantField（a.b 路徑），檔案內原本的值會被覆蓋
+type tenantReader struct {
+	docReader
+	field string
+	value interface{}
+}
+
+func (t *tenantReader) Next() (bson.M, error) {
+	doc, err := t.docReader.Next()
+	if err == nil {
+		setPath(doc, t.field, t.value)
+	}
+	return doc, err
+}
+
+// TruncateScope truncate 時刪除範圍的查詢：有 Scope 時為 Scope；只有 TenantField 時為這個 tenant 的文件，
+// 同一份 fixture 匯入另一個 tenant 不會清掉其他 tenant 的資料；都沒有時為 nil（清空整個 collection）
+func (o Options) TruncateScope() bson.M {
+	if len(o.Scope) > 0 {
+		return o.Scope
+	}
+	if o.TenantField != "" {
+		return bson.M{o.TenantField: o.TenantValue}
+	}
+	return nil
+}
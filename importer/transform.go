@@ -0,0 +1,248 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/yaml.v3"
+)
+
+// Transform 套用在符合 Collection（名稱或 glob）的每筆文件上，依序執行 Rename → Drop → Convert → Derive → Set
+type Transform struct {
+	Collection string                 `json:"collection" yaml:"collection"`
+	Rename     map[string]string      `json:"rename" yaml:"rename"`   // 舊欄位 → 新欄位
+	Drop       []string               `json:"drop" yaml:"drop"`       // 要移除的欄位
+	Convert    map[string]string      `json:"convert" yaml:"convert"` // 欄位 → 型別，原地轉換
+	Derive     map[string]Derivation  `json:"derive" yaml:"derive"`   // 新欄位 ← 由其他欄位轉換而來
+	Set        map[string]interface{} `json:"set" yaml:"set"`         // 欄位 → 常數值
+}
+
+// Derivation 由 From 欄位的值轉成 Type 後寫入新欄位，原欄位保留
+type Derivation struct {
+	From string `json:"from" yaml:"from"`
+	Type string `json:"type" yaml:"type"`
+}
+
+type transformFile struct {
+	Transforms []Transform `json:"transforms" yaml:"transforms"`
+}
+
+// transformTypes Convert / Derive 可用的型別，與 CSV 的 --fields 相同
+var transformTypes = []string{"string", "int", "long", "double", "decimal", "bool", "date", "objectid"}
+
+// LoadTransforms 讀取 YAML 或 JSON 格式的轉換規則（依副檔名判斷）
+func LoadTransforms(path string) ([]Transform, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf transformFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &tf)
+	} else {
+		err = yaml.Unmarshal(data, &tf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transform file %s: %v", path, err)
+	}
+
+	for i := range tf.Transforms {
+		t := &tf.Transforms[i]
+		if t.Collection == "" {
+			return nil, fmt.Errorf("transform %d: collection is required", i+1)
+		}
+		if _, err := filepath.Match(t.Collection, ""); err != nil {
+			return nil, fmt.Errorf("transform %d: invalid pattern %q: %v", i+1, t.Collection, err)
+		}
+		for field, typ := range t.Convert {
+			typ = strings.ToLower(typ)
+			if !contains(transformTypes, typ) {
+				return nil, fmt.Errorf("transform %d (%s): unknown type %q for %s (expected one of %s)",
+					i+1, t.Collection, typ, field, strings.Join(transformTypes, ", "))
+			}
+			t.Convert[field] = typ
+		}
+		for field, d := range t.Derive {
+			d.Type = strings.ToLower(d.Type)
+			if d.From == "" || !contains(transformTypes, d.Type) {
+				return nil, fmt.Errorf("transform %d (%s): derive %s needs from and one of the types %s",
+					i+1, t.Collection, field, strings.Join(transformTypes, ", "))
+			}
+			t.Derive[field] = d
+		}
+		for field, v := range t.Set {
+			t.Set[field] = normalizeConstant(v)
+		}
+	}
+	return tf.Transforms, nil
+}
+
+// transformsFor 依序挑出套用到 coll 的規則
+func transformsFor(transforms []Transform, coll string) []Transform {
+	var out []Transform
+	for _, t := range transforms {
+		if ok, _ := filepath.Match(t.Collection, coll); ok {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// apply 就地修改 doc；欄位不存在時略過，轉換失敗則回傳 error
+func (t Transform) apply(doc bson.M) error {
+	for _, from := range sortedKeys(t.Rename) {
+		if v, ok := getPath(doc, from); ok {
+			deletePath(doc, from)
+			setPath(doc, t.Rename[from], v)
+		}
+	}
+	for _, field := range t.Drop {
+		deletePath(doc, field)
+	}
+	for _, field := range sortedKeys(t.Convert) {
+		v, ok := getPath(doc, field)
+		if !ok {
+			continue
+		}
+		out, err := convertValue(v, t.Convert[field])
+		if err != nil {
+			return fmt.Errorf("convert %s to %s: %v", field, t.Convert[field], err)
+		}
+		setPath(doc, field, out)
+	}
+	for _, field := range sortedKeys(t.Derive) {
+		d := t.Derive[field]
+		v, ok := getPath(doc, d.From)
+		if !ok {
+			continue
+		}
+		out, err := convertValue(v, d.Type)
+		if err != nil {
+			return fmt.Errorf("derive %s from %s: %v", field, d.From, err)
+		}
+		setPath(doc, field, out)
+	}
+	for _, field := range sortedKeys(t.Set) {
+		setPath(doc, field, t.Set[field])
+	}
+	return nil
+}
+
+// convertValue 字串沿用 CSV 的轉換規則；數字可以轉成其他數字型別、日期（epoch 毫秒）或字串
+func convertValue(v interface{}, typ string) (interface{}, error) {
+	if s, ok := v.(string); ok {
+		return convertCSVValue(s, typ)
+	}
+	if typ == "string" {
+		switch x := v.(type) {
+		case primitive.ObjectID:
+			return x.Hex(), nil
+		case primitive.DateTime:
+			return x.Time().UTC().Format("2006-01-02T15:04:05.000Z"), nil
+		}
+		return fmt.Sprint(v), nil
+	}
+	if f, ok := toFloat(v); ok {
+		switch typ {
+		case "int":
+			if f < math.MinInt32 || f > math.MaxInt32 {
+				return nil, fmt.Errorf("%v overflows int", v)
+			}
+			return int32(f), nil
+		case "long":
+			return int64(f), nil
+		case "double":
+			return f, nil
+		case "decimal":
+			return primitive.ParseDecimal128(fmt.Sprint(v))
+		case "bool":
+			return f != 0, nil
+		case "date":
+			return primitive.DateTime(int64(f)), nil
+		}
+	}
+	// 已經是目標型別
+	switch v.(type) {
+	case primitive.DateTime:
+		if typ == "date" {
+			return v, nil
+		}
+	case primitive.ObjectID:
+		if typ == "objectid" {
+			return v, nil
+		}
+	case bool:
+		if typ == "bool" {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %s value", bsonTypeOf(v))
+}
+
+// normalizeConstant YAML / JSON 解出的常數轉成 BSON 友善的型別（整數轉 int32 / int64，巢狀 map 轉 bson.M）
+func normalizeConstant(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		if x >= math.MinInt32 && x <= math.MaxInt32 {
+			return int32(x)
+		}
+		return int64(x)
+	case float64:
+		if x == math.Trunc(x) && x >= math.MinInt32 && x <= math.MaxInt32 {
+			return int32(x)
+		}
+		return x
+	case map[string]interface{}:
+		m := bson.M{}
+		for k, e := range x {
+			m[k] = normalizeConstant(e)
+		}
+		return m
+	case []interface{}:
+		a := make(bson.A, len(x))
+		for i, e := range x {
+			a[i] = normalizeConstant(e)
+		}
+		return a
+	}
+	return v
+}
+
+// transformReader 對每筆文件套用 Transform；轉換失敗的文件以 parseError 回傳，搭配 --skip-invalid 可以略過
+type transformReader struct {
+	docReader
+	transforms []Transform
+	n          int
+}
+
+func (t *transformReader) Next() (bson.M, error) {
+	doc, err := t.docReader.Next()
+	if err != nil {
+		return doc, err
+	}
+	t.n++
+	for _, tr := range t.transforms {
+		if err := tr.apply(doc); err != nil {
+			raw, _ := bson.MarshalExtJSON(doc, false, false)
+			return nil, &parseError{Pos: fmt.Sprintf("document %d", t.n), Raw: string(raw), Err: err}
+		}
+	}
+	return doc, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,208 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	StrategyTruncate = "truncate"
+	StrategyUpsert   = "upsert"
+	StrategyMerge    = "merge"
+	StrategyAppend   = "append" // 不清空，直接插入
+	StrategyDelete   = "delete" // 刪除 key 與檔案內文件相同的文件（mongoimport --mode delete）
+)
+
+// keyFields KeyField 以逗號分隔的欄位（a.b 路徑），與 mongoimport --upsertFields 相同
+func keyFields(keyField string) []string {
+	var fields []string
+	for _, f := range strings.Split(keyField, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return []string{"_id"}
+	}
+	return fields
+}
+
+// keyFilter 以文件中 fields 的值組成 filter；跟 mongoimport 一樣，部分欄位不存在時以 null 比對，
+// 全部都不存在時 ok 為 false
+func keyFilter(doc interface{}, fields []string) (bson.D, bool, error) {
+	filter := make(bson.D, 0, len(fields))
+	found := false
+	for _, f := range fields {
+		v, ok, err := pathField(doc, f)
+		if err != nil {
+			return nil, false, err
+		}
+		found = found || ok
+		filter = append(filter, bson.E{Key: f, Value: v})
+	}
+	return filter, found, nil
+}
+
+// missingKey 沒有任何 key 欄位的文件：key 是 _id 時跟 mongoimport 一樣直接新增（回傳 nil），否則是錯誤
+func missingKey(i int, fields []string) error {
+	if len(fields) == 1 && fields[0] == "_id" {
+		return nil
+	}
+	return fmt.Errorf("document %d is missing key field %q", i, strings.Join(fields, ","))
+}
+
+// upsertDocuments 依 keyField 逐筆 replace（upsert），檔案內沒有的文件保持不動
+func upsertDocuments(ctx context.Context, coll *mongo.Collection, docs []interface{}, keyField string) (*mongo.BulkWriteResult, error) {
+	if len(docs) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	fields := keyFields(keyField)
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for i, d := range docs {
+		filter, ok, err := keyFilter(d, fields)
+		if err != nil {
+			return nil, fmt.Errorf("document %d %v", i, err)
+		}
+		if !ok {
+			if err := missingKey(i, fields); err != nil {
+				return nil, err
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(d))
+			continue
+		}
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(d).
+			SetUpsert(true))
+	}
+
+	return coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+}
+
+// mergeDocuments 依 keyField 只新增不存在的文件；update 為 true 時再以 $set 更新既有文件中檔案有的欄位，
+// 既有文件的其他欄位與檔案內沒有的文件都保持不動
+func mergeDocuments(ctx context.Context, coll *mongo.Collection, docs []interface{}, keyField string, update bool) (*mongo.BulkWriteResult, error) {
+	if len(docs) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	fields := keyFields(keyField)
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for i, d := range docs {
+		filter, ok, err := keyFilter(d, fields)
+		if err != nil {
+			return nil, fmt.Errorf("document %d %v", i, err)
+		}
+		if !ok {
+			if err := missingKey(i, fields); err != nil {
+				return nil, err
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(d))
+			continue
+		}
+
+		u := bson.M{"$setOnInsert": d}
+		if update {
+			// _id 不能出現在 $set；新增時由 $setOnInsert 帶入
+			if set, n := withoutID(d); n > 0 {
+				u = bson.M{"$set": set}
+				if id, ok, _ := topField(d, "_id"); ok && !(len(fields) == 1 && fields[0] == "_id") {
+					u["$setOnInsert"] = bson.M{"_id": id}
+				}
+			}
+		}
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(u).
+			SetUpsert(true))
+	}
+
+	return coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+}
+
+// deleteDocuments 依 keyField 逐筆刪除符合的文件；沒有 key 欄位的文件無法比對，回傳錯誤
+func deleteDocuments(ctx context.Context, coll *mongo.Collection, docs []interface{}, keyField string) (*mongo.BulkWriteResult, error) {
+	if len(docs) == 0 {
+		return &mongo.BulkWriteResult{}, nil
+	}
+
+	fields := keyFields(keyField)
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for i, d := range docs {
+		filter, _, err := keyFilter(d, fields)
+		if err != nil {
+			return nil, fmt.Errorf("document %d %v", i, err)
+		}
+		// 不存在或為 null 的 key 會比對到所有缺少這個欄位的文件，不能像 upsert 一樣以 null 比對
+		for _, e := range filter {
+			if e.Value == nil {
+				return nil, fmt.Errorf("document %d has no value for key field %q; the delete strategy needs every key field", i, e.Key)
+			}
+		}
+		models = append(models, mongo.NewDeleteOneModel().SetFilter(filter))
+	}
+
+	return coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+}
+
+// pathField 以 "a.b.c" 路徑讀取文件（bson.M 或 bson.D）的欄位；中間層不是子文件時視為不存在
+func pathField(doc interface{}, path string) (interface{}, bool, error) {
+	parts := strings.Split(path, ".")
+	v, ok, err := topField(doc, parts[0])
+	for _, p := range parts[1:] {
+		if err != nil || !ok {
+			break
+		}
+		if v, ok, err = topField(v, p); err != nil {
+			return nil, false, nil
+		}
+	}
+	return v, ok, err
+}
+
+// topField 讀取文件（bson.M，或 PreserveOrder 時的 bson.D）最上層的欄位
+func topField(doc interface{}, key string) (interface{}, bool, error) {
+	switch d := doc.(type) {
+	case bson.M:
+		v, ok := d[key]
+		return v, ok, nil
+	case bson.D:
+		for _, e := range d {
+			if e.Key == key {
+				return e.Value, true, nil
+			}
+		}
+		return nil, false, nil
+	}
+	return nil, false, errors.New("is not a BSON document")
+}
+
+// withoutID 回傳去掉 _id 的文件（型別不變）與剩下的欄位數
+func withoutID(doc interface{}) (interface{}, int) {
+	switch d := doc.(type) {
+	case bson.M:
+		out := make(bson.M, len(d))
+		for k, v := range d {
+			if k != "_id" {
+				out[k] = v
+			}
+		}
+		return out, len(out)
+	case bson.D:
+		out := make(bson.D, 0, len(d))
+		for _, e := range d {
+			if e.Key != "_id" {
+				out = append(out, e)
+			}
+		}
+		return out, len(out)
+	}
+	return doc, 0
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// View views.json 的一筆：在 Source（collection 或另一個 view）上以 Pipeline 定義的 view
+type View struct {
+	DB        string   `bson:"db,omitempty"` // 空字串表示 Options.DB
+	Name      string   `bson:"name"`
+	Source    string   `bson:"source"`
+	ViewOn    string   `bson:"viewOn,omitempty"` // mongodump metadata 的欄位名稱，與 Source 擇一
+	Pipeline  []bson.D `bson:"pipeline"`
+	Collation bson.D   `bson:"collation,omitempty"`
+}
+
+// loadViews 讀取 Extended JSON 的 view 定義，例如
+// [{"name": "active_users", "source": "users", "pipeline": [{"$match": {"active": true}}]}]；
+// 也接受 {"views": [...]}。view 可以建立在前面定義的 view 上，依檔案的順序建立
+func loadViews(path string) ([]View, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		// bson 不能直接解析最外層是 array 的 JSON，包一層再拆
+		data = append(append([]byte(`{"views":`), data...), '}')
+	}
+	var wrapper struct {
+		Views []View `bson:"views"`
+	}
+	if err := bson.UnmarshalExtJSON(data, false, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	for n := range wrapper.Views {
+		v := &wrapper.Views[n]
+		if v.Source == "" {
+			v.Source = v.ViewOn
+		}
+		if v.Name == "" || v.Source == "" {
+			return nil, fmt.Errorf("%s: view %d: name and source are required", path, n+1)
+		}
+	}
+	return wrapper.Views, nil
+}
+
+// isViewsFile 目錄模式下略過 ViewsFile 本身，views.json 放在資料目錄裡時不會被當成 views collection 匯入
+func (i *Importer) isViewsFile(file string) bool {
+	if i.opts.ViewsFile == "" {
+		return false
+	}
+	a, err1 := filepath.Abs(file)
+	b, err2 := filepath.Abs(i.opts.ViewsFile)
+	return err1 == nil && err2 == nil && a == b
+}
+
+// CreateViews 在資料匯入後依 ViewsFile 建立 view；已經存在的 view 會以新的定義重建，
+// 同名的 collection 則不會被動到。所有 view 都會嘗試建立，回傳成功的數量與所有錯誤
+func (i *Importer) CreateViews(ctx context.Context) (int, error) {
+	var errs []error
+	created := 0
+	for _, v := range i.views {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		if err := i.createView(ctx, v); err != nil {
+			i.log.Error(fmt.Sprintf("❌ Failed to create view %s: %v", v.Name, err), "view", v.Name, "source", v.Source, errAttr(err))
+			errs = append(errs, fmt.Errorf("view %s: %v", v.Name, err))
+			continue
+		}
+		created++
+	}
+	return created, errors.Join(errs...)
+}
+
+func (i *Importer) createView(ctx context.Context, v View) error {
+	// 跟 collection 一樣套用 Renames 與 CollectionPrefix，view 才會建立在載入的 collection 上
+	v.Name, v.Source = i.targetName(v.Name), i.targetName(v.Source)
+	dbName := v.DB
+	if dbName == "" {
+		dbName = i.opts.DB
+	}
+	db := i.client.Database(dbName)
+	existing := db.Collection(v.Name)
+
+	kind := ""
+	err := i.withOpTimeout(ctx, "list "+v.Name, func(ctx context.Context) error {
+		var err error
+		kind, err = collectionType(ctx, existing)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case "":
+	case "view":
+		if err := i.withOpTimeout(ctx, "drop view "+v.Name, existing.Drop); err != nil {
+			return fmt.Errorf("failed to drop the existing view: %v", err)
+		}
+	default:
+		return fmt.Errorf("%s.%s already exists as a %s", dbName, v.Name, kind)
+	}
+
+	pipeline := v.Pipeline
+	if pipeline == nil {
+		pipeline = []bson.D{}
+	}
+	cmd := bson.D{{Key: "create", Value: v.Name}, {Key: "viewOn", Value: v.Source}, {Key: "pipeline", Value: pipeline}}
+	switch {
+	case v.Collation != nil && !i.targetServer.Collation():
+		i.log.Warn(fmt.Sprintf("⚠️  %s does not support collations (requires 3.4); creating view %s without one", i.targetServer, v.Name),
+			"db", dbName, "view", v.Name, "server_version", i.targetServer.Version)
+	case v.Collation != nil:
+		cmd = append(cmd, bson.E{Key: "collation", Value: v.Collation})
+	}
+	err = i.withOpTimeout(ctx, "create view "+v.Name, func(ctx context.Context) error {
+		return db.RunCommand(ctx, cmd).Err()
+	})
+	if err != nil {
+		return err
+	}
+	i.log.Info(fmt.Sprintf("🔭 Created view %s.%s on %s (%d stages)", dbName, v.Name, v.Source, len(pipeline)),
+		"db", dbName, "view", v.Name, "source", v.Source, "stages", len(pipeline))
+	return nil
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce 編輯器存檔時常會連續觸發好幾個事件，同一個檔案安靜這麼久才重新匯入
+const watchDebounce = 300 * time.Millisecond
+
+// Watch 監看 dir，資料檔新增或修改時只重新匯入那個檔案，每個結果交給 onResult；
+// ctx 取消後回傳 nil
+func (i *Importer) Watch(ctx context.Context, dir string, onResult func(FileResult)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := i.watchDirs(w, dir); err != nil {
+		return err
+	}
+	i.log.Info(fmt.Sprintf("👀 Watching %s for changes (Ctrl+C to stop)", dir), "path", dir)
+
+	var (
+		mu      sync.Mutex
+		pending = map[string]*time.Timer{}
+		ready   = make(chan string, 16)
+		stop    = make(chan struct{})
+		done    = make(chan struct{})
+	)
+
+	// 同一時間只匯入一個檔案，避免同一個 collection 被兩次匯入交錯清空
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case file := <-ready:
+				fi, statErr := os.Stat(file)
+				results := i.importOne(ctx, file)
+				if statErr == nil {
+					i.opts.Metrics.Lag(time.Since(fi.ModTime()))
+				}
+				for _, res := range results {
+					onResult(res)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	defer func() {
+		mu.Lock()
+		for _, t := range pending {
+			t.Stop()
+		}
+		mu.Unlock()
+		close(stop)
+		<-done
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			i.log.Warn(fmt.Sprintf("⚠️  Watch error: %v", err), errAttr(err))
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+			file := ev.Name
+			if fi, err := os.Stat(file); i.opts.Recursive && ev.Has(fsnotify.Create) && err == nil && fi.IsDir() {
+				if err := i.watchDirs(w, file); err != nil {
+					i.log.Warn(fmt.Sprintf("⚠️  Watch error: %v", err), errAttr(err))
+				}
+				continue
+			}
+			i.noteSubdir(dir, file)
+			if !i.watches(file) {
+				continue
+			}
+			mu.Lock()
+			if t, ok := pending[file]; ok {
+				t.Reset(watchDebounce)
+			} else {
+				pending[file] = time.AfterFunc(watchDebounce, func() {
+					mu.Lock()
+					delete(pending, file)
+					mu.Unlock()
+					select {
+					case ready <- file:
+					case <-stop:
+					}
+				})
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// watchDirs 監看 dir；Recursive 時包含所有（非隱藏的）子目錄
+func (i *Importer) watchDirs(w *fsnotify.Watcher, dir string) error {
+	if !i.opts.Recursive {
+		if err := w.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %v", dir, err)
+		}
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// watches 只處理會被 ImportDir 匯入的檔案
+func (i *Importer) watches(file string) bool {
+	if dataExt(file) == "" || isSidecarFile(file) || i.isViewsFile(file) {
+		return false
+	}
+	if _, coll := i.resolveTarget(file); coll == "" || (i.opts.Collection != "" && coll != i.opts.Collection) || !i.selected(coll) {
+		return false
+	}
+	// 忽略編輯器的暫存檔，例如 .users.json.swp、~users.json
+	base := filepath.Base(file)
+	return base[0] != '.' && base[0] != '~'
+}
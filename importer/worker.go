@@ -0,0 +1,48 @@
+package importer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// runWorkers 以 n 個 goroutine 處理 files，結果依 files 原本的順序回傳；
+// ctx 被取消，或 failFast 時一旦有檔案失敗，就不再派發新的檔案，剩下的標記為 NotRun
+func runWorkers(ctx context.Context, files []string, n int, failFast bool, fn func(file string) FileResult) []FileResult {
+	results := make([]FileResult, len(files))
+	if n < 1 {
+		n = 1
+	}
+
+	var failed atomic.Bool
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = fn(files[i])
+				if results[i].Err != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+	for i := range files {
+		if ctx.Err() != nil || (failFast && failed.Load()) {
+			break
+		}
+		jobs <- i
+		dispatched++
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := dispatched; i < len(files); i++ {
+		results[i] = FileResult{File: files[i], NotRun: true}
+	}
+	return results
+}
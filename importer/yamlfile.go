@@ -0,0 +1,253 @@
+package importer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+)
+
+// yamlTags YAML fixture 可用的自訂 tag，對應到 Extended JSON 的型別，例如 `_id: !oid 5f1d7c...`、`at: !date 2024-01-02`
+var yamlTags = map[string]string{
+	"!oid":     "$oid",
+	"!date":    "$date",
+	"!decimal": "$numberDecimal",
+	"!long":    "$numberLong",
+	"!int":     "$numberInt",
+	"!double":  "$numberDouble",
+}
+
+// yamlReader 讀取 .yaml / .yml fixture：每個 YAML document 是一筆文件（mapping）或文件的 sequence，可以用 --- 分隔多個 document。
+// 每個 document 先轉成 Extended JSON 再解析，所以 {$oid: ...} 之類的寫法也能用；
+// 沒有加引號的日期（2024-01-02、2024-01-02T10:00:00Z）視為 date。一個 document 會整個讀進記憶體，適合手寫的小型資料集
+type yamlReader struct {
+	dec     *yaml.Decoder
+	strict  strictness
+	order   *orderTracker
+	pending []*yaml.Node
+	doc     int
+	index   int
+}
+
+func newYAMLReader(r io.Reader, strict strictness, order *orderTracker) *yamlReader {
+	return &yamlReader{dec: yaml.NewDecoder(r), strict: strict, order: order}
+}
+
+func (y *yamlReader) Next() (bson.M, error) {
+	for len(y.pending) == 0 {
+		var root yaml.Node
+		if err := y.dec.Decode(&root); err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("failed to parse YAML document %d: %v", y.doc+1, err)
+		}
+		y.doc++
+		y.index = 0
+		if len(root.Content) == 0 {
+			continue
+		}
+		n := resolveAlias(root.Content[0])
+		switch {
+		case n.Kind == yaml.SequenceNode:
+			y.pending = n.Content
+		case n.Kind == yaml.MappingNode:
+			y.pending = []*yaml.Node{n}
+		case n.Tag == "!!null":
+			// 空的 document
+		default:
+			return nil, &parseError{Pos: fmt.Sprintf("document %d", y.doc), Err: fmt.Errorf("line %d: expected a mapping or a sequence of mappings", n.Line)}
+		}
+	}
+	n := resolveAlias(y.pending[0])
+	y.pending = y.pending[1:]
+	y.index++
+	pos := fmt.Sprintf("document %d element %d", y.doc, y.index)
+	if n.Kind != yaml.MappingNode {
+		return nil, &parseError{Pos: pos, Err: fmt.Errorf("line %d: expected a mapping", n.Line)}
+	}
+	var b strings.Builder
+	if err := writeYAMLAsExtJSON(&b, n); err != nil {
+		return nil, &parseError{Pos: pos, Err: err}
+	}
+	m, err := decodeExtJSON([]byte(b.String()), ExtJSONRelaxed, y.strict, y.order)
+	if err != nil {
+		return nil, &parseError{Pos: pos, Raw: b.String(), Err: err}
+	}
+	return m, nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// writeYAMLAsExtJSON 把一個 YAML node 寫成 Extended JSON，保留 mapping 的欄位順序
+func writeYAMLAsExtJSON(b *strings.Builder, n *yaml.Node) error {
+	n = resolveAlias(n)
+	switch n.Kind {
+	case yaml.MappingNode:
+		pairs, err := yamlPairs(n)
+		if err != nil {
+			return err
+		}
+		b.WriteByte('{')
+		for i, p := range pairs {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeJSONString(b, p[0].Value)
+			b.WriteByte(':')
+			if err := writeYAMLAsExtJSON(b, p[1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeYAMLAsExtJSON(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.ScalarNode:
+		return writeYAMLScalar(b, n)
+	default:
+		return fmt.Errorf("line %d: unsupported YAML node", n.Line)
+	}
+	return nil
+}
+
+// yamlPairs mapping 的 key / value；<<: *anchor 合併進來的欄位不會覆蓋明確寫出的欄位
+func yamlPairs(n *yaml.Node) ([][2]*yaml.Node, error) {
+	var pairs, merged [][2]*yaml.Node
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Tag == "!!merge" {
+			v = resolveAlias(v)
+			sources := []*yaml.Node{v}
+			if v.Kind == yaml.SequenceNode {
+				sources = v.Content
+			}
+			for _, src := range sources {
+				src = resolveAlias(src)
+				if src.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("line %d: << expects a mapping", k.Line)
+				}
+				p, err := yamlPairs(src)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, p...)
+			}
+			continue
+		}
+		if k.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: keys must be scalars", k.Line)
+		}
+		seen[k.Value] = true
+		pairs = append(pairs, [2]*yaml.Node{k, v})
+	}
+	for _, p := range merged {
+		if !seen[p[0].Value] {
+			seen[p[0].Value] = true
+			pairs = append(pairs, p)
+		}
+	}
+	return pairs, nil
+}
+
+func writeYAMLScalar(b *strings.Builder, n *yaml.Node) error {
+	if key, ok := yamlTags[n.Tag]; ok {
+		value := n.Value
+		if key == "$date" {
+			t, err := parseYAMLTime(value)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid date %q", n.Line, value)
+			}
+			value = t.Format(yamlDateLayout)
+		}
+		b.WriteString(`{"` + key + `":`)
+		writeJSONString(b, value)
+		b.WriteByte('}')
+		return nil
+	}
+	switch n.ShortTag() {
+	case "!!null":
+		b.WriteString("null")
+	case "!!bool":
+		var v bool
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %v", n.Line, err)
+		}
+		b.WriteString(strconv.FormatBool(v))
+	case "!!int":
+		var v int64
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %v", n.Line, err)
+		}
+		b.WriteString(strconv.FormatInt(v, 10))
+	case "!!float":
+		var v float64
+		if err := n.Decode(&v); err != nil {
+			return fmt.Errorf("line %d: %v", n.Line, err)
+		}
+		// 一律寫成 $numberDouble，整數值的 float（1.0）也保持 double
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		switch {
+		case math.IsNaN(v):
+			s = "NaN"
+		case math.IsInf(v, 1):
+			s = "Infinity"
+		case math.IsInf(v, -1):
+			s = "-Infinity"
+		}
+		b.WriteString(`{"$numberDouble":"` + s + `"}`)
+	case "!!timestamp":
+		t, err := parseYAMLTime(n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid date %q", n.Line, n.Value)
+		}
+		b.WriteString(`{"$date":"` + t.Format(yamlDateLayout) + `"}`)
+	case "!!binary":
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.Value), ""))
+		if err != nil {
+			return fmt.Errorf("line %d: invalid binary: %v", n.Line, err)
+		}
+		b.WriteString(`{"$binary":{"base64":"` + base64.StdEncoding.EncodeToString(data) + `","subType":"00"}}`)
+	case "!!str":
+		writeJSONString(b, n.Value)
+	default:
+		return fmt.Errorf("line %d: unknown tag %s (expected one of !oid, !date, !decimal, !long, !int, !double)", n.Line, n.Tag)
+	}
+	return nil
+}
+
+// yamlDateLayout Extended JSON 的 $date；BSON 的 date 只到毫秒
+const yamlDateLayout = "2006-01-02T15:04:05.999Z07:00"
+
+// parseYAMLTime 接受 YAML timestamp 的各種寫法；沒有時區時視為 UTC
+func parseYAMLTime(s string) (time.Time, error) {
+	var t time.Time
+	err := (&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: s}).Decode(&t)
+	return t.UTC(), err
+}
+
+func writeJSONString(b *strings.Builder, s string) {
+	data, _ := json.Marshal(s)
+	b.Write(data)
+}
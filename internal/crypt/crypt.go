@@ -0,0 +1,211 @@
+// Package crypt 以 AES-256-GCM 分段加密匯出檔，匯入時邊讀邊解密，不需要先解密到磁碟。
+//
+// 檔案格式：header（magic "MTENC"、版本 1、7 bytes 的隨機 nonce prefix）之後是一段段的密文，
+// 每段是最多 64KiB 的明文加上 16 bytes 的 GCM tag。nonce 為 prefix + 4 bytes 的段落序號 + 1 byte 的結尾旗標，
+// header 作為每段的 additional data；最後一段一定短於 64KiB（可以是空的），被截斷、調換順序或竄改的檔案都無法解密。
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Ext 加密檔的副檔名，加在原本的檔名之後，例如 users.json.enc
+const Ext = ".enc"
+
+// KeySize AES-256 的金鑰長度
+const KeySize = 32
+
+const (
+	magic       = "MTENC"
+	version     = 1
+	prefixSize  = 7
+	headerSize  = len(magic) + 1 + prefixSize
+	chunkSize   = 64 * 1024
+	tagSize     = 16
+	maxChunks   = 1<<32 - 1
+	lastFlag    = 1
+	nonceLength = prefixSize + 4 + 1
+)
+
+// ErrDecrypt 金鑰錯誤或檔案損壞；GCM 無法分辨兩者
+var ErrDecrypt = errors.New("failed to decrypt: wrong key or corrupted file")
+
+// ParseKey 解析 base64 編碼的 32 bytes 金鑰，例如 openssl rand -base64 32 的輸出
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64: %v", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bytes, expected %d (generate one with: openssl rand -base64 32)", len(key), KeySize)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(prefix []byte, n uint64, last bool) []byte {
+	out := make([]byte, nonceLength)
+	copy(out, prefix)
+	binary.BigEndian.PutUint32(out[prefixSize:], uint32(n))
+	if last {
+		out[nonceLength-1] = lastFlag
+	}
+	return out
+}
+
+// Writer 把明文加密後寫到底層的 io.Writer；Close 寫出最後一段，但不會關閉底層的 writer
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	n      uint64
+	closed bool
+}
+
+// NewWriter 寫出 header 並回傳加密的 Writer
+func NewWriter(w io.Writer, key []byte) (*Writer, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[len(magic)] = version
+	if _, err := rand.Read(header[len(magic)+1:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to a closed crypt.Writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		// 緩衝區滿了而且還有資料時才寫出，最後一段留給 Close
+		if len(w.buf) == chunkSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close 寫出最後一段；沒有呼叫 Close 的檔案無法解密
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if len(w.buf) == chunkSize {
+		if err := w.seal(false); err != nil {
+			return err
+		}
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *Writer) seal(last bool) error {
+	if w.n >= maxChunks {
+		return errors.New("file too large to encrypt")
+	}
+	prefix := w.header[len(magic)+1:]
+	out := w.aead.Seal(nil, nonce(prefix, w.n, last), w.buf, w.header)
+	w.n++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(out)
+	return err
+}
+
+// Reader 邊讀邊解密；讀到結尾時確認有最後一段，被截斷的檔案回傳錯誤而不是 io.EOF
+type Reader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	in     []byte
+	buf    []byte
+	n      uint64
+	done   bool
+}
+
+// NewReader 讀取並檢查 header
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(magic)]) != magic {
+		return nil, errors.New("not an encrypted export file")
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("unsupported encryption format version %d", header[len(magic)])
+	}
+	return &Reader{r: r, aead: aead, header: header, in: make([]byte, chunkSize+tagSize)}, nil
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *Reader) open() error {
+	n, err := io.ReadFull(r.r, r.in)
+	last := false
+	switch {
+	case err == io.EOF:
+		return errors.New("encrypted file is truncated")
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	}
+	if n < tagSize {
+		return errors.New("encrypted file is truncated")
+	}
+	prefix := r.header[len(magic)+1:]
+	plain, err := r.aead.Open(r.in[:0], nonce(prefix, r.n, last), r.in[:n], r.header)
+	if err != nil {
+		if !last {
+			return ErrDecrypt
+		}
+		return fmt.Errorf("%w (or the file is truncated)", ErrDecrypt)
+	}
+	r.n++
+	r.buf, r.done = plain, last
+	return nil
+}
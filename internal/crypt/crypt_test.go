@@ -0,0 +1,179 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encrypt(t *testing.T, key, plain []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := NewWriter(&out, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// chunkAt 第 n 段密文在檔案中的位置（前面的段落都是完整的 64KiB）
+func chunkAt(n int) int {
+	return headerSize + n*(chunkSize+tagSize)
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 123} {
+		plain := randomBytes(t, size)
+		got, err := decrypt(key, encrypt(t, key, plain))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: decrypted %d bytes that do not match the plaintext", size, len(got))
+		}
+	}
+}
+
+func TestRoundTripSmallWrites(t *testing.T) {
+	key := testKey(t)
+	plain := randomBytes(t, 2*chunkSize+7)
+	var out bytes.Buffer
+	w, err := NewWriter(&out, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := plain; len(p) > 0; {
+		n := min(len(p), 1000)
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := decrypt(key, out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatal("decrypted data does not match the plaintext")
+	}
+}
+
+func TestTruncatedAtChunkBoundary(t *testing.T) {
+	key := testKey(t)
+	data := encrypt(t, key, randomBytes(t, 2*chunkSize+10))
+	for _, n := range []int{1, 2} {
+		if _, err := decrypt(key, data[:chunkAt(n)]); err == nil {
+			t.Fatalf("file cut after %d full chunks decrypted without an error", n)
+		}
+	}
+	// 剛好是整數段的明文最後有一段空的結尾，去掉它也要失敗
+	data = encrypt(t, key, randomBytes(t, 2*chunkSize))
+	if _, err := decrypt(key, data[:chunkAt(2)]); err == nil {
+		t.Fatal("file without its empty last chunk decrypted without an error")
+	}
+}
+
+func TestTruncatedInsideChunk(t *testing.T) {
+	key := testKey(t)
+	data := encrypt(t, key, randomBytes(t, 2*chunkSize+10))
+	if _, err := decrypt(key, data[:chunkAt(1)+100]); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+	if _, err := decrypt(key, data[:len(data)-1]); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestReorderedChunks(t *testing.T) {
+	key := testKey(t)
+	data := encrypt(t, key, randomBytes(t, 2*chunkSize+10))
+	swapped := append([]byte{}, data[:chunkAt(0)]...)
+	swapped = append(swapped, data[chunkAt(1):chunkAt(2)]...)
+	swapped = append(swapped, data[chunkAt(0):chunkAt(1)]...)
+	swapped = append(swapped, data[chunkAt(2):]...)
+	if _, err := decrypt(key, swapped); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestTamperedByte(t *testing.T) {
+	key := testKey(t)
+	data := encrypt(t, key, randomBytes(t, chunkSize+10))
+	data[chunkAt(0)+5] ^= 1
+	if _, err := decrypt(key, data); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestTamperedHeader(t *testing.T) {
+	key := testKey(t)
+	data := encrypt(t, key, []byte("hello"))
+	data[headerSize-1] ^= 1
+	if _, err := decrypt(key, data); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	data := encrypt(t, testKey(t), []byte("hello"))
+	if _, err := decrypt(testKey(t), data); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("got %v, want ErrDecrypt", err)
+	}
+}
+
+func TestNotEncrypted(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader([]byte(`{"a": 1}`)), testKey(t)); err == nil {
+		t.Fatal("plain JSON was accepted as an encrypted file")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := ParseKey("c2hvcnQ="); err == nil {
+		t.Fatal("a 5-byte key was accepted")
+	}
+	if _, err := ParseKey("not base64!"); err == nil {
+		t.Fatal("invalid base64 was accepted")
+	}
+	key, err := ParseKey(" AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n")
+	if err != nil || len(key) != KeySize {
+		t.Fatalf("got %d bytes, %v", len(key), err)
+	}
+}
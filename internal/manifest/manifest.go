@@ -0,0 +1,159 @@
+// Package manifest 匯出目錄的 manifest.json：每個檔案的 SHA-256、大小與文件數，匯入前據此拒絕被竄改或不完整的資料。
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// File 匯出目錄內的 manifest 檔名，本身不會被匯入
+const File = "manifest.json"
+
+// Version 目前的 manifest 格式版本
+const Version = 1
+
+// Manifest 一次匯出的所有檔案；有 collection 匯出失敗時 Complete 為 false，Failed 列出失敗的 collection
+type Manifest struct {
+	Version    int       `json:"version"`
+	DB         string    `json:"db"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Complete   bool      `json:"complete"`
+	Failed     []string  `json:"failed,omitempty"`
+	Files      []Entry   `json:"files"`
+}
+
+// Entry 單一檔案；Docs 只有資料檔才有
+type Entry struct {
+	File       string    `json:"file"` // 相對於目錄的路徑
+	Collection string    `json:"collection"`
+	Docs       *int      `json:"docs,omitempty"`
+	Bytes      int64     `json:"bytes"`
+	SHA256     string    `json:"sha256"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+// Write 先寫暫存檔再 rename，中斷時不會留下寫一半的 manifest
+func Write(dir string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, File)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Load 讀取 dir 的 manifest；沒有 manifest.json，或它不是 manifest（例如名為 manifest 的 collection）時回傳 nil, nil
+func Load(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, File))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var probe map[string]json.RawMessage
+	if json.Unmarshal(data, &probe) != nil || probe["files"] == nil || probe["version"] == nil {
+		return nil, nil
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", File, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported version %d", File, m.Version)
+	}
+	return &m, nil
+}
+
+// Lookup 依相對路徑找檔案
+func (m *Manifest) Lookup(rel string) (Entry, bool) {
+	for _, e := range m.Files {
+		if e.File == rel {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Verify 檢查匯出是否完整，以及每個檔案都存在且大小與 SHA-256 相符；回傳所有問題
+func (m *Manifest) Verify(dir string) error {
+	var errs []error
+	if !m.Complete {
+		errs = append(errs, fmt.Errorf("the export did not complete (failed collections: %v)", m.Failed))
+	}
+	for _, e := range m.Files {
+		sum, n, err := HashFile(filepath.Join(dir, filepath.FromSlash(e.File)))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			errs = append(errs, fmt.Errorf("%s is missing", e.File))
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %v", e.File, err))
+		case n != e.Bytes:
+			errs = append(errs, fmt.Errorf("%s is %d bytes, expected %d", e.File, n, e.Bytes))
+		case sum != e.SHA256:
+			errs = append(errs, fmt.Errorf("%s does not match its SHA-256", e.File))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HashFile 檔案的 SHA-256（hex）與大小
+func HashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := NewHasher(io.Discard)
+	if _, err := io.Copy(h, f); err != nil {
+		return "", 0, err
+	}
+	return h.Sum(), h.Bytes(), nil
+}
+
+// Hasher 寫入時一併計算 SHA-256 與位元組數，匯出大檔案時不必再讀一次
+type Hasher struct {
+	w io.Writer
+	h hash.Hash
+	n int64
+}
+
+func NewHasher(w io.Writer) *Hasher {
+	return &Hasher{w: w, h: sha256.New()}
+}
+
+func (h *Hasher) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.h.Write(p[:n])
+	h.n += int64(n)
+	return n, err
+}
+
+// Sum 目前為止寫入內容的 SHA-256（hex）
+func (h *Hasher) Sum() string { return hex.EncodeToString(h.h.Sum(nil)) }
+
+// Bytes 目前為止寫入的位元組數
+func (h *Hasher) Bytes() int64 { return h.n }
+
+// ResumeHasher 接續寫入已有 prefix 的檔案：prefix 的內容只計入 SHA-256 與位元組數，之後的寫入才寫到 w
+func ResumeHasher(w io.Writer, prefix io.Reader) (*Hasher, error) {
+	h := NewHasher(io.Discard)
+	if _, err := io.Copy(h, prefix); err != nil {
+		return nil, err
+	}
+	h.w = w
+	return h, nil
+}
@@ -0,0 +1,164 @@
+// Package metrics 以 Prometheus 文字格式提供 /metrics，給 watch、sync、tail 這些常駐模式監控用；
+// 不依賴 Prometheus client，只實作用到的 counter、gauge 與 histogram
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// batchBuckets 批次寫入時間的 histogram 上界（秒）
+var batchBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Metrics 累計處理的文件數、錯誤數、批次寫入時間與延遲；nil 時所有方法都不做事，呼叫端不必判斷是否啟用
+type Metrics struct {
+	mode string // import、sync 或 tail，輸出為 mode label
+
+	mu      sync.Mutex
+	docs    map[[2]string]float64 // collection、operation → 文件數
+	errors  map[string]float64    // collection → 錯誤數
+	buckets []uint64              // 每個 batchBuckets 上界的累計次數
+	sum     float64
+	count   uint64
+	lag     float64
+	lagSet  bool
+}
+
+// New mode 會成為每個 metric 的 mode label
+func New(mode string) *Metrics {
+	return &Metrics{mode: mode, docs: map[[2]string]float64{}, errors: map[string]float64{}, buckets: make([]uint64, len(batchBuckets))}
+}
+
+// Docs 累計寫入（或讀出）的文件數，operation 例如 insert、upsert、update、delete
+func (m *Metrics) Docs(collection, operation string, n int) {
+	if m == nil || n == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.docs[[2]string{collection, operation}] += float64(n)
+}
+
+// Error 累計失敗的檔案、批次或事件
+func (m *Metrics) Error(collection string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[collection]++
+}
+
+// Batch 記錄一次批次寫入（含重試）的時間
+func (m *Metrics) Batch(d time.Duration) {
+	if m == nil {
+		return
+	}
+	s := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for n, le := range batchBuckets {
+		if s <= le {
+			m.buckets[n]++
+		}
+	}
+	m.sum += s
+	m.count++
+}
+
+// Lag 最近一次處理的變更距離發生時的時間：sync / tail 為 change event 的 cluster time，watch 為檔案修改的時間
+func (m *Metrics) Lag(d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lag, m.lagSet = max(d.Seconds(), 0), true
+}
+
+// ServeHTTP 以 Prometheus 文字格式（text/plain; version=0.0.4）輸出目前的值
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b strings.Builder
+	m.write(&b)
+	w.Write([]byte(b.String()))
+}
+
+func (m *Metrics) write(b *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mode := label("mode", m.mode)
+
+	b.WriteString("# HELP mongo_tools_documents_total Documents processed.\n# TYPE mongo_tools_documents_total counter\n")
+	keys := make([][2]string, 0, len(m.docs))
+	for k := range m.docs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, c int) bool {
+		if keys[a][0] != keys[c][0] {
+			return keys[a][0] < keys[c][0]
+		}
+		return keys[a][1] < keys[c][1]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(b, "mongo_tools_documents_total{%s,%s,%s} %s\n", mode, label("collection", k[0]), label("operation", k[1]), number(m.docs[k]))
+	}
+
+	b.WriteString("# HELP mongo_tools_errors_total Failed files, batches or change events.\n# TYPE mongo_tools_errors_total counter\n")
+	colls := make([]string, 0, len(m.errors))
+	for c := range m.errors {
+		colls = append(colls, c)
+	}
+	sort.Strings(colls)
+	for _, c := range colls {
+		fmt.Fprintf(b, "mongo_tools_errors_total{%s,%s} %s\n", mode, label("collection", c), number(m.errors[c]))
+	}
+
+	b.WriteString("# HELP mongo_tools_batch_duration_seconds Time to write one batch, including retries.\n# TYPE mongo_tools_batch_duration_seconds histogram\n")
+	for n, le := range batchBuckets {
+		fmt.Fprintf(b, "mongo_tools_batch_duration_seconds_bucket{%s,le=\"%s\"} %d\n", mode, number(le), m.buckets[n])
+	}
+	fmt.Fprintf(b, "mongo_tools_batch_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", mode, m.count)
+	fmt.Fprintf(b, "mongo_tools_batch_duration_seconds_sum{%s} %s\n", mode, number(m.sum))
+	fmt.Fprintf(b, "mongo_tools_batch_duration_seconds_count{%s} %d\n", mode, m.count)
+
+	if m.lagSet {
+		b.WriteString("# HELP mongo_tools_lag_seconds Age of the most recently applied change.\n# TYPE mongo_tools_lag_seconds gauge\n")
+		fmt.Fprintf(b, "mongo_tools_lag_seconds{%s} %s\n", mode, number(m.lag))
+	}
+}
+
+func label(name, value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return name + `="` + r.Replace(value) + `"`
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Serve 在 addr（例如 ":9090"）提供 /metrics，直到 ctx 被取消；回傳實際監聽的位址，無法監聽時回傳錯誤
+func (m *Metrics) Serve(ctx context.Context, addr string) (net.Addr, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	return ln.Addr(), nil
+}
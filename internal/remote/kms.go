@@ -0,0 +1,64 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KMSDecrypt 以環境變數的 AWS 憑證呼叫 KMS Decrypt，解開 aws kms generate-data-key 產生的 CiphertextBlob；
+// AWS_ENDPOINT_URL_KMS 可指定 KMS 相容服務（LocalStack 等）
+func KMSDecrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	c := loadS3Config()
+	if c.AccessKey == "" {
+		return nil, fmt.Errorf("KMS requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	endpoint := strings.TrimSuffix(firstEnv("AWS_ENDPOINT_URL_KMS"), "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", c.Region)
+	}
+	u, err := url.Parse(endpoint + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid KMS endpoint %s: %v", endpoint, err)
+	}
+
+	// []byte 以 base64 編碼，正是 KMS JSON API 的 blob 格式
+	body, err := json.Marshal(struct {
+		CiphertextBlob []byte
+	}{ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	signV4(req, c, "kms", sha256Hex(string(body)), time.Now().UTC())
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("KMS Decrypt", resp)
+	}
+
+	var out struct {
+		Plaintext []byte
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to parse KMS Decrypt response: %v", err)
+	}
+	if len(out.Plaintext) == 0 {
+		return nil, fmt.Errorf("KMS Decrypt returned no plaintext")
+	}
+	return out.Plaintext, nil
+}
@@ -0,0 +1,142 @@
+// Package remote 讀取 http(s):// 與 s3:// 上的檔案，讓匯入來源不必先下載到本機。
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client 不設整體 timeout：大檔案的下載時間由呼叫端的 ctx 控制
+var client = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+	},
+}
+
+// IsURL 是否為 http://、https:// 或 s3:// 路徑
+func IsURL(p string) bool {
+	for _, scheme := range []string{"http://", "https://", "s3://"} {
+		if strings.HasPrefix(strings.ToLower(p), scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrefix s3:// 結尾為 "/"（或只有 bucket）時視為目錄，會列出底下的所有物件
+func IsPrefix(p string) bool {
+	if !strings.HasPrefix(strings.ToLower(p), "s3://") {
+		return false
+	}
+	_, key, _ := splitS3(p)
+	return key == "" || strings.HasSuffix(key, "/")
+}
+
+// Open 開始下載；size 為 Content-Length，未知時為 -1。
+// 物件不存在時回傳的 error 符合 errors.Is(err, fs.ErrNotExist)
+func Open(ctx context.Context, p string) (body io.ReadCloser, size int64, err error) {
+	var req *http.Request
+	if strings.HasPrefix(strings.ToLower(p), "s3://") {
+		bucket, key, err := splitS3(p)
+		if err != nil {
+			return nil, 0, err
+		}
+		req, err = newS3Request(ctx, bucket, key, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, p, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, 0, statusError(p, resp)
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// ReadFile 讀取整個檔案，用於 sidecar 之類的小檔案
+func ReadFile(ctx context.Context, p string) ([]byte, error) {
+	body, _, err := Open(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// List 列出 s3:// prefix 底下的所有物件（不含子目錄的分隔處理），回傳完整的 s3:// 路徑
+func List(ctx context.Context, p string) ([]string, error) {
+	if !strings.HasPrefix(strings.ToLower(p), "s3://") {
+		return nil, fmt.Errorf("listing is only supported for s3:// URLs: %s", p)
+	}
+	bucket, prefix, err := splitS3(p)
+	if err != nil {
+		return nil, err
+	}
+	return listS3(ctx, bucket, prefix)
+}
+
+// Dir 回傳 URL 所在的目錄（含結尾的 "/"），用來找同目錄的 sidecar 檔
+func Dir(p string) string {
+	u, err := url.Parse(p)
+	if err != nil {
+		return p[:strings.LastIndex(p, "/")+1]
+	}
+	u.RawQuery, u.Fragment = "", ""
+	s := u.String()
+	return s[:strings.LastIndex(s, "/")+1]
+}
+
+// Base 回傳 URL 最後一段（不含 query string）
+func Base(p string) string {
+	if u, err := url.Parse(p); err == nil {
+		p = u.Path
+	}
+	return p[strings.LastIndex(p, "/")+1:]
+}
+
+func statusError(p string, resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	detail := strings.TrimSpace(string(msg))
+	if code := xmlTag(detail, "Code"); code != "" {
+		detail = code + ": " + xmlTag(detail, "Message")
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", p, fs.ErrNotExist)
+	}
+	if detail == "" {
+		return fmt.Errorf("%s: %s", p, resp.Status)
+	}
+	return fmt.Errorf("%s: %s (%s)", p, resp.Status, detail)
+}
+
+// xmlTag 從 S3 的錯誤回應取出單一欄位，不值得為此完整解析 XML
+func xmlTag(s, tag string) string {
+	start := strings.Index(s, "<"+tag+">")
+	if start < 0 {
+		return ""
+	}
+	s = s[start+len(tag)+2:]
+	end := strings.Index(s, "</"+tag+">")
+	if end < 0 {
+		return ""
+	}
+	return s[:end]
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AWSCredentials STS 發出的暫時性憑證
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// AssumeRole 以環境變數的 AWS 憑證呼叫 STS AssumeRole 取得 roleARN 的暫時性憑證；
+// AWS_ENDPOINT_URL_STS 可指定 STS 相容服務
+func AssumeRole(ctx context.Context, roleARN, sessionName string) (AWSCredentials, error) {
+	c := loadS3Config()
+	if c.AccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("assuming %s requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", roleARN)
+	}
+
+	endpoint := strings.TrimSuffix(firstEnv("AWS_ENDPOINT_URL_STS"), "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", c.Region)
+	}
+	u, err := url.Parse(endpoint + "/")
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("invalid STS endpoint %s: %v", endpoint, err)
+	}
+	u.RawQuery = canonicalQuery(url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {roleARN},
+		"RoleSessionName": {sessionName},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	signV4(req, c, "sts", emptyPayloadHash, time.Now().UTC())
+	resp, err := client.Do(req)
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return AWSCredentials{}, statusError("AssumeRole "+roleARN, resp)
+	}
+
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to parse AssumeRole response: %v", err)
+	}
+	if out.Credentials.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("AssumeRole %s returned no credentials", roleARN)
+	}
+	return AWSCredentials(out.Credentials), nil
+}
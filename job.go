@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultJobWait --job 沒有指定 --wait-for-mongo 時等 MongoDB 可以連線的時間
+const defaultJobWait = 2 * time.Minute
+
+// defaultTerminationLog Kubernetes 預設的 terminationMessagePath
+const defaultTerminationLog = "/dev/termination-log"
+
+// maxTerminationMessage Kubernetes 只保留 termination message 的前 4096 bytes
+const maxTerminationMessage = 4096
+
+// 重新連線的等待時間，從 waitBackoff 開始每次加倍
+const (
+	waitBackoff    = time.Second
+	maxWaitBackoff = 15 * time.Second
+)
+
+// waitForMongo 連線並 ping；wait > 0 時連不上就重試到 wait 用完，讓叢集還在啟動時的 init job 不會 crash loop
+func waitForMongo(ctx context.Context, clientOpts *options.ClientOptions, timeout, wait time.Duration) (*mongo.Client, error) {
+	deadline := time.Now().Add(wait)
+	backoff := waitBackoff
+	for attempt := 1; ; attempt++ {
+		client, err := connect(ctx, clientOpts, timeout)
+		if err == nil {
+			if attempt > 1 {
+				logger.Info(fmt.Sprintf("✅ MongoDB is reachable after %d attempts", attempt), "attempts", attempt)
+			}
+			return client, nil
+		}
+		remaining := time.Until(deadline)
+		if wait <= 0 || remaining <= 0 || ctx.Err() != nil {
+			if wait > 0 {
+				return nil, fmt.Errorf("%v (gave up after waiting %s)", err, wait)
+			}
+			return nil, err
+		}
+		d := min(backoff, remaining)
+		logger.Warn(fmt.Sprintf("⏳ MongoDB is not reachable yet (attempt %d), retrying in %s: %v", attempt, d.Round(time.Millisecond), err),
+			"attempt", attempt, "backoff_ms", d.Milliseconds(), "remaining_ms", remaining.Milliseconds(), errAttr(err))
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(d):
+		}
+		backoff = min(backoff*2, maxWaitBackoff)
+	}
+}
+
+// lastMessages 最後一筆 info / warn 與最後一筆 error 的訊息
+type lastMessages struct {
+	mu          sync.Mutex
+	info, error string
+}
+
+// recordingHandler 轉交給原本的 handler，同時記錄 lastMessages
+type recordingHandler struct {
+	slog.Handler
+	last *lastMessages
+}
+
+func (h recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelInfo {
+		h.last.mu.Lock()
+		if r.Level >= slog.LevelError {
+			h.last.error = undecorated(r.Message)
+		} else {
+			h.last.info = undecorated(r.Message)
+		}
+		h.last.mu.Unlock()
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return recordingHandler{h.Handler.WithAttrs(attrs), h.last}
+}
+
+func (h recordingHandler) WithGroup(name string) slog.Handler {
+	return recordingHandler{h.Handler.WithGroup(name), h.last}
+}
+
+// recordMessages 之後的 log 會記錄在回傳的 lastMessages，給 writeTerminationMessage 使用
+func recordMessages() *lastMessages {
+	last := &lastMessages{}
+	logger = slog.New(recordingHandler{logger.Handler(), last})
+	return last
+}
+
+// writeTerminationMessage 把結束代碼與最後的錯誤（成功時為最後一筆訊息）寫到 path，kubectl describe pod 會顯示；
+// 檔案由 Kubernetes 建立，不存在時（不是在 pod 內執行）什麼都不做
+func writeTerminationMessage(path string, code int, last *lastMessages) {
+	if path == "" {
+		return
+	}
+	last.mu.Lock()
+	msg := last.info
+	if code != exitOK && last.error != "" {
+		msg = last.error
+	}
+	last.mu.Unlock()
+
+	text := fmt.Sprintf("exit code %d: %s", code, msg)
+	if len(text) > maxTerminationMessage {
+		// 不要切在 UTF-8 字元中間
+		n := maxTerminationMessage
+		for n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		text = text[:n]
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	f.WriteString(text)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// logger 全域 logger；預設是人看的格式（跟原本的 emoji 輸出一樣），--log-format=json 改為結構化輸出
+var logger = slog.New(newHumanHandler(slog.LevelInfo))
+
+// setupLogging 依 --log-format / --log-level 設定 logger；stdout 是 info 以下（json 時為全部）的輸出位置，
+// 資料本身要寫到 stdout 時改傳 os.Stderr
+func setupLogging(format, level string, stdout io.Writer) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q (expected debug, info, warn or error)", level)
+	}
+
+	switch format {
+	case "", "text":
+		h := newHumanHandler(lvl)
+		h.stdout = stdout
+		logger = slog.New(h)
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(stdout, &slog.HandlerOptions{
+			Level:       lvl,
+			ReplaceAttr: stripMessageDecoration,
+		}))
+	default:
+		return fmt.Errorf("invalid log format %q (expected text or json)", format)
+	}
+	return nil
+}
+
+// onFatal fatal 在結束前依序呼叫，例如送出失敗通知、寫出 --job 的 termination message
+var onFatal []func(code int, msg string)
+
+// fatal 記錄錯誤後以 exitFailure 結束程式
+func fatal(msg string, args ...any) {
+	fatalCode(exitFailure, msg, args...)
+}
+
+// fatalCode 同 fatal，以 code 結束
+func fatalCode(code int, msg string, args ...any) {
+	logger.Error(msg, args...)
+	for _, fn := range onFatal {
+		fn(code, msg)
+	}
+	os.Exit(code)
+}
+
+// errAttr 統一錯誤欄位的名稱
+func errAttr(err error) slog.Attr {
+	return slog.String("error", err.Error())
+}
+
+// stripMessageDecoration JSON 輸出時拿掉訊息開頭的 emoji 與縮排
+func stripMessageDecoration(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.MessageKey {
+		return slog.String(slog.MessageKey, undecorated(a.Value.String()))
+	}
+	return a
+}
+
+// undecorated 拿掉訊息開頭的 emoji 與縮排
+func undecorated(msg string) string {
+	return strings.TrimLeftFunc(msg, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// humanHandler 只輸出訊息本身：info 以下寫到 stdout，warn 以上跟 log.Printf 一樣加上時間寫到 stderr
+type humanHandler struct {
+	level  slog.Leveler
+	mu     *sync.Mutex
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func newHumanHandler(level slog.Leveler) *humanHandler {
+	return &humanHandler{level: level, mu: &sync.Mutex{}, stdout: os.Stdout, stderr: os.Stderr}
+}
+
+func (h *humanHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *humanHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.Level >= slog.LevelWarn {
+		_, err := fmt.Fprintf(h.stderr, "%s %s\n", r.Time.Format("2006/01/02 15:04:05"), r.Message)
+		return err
+	}
+	_, err := fmt.Fprintln(h.stdout, r.Message)
+	return err
+}
+
+// 人看的格式中欄位已經寫在訊息裡，attrs 只給 JSON 用
+func (h *humanHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *humanHandler) WithGroup(string) slog.Handler      { return h }
@@ -1,151 +1,397 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/hayletdomybest/mongo-tools/exporter"
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"github.com/hayletdomybest/mongo-tools/internal/metrics"
 	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// 結束代碼
+const (
+	exitOK          = 0
+	exitFailure     = 1 // 有檔案 / collection 處理失敗
+	exitDifferent   = 2 // diff 發現差異
+	exitUnreachable = 3 // --wait-for-mongo / --job 等到逾時仍無法連線，或 ping 失敗；沒有等待時連線失敗為 exitFailure
+
+	exitInterrupted = 130 // 收到 SIGINT / SIGTERM 而中斷（128 + SIGINT）
+)
+
 func main() {
-	loadEnv()
+	os.Exit(run())
+}
 
-	mongoURI := os.Getenv("MONGO_URI")
-	dbName := os.Getenv("MONGO_DB")
-	jsonPath := os.Getenv("JSON_PATH")
+func run() (code int) {
+	early := preParse(os.Args[1:])
+	// PROFILE 也可以寫在 .env 裡，所以先載入 .env 再套用 profile
+	if !early.NoEnv {
+		loadEnv(early)
+	}
+	profileName, profileFile := early.Profile, early.ProfileFile
+	if profileName == "" {
+		profileName = os.Getenv("PROFILE")
+	}
+	if profileName != "" {
+		if profileFile == "" {
+			profileFile = envOr("PROFILE_FILE", defaultProfileFile)
+		}
+		if err := applyProfile(profileName, profileFile); err != nil {
+			log.Fatalf("Invalid profile: %v", err)
+		}
+	}
 
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(mongoURI))
-	if err != nil {
-		log.Fatalf("Mongo connect error: %v", err)
+	cmd, cfg := parseArgs(os.Args[1:])
+	logOut := os.Stdout
+	if (cmd == "tail" && cfg.TailOut == "-") || summaryToStdout(cmd, cfg) {
+		logOut = os.Stderr
+	}
+	if err := setupLogging(cfg.LogFormat, cfg.LogLevel, logOut); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.JobMode {
+		last := recordMessages()
+		onFatal = append(onFatal, func(code int, msg string) {
+			writeTerminationMessage(cfg.TerminationLog, code, last)
+		})
+		// 最後才執行的 defer：code 已經是最後的結束代碼
+		defer func() {
+			writeTerminationMessage(cfg.TerminationLog, code, last)
+		}()
+	}
+	if cmd == "import" || cmd == "serve" {
+		// 連線失敗、路徑錯誤等直接結束的錯誤也要通知
+		runStarted := time.Now()
+		onFatal = append(onFatal, func(code int, msg string) {
+			notifyRun(context.Background(), cfg, runStarted, nil, outcomeFailed, "", msg)
+		})
+	}
+	if profileName != "" {
+		logger.Info(fmt.Sprintf("🔧 Using profile %s from %s", profileName, profileFile), "profile", profileName, "file", profileFile)
 	}
-	defer client.Disconnect(context.TODO())
 
-	db := client.Database(dbName)
+	// generate --out 只寫檔案，不連線到 MongoDB
+	offline := cmd == "generate" && cfg.GenerateOut != ""
+	var clientOpts *options.ClientOptions
+	if !offline {
+		var err error
+		if clientOpts, err = clientOptions(cfg); err != nil {
+			fatal(err.Error(), errAttr(err))
+		}
+		wc := describeWriteConcern(clientOpts.WriteConcern)
+		logger.Debug(fmt.Sprintf("🔒 Write concern: %s", wc), "write_concern", wc)
+	}
+
+	sigCtx, interrupted := trapSignals(context.Background())
+	ctx, cancel := withTimeout(sigCtx, cfg.RunTimeout)
+	defer cancel()
+	defer func() {
+		switch {
+		case interrupted() && !cfg.Watch && cfg.Schedule == nil && cmd != "sync" && cmd != "tail" && cmd != "serve" && cmd != "consume":
+			// watch、schedule、sync、tail、serve 與 consume 本來就以 Ctrl+C 結束
+			code = exitInterrupted
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			logger.Error(fmt.Sprintf("❌ Run timed out after %s", cfg.RunTimeout), "run_timeout", cfg.RunTimeout.String())
+		}
+	}()
 
-	fi, err := os.Stat(jsonPath)
+	key, err := encryptionKey(ctx, cfg)
 	if err != nil {
-		log.Fatalf("Invalid JSON_PATH: %v", err)
+		fatal(fmt.Sprintf("❌ Invalid encryption key: %v", err), errAttr(err))
 	}
+	cfg.Import.DecryptKey, cfg.Export.EncryptKey = key, key
 
-	if fi.IsDir() {
-		files, err := filepath.Glob(filepath.Join(jsonPath, "*.json"))
-		if err != nil {
-			log.Fatalf("Error reading directory: %v", err)
+	if offline {
+		return runGenerate(ctx, nil, nil, cfg)
+	}
+	client, err := waitForMongo(ctx, clientOpts, cfg.OpTimeout, cfg.WaitForMongo)
+	if err != nil {
+		if interrupted() {
+			return exitInterrupted
 		}
-		for _, file := range files {
-			processFile(db, file)
+		if cfg.WaitForMongo > 0 {
+			// --wait-for-mongo / --job 等到逾時
+			fatalCode(exitUnreachable, fmt.Sprintf("Mongo connect error: %v", err), errAttr(err))
 		}
+		fatal(fmt.Sprintf("Mongo connect error: %v", err), errAttr(err))
+	}
+	defer client.Disconnect(context.TODO())
+	// 偵測一次，所有 importer 共用；不支援的功能在使用時警告並改用替代做法
+	if server, err := importer.DetectServer(ctx, client); err != nil {
+		logger.Warn(fmt.Sprintf("⚠️  Could not detect the server version: %v", err), errAttr(err))
 	} else {
-		processFile(db, jsonPath)
+		logger.Info(fmt.Sprintf("🖥️  Connected to %s", server), "server_version", server.Version, "wire_version", server.WireVersion, "topology", server.Topology)
+		cfg.Import.Server = &server
+	}
+	if cfg.MetricsAddr != "" {
+		m := metrics.New(cmd)
+		addr, err := m.Serve(ctx, cfg.MetricsAddr)
+		if err != nil {
+			fatal(fmt.Sprintf("Failed to serve metrics on %s: %v", cfg.MetricsAddr, err), "metrics_addr", cfg.MetricsAddr, errAttr(err))
+		}
+		logger.Info(fmt.Sprintf("📈 Serving metrics on http://%s/metrics", addr), "metrics_addr", addr.String())
+		cfg.Import.Metrics, cfg.Copy.Metrics, cfg.Export.Metrics, cfg.Consume.Metrics = m, m, m, m
 	}
 
-	fmt.Println("✅ All imports completed.")
-}
-
-func loadEnv() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+	switch cmd {
+	case "export":
+		if cfg.GridFS != "" {
+			return runGridFS(ctx, client, clientOpts, cfg, cmd)
+		}
+		cfg.Export.Logger = logger
+		exp, err := exporter.New(client, cfg.Export)
+		if err != nil {
+			fatal(fmt.Sprintf("Invalid export options: %v", err), errAttr(err))
+		}
+		results, err := exp.ExportDatabase(ctx, cfg.Path)
+		if err != nil {
+			fatal(fmt.Sprintf("❌ Export failed: %v", err), errAttr(err))
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			logger.Error(fmt.Sprintf("❌ %d collections failed to export", failed), "failed", failed)
+			if cfg.Export.Resume {
+				logger.Info("⏯️  Run the same command again to continue the failed collections from their checkpoints")
+			}
+			return exitFailure
+		}
+		logger.Info("✅ All exports completed.")
+	case "tail":
+		return runTail(ctx, client, cfg)
+	case "consume":
+		return runConsume(ctx, client, cfg)
+	case "ping":
+		return runPing(ctx, client, clientOpts, cfg)
+	case "stats":
+		return runStats(ctx, client, cfg)
+	case "snapshot":
+		return runSnapshot(ctx, client, cfg)
+	case "restore":
+		return runRestore(ctx, client, clientOpts, cfg, interrupted)
+	case "diff":
+		return runDiff(ctx, client, cfg)
+	case "copy", "sync":
+		return runCopy(ctx, client, clientOpts, cfg, cmd)
+	case "verify":
+		return runVerify(ctx, client, cfg)
+	case "generate":
+		return runGenerate(ctx, client, clientOpts, cfg)
+	case "serve":
+		return runServe(ctx, client, clientOpts, cfg)
+	case "drop":
+		var targets []namespace
+		for _, name := range strings.Split(cfg.Collection, ",") {
+			targets = append(targets, namespace{cfg.DB, strings.TrimSpace(name)})
+		}
+		confirmDestructive(ctx, client, clientOpts, cfg, "drop these collections", targets)
+		if failed := dropCollections(ctx, client.Database(cfg.DB), strings.Split(cfg.Collection, ","), cfg.OpTimeout); failed > 0 {
+			return exitFailure
+		}
+	default:
+		if cfg.GridFS != "" {
+			return runGridFS(ctx, client, clientOpts, cfg, cmd)
+		}
+		if cfg.Schedule != nil {
+			return runSchedule(ctx, client, clientOpts, cfg, interrupted)
+		}
+		if cfg.Plan != nil {
+			_, code := runPlan(ctx, client, clientOpts, cfg, interrupted)
+			return code
+		}
+		_, code := runImport(ctx, client, clientOpts, cfg, interrupted)
+		return code
 	}
+	return exitOK
 }
 
-func processFile(db *mongo.Database, filePath string) {
-	coll := extractCollectionName(filePath)
-	if coll == "" {
-		log.Printf("⚠️  Skipping unrecognized file: %s\n", filePath)
-		return
+// runImport 匯入 --path 一次；--schedule 時每次排程各呼叫一次，路徑錯誤只讓這一次失敗
+func runImport(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, interrupted func() bool) ([]importer.FileResult, int) {
+	cfg.Import.Logger = logger
+	imp, err := importer.New(ctx, client, cfg.Import)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid import options: %v", err), errAttr(err))
 	}
+	defer imp.Close()
 
-	fmt.Printf("📥 Importing %s → collection: %s\n", filepath.Base(filePath), coll)
-
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		log.Printf("❌ Failed to read file: %s (%v)\n", filePath, err)
-		return
+	// upsert / merge / append 不會刪除文件，不需要確認
+	if cfg.Import.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete) {
+		planned, err := imp.Targets(ctx, cfg.Path)
+		if err != nil {
+			return nil, invalidPath(ctx, cfg, err)
+		}
+		targets := make([]namespace, 0, len(planned))
+		for _, t := range planned {
+			if deletes(t.Strategy) {
+				targets = append(targets, namespace{t.DB, t.Collection})
+			}
+		}
+		confirmDestructive(ctx, client, clientOpts, cfg, deleteAction(cfg.Import), targets)
 	}
 
-	docs, err := parseExtendedJSON(data)
+	started := time.Now()
+	results, err := imp.ImportPath(ctx, cfg.Path)
 	if err != nil {
-		log.Printf("❌ Failed to parse Extended JSON in %s: %v\n", filePath, err)
-		return
+		return nil, invalidPath(ctx, cfg, err)
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// 清空舊資料
-	if _, err := db.Collection(coll).DeleteMany(ctx, bson.M{}); err != nil {
-		log.Printf("❌ Failed to clear collection %s: %v\n", coll, err)
-		return
+	printSummary(results, cfg)
+	saveReport(cfg, started, results)
+	if interrupted() {
+		printInterrupted(results, cfg.Import)
+		notifyRun(ctx, cfg, started, results, outcomeInterrupted, "", "")
+		return results, exitInterrupted
+	}
+	viewsFailed := false
+	if cfg.Import.ViewsFile != "" {
+		if _, err := imp.CreateViews(ctx); err != nil {
+			viewsFailed = true
+		}
+	}
+	// watch 只通知初次匯入的結果
+	switch {
+	case viewsFailed:
+		notifyRun(ctx, cfg, started, results, outcomeFailed, "", "Some views could not be created")
+	case countFailed(results) > 0:
+		notifyRun(ctx, cfg, started, results, outcomeFailed, "", "")
+	default:
+		notifyRun(ctx, cfg, started, results, outcomeSucceeded, "", "")
+	}
+	if cfg.Watch {
+		return results, watch(ctx, imp, cfg, started, results)
 	}
+	if failed := countFailed(results); failed > 0 {
+		logger.Error(fmt.Sprintf("❌ %d of %d files failed to import", failed, len(results)), "failed", failed, "files", len(results))
+		return results, exitFailure
+	}
+	if viewsFailed {
+		logger.Error("❌ Some views could not be created")
+		return results, exitFailure
+	}
+	logger.Info("✅ All imports completed.")
+	return results, exitOK
+}
 
-	// 插入新資料
-	if _, err := db.Collection(coll).InsertMany(ctx, docs); err != nil {
-		log.Printf("❌ Failed to insert into %s: %v\n", coll, err)
-	} else {
-		fmt.Printf("✅ Inserted %d docs into %s\n", len(docs), coll)
+// invalidPath --path 無法讀取：記錄並通知後回傳 exitFailure；--schedule 時下一次排程仍然會執行
+func invalidPath(ctx context.Context, cfg config, err error) int {
+	msg := fmt.Sprintf("Invalid JSON_PATH: %v", err)
+	switch {
+	case errors.Is(err, importer.ErrManifest):
+		msg = fmt.Sprintf("❌ Refusing to import %s: %v; pass --no-verify to import it anyway", cfg.Path, err)
+	case errors.Is(err, importer.ErrSharedTarget):
+		msg = fmt.Sprintf("❌ Refusing to import %s: %v", cfg.Path, err)
 	}
+	logger.Error(msg, "path", cfg.Path, errAttr(err))
+	notifyRun(ctx, cfg, time.Now(), nil, outcomeFailed, "", msg)
+	return exitFailure
 }
 
-// parseExtendedJSON 支援 整份 JSON Array 或 NDJSON，每笔都用 relaxed 模式解析 Extended JSON
-func parseExtendedJSON(data []byte) ([]interface{}, error) {
-	data = bytes.TrimSpace(data)
-	if len(data) == 0 {
-		return nil, nil
+// watch 初次匯入後持續監看目錄，直到 Ctrl+C / SIGTERM（ctx 被取消）；--report 在每次重新匯入後更新
+func watch(ctx context.Context, imp *importer.Importer, cfg config, started time.Time, results []importer.FileResult) int {
+	err := imp.Watch(ctx, cfg.Path, func(res importer.FileResult) {
+		printSummary([]importer.FileResult{res}, cfg)
+		results = replaceResult(results, res)
+		saveReport(cfg, started, results)
+	})
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ %v", err), errAttr(err))
+		return exitFailure
 	}
+	logger.Info("👋 Stopped watching.")
+	return exitOK
+}
 
-	var docs []interface{}
+// trapSignals 第一次 SIGINT / SIGTERM 時取消 ctx，讓進行中的寫入結束、記錄進度後再離開；
+// 之後恢復預設行為，再按一次 Ctrl+C 會直接結束
+func trapSignals(parent context.Context) (context.Context, func() bool) {
+	ctx, cancel := context.WithCancel(parent)
+	var got atomic.Bool
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		s := <-sigs
+		signal.Stop(sigs)
+		got.Store(true)
+		logger.Warn(fmt.Sprintf("⚠️  Received %s; stopping after in-flight writes (press Ctrl+C again to quit immediately)", s), "signal", s.String())
+		cancel()
+	}()
+	return ctx, got.Load
+}
 
-	// 整份 JSON Array
-	if data[0] == '[' {
-		var arr []bson.M
-		// <--- relaxed 模式：false
-		if err := bson.UnmarshalExtJSON(data, false, &arr); err != nil {
-			return nil, fmt.Errorf("failed to parse JSON array: %v", err)
-		}
-		for _, m := range arr {
-			docs = append(docs, m)
-		}
-		return docs, nil
+// connect 建立連線並 ping 一次，連不上時在開始處理之前就失敗；ping 以 --op-timeout 限制
+func connect(ctx context.Context, clientOpts *options.ClientOptions, timeout time.Duration) (*mongo.Client, error) {
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		return nil, err
 	}
+	pingCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	if err := client.Ping(pingCtx, clientOpts.ReadPreference); err != nil {
+		client.Disconnect(context.TODO())
+		return nil, err
+	}
+	return client, nil
+}
 
-	// 否则当作 NDJSON（每行一笔）
-	scanner := bufio.NewScanner(bytes.NewReader(data))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+// withTimeout timeout 為 0 時不限制
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// dropCollections 刪除指定的 collection，回傳失敗的數量；每個 drop 以 timeout 限制
+func dropCollections(ctx context.Context, db *mongo.Database, names []string, timeout time.Duration) int {
+	failed := 0
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
 			continue
 		}
-		var m bson.M
-		// <--- relaxed 模式：false
-		if err := bson.UnmarshalExtJSON([]byte(line), false, &m); err != nil {
-			return nil, fmt.Errorf("failed to parse line as Extended JSON: %v", err)
+		dropCtx, cancel := withTimeout(ctx, timeout)
+		err := db.Collection(name).Drop(dropCtx)
+		cancel()
+		if err != nil {
+			logger.Error(fmt.Sprintf("❌ Failed to drop collection %s: %v", name, err), "collection", name, errAttr(err))
+			failed++
+			continue
 		}
-		docs = append(docs, m)
+		logger.Info(fmt.Sprintf("🗑️  Dropped collection: %s", name), "collection", name)
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return docs, nil
+	return failed
 }
 
-func extractCollectionName(filePath string) string {
-	name := filepath.Base(filePath)
-	if !strings.HasSuffix(name, ".json") {
-		return ""
+// loadEnv 讀取 --env-file / ENV_FILE 指定的檔案，沒指定時讀取 .env；
+// 預設的 .env 不存在不算錯誤（設定可以全部來自環境變數與旗標），明確指定的檔案則必須存在
+func loadEnv(early earlyFlags) {
+	file, explicit := early.EnvFile, early.EnvFileSet
+	if !explicit {
+		file, explicit = os.LookupEnv("ENV_FILE")
 	}
-	parts := strings.Split(name, ".")
-	if len(parts) < 2 {
-		return ""
+	if !explicit || file == "" {
+		file, explicit = ".env", false
+	}
+	if err := godotenv.Load(file); err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+		log.Fatalf("Error loading env file %s: %v", file, err)
 	}
-	return parts[len(parts)-2]
 }
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// applyMonitoring 依 --slow-command 與 -vv 掛上 driver 的 monitor，匯入卡住或叢集不穩時看得出 driver 在等什麼：
+// --slow-command 超過門檻的指令記一筆警告；-vv 另外以 debug 記錄每個指令與失敗（可重試的錯誤 driver 會自動重試一次）、
+// 連線池被清空、取得連線失敗、server 狀態變化與 server selection
+func applyMonitoring(opts *options.ClientOptions, cfg config) {
+	verbose := cfg.Verbose >= 2
+	if cfg.SlowCommand <= 0 && !verbose {
+		return
+	}
+	m := &commandLog{slow: cfg.SlowCommand, verbose: verbose}
+	opts.SetMonitor(&event.CommandMonitor{Started: m.start, Succeeded: m.succeed, Failed: m.fail})
+	if !verbose {
+		return
+	}
+	opts.SetPoolMonitor(&event.PoolMonitor{Event: logPoolEvent})
+	opts.SetServerMonitor(&event.ServerMonitor{ServerDescriptionChanged: logServerChange})
+	opts.SetLoggerOptions(options.Logger().
+		SetSink(driverSink{}).
+		SetComponentLevel(options.LogComponentServerSelection, options.LogLevelDebug))
+}
+
+// commandLog 記錄指令的開始與結束；結束的事件沒有 collection，以 request ID 對回開始時的 namespace
+type commandLog struct {
+	slow     time.Duration
+	verbose  bool
+	inflight sync.Map // request ID → namespace
+}
+
+func (c *commandLog) start(_ context.Context, e *event.CommandStartedEvent) {
+	ns := e.DatabaseName
+	if coll, ok := e.Command.Index(0).Value().StringValueOK(); ok {
+		ns += "." + coll
+	}
+	c.inflight.Store(e.RequestID, ns)
+	if c.verbose {
+		logger.Debug(fmt.Sprintf("➡️  %s on %s", e.CommandName, ns),
+			"command", e.CommandName, "namespace", ns, "request_id", e.RequestID, "connection", e.ConnectionID)
+	}
+}
+
+func (c *commandLog) succeed(_ context.Context, e *event.CommandSucceededEvent) {
+	c.finish(&e.CommandFinishedEvent, "")
+}
+
+func (c *commandLog) fail(_ context.Context, e *event.CommandFailedEvent) {
+	c.finish(&e.CommandFinishedEvent, e.Failure)
+}
+
+func (c *commandLog) finish(e *event.CommandFinishedEvent, failure string) {
+	ns := e.DatabaseName
+	if v, ok := c.inflight.LoadAndDelete(e.RequestID); ok {
+		ns = v.(string)
+	}
+	took := e.Duration.Round(time.Millisecond)
+	attrs := []any{"command", e.CommandName, "namespace", ns, "request_id", e.RequestID, "connection", e.ConnectionID, "duration_ms", e.Duration.Milliseconds()}
+	if failure != "" {
+		attrs = append(attrs, "error", failure)
+	}
+	switch {
+	case c.slow > 0 && e.Duration >= c.slow && failure != "":
+		logger.Warn(fmt.Sprintf("🐢 Slow %s on %s failed after %s: %s", e.CommandName, ns, took, failure), attrs...)
+	case c.slow > 0 && e.Duration >= c.slow:
+		logger.Warn(fmt.Sprintf("🐢 Slow %s on %s took %s", e.CommandName, ns, took), attrs...)
+	case !c.verbose:
+	case failure != "":
+		logger.Debug(fmt.Sprintf("⚠️  %s on %s failed after %s: %s", e.CommandName, ns, took, failure), attrs...)
+	default:
+		logger.Debug(fmt.Sprintf("✔️  %s on %s took %s", e.CommandName, ns, took), attrs...)
+	}
+}
+
+// logPoolEvent 只記錄看得出連線問題的事件；連線池被清空表示 driver 把 server 標成 unknown，正在進行中的指令會重試或失敗
+func logPoolEvent(e *event.PoolEvent) {
+	switch e.Type {
+	case event.PoolCleared:
+		logger.Warn(fmt.Sprintf("⚠️  Connection pool of %s cleared", e.Address), "address", e.Address)
+	case event.GetFailed:
+		logger.Debug(fmt.Sprintf("🔌 Could not check out a connection to %s: %s", e.Address, e.Reason), "address", e.Address, "reason", e.Reason)
+	case event.ConnectionClosed:
+		if e.Reason == event.ReasonError || e.Reason == event.ReasonConnectionErrored || e.Reason == event.ReasonTimedOut {
+			logger.Debug(fmt.Sprintf("🔌 Connection %d to %s closed: %s", e.ConnectionID, e.Address, e.Reason),
+				"address", e.Address, "connection_id", e.ConnectionID, "reason", e.Reason)
+		}
+	}
+}
+
+func logServerChange(e *event.ServerDescriptionChangedEvent) {
+	before, after := e.PreviousDescription.Kind.String(), e.NewDescription.Kind.String()
+	if before == after {
+		return
+	}
+	attrs := []any{"address", e.Address.String(), "from", before, "to", after}
+	if err := e.NewDescription.LastError; err != nil {
+		attrs = append(attrs, errAttr(err))
+	}
+	logger.Debug(fmt.Sprintf("🛰️  %s: %s → %s", e.Address, before, after), attrs...)
+}
+
+// driverSink 把 driver 自己的結構化 log（server selection）轉給 logger
+type driverSink struct{}
+
+func (driverSink) Info(_ int, msg string, keysAndValues ...interface{}) {
+	logger.Debug("🔎 "+msg, keysAndValues...)
+}
+
+func (driverSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	logger.Debug("🔎 "+msg, append(keysAndValues, errAttr(err))...)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultPingCollection ping 沒有 --collection 時用來測試寫入的 collection，測試後刪除
+const defaultPingCollection = "_mongo_tools_ping"
+
+// runPing 檢查連線（main 已經連上並偵測過 server）、登入的使用者與角色，以及在 --db 插入再刪除一筆文件的權限
+func runPing(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config) int {
+	hosts := strings.Join(clientOpts.Hosts, ",")
+	started := time.Now()
+	pingCtx, cancel := withTimeout(ctx, cfg.OpTimeout)
+	err := client.Ping(pingCtx, clientOpts.ReadPreference)
+	cancel()
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Ping %s failed: %v", hosts, err), "hosts", hosts, errAttr(err))
+		return exitUnreachable
+	}
+	rtt := time.Since(started)
+	logger.Info(fmt.Sprintf("✅ Ping %s: %s", hosts, rtt.Round(time.Microsecond)), "hosts", hosts, "rtt_ms", float64(rtt.Microseconds())/1000)
+	if s := cfg.Import.Server; s != nil {
+		logger.Info(fmt.Sprintf("🖥️  Server: %s", s), "server_version", s.Version, "wire_version", s.WireVersion, "topology", s.Topology)
+	}
+
+	var status struct {
+		AuthInfo struct {
+			Users []struct {
+				User string `bson:"user"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUsers"`
+			Roles []struct {
+				Role string `bson:"role"`
+				DB   string `bson:"db"`
+			} `bson:"authenticatedUserRoles"`
+		} `bson:"authInfo"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "connectionStatus", Value: 1}}).Decode(&status); err != nil {
+		logger.Warn(fmt.Sprintf("⚠️  Could not read the authenticated user: %v", err), errAttr(err))
+	} else if len(status.AuthInfo.Users) == 0 {
+		logger.Info("🔑 Not authenticated (the server allows anonymous access)")
+	} else {
+		users := make([]string, len(status.AuthInfo.Users))
+		for n, u := range status.AuthInfo.Users {
+			users[n] = u.User + "@" + u.DB
+		}
+		roles := make([]string, len(status.AuthInfo.Roles))
+		for n, r := range status.AuthInfo.Roles {
+			roles[n] = r.Role + "@" + r.DB
+		}
+		logger.Info(fmt.Sprintf("🔑 Authenticated as %s (roles: %s)", strings.Join(users, ", "), strings.Join(roles, ", ")),
+			"users", users, "roles", roles)
+	}
+
+	name := cfg.Collection
+	if name == "" {
+		name = defaultPingCollection
+	}
+	ns := cfg.DB + "." + name
+	if err := checkWrite(ctx, client.Database(cfg.DB), name, cfg.OpTimeout); err != nil {
+		logger.Error(fmt.Sprintf("❌ Cannot write to %s: %v", ns, err), "target", ns, errAttr(err))
+		return exitFailure
+	}
+	logger.Info(fmt.Sprintf("✅ Can insert into and delete from %s", ns), "target", ns)
+	return exitOK
+}
+
+// checkWrite 插入一筆測試文件後刪除；collection 原本不存在時一併刪除 collection
+func checkWrite(ctx context.Context, db *mongo.Database, name string, timeout time.Duration) error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	existing, err := db.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %v", err)
+	}
+	coll := db.Collection(name)
+	host, _ := os.Hostname()
+	id := primitive.NewObjectID()
+	if _, err := coll.InsertOne(ctx, bson.D{{Key: "_id", Value: id}, {Key: "host", Value: host}, {Key: "pingedAt", Value: primitive.NewDateTimeFromTime(time.Now())}}); err != nil {
+		return fmt.Errorf("insert failed: %v", err)
+	}
+	if _, err := coll.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+		return fmt.Errorf("inserted a test document but could not delete it (_id %s): %v", id.Hex(), err)
+	}
+	if len(existing) == 0 {
+		if err := coll.Drop(ctx); err != nil {
+			return fmt.Errorf("could not drop the test collection: %v", err)
+		}
+	}
+	return nil
+}
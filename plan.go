@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// runPlan 依 --plan 排好的順序逐一匯入每個步驟；清空目標之前一次確認所有 truncate 步驟的 collection，
+// 依賴的步驟失敗（或沒有執行）時略過該步驟
+func runPlan(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, interrupted func() bool) ([]importer.FileResult, int) {
+	plan := cfg.Plan
+	cfg.Import.Logger = logger
+	imps := make([]*importer.Importer, len(plan.Steps))
+	var targets []namespace
+	for n, step := range plan.Steps {
+		opts := step.Options(cfg.Import)
+		imp, err := importer.New(ctx, client, opts)
+		if err != nil {
+			fatal(fmt.Sprintf("Invalid import options for plan step %s: %v", step.Name, err), "step", step.Name, errAttr(err))
+		}
+		defer imp.Close()
+		imps[n] = imp
+		// upsert / merge / append 不會刪除文件，不需要確認
+		if !opts.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete) {
+			continue
+		}
+		planned, err := imp.Targets(ctx, step.Source)
+		if err != nil {
+			fatal(fmt.Sprintf("Invalid source for plan step %s: %v", step.Name, err), "step", step.Name, "path", step.Source, errAttr(err))
+		}
+		for _, t := range planned {
+			if deletes(t.Strategy) {
+				targets = append(targets, namespace{t.DB, t.Collection})
+			}
+		}
+	}
+	confirmDestructive(ctx, client, clientOpts, cfg, deleteAction(cfg.Import), targets)
+
+	started := time.Now()
+	var results []importer.FileResult
+	failedSteps := map[string]bool{}
+	for n, step := range plan.Steps {
+		var blocked []string
+		for _, dep := range step.DependsOn {
+			if failedSteps[dep] {
+				blocked = append(blocked, dep)
+			}
+		}
+		if len(blocked) > 0 || ctx.Err() != nil {
+			if len(blocked) > 0 {
+				logger.Warn(fmt.Sprintf("⏭️  Skipping plan step %s: %s did not complete", step.Name, strings.Join(blocked, ", ")),
+					"step", step.Name, "depends_on", blocked)
+			}
+			failedSteps[step.Name] = true
+			results = append(results, importer.FileResult{File: step.Source, DB: step.DB, Collection: step.Collection, NotRun: true})
+			continue
+		}
+
+		logger.Info(fmt.Sprintf("🧭 Plan step %d/%d: %s (%s)", n+1, len(plan.Steps), step.Name, step.Source),
+			"step", step.Name, "index", n+1, "steps", len(plan.Steps), "path", step.Source)
+		stepResults, err := imps[n].ImportPath(ctx, step.Source)
+		if err != nil {
+			logger.Error(fmt.Sprintf("❌ Plan step %s failed: %v", step.Name, err), "step", step.Name, "path", step.Source, errAttr(err))
+			stepResults = []importer.FileResult{{File: step.Source, DB: step.DB, Collection: step.Collection, Err: err}}
+		}
+		for _, r := range stepResults {
+			if r.Err != nil || r.NotRun {
+				failedSteps[step.Name] = true
+			}
+		}
+		results = append(results, stepResults...)
+	}
+
+	printSummary(results, cfg)
+	saveReport(cfg, started, results)
+	if interrupted() {
+		printInterrupted(results, cfg.Import)
+		notifyRun(ctx, cfg, started, results, outcomeInterrupted, "", "")
+		return results, exitInterrupted
+	}
+	viewsFailed := false
+	if cfg.Import.ViewsFile != "" {
+		if _, err := imps[len(imps)-1].CreateViews(ctx); err != nil {
+			viewsFailed = true
+		}
+	}
+	switch {
+	case len(failedSteps) > 0:
+		notifyRun(ctx, cfg, started, results, outcomeFailed, "", fmt.Sprintf("%d of %d plan steps did not complete", len(failedSteps), len(plan.Steps)))
+	case viewsFailed:
+		notifyRun(ctx, cfg, started, results, outcomeFailed, "", "Some views could not be created")
+	default:
+		notifyRun(ctx, cfg, started, results, outcomeSucceeded, "", "")
+	}
+	if len(failedSteps) > 0 {
+		logger.Error(fmt.Sprintf("❌ %d of %d plan steps did not complete", len(failedSteps), len(plan.Steps)),
+			"failed", len(failedSteps), "steps", len(plan.Steps))
+		return results, exitFailure
+	}
+	if viewsFailed {
+		logger.Error("❌ Some views could not be created")
+		return results, exitFailure
+	}
+	logger.Info("✅ All plan steps completed.")
+	return results, exitOK
+}
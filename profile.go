@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultProfileFile --profile 預設讀取的設定檔
+const defaultProfileFile = "mongo-tools.yaml"
+
+// profile 一組環境（dev、staging…）的設定；每個欄位對應一個環境變數，Env 可以再設定其他任何環境變數
+type profile struct {
+	URI      string            `yaml:"uri"`
+	DB       string            `yaml:"db"`
+	Path     string            `yaml:"path"`
+	Strategy string            `yaml:"strategy"`
+	Env      map[string]string `yaml:"env"`
+}
+
+type profileFile struct {
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// applyProfile 把 profile 的設定寫入環境變數：優先於 .env 與既有的環境變數，但仍會被命令列旗標覆蓋
+func applyProfile(name, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var pf profileFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	p, ok := pf.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(pf.Profiles))
+		for n := range pf.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("profile %q not found in %s (available: %s)", name, file, strings.Join(names, ", "))
+	}
+
+	env := map[string]string{}
+	for k, v := range p.Env {
+		env[k] = v
+	}
+	for k, v := range map[string]string{"MONGO_URI": p.URI, "MONGO_DB": p.DB, "JSON_PATH": p.Path, "IMPORT_STRATEGY": p.Strategy} {
+		if v != "" {
+			env[k] = v
+		}
+	}
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("failed to set %s: %v", k, err)
+		}
+	}
+	return nil
+}
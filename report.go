@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+)
+
+// report --report 輸出的 JSON，CI 可以保存並檢查
+type report struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DurationMS int64        `json:"duration_ms"`
+	DB         string       `json:"db"`
+	Strategy   string       `json:"strategy"`
+	Status     string       `json:"status"` // ok 或 failed
+	Totals     reportTotals `json:"totals"`
+	Files      []reportFile `json:"files"`
+}
+
+type reportTotals struct {
+	Files      int `json:"files"`
+	Failed     int `json:"failed"`
+	Parsed     int `json:"parsed"`
+	Inserted   int `json:"inserted"`
+	Invalid    int `json:"invalid"`
+	Oversized  int `json:"oversized"`
+	Filtered   int `json:"filtered"`
+	Duplicates int `json:"duplicates"`
+	Deduped    int `json:"deduped"`
+	Unchanged  int `json:"unchanged_docs"`
+	Warnings   int `json:"warnings"`
+}
+
+type reportFile struct {
+	File       string   `json:"file"`
+	Collection string   `json:"collection,omitempty"`
+	Status     string   `json:"status"`
+	Parsed     int      `json:"parsed"`
+	Inserted   int      `json:"inserted"`
+	Invalid    int      `json:"invalid"`
+	Oversized  int      `json:"oversized"`
+	Filtered   int      `json:"filtered"`
+	Duplicates int      `json:"duplicates"`
+	Deduped    int      `json:"deduped"`
+	Unchanged  int      `json:"unchanged_docs"`
+	DurationMS int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+	Warnings   []string `json:"warnings"`
+}
+
+// saveReport 沒有 --report 時什麼都不做；寫入失敗只記錄，不影響結束代碼
+func saveReport(cfg config, started time.Time, results []importer.FileResult) {
+	if cfg.ReportFile == "" {
+		return
+	}
+	if err := writeReport(cfg.ReportFile, cfg, started, results); err != nil {
+		logger.Error(fmt.Sprintf("❌ Failed to write report %s: %v", cfg.ReportFile, err), "report", cfg.ReportFile, errAttr(err))
+		return
+	}
+	logger.Debug(fmt.Sprintf("📊 Wrote report to %s", cfg.ReportFile), "report", cfg.ReportFile)
+}
+
+// replaceResult 重新匯入的檔案取代上次的結果，新檔案加在最後
+func replaceResult(results []importer.FileResult, res importer.FileResult) []importer.FileResult {
+	for k := range results {
+		if results[k].File == res.File {
+			results[k] = res
+			return results
+		}
+	}
+	return append(results, res)
+}
+
+// writeReport 寫出 --report；先寫暫存檔再 rename，CI 不會讀到寫一半的檔案
+func writeReport(path string, cfg config, started time.Time, results []importer.FileResult) error {
+	data, err := json.MarshalIndent(buildReport(cfg, started, time.Now(), results), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// buildReport --report 與 serve 的 GET /status 共用的內容
+func buildReport(cfg config, started, finished time.Time, results []importer.FileResult) report {
+	rep := report{
+		StartedAt:  started.UTC(),
+		FinishedAt: finished.UTC(),
+		DurationMS: finished.Sub(started).Milliseconds(),
+		DB:         cfg.DB,
+		Strategy:   cfg.Import.Strategy,
+		Status:     "ok",
+		Files:      make([]reportFile, 0, len(results)),
+	}
+	for _, r := range results {
+		f := newReportFile(r)
+		if r.Err != nil {
+			rep.Totals.Failed++
+			rep.Status = "failed"
+		}
+		rep.Totals.Files++
+		rep.Totals.Parsed += r.Parsed
+		rep.Totals.Inserted += r.Docs
+		rep.Totals.Invalid += r.Invalid
+		rep.Totals.Oversized += r.Oversized
+		rep.Totals.Filtered += r.Filtered
+		rep.Totals.Duplicates += r.Duplicates
+		rep.Totals.Deduped += r.Deduped
+		rep.Totals.Unchanged += r.UnchangedDocs
+		rep.Totals.Warnings += len(r.Warnings)
+		rep.Files = append(rep.Files, f)
+	}
+	return rep
+}
+
+// newReportFile --report 與 --summary-format json 的單一檔案結果
+func newReportFile(r importer.FileResult) reportFile {
+	f := reportFile{
+		File:       r.File,
+		Collection: r.Namespace(),
+		Status:     r.Status(),
+		Parsed:     r.Parsed,
+		Inserted:   r.Docs,
+		Invalid:    r.Invalid,
+		Oversized:  r.Oversized,
+		Filtered:   r.Filtered,
+		Duplicates: r.Duplicates,
+		Deduped:    r.Deduped,
+		Unchanged:  r.UnchangedDocs,
+		DurationMS: r.Duration.Milliseconds(),
+		Warnings:   r.Warnings,
+	}
+	if f.Warnings == nil {
+		f.Warnings = []string{}
+	}
+	if r.Err != nil {
+		f.Error = r.Err.Error()
+	}
+	return f
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"github.com/hayletdomybest/mongo-tools/internal/cron"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scheduleStatus --schedule-status 的內容，每次開始與結束時更新
+type scheduleStatus struct {
+	Schedule string       `json:"schedule"`
+	Running  bool         `json:"running"`
+	NextRun  *time.Time   `json:"next_run,omitempty"`
+	Runs     int          `json:"runs"`
+	Skipped  int          `json:"skipped"` // 前一次還在執行而略過的排程
+	LastRun  *scheduleRun `json:"last_run,omitempty"`
+}
+
+type scheduleRun struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DurationMS int64        `json:"duration_ms"`
+	Status     string       `json:"status"` // succeeded、failed 或 interrupted
+	ExitCode   int          `json:"exit_code"`
+	Totals     reportTotals `json:"totals"`
+}
+
+// runSchedule 依 --schedule 重複執行匯入（或 --plan），直到 Ctrl+C / SIGTERM。
+// 同一時間只會有一次匯入；執行時間超過排程間隔時，錯過的排程不補跑，記錄在 skipped
+func runSchedule(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, interrupted func() bool) int {
+	sched := cfg.Schedule
+	status := scheduleStatus{Schedule: sched.String()}
+	next := sched.Next(time.Now())
+	for {
+		nextUTC := next.UTC()
+		status.NextRun = &nextUTC
+		saveScheduleStatus(cfg, status)
+		logger.Info(fmt.Sprintf("🕒 Next import at %s (%s)", next.Format(time.RFC3339), sched), "next_run", next.Format(time.RFC3339), "schedule", sched.String())
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			status.NextRun = nil
+			saveScheduleStatus(cfg, status)
+			logger.Info("👋 Stopped the schedule.")
+			return exitOK
+		case <-timer.C:
+		}
+
+		status.Running, status.NextRun = true, nil
+		saveScheduleStatus(cfg, status)
+		started := time.Now()
+		var results []importer.FileResult
+		var code int
+		if cfg.Plan != nil {
+			results, code = runPlan(ctx, client, clientOpts, cfg, interrupted)
+		} else {
+			results, code = runImport(ctx, client, clientOpts, cfg, interrupted)
+		}
+		finished := time.Now()
+
+		run := &scheduleRun{
+			StartedAt:  started.UTC(),
+			FinishedAt: finished.UTC(),
+			DurationMS: finished.Sub(started).Milliseconds(),
+			Status:     outcomeSucceeded,
+			ExitCode:   code,
+			Totals:     buildReport(cfg, started, finished, results).Totals,
+		}
+		switch code {
+		case exitOK:
+		case exitInterrupted:
+			run.Status = outcomeInterrupted
+		default:
+			run.Status = outcomeFailed
+		}
+		status.Running, status.LastRun = false, run
+		status.Runs++
+		if interrupted() || ctx.Err() != nil {
+			saveScheduleStatus(cfg, status)
+			return code
+		}
+
+		next = sched.Next(finished)
+		if missed := missedRuns(sched, started, finished); missed > 0 {
+			status.Skipped += missed
+			logger.Warn(fmt.Sprintf("⏭️  The import took %s; skipped %d scheduled runs that came due while it was running", finished.Sub(started).Round(time.Second), missed),
+				"skipped", missed, "duration_ms", run.DurationMS)
+		}
+	}
+}
+
+// missedRuns 在 (started, finished) 之間到期的排程數，最多算到 1000
+func missedRuns(sched *cron.Schedule, started, finished time.Time) int {
+	n := 0
+	for t := sched.Next(started); !t.IsZero() && t.Before(finished) && n < 1000; t = sched.Next(t) {
+		n++
+	}
+	return n
+}
+
+// saveScheduleStatus 沒有 --schedule-status 時什麼都不做；先寫暫存檔再 rename，寫入失敗只記錄
+func saveScheduleStatus(cfg config, status scheduleStatus) {
+	if cfg.ScheduleStatus == "" {
+		return
+	}
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err == nil {
+		tmp := cfg.ScheduleStatus + ".tmp"
+		if err = os.WriteFile(tmp, append(data, '\n'), 0o644); err == nil {
+			err = os.Rename(tmp, cfg.ScheduleStatus)
+		}
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Failed to write schedule status %s: %v", cfg.ScheduleStatus, err), "file", cfg.ScheduleStatus, errAttr(err))
+	}
+}
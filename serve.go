@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	serveQueueSize = 100  // 等待執行的 job 上限，滿了回 503
+	serveMaxJobs   = 1000 // 保留狀態的 job 數，超過時丟掉最舊的已結束 job
+)
+
+// serveJob POST /import 建立的 job；GET /status/<id> 回傳它的 JSON
+type serveJob struct {
+	ID         string     `json:"job"`
+	Status     string     `json:"status"` // queued、running、succeeded、failed 或 cancelled（serve 停止時還沒開始）
+	Source     string     `json:"source"` // 上傳的檔名或 --path 之下的路徑
+	DB         string     `json:"db"`
+	Collection string     `json:"collection,omitempty"`
+	Strategy   string     `json:"strategy"`
+	Submitted  time.Time  `json:"submitted_at"`
+	Started    *time.Time `json:"started_at,omitempty"`
+	Finished   *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	Report     *report    `json:"report,omitempty"`
+
+	path   string // 實際匯入的檔案或目錄
+	upload string // 上傳檔案的暫存目錄，job 結束後刪除
+}
+
+// jobRequest POST /import 的 JSON body 或 multipart 欄位；沒給的值沿用啟動 serve 時的旗標
+type jobRequest struct {
+	Path       string `json:"path"`
+	DB         string `json:"db"`
+	Collection string `json:"collection"`
+	Strategy   string `json:"strategy"`
+}
+
+// server serve 模式的狀態：job 依序由單一 worker 執行，避免同時清空同一個 collection
+type server struct {
+	client     *mongo.Client
+	clientOpts *options.ClientOptions
+	cfg        config
+	tmpDir     string
+
+	mu    sync.Mutex
+	jobs  map[string]*serveJob
+	order []string // 建立順序，清理舊 job 用
+	queue chan *serveJob
+}
+
+// runServe 提供 POST /import 與 GET /status/<job>，直到 Ctrl+C / SIGTERM
+func runServe(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config) int {
+	if _, err := prodMatch(clientOpts, nil); err != nil {
+		fatal(fmt.Sprintf("Invalid PROD_PATTERN: %v", err), errAttr(err))
+	}
+	tmpDir, err := os.MkdirTemp("", "mongo-tools-serve-")
+	if err != nil {
+		fatal(fmt.Sprintf("❌ Failed to create the upload directory: %v", err), errAttr(err))
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := &server{client: client, clientOpts: clientOpts, cfg: cfg, tmpDir: tmpDir, jobs: map[string]*serveJob{}, queue: make(chan *serveJob, serveQueueSize)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/import", s.handleImport)
+	mux.HandleFunc("/status/", s.handleStatus)
+
+	ln, err := net.Listen("tcp", cfg.ServeAddr)
+	if err != nil {
+		fatal(fmt.Sprintf("Failed to listen on %s: %v", cfg.ServeAddr, err), "listen", cfg.ServeAddr, errAttr(err))
+	}
+	srv := &http.Server{Handler: s.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+	// Serve 意外結束時停止整個 serve，而不是留下不再接受 request 的 worker
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	var serveErr error
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			serveErr = err
+			logger.Error(fmt.Sprintf("❌ Stopped serving on %s: %v", ln.Addr(), err), "listen", ln.Addr().String(), errAttr(err))
+			stop()
+		}
+	}()
+	if cfg.Path != "" {
+		logger.Info(fmt.Sprintf("🛰️  Serving imports on http://%s (paths under %s)", ln.Addr(), cfg.Path), "listen", ln.Addr().String(), "root", cfg.Path)
+	} else {
+		logger.Info(fmt.Sprintf("🛰️  Serving imports on http://%s (uploads only)", ln.Addr()), "listen", ln.Addr().String())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case job := <-s.queue:
+				if ctx.Err() != nil { // select 在兩者都就緒時隨機選一個
+					s.cancelJob(job)
+					return
+				}
+				s.run(ctx, job)
+			}
+		}
+	}()
+
+	<-ctx.Done()
+	shutdown, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdown)
+	<-served
+	<-done
+	s.cancelQueued()
+	if serveErr != nil {
+		return exitFailure
+	}
+	logger.Info("👋 Stopped serving.")
+	return exitOK
+}
+
+// cancelQueued serve 停止時把還在佇列中的 job 標為 cancelled，不會一直停在 queued
+func (s *server) cancelQueued() {
+	for {
+		select {
+		case job := <-s.queue:
+			s.cancelJob(job)
+		default:
+			return
+		}
+	}
+}
+
+func (s *server) cancelJob(job *serveJob) {
+	s.removeUpload(job)
+	s.update(job, func() {
+		t := time.Now().UTC()
+		job.Status, job.Finished, job.Error = "cancelled", &t, "serve stopped before the job started"
+	})
+	logger.Warn(fmt.Sprintf("⚠️  Cancelled job %s: %s", job.ID, job.Source), "job", job.ID, "source", job.Source)
+}
+
+// isLoopback addr 只在本機監聽（127.0.0.1、::1 或 localhost）；沒有 host 的 :8080 監聽所有介面
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorize 有 --serve-token 時要求 Authorization: Bearer <token>
+func (s *server) authorize(next http.Handler) http.Handler {
+	if s.cfg.ServeToken == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.cfg.ServeToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleImport POST /import：multipart/form-data 的 file（上傳）或 path（--path 之下的檔案或目錄），
+// 也可以是 JSON body {"path": ...}；另外可指定 db、collection 與 strategy。回傳 202 與 job id
+func (s *server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	job := &serveJob{ID: newJobID(), Status: "queued", Submitted: time.Now().UTC()}
+	// 上傳超過上限時讀取失敗，回 400
+	r.Body = http.MaxBytesReader(w, r.Body, s.cfg.ServeMaxUpload)
+	var req jobRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "multipart/form-data":
+		if err := s.readMultipart(r, job, &req); err != nil {
+			s.removeUpload(job)
+			writeJSONError(w, bodyErrorStatus(err), err.Error())
+			return
+		}
+	case "application/json":
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, bodyErrorStatus(err), fmt.Sprintf("invalid JSON body: %v", err))
+			return
+		}
+	default:
+		writeJSONError(w, http.StatusUnsupportedMediaType, "send multipart/form-data or application/json")
+		return
+	}
+
+	if err := s.resolve(job, req); err != nil {
+		s.removeUpload(job)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	select {
+	case s.queue <- job:
+		s.jobs[job.ID] = job
+		s.order = append(s.order, job.ID)
+		s.prune()
+		s.mu.Unlock()
+	default:
+		s.mu.Unlock()
+		s.removeUpload(job)
+		writeJSONError(w, http.StatusServiceUnavailable, fmt.Sprintf("too many queued jobs (%d); try again later", serveQueueSize))
+		return
+	}
+	logger.Info(fmt.Sprintf("📥 Queued job %s: %s", job.ID, job.Source), "job", job.ID, "source", job.Source, "db", job.DB, "collection", job.Collection, "strategy", job.Strategy)
+	writeJSON(w, http.StatusAccepted, map[string]string{"job": job.ID, "status": "queued", "status_url": "/status/" + job.ID})
+}
+
+// bodyErrorStatus body 超過 --max-upload-mb 時回 413，其他讀取錯誤回 400
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// readMultipart 逐段讀取 multipart body，上傳的檔案直接寫到暫存目錄，不整個讀進記憶體
+func (s *server) readMultipart(r *http.Request, job *serveJob, req *jobRequest) error {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+	fields := map[string]*string{"path": &req.Path, "db": &req.DB, "collection": &req.Collection, "strategy": &req.Strategy}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := part.FormName()
+		if name == "file" {
+			if job.upload != "" {
+				return errors.New("only one file can be uploaded per job")
+			}
+			base := filepath.Base(part.FileName())
+			if base == "." || base == string(filepath.Separator) {
+				return errors.New("the uploaded file needs a file name; it determines the format and (without collection) the target collection")
+			}
+			job.upload = filepath.Join(s.tmpDir, job.ID)
+			if err := os.Mkdir(job.upload, 0o700); err != nil {
+				return err
+			}
+			job.path = filepath.Join(job.upload, base)
+			f, err := os.Create(job.path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, part)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to save %s: %w", base, err)
+			}
+			job.Source = base
+			continue
+		}
+		if field, ok := fields[name]; ok {
+			b, err := io.ReadAll(io.LimitReader(part, 4096))
+			if err != nil {
+				return err
+			}
+			*field = strings.TrimSpace(string(b))
+		}
+	}
+}
+
+// resolve 檢查 request 並填入 job 的來源與設定；path 只能指向 --path 之下
+func (s *server) resolve(job *serveJob, req jobRequest) error {
+	switch {
+	case job.upload != "" && req.Path != "":
+		return errors.New("send either file or path, not both")
+	case job.upload == "" && req.Path == "":
+		return errors.New("missing file or path")
+	case req.Path != "":
+		if s.cfg.Path == "" {
+			return errors.New("path imports are disabled; start serve with --path <dir>")
+		}
+		job.path = filepath.Join(s.cfg.Path, filepath.Clean("/"+req.Path))
+		if _, err := os.Stat(job.path); err != nil {
+			return fmt.Errorf("path %s: %v", req.Path, errors.Unwrap(err))
+		}
+		job.Source = req.Path
+	}
+
+	job.DB, job.Collection, job.Strategy = s.cfg.Import.DB, s.cfg.Import.Collection, s.cfg.Import.Strategy
+	if req.DB != "" {
+		job.DB = req.DB
+	}
+	if req.Collection != "" {
+		job.Collection = req.Collection
+	}
+	if req.Strategy != "" {
+		job.Strategy = req.Strategy
+	}
+	if deletes(job.Strategy) && !s.cfg.Yes {
+		return fmt.Errorf("%s deletes documents and is disabled; start serve with --yes to allow it", job.Strategy)
+	}
+	return nil
+}
+
+// prune 超過 serveMaxJobs 時丟掉最舊的已結束 job；呼叫端持有 s.mu
+func (s *server) prune() {
+	for n := 0; len(s.jobs) > serveMaxJobs && n < len(s.order); {
+		id := s.order[n]
+		if job := s.jobs[id]; job.Finished != nil {
+			delete(s.jobs, id)
+			s.order = append(s.order[:n], s.order[n+1:]...)
+			continue
+		}
+		n++
+	}
+}
+
+// handleStatus GET /status/<job>
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeJSONError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/status/")
+	s.mu.Lock()
+	job, ok := s.jobs[id]
+	var snapshot serveJob
+	if ok {
+		snapshot = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown job %q", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// run 執行一個 job：每個 job 有自己的 importer，設定為啟動時的旗標加上 request 的覆蓋值
+func (s *server) run(ctx context.Context, job *serveJob) {
+	defer s.removeUpload(job)
+	started := time.Now()
+	s.update(job, func() {
+		t := started.UTC()
+		job.Status, job.Started = "running", &t
+	})
+	log := logger.With("job", job.ID)
+	log.Info(fmt.Sprintf("🚀 Running job %s: %s", job.ID, job.Source), "source", job.Source)
+
+	results, err := s.importJob(ctx, job, log)
+
+	cfg := s.cfg
+	cfg.DB, cfg.Import.Strategy = job.DB, job.Strategy
+	finished := time.Now()
+	s.update(job, func() {
+		t := finished.UTC()
+		job.Finished = &t
+		job.Status = "succeeded"
+		if results != nil {
+			rep := buildReport(cfg, started, finished, results)
+			job.Report = &rep
+		}
+		switch {
+		case err != nil:
+			job.Status, job.Error = "failed", err.Error()
+		case countFailed(results) > 0:
+			job.Status, job.Error = "failed", fmt.Sprintf("%d of %d files failed to import", countFailed(results), len(results))
+		}
+	})
+	outcome := outcomeSucceeded
+	if job.Status == "failed" {
+		outcome = outcomeFailed
+	}
+	notifyRun(ctx, cfg, started, results, outcome, job.ID, job.Error)
+	if job.Status == "failed" {
+		log.Error(fmt.Sprintf("❌ Job %s failed: %s", job.ID, job.Error), "error", job.Error, "duration_ms", finished.Sub(started).Milliseconds())
+		return
+	}
+	log.Info(fmt.Sprintf("✅ Job %s completed", job.ID), "files", len(results), "duration_ms", finished.Sub(started).Milliseconds())
+}
+
+func (s *server) importJob(ctx context.Context, job *serveJob, log *slog.Logger) ([]importer.FileResult, error) {
+	opts := s.cfg.Import
+	opts.DB, opts.Collection, opts.Strategy = job.DB, job.Collection, job.Strategy
+	opts.Logger = log
+	imp, err := importer.New(ctx, s.client, opts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid import options: %v", err)
+	}
+	defer imp.Close()
+
+	// 沒有終端機可以確認；看起來是正式環境時沒有 --allow-prod 一律拒絕清空或刪除
+	if opts.UsesStrategy(importer.StrategyTruncate, importer.StrategyDelete) && !s.cfg.AllowProd {
+		planned, err := imp.Targets(ctx, job.path)
+		if err != nil {
+			return nil, err
+		}
+		var targets []namespace
+		for _, t := range planned {
+			if deletes(t.Strategy) {
+				targets = append(targets, namespace{t.DB, t.Collection})
+			}
+		}
+		if matched, _ := prodMatch(s.clientOpts, targets); matched != "" {
+			return nil, fmt.Errorf("refusing to %s: it looks like production (matched %q); start serve with --allow-prod to proceed", deleteAction(opts), matched)
+		}
+	}
+
+	results, err := imp.ImportPath(ctx, job.path)
+	if err != nil {
+		return nil, err
+	}
+	printSummary(results, s.cfg)
+	if opts.ViewsFile != "" {
+		if _, err := imp.CreateViews(ctx); err != nil {
+			return results, fmt.Errorf("some views could not be created: %v", err)
+		}
+	}
+	if ctx.Err() != nil {
+		return results, errors.New("interrupted")
+	}
+	return results, nil
+}
+
+func (s *server) update(job *serveJob, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+}
+
+func (s *server) removeUpload(job *serveJob) {
+	if job.upload != "" {
+		os.RemoveAll(job.upload)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
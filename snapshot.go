@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/exporter"
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// snapshot 目錄內的檔案：data/ 是 import 可以直接讀回的 .bson 與 sidecar，放在子目錄裡
+// snapshot.json 與 views.json 才不會被當成 collection 匯入
+const (
+	snapshotInfoFile  = "snapshot.json"
+	snapshotViewsFile = "views.json"
+	snapshotDataDir   = "data"
+	snapshotTimeFmt   = "20060102-150405"
+)
+
+// snapshotInfo snapshot.json；所有 collection 與 view 都匯出成功後才寫出，沒有它的目錄不是完整的 snapshot
+type snapshotInfo struct {
+	DB          string               `json:"db"`
+	CreatedAt   time.Time            `json:"created_at"`
+	DurationMS  int64                `json:"duration_ms"`
+	Collections []snapshotCollection `json:"collections"`
+	Views       []string             `json:"views"`
+}
+
+type snapshotCollection struct {
+	Name string `json:"name"`
+	Docs int    `json:"docs"`
+}
+
+// snapshot --dir 底下的一個 snapshot
+type snapshot struct {
+	Name string
+	Dir  string
+	Info snapshotInfo
+}
+
+// runSnapshot 把 --db 的每個 collection（含索引與建立選項）與 view 的定義存到 <dir>/<db>-<UTC 時間>/；
+// 任何一個 collection 失敗時刪除這個不完整的 snapshot，成功後依 --keep / --keep-days 刪除舊的 snapshot
+func runSnapshot(ctx context.Context, client *mongo.Client, cfg config) int {
+	started := time.Now()
+	name := fmt.Sprintf("%s-%s", cfg.DB, started.UTC().Format(snapshotTimeFmt))
+	dir := filepath.Join(cfg.SnapshotDir, name)
+	if err := os.MkdirAll(cfg.SnapshotDir, 0o755); err != nil {
+		fatal(fmt.Sprintf("❌ Failed to create snapshot directory %s: %v", cfg.SnapshotDir, err), "dir", cfg.SnapshotDir, errAttr(err))
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		fatal(fmt.Sprintf("❌ Failed to create snapshot %s: %v", dir, err), "snapshot", dir, errAttr(err))
+	}
+	logger.Info(fmt.Sprintf("📸 Taking snapshot of %s → %s", cfg.DB, dir), "db", cfg.DB, "snapshot", dir)
+
+	// snapshot 一律是整個 database 的 bson，加上 import 讀取的 indexes.json / options.json
+	cfg.Export.Logger, cfg.Export.Format, cfg.Export.Sidecars, cfg.Export.Collection = logger, exporter.FormatBSON, true, ""
+	exp, err := exporter.New(client, cfg.Export)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid export options: %v", err), errAttr(err))
+	}
+	results, err := exp.ExportDatabase(ctx, filepath.Join(dir, snapshotDataDir))
+	var views []string
+	if err == nil {
+		views, err = exp.ExportViews(ctx, filepath.Join(dir, snapshotViewsFile))
+	}
+	info := snapshotInfo{DB: cfg.DB, CreatedAt: started.UTC(), Collections: make([]snapshotCollection, 0, len(results)), Views: views}
+	failed, docs := 0, 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			continue
+		}
+		info.Collections = append(info.Collections, snapshotCollection{Name: r.Collection, Docs: r.Docs})
+		docs += r.Docs
+	}
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d collections failed to export", failed)
+	}
+	if err == nil {
+		info.DurationMS = time.Since(started).Milliseconds()
+		err = writeSnapshotInfo(dir, info)
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Snapshot failed: %v", err), "snapshot", dir, errAttr(err))
+		if rerr := os.RemoveAll(dir); rerr != nil {
+			logger.Warn(fmt.Sprintf("⚠️  Failed to remove the incomplete snapshot %s: %v", dir, rerr), "snapshot", dir, errAttr(rerr))
+		}
+		return exitFailure
+	}
+	logger.Info(fmt.Sprintf("✅ Snapshot %s: %d collections, %d docs, %d views", name, len(info.Collections), docs, len(views)),
+		"snapshot", dir, "collections", len(info.Collections), "docs", docs, "views", len(views), "duration_ms", info.DurationMS)
+	if cfg.SnapshotKeep > 0 || cfg.SnapshotKeepDays > 0 {
+		if err := pruneSnapshots(cfg.SnapshotDir, cfg.DB, cfg.SnapshotKeep, cfg.SnapshotKeepDays, started); err != nil {
+			logger.Error(fmt.Sprintf("❌ Failed to prune old snapshots: %v", err), "dir", cfg.SnapshotDir, errAttr(err))
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// pruneSnapshots 刪除 db 的舊 snapshot：只有 keep 個最新的與 keepDays 天內的會保留（兩者都給時保留其中任一條件成立的）；
+// 0 表示不依這個條件保留。沒有 snapshot.json 的目錄可能正在寫入，不會被刪除
+func pruneSnapshots(root, db string, keep, keepDays int, now time.Time) error {
+	snaps, err := listSnapshots(root, db)
+	if err != nil {
+		return err
+	}
+	cutoff := now.AddDate(0, 0, -keepDays)
+	var errs []error
+	pruned := 0
+	for n, s := range snaps {
+		newest := keep > 0 && n >= len(snaps)-keep
+		recent := keepDays > 0 && !s.Info.CreatedAt.Before(cutoff)
+		if newest || recent {
+			continue
+		}
+		if err := os.RemoveAll(s.Dir); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", s.Name, err))
+			continue
+		}
+		pruned++
+		logger.Info(fmt.Sprintf("🧹 Removed snapshot %s taken %s", s.Name, s.Info.CreatedAt.Local().Format(time.RFC3339)),
+			"snapshot", s.Dir, "created_at", s.Info.CreatedAt)
+	}
+	logger.Debug(fmt.Sprintf("🧹 Pruned %d of %d snapshots of %s", pruned, len(snaps), db), "db", db, "pruned", pruned, "snapshots", len(snaps))
+	return errors.Join(errs...)
+}
+
+// writeSnapshotInfo 先寫暫存檔再 rename，中斷時不會留下看起來完整的 snapshot
+func writeSnapshotInfo(dir string, info snapshotInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, snapshotInfoFile)
+	if err := os.WriteFile(path+".tmp", append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func readSnapshot(dir string) (snapshot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotInfoFile))
+	if err != nil {
+		return snapshot{}, err
+	}
+	s := snapshot{Name: filepath.Base(dir), Dir: dir}
+	if err := json.Unmarshal(data, &s.Info); err != nil {
+		return snapshot{}, fmt.Errorf("invalid %s: %v", filepath.Join(dir, snapshotInfoFile), err)
+	}
+	return s, nil
+}
+
+// listSnapshots root 底下 db 的完整 snapshot，由舊到新
+func listSnapshots(root, db string) ([]snapshot, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []snapshot
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), db+"-") {
+			continue
+		}
+		s, err := readSnapshot(filepath.Join(root, e.Name()))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // 進行中或失敗的 snapshot
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.Info.DB == db {
+			out = append(out, s)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].Info.CreatedAt.Before(out[b].Info.CreatedAt) })
+	return out, nil
+}
+
+// findSnapshot name 可以是 root 底下的名稱或 snapshot 目錄的路徑；空字串表示 db 最新的 snapshot
+func findSnapshot(root, db, name string) (snapshot, error) {
+	if name != "" {
+		dir := filepath.Join(root, name)
+		if _, err := os.Stat(dir); err != nil {
+			dir = name
+		}
+		s, err := readSnapshot(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return snapshot{}, fmt.Errorf("%s is not a complete snapshot", dir)
+		}
+		return s, err
+	}
+	snaps, err := listSnapshots(root, db)
+	if err != nil {
+		return snapshot{}, err
+	}
+	if len(snaps) == 0 {
+		return snapshot{}, fmt.Errorf("no snapshots of %s in %s", db, root)
+	}
+	return snaps[len(snaps)-1], nil
+}
+
+// runRestore 以 truncate 匯入 snapshot 的 data/ 並重建 view，--db 內 snapshot 有的 collection 會回到 snapshot 的內容；
+// --drop-extra 在全部成功後再刪除 snapshot 沒有的 collection 與 view
+func runRestore(ctx context.Context, client *mongo.Client, clientOpts *options.ClientOptions, cfg config, interrupted func() bool) int {
+	snap, err := findSnapshot(cfg.SnapshotDir, cfg.DB, cfg.Snapshot)
+	if err != nil {
+		fatal(fmt.Sprintf("❌ %v", err), "dir", cfg.SnapshotDir, "snapshot", cfg.Snapshot, errAttr(err))
+	}
+	if snap.Info.DB != cfg.DB {
+		logger.Warn(fmt.Sprintf("⚠️  Restoring snapshot %s of %s into %s", snap.Name, snap.Info.DB, cfg.DB),
+			"snapshot", snap.Dir, "snapshot_db", snap.Info.DB, "db", cfg.DB)
+	}
+	logger.Info(fmt.Sprintf("⏪ Restoring %s to snapshot %s taken %s", cfg.DB, snap.Name, snap.Info.CreatedAt.Local().Format(time.RFC3339)),
+		"db", cfg.DB, "snapshot", snap.Dir, "created_at", snap.Info.CreatedAt)
+
+	var extra []string
+	if cfg.DropExtra {
+		if extra, err = extraCollections(ctx, client.Database(cfg.DB), snap.Info, cfg.OpTimeout); err != nil {
+			fatal(fmt.Sprintf("❌ Failed to list collections in %s: %v", cfg.DB, err), "db", cfg.DB, errAttr(err))
+		}
+		targets := make([]namespace, 0, len(extra))
+		for _, name := range extra {
+			targets = append(targets, namespace{cfg.DB, name})
+		}
+		confirmDestructive(ctx, client, clientOpts, cfg, "drop these collections and views, which are not in snapshot "+snap.Name+", after the restore", targets)
+	}
+
+	cfg.Path = filepath.Join(snap.Dir, snapshotDataDir)
+	cfg.Import.Strategy = importer.StrategyTruncate
+	cfg.Import.ViewsFile = ""
+	if len(snap.Info.Views) > 0 {
+		cfg.Import.ViewsFile = filepath.Join(snap.Dir, snapshotViewsFile)
+	}
+	_, code := runImport(ctx, client, clientOpts, cfg, interrupted)
+	if code != exitOK || len(extra) == 0 {
+		return code
+	}
+	if failed := dropCollections(ctx, client.Database(cfg.DB), extra, cfg.OpTimeout); failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// extraCollections --db 內 snapshot 沒有的 collection 與 view，不含 system.*
+func extraCollections(ctx context.Context, db *mongo.Database, info snapshotInfo, timeout time.Duration) ([]string, error) {
+	keep := map[string]bool{}
+	for _, c := range info.Collections {
+		keep[c.Name] = true
+	}
+	for _, v := range info.Views {
+		keep[v] = true
+	}
+	listCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	names, err := db.ListCollectionNames(listCtx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var extra []string
+	for _, name := range names {
+		if !keep[name] && !strings.HasPrefix(name, "system.") {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return extra, nil
+}
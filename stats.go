@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// statsSnapshot stats --out 寫出、--compare 讀入的 JSON
+type statsSnapshot struct {
+	TakenAt     time.Time   `json:"taken_at"`
+	DB          string      `json:"db"`
+	Collections []collStats `json:"collections"`
+}
+
+// collStats 單一 collection 的大小；sharded collection 為所有 shard 的總和
+type collStats struct {
+	Collection     string           `json:"collection"`
+	Docs           int64            `json:"docs"`
+	AvgDocSize     int64            `json:"avg_doc_size"`
+	DataSize       int64            `json:"data_size"`
+	StorageSize    int64            `json:"storage_size"`
+	TotalIndexSize int64            `json:"total_index_size"`
+	IndexSizes     map[string]int64 `json:"index_sizes"`
+}
+
+// runStats 印出 --db 每個 collection 的文件數、平均文件大小、資料 / 儲存 / 索引大小；
+// --out 另存成 JSON，--compare 與之前存的結果比較（例如匯入前後）
+func runStats(ctx context.Context, client *mongo.Client, cfg config) int {
+	db := client.Database(cfg.DB)
+	names, err := statsCollections(ctx, db, cfg.Collection)
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Failed to list collections in %s: %v", cfg.DB, err), "db", cfg.DB, errAttr(err))
+		return exitFailure
+	}
+	snapshot := statsSnapshot{TakenAt: time.Now().UTC(), DB: cfg.DB, Collections: make([]collStats, 0, len(names))}
+	failed := 0
+	for _, name := range names {
+		s, err := readCollStats(ctx, db, name)
+		if err != nil {
+			logger.Error(fmt.Sprintf("❌ Failed to read stats of %s.%s: %v", cfg.DB, name, err), "collection", name, errAttr(err))
+			failed++
+			continue
+		}
+		snapshot.Collections = append(snapshot.Collections, s)
+	}
+
+	var before map[string]collStats
+	if cfg.StatsCompare != "" {
+		prev, err := loadStatsSnapshot(cfg.StatsCompare)
+		if err != nil {
+			logger.Error(fmt.Sprintf("❌ Failed to read %s: %v", cfg.StatsCompare, err), "file", cfg.StatsCompare, errAttr(err))
+			return exitFailure
+		}
+		before = map[string]collStats{}
+		for _, s := range prev.Collections {
+			before[s.Collection] = s
+		}
+		logger.Info(fmt.Sprintf("🔎 Comparing with %s taken at %s", cfg.StatsCompare, prev.TakenAt.Local().Format(time.RFC3339)),
+			"file", cfg.StatsCompare, "taken_at", prev.TakenAt)
+	}
+	printStats(snapshot, before, cfg.LogFormat == "json")
+
+	if cfg.StatsOut != "" {
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err == nil {
+			err = os.WriteFile(cfg.StatsOut, append(data, '\n'), 0o644)
+		}
+		if err != nil {
+			logger.Error(fmt.Sprintf("❌ Failed to write %s: %v", cfg.StatsOut, err), "file", cfg.StatsOut, errAttr(err))
+			return exitFailure
+		}
+		logger.Info(fmt.Sprintf("💾 Saved stats to %s; compare after the import with --compare %s", cfg.StatsOut, cfg.StatsOut), "file", cfg.StatsOut)
+	}
+	if failed > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// statsCollections --collection（逗號分隔），或 database 內所有一般 collection（略過 view 與 system.*）
+func statsCollections(ctx context.Context, db *mongo.Database, only string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(only, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) > 0 {
+		return names, nil
+	}
+	all, err := db.ListCollectionNames(ctx, bson.M{"type": "collection"})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range all {
+		if !strings.HasPrefix(name, "system.") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// readCollStats 以 $collStats 讀取 storageStats；sharded collection 每個 shard 各有一筆，加總後平均文件大小重新計算
+func readCollStats(ctx context.Context, db *mongo.Database, name string) (collStats, error) {
+	out := collStats{Collection: name, IndexSizes: map[string]int64{}}
+	cursor, err := db.Collection(name).Aggregate(ctx, bson.A{bson.M{"$collStats": bson.M{"storageStats": bson.M{}}}})
+	if err != nil {
+		return out, err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		// 數值依 server 版本可能是 int32、int64 或 double
+		var doc struct {
+			StorageStats struct {
+				Count          float64            `bson:"count"`
+				Size           float64            `bson:"size"`
+				StorageSize    float64            `bson:"storageSize"`
+				TotalIndexSize float64            `bson:"totalIndexSize"`
+				IndexSizes     map[string]float64 `bson:"indexSizes"`
+			} `bson:"storageStats"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return out, err
+		}
+		s := doc.StorageStats
+		out.Docs += int64(s.Count)
+		out.DataSize += int64(s.Size)
+		out.StorageSize += int64(s.StorageSize)
+		out.TotalIndexSize += int64(s.TotalIndexSize)
+		for index, size := range s.IndexSizes {
+			out.IndexSizes[index] += int64(size)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return out, err
+	}
+	if out.Docs > 0 {
+		out.AvgDocSize = out.DataSize / out.Docs
+	}
+	return out, nil
+}
+
+func loadStatsSnapshot(path string) (statsSnapshot, error) {
+	var s statsSnapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// printStats 印出每個 collection 一列與每個索引的大小；before 非 nil 時在數值後面加上變化量，
+// 之前有、現在沒有的 collection 列為 dropped
+func printStats(snapshot statsSnapshot, before map[string]collStats, structured bool) {
+	var total collStats
+	seen := map[string]bool{}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if !structured {
+		fmt.Fprintln(w, "\nCOLLECTION\tDOCS\tAVG DOC\tDATA\tSTORAGE\tINDEXES\tINDEX SIZE")
+	}
+	for _, s := range snapshot.Collections {
+		seen[s.Collection] = true
+		prev, had := before[s.Collection]
+		if structured {
+			attrs := []any{"collection", s.Collection, "docs", s.Docs, "avg_doc_size", s.AvgDocSize, "data_size", s.DataSize,
+				"storage_size", s.StorageSize, "indexes", len(s.IndexSizes), "total_index_size", s.TotalIndexSize, "index_sizes", s.IndexSizes}
+			if had {
+				attrs = append(attrs, "docs_delta", s.Docs-prev.Docs, "data_size_delta", s.DataSize-prev.DataSize,
+					"storage_size_delta", s.StorageSize-prev.StorageSize, "total_index_size_delta", s.TotalIndexSize-prev.TotalIndexSize)
+			} else if before != nil {
+				attrs = append(attrs, "new", true)
+			}
+			logger.Info("collection stats", attrs...)
+		} else {
+			name := s.Collection
+			if before != nil && !had {
+				name += " (new)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", name,
+				withDelta(s.Docs, prev.Docs, had, formatCount),
+				formatBytes(s.AvgDocSize),
+				withDelta(s.DataSize, prev.DataSize, had, formatBytes),
+				withDelta(s.StorageSize, prev.StorageSize, had, formatBytes),
+				len(s.IndexSizes),
+				withDelta(s.TotalIndexSize, prev.TotalIndexSize, had, formatBytes))
+		}
+		total.Docs += s.Docs
+		total.DataSize += s.DataSize
+		total.StorageSize += s.StorageSize
+		total.TotalIndexSize += s.TotalIndexSize
+	}
+	var dropped []string
+	for name := range before {
+		if !seen[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	sort.Strings(dropped)
+	for _, name := range dropped {
+		if structured {
+			logger.Info("collection stats", "collection", name, "dropped", true, "docs_delta", -before[name].Docs)
+		} else {
+			fmt.Fprintf(w, "%s (dropped)\t%s\t\t\t\t\t\n", name, withDelta(0, before[name].Docs, true, formatCount))
+		}
+	}
+	w.Flush()
+
+	if !structured {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nCOLLECTION\tINDEX\tSIZE")
+		for _, s := range snapshot.Collections {
+			indexes := make([]string, 0, len(s.IndexSizes))
+			for index := range s.IndexSizes {
+				indexes = append(indexes, index)
+			}
+			sort.Strings(indexes)
+			for _, index := range indexes {
+				prev, had := before[s.Collection].IndexSizes[index]
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.Collection, index, withDelta(s.IndexSizes[index], prev, had, formatBytes))
+			}
+		}
+		w.Flush()
+	}
+	logger.Info(fmt.Sprintf("\n📊 %d collections in %s, %d docs, %s data, %s storage, %s indexes", len(snapshot.Collections), snapshot.DB,
+		total.Docs, formatBytes(total.DataSize), formatBytes(total.StorageSize), formatBytes(total.TotalIndexSize)),
+		"db", snapshot.DB, "collections", len(snapshot.Collections), "docs", total.Docs, "data_size", total.DataSize,
+		"storage_size", total.StorageSize, "total_index_size", total.TotalIndexSize)
+}
+
+// withDelta 例如「1200 (+200)」；沒有比較對象或沒有變化時只有 now
+func withDelta(now, prev int64, had bool, format func(int64) string) string {
+	value := format(now)
+	if !had || now == prev {
+		return value
+	}
+	if now > prev {
+		return fmt.Sprintf("%s (+%s)", value, format(now-prev))
+	}
+	return fmt.Sprintf("%s (-%s)", value, format(prev-now))
+}
+
+func formatCount(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// formatBytes 以 1024 為單位，例如 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
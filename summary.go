@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+)
+
+// countFailed 計算失敗的檔案數
+func countFailed(results []importer.FileResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// --summary-format
+const (
+	summaryTable = "table" // 對齊的表格；--log-format json 時改為每個檔案一筆 log
+	summaryJSON  = "json"  // 每個檔案一個物件的 JSON array，欄位與 --report 的 files 相同
+	summaryCSV   = "csv"
+)
+
+// summaryToStdout json / csv 的結果表要能直接 pipe 給其他程式，log 改寫到 stderr
+func summaryToStdout(cmd string, cfg config) bool {
+	return (cmd == "import" || cmd == "serve") && (cfg.SummaryFormat == summaryJSON || cfg.SummaryFormat == summaryCSV)
+}
+
+// printSummary 匯入結束後印出每個檔案的結果表（檔案、collection、讀出與寫入的文件數、耗時、狀態），格式依 --summary-format
+func printSummary(results []importer.FileResult, cfg config) {
+	if len(results) == 0 {
+		return
+	}
+
+	var err error
+	switch {
+	case cfg.SummaryFormat == summaryJSON:
+		err = writeSummaryJSON(os.Stdout, results)
+	case cfg.SummaryFormat == summaryCSV:
+		err = writeSummaryCSV(os.Stdout, results)
+	case cfg.LogFormat == "json":
+		logSummary(results)
+	default:
+		err = writeSummaryTable(os.Stdout, results)
+	}
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Failed to write the summary: %v", err), errAttr(err))
+	}
+
+	var docs, invalid, failed int
+	for _, r := range results {
+		docs += r.Docs
+		invalid += r.Invalid
+		if r.Err != nil {
+			failed++
+		}
+	}
+	logger.Info(fmt.Sprintf("\n📊 %d files, %d docs, %d invalid skipped, %d failed", len(results), docs, invalid, failed),
+		"files", len(results), "count", docs, "invalid", invalid, "failed", failed)
+}
+
+func writeSummaryTable(out io.Writer, results []importer.FileResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nFILE\tCOLLECTION\tPARSED\tINSERTED\tINVALID\tDURATION\tSTATUS")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n",
+			displayName(r.File), r.Namespace(), r.Parsed, r.Docs, r.Invalid, r.Duration.Round(time.Millisecond), r.Status())
+	}
+	return w.Flush()
+}
+
+func writeSummaryJSON(out io.Writer, results []importer.FileResult) error {
+	files := make([]reportFile, 0, len(results))
+	for _, r := range results {
+		files = append(files, newReportFile(r))
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(files)
+}
+
+func writeSummaryCSV(out io.Writer, results []importer.FileResult) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"file", "collection", "parsed", "inserted", "invalid", "duration_ms", "status", "error"})
+	for _, r := range results {
+		var msg string
+		if r.Err != nil {
+			msg = r.Err.Error()
+		}
+		w.Write([]string{r.File, r.Namespace(), strconv.Itoa(r.Parsed), strconv.Itoa(r.Docs), strconv.Itoa(r.Invalid),
+			strconv.FormatInt(r.Duration.Milliseconds(), 10), r.Status(), msg})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// logSummary --log-format json 時每個檔案一筆 log
+func logSummary(results []importer.FileResult) {
+	for _, r := range results {
+		attrs := []any{"file", r.File, "collection", r.Namespace(), "parsed", r.Parsed, "count", r.Docs, "invalid", r.Invalid,
+			"oversized", r.Oversized, "filtered", r.Filtered, "duplicates", r.Duplicates, "deduped", r.Deduped, "unchanged_docs", r.UnchangedDocs, "duration_ms", r.Duration.Milliseconds(), "status", r.Status()}
+		if r.Err != nil {
+			attrs = append(attrs, errAttr(r.Err))
+		}
+		logger.Info("file summary", attrs...)
+	}
+}
+
+// printInterrupted 中斷後說明哪些檔案完成、哪些沒有，以及重新執行時會發生什麼
+func printInterrupted(results []importer.FileResult, opts importer.Options) {
+	var done, interrupted, notRun []string
+	for _, r := range results {
+		switch {
+		case r.Interrupted:
+			interrupted = append(interrupted, displayName(r.File))
+		case r.NotRun:
+			notRun = append(notRun, displayName(r.File))
+		case r.Err == nil:
+			done = append(done, displayName(r.File))
+		}
+	}
+	logger.Warn(fmt.Sprintf("⚠️  Interrupted: %d files completed, %d interrupted, %d not started", len(done), len(interrupted), len(notRun)),
+		"completed", done, "interrupted", interrupted, "not_run", notRun)
+	if len(interrupted) == 0 {
+		return
+	}
+	switch {
+	case opts.Resume:
+		logger.Info("⏯️  Run the same command again to continue the interrupted files from their checkpoints")
+	case opts.Transactional || opts.AtomicSwap:
+		logger.Info("🔒 Interrupted files were not applied; run again to import them")
+	case opts.Strategy == importer.StrategyTruncate:
+		logger.Warn("⚠️  Interrupted collections are partially loaded; run again to reload them (or use --resume next time)")
+	case opts.Strategy == importer.StrategyAppend:
+		logger.Warn("⚠️  Interrupted files were partially appended; running again appends those documents again (use --resume next time)")
+	default:
+		logger.Info("🔁 Interrupted files were partially written; running again is safe with " + opts.Strategy)
+	}
+}
+
+// displayName 表格中只顯示檔名
+func displayName(file string) string {
+	if file == importer.Stdin {
+		return "stdin"
+	}
+	return filepath.Base(file)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hayletdomybest/mongo-tools/exporter"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runTail 把 --collection 的 change stream 附加寫到 --out，直到 Ctrl+C / SIGTERM
+func runTail(ctx context.Context, client *mongo.Client, cfg config) int {
+	cfg.Export.Logger = logger
+	exp, err := exporter.New(client, cfg.Export)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid export options: %v", err), errAttr(err))
+	}
+
+	var w io.Writer = os.Stdout
+	if cfg.TailOut != "-" {
+		f, err := os.OpenFile(cfg.TailOut, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			fatal(fmt.Sprintf("❌ Failed to open %s: %v", cfg.TailOut, err), "file", cfg.TailOut, errAttr(err))
+		}
+		defer f.Close()
+		w = f
+	}
+
+	stats, err := exp.Tail(ctx, w, cfg.TailFullDocuments)
+	logger.Info(fmt.Sprintf("📊 %d written, %d skipped", stats.Written, stats.Skipped),
+		"collection", cfg.Collection, "written", stats.Written, "skipped", stats.Skipped)
+	if err != nil {
+		logger.Error(fmt.Sprintf("❌ Tail failed: %v", err), "collection", cfg.Collection, errAttr(err))
+		return exitFailure
+	}
+	logger.Info("👋 Stopped tailing.")
+	return exitOK
+}
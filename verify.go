@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hayletdomybest/mongo-tools/importer"
+	"github.com/hayletdomybest/mongo-tools/verify"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// runVerify 逐一比較 --path 的資料檔與對應 collection 的文件數（--hash 時還有內容雜湊，--drift 時還有匯入後被修改的文件）；
+// 不一致時回傳 exitDifferent，無法讀取時回傳 exitFailure
+func runVerify(ctx context.Context, client *mongo.Client, cfg config) int {
+	cfg.Import.Logger = logger
+	imp, err := importer.New(ctx, client, cfg.Import)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid verify options: %v", err), errAttr(err))
+	}
+	defer imp.Close()
+	targets, err := imp.Targets(ctx, cfg.Path)
+	if err != nil {
+		fatal(fmt.Sprintf("Invalid JSON_PATH: %v", err), "path", cfg.Path, errAttr(err))
+	}
+
+	structured := cfg.LogFormat == "json"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if !structured {
+		fmt.Fprintln(w, "\nFILE\tCOLLECTION\tFILE DOCS\tCOLLECTION DOCS\tSTATUS")
+	}
+	mismatched, failed := 0, 0
+	var drifted []importer.DriftResult
+	var driftedNS []string
+	for _, t := range targets {
+		ns := t.DB + "." + t.Collection
+		coll := client.Database(t.DB).Collection(t.Collection)
+		res, err := verifyFile(ctx, imp, coll, t.File, cfg.Verify)
+		var drift importer.DriftResult
+		if err == nil && cfg.Drift {
+			drift, err = imp.Drift(ctx, coll)
+		}
+		status := "match"
+		switch {
+		case err != nil:
+			status = "error"
+			failed++
+		case !res.Match():
+			status = "mismatch"
+			mismatched++
+		case drift.Drifted > 0:
+			status = "drifted"
+			mismatched++
+		}
+		if drift.Drifted > 0 {
+			drifted, driftedNS = append(drifted, drift), append(driftedNS, ns)
+		}
+		if structured {
+			attrs := []any{"file", t.File, "collection", ns, "file_docs", res.FileDocs, "collection_docs", res.CollectionDocs, "status", status}
+			if cfg.Verify.Hash {
+				attrs = append(attrs, "file_hash", res.FileHash, "collection_hash", res.CollectionHash)
+			}
+			if cfg.Drift {
+				attrs = append(attrs, "drifted", drift.Drifted)
+			}
+			if err != nil {
+				attrs = append(attrs, errAttr(err))
+			}
+			logger.Info("verify result", attrs...)
+			continue
+		}
+		if err != nil {
+			status += ": " + err.Error()
+		} else if res.FileDocs == res.CollectionDocs && res.FileHash != res.CollectionHash {
+			status += " (content)"
+		} else if drift.Drifted > 0 {
+			status += fmt.Sprintf(" (%d of %d docs changed since import)", drift.Drifted, drift.Checked)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", displayName(t.File), ns, res.FileDocs, res.CollectionDocs, status)
+	}
+	w.Flush()
+	for n, d := range drifted {
+		ids := make([]string, len(d.IDs))
+		for k, id := range d.IDs {
+			ids[k] = fmt.Sprint(id)
+		}
+		logger.Warn(fmt.Sprintf("⚠️  %d docs in %s changed since they were imported, e.g. _id %s", d.Drifted, driftedNS[n], strings.Join(ids, ", ")),
+			"collection", driftedNS[n], "drifted", d.Drifted, "ids", ids)
+	}
+
+	logger.Info(fmt.Sprintf("\n📊 %d files, %d match, %d mismatch, %d failed", len(targets), len(targets)-mismatched-failed, mismatched, failed),
+		"files", len(targets), "mismatch", mismatched, "failed", failed)
+	switch {
+	case failed > 0:
+		return exitFailure
+	case mismatched > 0:
+		return exitDifferent
+	}
+	logger.Info("✅ All collections match their files.")
+	return exitOK
+}
+
+func verifyFile(ctx context.Context, imp *importer.Importer, coll *mongo.Collection, file string, opts verify.Options) (verify.Result, error) {
+	docs, err := imp.Documents(ctx, file)
+	if err != nil {
+		return verify.Result{}, err
+	}
+	defer docs.Close()
+	return verify.Collection(ctx, docs, coll, opts)
+}
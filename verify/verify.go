@@ -0,0 +1,172 @@
+// Package verify 檢查 collection 的內容是否與資料檔一致（文件數，以及選擇性的內容雜湊），
+// 供 CI 在 seed 之後確認資料正確。
+package verify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Source 逐筆提供資料檔的文件，讀完回傳 io.EOF；*importer.Documents 即符合
+type Source interface {
+	Next() (bson.M, error)
+}
+
+// Options 檢查設定
+type Options struct {
+	Hash   bool     // 除了文件數也比較內容雜湊；需要讀過整個 collection
+	Ignore []string // 計算雜湊時忽略的最上層欄位，例如匯入時加上的中繼欄位
+}
+
+// Result 單一檔案的檢查結果
+type Result struct {
+	FileDocs       int64
+	CollectionDocs int64
+	FileHash       string // Hash 時才有值
+	CollectionHash string
+}
+
+// Match 文件數相同，且（Hash 時）內容雜湊相同
+func (r Result) Match() bool {
+	return r.FileDocs == r.CollectionDocs && r.FileHash == r.CollectionHash
+}
+
+// Collection 讀完 src 後與 target 比較。雜湊與文件、欄位的順序無關；
+// 檔案中有沒有 _id 的文件時（匯入時由 server 產生），兩邊都不把 _id 算進雜湊
+func Collection(ctx context.Context, src Source, target *mongo.Collection, opts Options) (Result, error) {
+	var res Result
+	var withID, withoutID [][]byte
+	missingID := false
+	for {
+		doc, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		res.FileDocs++
+		if !opts.Hash {
+			continue
+		}
+		if _, ok := doc["_id"]; !ok {
+			missingID = true
+		}
+		h, err := docHash(doc, false, opts.Ignore)
+		if err != nil {
+			return res, err
+		}
+		withID = append(withID, h)
+		if h, err = docHash(doc, true, opts.Ignore); err != nil {
+			return res, err
+		}
+		withoutID = append(withoutID, h)
+	}
+
+	if !opts.Hash {
+		n, err := target.CountDocuments(ctx, bson.D{})
+		res.CollectionDocs = n
+		return res, err
+	}
+
+	fileHashes := withID
+	if missingID {
+		fileHashes = withoutID
+	}
+	res.FileHash = combine(fileHashes)
+
+	cursor, err := target.Find(ctx, bson.D{})
+	if err != nil {
+		return res, err
+	}
+	defer cursor.Close(ctx)
+	var hashes [][]byte
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return res, err
+		}
+		res.CollectionDocs++
+		h, err := docHash(doc, missingID, opts.Ignore)
+		if err != nil {
+			return res, err
+		}
+		hashes = append(hashes, h)
+	}
+	if err := cursor.Err(); err != nil {
+		return res, err
+	}
+	res.CollectionHash = combine(hashes)
+	return res, nil
+}
+
+// docHash 把欄位依名稱排序後以 BSON 編碼再取 SHA-256，欄位順序不影響結果；ignore 的最上層欄位不算進雜湊
+func docHash(doc bson.M, skipID bool, ignore []string) ([]byte, error) {
+	d := normalize(doc).(bson.D)
+	if skipID {
+		ignore = append([]string{"_id"}, ignore...)
+	}
+	for _, field := range ignore {
+		for k, e := range d {
+			if e.Key == field {
+				d = append(d[:k:k], d[k+1:]...)
+				break
+			}
+		}
+	}
+	b, err := bson.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+// normalize 子文件轉成依 key 排序的 bson.D，陣列保留原本的順序
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bson.M:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := make(bson.D, 0, len(v))
+		for _, k := range keys {
+			d = append(d, bson.E{Key: k, Value: normalize(v[k])})
+		}
+		return d
+	case bson.D:
+		m := make(bson.M, len(v))
+		for _, e := range v {
+			m[e.Key] = e.Value
+		}
+		return normalize(m)
+	case bson.A:
+		out := make(bson.A, len(v))
+		for i, x := range v {
+			out[i] = normalize(x)
+		}
+		return out
+	case []interface{}:
+		return normalize(bson.A(v))
+	}
+	return v
+}
+
+// combine 排序後串接再雜湊，文件的順序不影響結果
+func combine(hashes [][]byte) string {
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i], hashes[j]) < 0 })
+	h := sha256.New()
+	for _, x := range hashes {
+		h.Write(x)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}